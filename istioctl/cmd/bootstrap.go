@@ -0,0 +1,607 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	bootstrapv3 "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	agentconfig "istio.io/istio/pilot/cmd/pilot-agent/config"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/bootstrap"
+	"istio.io/istio/pkg/config/constants"
+	_ "istio.io/istio/pkg/config/xds" // Register the Envoy types used in the bootstrap
+	istioagent "istio.io/istio/pkg/istio-agent"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+const (
+	// The IPs of the rendered proxy, when there is no live pod to take them from.
+	renderPodIP  = "10.0.0.1"
+	renderHostIP = "10.0.0.2"
+
+	// proxyWorkingDir is the working directory of the agent, which relative paths of the proxy config
+	// are resolved against.
+	proxyWorkingDir = "/"
+)
+
+func bootstrapCmd() *cobra.Command {
+	bootstrapCmd := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Render and inspect the Envoy bootstrap of workloads",
+	}
+	bootstrapCmd.AddCommand(bootstrapRenderCmd())
+	return bootstrapCmd
+}
+
+func bootstrapRenderCmd() *cobra.Command {
+	var manifestFile, meshFile, templateFile, livePod, output string
+
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Renders the Envoy bootstrap of an injected pod or workload manifest",
+		Long: `Renders the Envoy bootstrap the Istio agent would generate for the istio-proxy container of a pod or
+workload manifest, without deploying it. The node metadata and bootstrap options are derived from the
+container environment, arguments and the pod annotations, as the agent does at startup. The bootstrap
+is validated against the Envoy API.
+
+The manifest must be injected, for example with istioctl kube-inject. Platform metadata, such as the GCP
+project, is not reproduced. Unless --diff is set, the pod and host IPs are placeholders.
+
+With --diff, the identity and IPs of the given running pod are used, its bootstrap template is read from
+the istio-proxy container, and the rendered bootstrap is compared to the one of its Envoy.`,
+		Example: `  # Render the bootstrap of a deployment
+  istioctl kube-inject -f deployment.yaml -o injected.yaml
+  istioctl x bootstrap render -f injected.yaml --template envoy_bootstrap.json
+
+  # Render the bootstrap of a gateway, using the mesh config mounted in the gateway pods
+  kubectl -n istio-system get cm istio -o jsonpath="{.data.mesh}" > /tmp/mesh.yaml
+  istioctl x bootstrap render -f gateway.yaml --meshConfigFile /tmp/mesh.yaml --template envoy_bootstrap.json
+
+  # Compare the bootstrap of an edited deployment to the bootstrap of one of its running pods
+  istioctl x bootstrap render -f injected.yaml --diff productpage-v1-7f44c4d57c-abcde.default`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			if manifestFile == "" {
+				c.Println(c.UsageString())
+				return fmt.Errorf("render requires the --filename of a pod or workload manifest")
+			}
+			if templateFile == "" && livePod == "" {
+				c.Println(c.UsageString())
+				return fmt.Errorf("render requires a bootstrap --template, or a pod to --diff against")
+			}
+			data, err := readFile(manifestFile)
+			if err != nil {
+				return err
+			}
+			pod, err := podFromManifest(data)
+			if err != nil {
+				return err
+			}
+			if pod.Namespace == "" {
+				pod.Namespace = handlers.HandleNamespace(namespace, defaultNamespace)
+			}
+			var meshContents string
+			if meshFile != "" {
+				b, err := ioutil.ReadFile(meshFile)
+				if err != nil {
+					return err
+				}
+				meshContents = string(b)
+			}
+
+			var live *corev1.Pod
+			if livePod != "" {
+				if live, err = getLivePod(livePod); err != nil {
+					return err
+				}
+				setPodIdentity(pod, live)
+			} else {
+				pod.Status.PodIP = renderPodIP
+				pod.Status.HostIP = renderHostIP
+			}
+
+			node, err := proxyNode(pod, meshContents)
+			if err != nil {
+				return err
+			}
+			if node.Metadata.ProxyConfig.CustomConfigFile != "" {
+				return fmt.Errorf("the proxy uses the custom bootstrap %s, which is not rendered",
+					node.Metadata.ProxyConfig.CustomConfigFile)
+			}
+			if templateFile == "" {
+				if templateFile, err = fetchBootstrapTemplate(live, node.Metadata.ProxyConfig); err != nil {
+					return err
+				}
+				defer os.Remove(templateFile)
+			}
+
+			out := &bytes.Buffer{}
+			if err := bootstrap.New(bootstrap.Config{Node: node}).WriteTo(templateFile, out); err != nil {
+				return fmt.Errorf("failed to render bootstrap: %v", err)
+			}
+			rendered, err := validateBootstrap(out.Bytes())
+			if err != nil {
+				return err
+			}
+
+			if live != nil {
+				envoy, err := liveBootstrap(live)
+				if err != nil {
+					return err
+				}
+				return diffBootstrap(c.OutOrStdout(), rendered, envoy)
+			}
+			jsonm := &jsonpb.Marshaler{Indent: "    "}
+			js, err := jsonm.MarshalToString(rendered)
+			if err != nil {
+				return err
+			}
+			if output == yamlOutput {
+				b, err := yaml.JSONToYAML([]byte(js))
+				if err != nil {
+					return err
+				}
+				js = string(b)
+			}
+			fmt.Fprintln(c.OutOrStdout(), js)
+			return nil
+		},
+	}
+
+	renderCmd.Flags().StringVarP(&manifestFile, "filename", "f", "",
+		"Injected pod or workload manifest, or - for stdin")
+	renderCmd.Flags().StringVar(&meshFile, "meshConfigFile", "",
+		"Mesh configuration file mounted in the proxy, as for gateways. Sidecars get their proxy config from the injection")
+	renderCmd.Flags().StringVar(&templateFile, "template", "",
+		"Bootstrap template file. Defaults to the template of the pod given with --diff")
+	renderCmd.Flags().StringVar(&livePod, "diff", "",
+		"Running pod, as [<type>/]<name>[.<namespace>], to compare the rendered bootstrap with")
+	renderCmd.Flags().StringVarP(&output, "output", "o", jsonOutput, "Output format: one of json|yaml")
+	return renderCmd
+}
+
+// podFromManifest returns the pod of a pod manifest, or the pod template of a workload manifest.
+func podFromManifest(data []byte) (*corev1.Pod, error) {
+	var meta metav1.TypeMeta
+	if err := yaml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	if meta.Kind == "Pod" {
+		pod := &corev1.Pod{}
+		if err := yaml.Unmarshal(data, pod); err != nil {
+			return nil, fmt.Errorf("failed to parse pod: %v", err)
+		}
+		return pod, nil
+	}
+
+	var workload struct {
+		metav1.ObjectMeta `json:"metadata"`
+		Spec              struct {
+			Template *corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(data, &workload); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", meta.Kind, err)
+	}
+	if workload.Spec.Template == nil {
+		return nil, fmt.Errorf("%s %s has no pod template", meta.Kind, workload.Name)
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: workload.Spec.Template.ObjectMeta,
+		Spec:       workload.Spec.Template.Spec,
+	}
+	if pod.Name == "" {
+		pod.Name = workload.Name
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = workload.Namespace
+	}
+	return pod, nil
+}
+
+func getLivePod(podflag string) (*corev1.Pod, error) {
+	podName, ns, err := getPodName(podflag)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubeClient(kubeconfig, configContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	return client.Kube().CoreV1().Pods(ns).Get(context.TODO(), podName, metav1.GetOptions{})
+}
+
+// setPodIdentity sets the identity of the running pod on the rendered pod, as well as the labels added
+// by the controller of the pod, such as pod-template-hash.
+func setPodIdentity(pod, live *corev1.Pod) {
+	pod.Name = live.Name
+	pod.Namespace = live.Namespace
+	pod.UID = live.UID
+	pod.Spec.NodeName = live.Spec.NodeName
+	pod.Status.PodIP = live.Status.PodIP
+	pod.Status.PodIPs = live.Status.PodIPs
+	pod.Status.HostIP = live.Status.HostIP
+	for k, v := range live.Labels {
+		if _, f := pod.Labels[k]; !f {
+			if pod.Labels == nil {
+				pod.Labels = map[string]string{}
+			}
+			pod.Labels[k] = v
+		}
+	}
+}
+
+// agentArgs are the pilot-agent proxy arguments.
+type agentArgs struct {
+	agentconfig.ProxyArgs
+	proxyType model.NodeType
+}
+
+func parseAgentArgs(args []string) (*agentArgs, error) {
+	a := &agentArgs{proxyType: model.SidecarProxy}
+	fs := pflag.NewFlagSet("pilot-agent", pflag.ContinueOnError)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	a.AttachFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s arguments: %v", inject.ProxyContainerName, err)
+	}
+	// The arguments are "proxy <type>"
+	if pos := fs.Args(); len(pos) > 1 {
+		a.proxyType = model.NodeType(pos[1])
+		if !model.IsApplicationNodeType(a.proxyType) {
+			return nil, fmt.Errorf("invalid proxy type %q", pos[1])
+		}
+	}
+	return a, nil
+}
+
+// proxyNode returns the node the agent of the istio-proxy container of the pod would generate the
+// bootstrap for.
+func proxyNode(pod *corev1.Pod, meshContents string) (*model.Node, error) {
+	var container *corev1.Container
+	for i, c := range pod.Spec.Containers {
+		if c.Name == inject.ProxyContainerName {
+			container = &pod.Spec.Containers[i]
+			break
+		}
+	}
+	if container == nil {
+		return nil, fmt.Errorf("pod %s has no %s container, inject it with istioctl kube-inject first",
+			pod.Name, inject.ProxyContainerName)
+	}
+	envs := containerEnv(pod, container)
+	envMap := map[string]string{}
+	for _, e := range envs {
+		kv := strings.SplitN(e, "=", 2)
+		envMap[kv[0]] = kv[1]
+	}
+	args := make([]string, 0, len(container.Args))
+	for _, arg := range container.Args {
+		args = append(args, expandEnv(arg, envMap))
+	}
+	a, err := parseAgentArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := a.Concurrency
+	if concurrency == 0 && a.proxyType == model.SidecarProxy {
+		concurrency = agentconfig.ConcurrencyForCPU(container.Resources.Limits.Cpu().MilliValue(),
+			container.Resources.Requests.Cpu().MilliValue())
+	}
+	proxyConfig, err := agentconfig.ProxyConfigFor(meshContents, pod.Annotations, a.ServiceCluster,
+		envMap["PROXY_CONFIG"], concurrency, a.proxyType == model.SidecarProxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proxy config: %v", err)
+	}
+	if a.TemplateFile != "" && proxyConfig.CustomConfigFile == "" {
+		proxyConfig.ProxyBootstrapTemplatePath = a.TemplateFile
+	}
+
+	domain := a.DNSDomain
+	if domain == "" {
+		domain = envMap["POD_NAMESPACE"] + ".svc." + constants.DefaultKubernetesDomain
+	}
+	proxy := &model.Proxy{
+		Type:        a.proxyType,
+		IPAddresses: []string{envMap["INSTANCE_IP"]},
+		ID:          envMap["POD_NAME"] + "." + envMap["POD_NAMESPACE"],
+		DNSDomain:   domain,
+	}
+	var pilotSAN []string
+	if proxyConfig.ControlPlaneAuthPolicy == meshconfig.AuthenticationPolicy_MUTUAL_TLS {
+		pilotSAN = []string{agentconfig.GetPilotSan(proxyConfig.DiscoveryAddress)}
+	}
+	viaAgent := true
+	if v, f := envMap["PROXY_XDS_VIA_AGENT"]; f {
+		viaAgent = isTrue(v)
+	}
+	certProvider := envMap["PILOT_CERT_PROVIDER"]
+	if certProvider == "" {
+		certProvider = "istiod"
+	}
+
+	node, err := bootstrap.GetNodeMetaData(bootstrap.MetadataOptions{
+		ID:                  proxy.ServiceNode(),
+		Envs:                envs,
+		InstanceIPs:         proxy.IPAddresses,
+		StsPort:             a.StsPort,
+		ProxyConfig:         proxyConfig,
+		ProxyViaAgent:       viaAgent,
+		PilotSubjectAltName: pilotSAN,
+		OutlierLogPath:      a.OutlierLogPath,
+		PilotCertProvider:   certProvider,
+		ProvCert: istioagent.BootstrapRootCA(istioagent.RootCAForXDS(istioagent.XDSRootCAOptions{
+			XDSRootCerts:       envMap["XDS_ROOT_CA"],
+			LegacyCertsMounted: hasMount(pod, "/etc/certs"),
+			PilotCertProvider:  certProvider,
+			ProvCert:           envMap["PROV_CERT"],
+			FileMountedCerts:   isTrue(envMap["FILE_MOUNTED_CERTS"]),
+			ProxyMetadata:      proxyConfig.ProxyMetadata,
+		})),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract node metadata: %v", err)
+	}
+	// The agent reads the pod labels from the downward API
+	if len(pod.Labels) > 0 && node.Metadata.Labels == nil {
+		node.Metadata.Labels = map[string]string{}
+	}
+	for k, v := range pod.Labels {
+		node.Metadata.Labels[k] = v
+	}
+	return node, nil
+}
+
+// containerEnv returns the environment of the container, resolving the variables the kubelet would.
+// Variables from config maps and secrets are left out.
+func containerEnv(pod *corev1.Pod, c *corev1.Container) []string {
+	resolved := map[string]string{}
+	envs := make([]string, 0, len(c.Env))
+	for _, e := range c.Env {
+		val := expandEnv(e.Value, resolved)
+		if from := e.ValueFrom; from != nil {
+			var f bool
+			switch {
+			case from.FieldRef != nil:
+				val, f = podField(pod, from.FieldRef.FieldPath)
+			case from.ResourceFieldRef != nil:
+				val, f = containerResource(pod, c, from.ResourceFieldRef)
+			}
+			if !f {
+				continue
+			}
+		}
+		resolved[e.Name] = val
+		envs = append(envs, e.Name+"="+val)
+	}
+	return envs
+}
+
+func podField(pod *corev1.Pod, fieldPath string) (string, bool) {
+	switch fieldPath {
+	case "metadata.name":
+		return pod.Name, true
+	case "metadata.namespace":
+		return pod.Namespace, true
+	case "metadata.uid":
+		return string(pod.UID), true
+	case "spec.nodeName":
+		return pod.Spec.NodeName, true
+	case "spec.serviceAccountName":
+		return pod.Spec.ServiceAccountName, true
+	case "status.podIP":
+		return pod.Status.PodIP, true
+	case "status.hostIP":
+		return pod.Status.HostIP, true
+	}
+	for prefix, m := range map[string]map[string]string{
+		"metadata.labels['":      pod.Labels,
+		"metadata.annotations['": pod.Annotations,
+	} {
+		if strings.HasPrefix(fieldPath, prefix) && strings.HasSuffix(fieldPath, "']") {
+			return m[strings.TrimSuffix(strings.TrimPrefix(fieldPath, prefix), "']")], true
+		}
+	}
+	return "", false
+}
+
+func containerResource(pod *corev1.Pod, c *corev1.Container, ref *corev1.ResourceFieldSelector) (string, bool) {
+	if ref.ContainerName != "" && ref.ContainerName != c.Name {
+		for i := range pod.Spec.Containers {
+			if pod.Spec.Containers[i].Name == ref.ContainerName {
+				c = &pod.Spec.Containers[i]
+			}
+		}
+	}
+	var list corev1.ResourceList
+	name := ref.Resource
+	switch {
+	case strings.HasPrefix(name, "limits."):
+		list, name = c.Resources.Limits, strings.TrimPrefix(name, "limits.")
+	case strings.HasPrefix(name, "requests."):
+		list, name = c.Resources.Requests, strings.TrimPrefix(name, "requests.")
+	}
+	q, f := list[corev1.ResourceName(name)]
+	if !f {
+		// Defaults to the node allocatable resources, which are unknown
+		return "", false
+	}
+	divisor := ref.Divisor.MilliValue()
+	if divisor == 0 {
+		divisor = 1000
+	}
+	return strconv.FormatInt(int64(math.Ceil(float64(q.MilliValue())/float64(divisor))), 10), true
+}
+
+// expandEnv expands the $(VAR) references of s, as the kubelet does for the environment and arguments.
+func expandEnv(s string, env map[string]string) string {
+	out := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			out.WriteByte('$')
+			i++
+			continue
+		case '(':
+			if end := strings.IndexByte(s[i:], ')'); end > 0 {
+				name := s[i+2 : i+end]
+				if v, f := env[name]; f {
+					out.WriteString(v)
+					i += end
+					continue
+				}
+			}
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+func isTrue(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
+
+func hasMount(pod *corev1.Pod, mountPath string) bool {
+	for _, c := range pod.Spec.Containers {
+		if c.Name != inject.ProxyContainerName {
+			continue
+		}
+		for _, m := range c.VolumeMounts {
+			if path.Clean(m.MountPath) == mountPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchBootstrapTemplate copies the bootstrap template from the istio-proxy container of the pod to a
+// temporary file.
+func fetchBootstrapTemplate(pod *corev1.Pod, pc *model.NodeMetaProxyConfig) (string, error) {
+	templatePath := bootstrap.DefaultCfgDir
+	if pc.ProxyBootstrapTemplatePath != "" {
+		templatePath = pc.ProxyBootstrapTemplatePath
+	}
+	if !path.IsAbs(templatePath) {
+		templatePath = path.Join(proxyWorkingDir, templatePath)
+	}
+	client, err := kubeClient(kubeconfig, configContext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	stdout, stderr, err := client.PodExec(pod.Name, pod.Namespace, inject.ProxyContainerName, "cat "+templatePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bootstrap template %s of %s.%s: %v %s",
+			templatePath, pod.Name, pod.Namespace, err, stderr)
+	}
+	f, err := ioutil.TempFile("", "bootstrap-*"+path.Ext(templatePath))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(stdout); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// validateBootstrap parses the rendered bootstrap, in JSON or YAML, into the Envoy bootstrap proto
+// and validates it.
+func validateBootstrap(rendered []byte) (*bootstrapv3.Bootstrap, error) {
+	js, err := yaml.YAMLToJSON(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("rendered bootstrap is not valid JSON or YAML: %v", err)
+	}
+	bs := &bootstrapv3.Bootstrap{}
+	if err := jsonpb.Unmarshal(bytes.NewReader(js), bs); err != nil {
+		return nil, fmt.Errorf("rendered bootstrap is not a valid Envoy bootstrap: %v", err)
+	}
+	if err := bs.Validate(); err != nil {
+		return nil, fmt.Errorf("rendered bootstrap is not a valid Envoy bootstrap: %v", err)
+	}
+	return bs, nil
+}
+
+func liveBootstrap(pod *corev1.Pod) (*bootstrapv3.Bootstrap, error) {
+	debug, err := extractConfigDump(pod.Name, pod.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	cw := &configdump.Wrapper{}
+	if err := cw.UnmarshalJSON(debug); err != nil {
+		return nil, fmt.Errorf("error unmarshalling config dump response from Envoy: %v", err)
+	}
+	dump, err := cw.GetBootstrapConfigDump()
+	if err != nil {
+		return nil, err
+	}
+	return dump.Bootstrap, nil
+}
+
+// diffBootstrap prints a diff between the rendered and the Envoy bootstrap to the passed writer
+func diffBootstrap(w io.Writer, rendered, envoy *bootstrapv3.Bootstrap) error {
+	jsonm := &jsonpb.Marshaler{Indent: "   "}
+	renderedBytes, envoyBytes := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := jsonm.Marshal(renderedBytes, rendered); err != nil {
+		return err
+	}
+	if err := jsonm.Marshal(envoyBytes, envoy); err != nil {
+		return err
+	}
+	diff := difflib.UnifiedDiff{
+		FromFile: "Rendered Bootstrap",
+		A:        difflib.SplitLines(renderedBytes.String()),
+		ToFile:   "Envoy Bootstrap",
+		B:        difflib.SplitLines(envoyBytes.String()),
+		Context:  3,
+	}
+	text, err := difflib.GetUnifiedDiffString(diff)
+	if err != nil {
+		return err
+	}
+	if text != "" {
+		fmt.Fprintln(w, text)
+	} else {
+		fmt.Fprintln(w, "Bootstrap Match")
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"istio.io/istio/pkg/bootstrap"
+	"istio.io/istio/pkg/test/env"
+)
+
+const injectedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: productpage
+  namespace: bookinfo
+spec:
+  template:
+    metadata:
+      labels:
+        app: productpage
+      annotations:
+        proxy.istio.io/config: |
+          concurrency: 4
+    spec:
+      serviceAccountName: bookinfo-productpage
+      containers:
+      - name: productpage
+        image: productpage
+      - name: istio-proxy
+        image: proxyv2
+        args:
+        - proxy
+        - sidecar
+        - --domain
+        - $(POD_NAMESPACE).svc.cluster.local
+        - --proxyLogLevel=warning
+        - --log_output_level
+        - default:info
+        env:
+        - name: POD_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.name
+        - name: POD_NAMESPACE
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.namespace
+        - name: INSTANCE_IP
+          valueFrom:
+            fieldRef:
+              fieldPath: status.podIP
+        - name: HOST_IP
+          valueFrom:
+            fieldRef:
+              fieldPath: status.hostIP
+        - name: SERVICE_ACCOUNT
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.serviceAccountName
+        - name: CANONICAL_SERVICE
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.labels['app']
+        - name: PROXY_CONFIG
+          value: |
+            {"discoveryAddress":"istiod.istio-system.svc:15012"}
+        - name: ISTIO_META_WORKLOAD_NAME
+          value: $(POD_NAME)-workload
+`
+
+func TestBootstrapRender(t *testing.T) {
+	pod, err := podFromManifest([]byte(injectedDeployment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Name != "productpage" || pod.Namespace != "bookinfo" || len(pod.Spec.Containers) != 2 {
+		t.Fatalf("unexpected pod %+v", pod)
+	}
+	pod.Status.PodIP = renderPodIP
+	pod.Status.HostIP = renderHostIP
+
+	node, err := proxyNode(pod, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sidecar~10.0.0.1~productpage.bookinfo~bookinfo.svc.cluster.local"; node.ID != want {
+		t.Errorf("got ID %s, want %s", node.ID, want)
+	}
+	meta := node.Metadata
+	if meta.Namespace != "bookinfo" || meta.ServiceAccount != "bookinfo-productpage" || meta.HostIP != renderHostIP {
+		t.Errorf("unexpected node metadata %+v", meta)
+	}
+	if meta.Labels["app"] != "productpage" {
+		t.Errorf("expected pod labels in the node metadata, got %v", meta.Labels)
+	}
+	if node.RawMetadata["WORKLOAD_NAME"] != "productpage-workload" {
+		t.Errorf("expected expanded ISTIO_META_ variables in the node metadata, got %v", node.RawMetadata)
+	}
+	if meta.ProxyConfig.Concurrency.GetValue() != 4 || meta.ProxyConfig.DiscoveryAddress != "istiod.istio-system.svc:15012" {
+		t.Errorf("expected the proxy config env and annotation to apply, got %+v", meta.ProxyConfig)
+	}
+
+	out := &bytes.Buffer{}
+	template := filepath.Join(env.IstioSrc, "tools/packaging/common/envoy_bootstrap.json")
+	if err := bootstrap.New(bootstrap.Config{Node: node}).WriteTo(template, out); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := validateBootstrap(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs.Node.Id != node.ID {
+		t.Errorf("got bootstrap node %s, want %s", bs.Node.Id, node.ID)
+	}
+
+	diff := &bytes.Buffer{}
+	if err := diffBootstrap(diff, bs, bs); err != nil {
+		t.Fatal(err)
+	}
+	if diff.String() != "Bootstrap Match\n" {
+		t.Errorf("expected the bootstrap to match itself, got %s", diff.String())
+	}
+}
+
+func TestBootstrapRenderInvalid(t *testing.T) {
+	if _, err := validateBootstrap([]byte(`{"node": {"id": "sidecar"}, "admin": {"unknown": true}}`)); err == nil {
+		t.Errorf("expected unknown fields to be rejected")
+	}
+	if _, err := podFromManifest([]byte("kind: Service\nmetadata:\n  name: productpage\n")); err == nil {
+		t.Errorf("expected manifests without a pod template to be rejected")
+	}
+	pod, err := podFromManifest([]byte("kind: Pod\nmetadata:\n  name: productpage\nspec:\n  containers:\n  - name: app\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := proxyNode(pod, ""); err == nil {
+		t.Errorf("expected pods without a proxy to be rejected")
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	vars := map[string]string{"POD_NAMESPACE": "bookinfo"}
+	for in, want := range map[string]string{
+		"$(POD_NAMESPACE).svc": "bookinfo.svc",
+		"$$(POD_NAMESPACE)":    "$(POD_NAMESPACE)",
+		"$(UNKNOWN)":           "$(UNKNOWN)",
+		"$(POD_NAMESPACE":      "$(POD_NAMESPACE",
+		"cost: 5$":             "cost: 5$",
+	} {
+		if got := expandEnv(in, vars); got != want {
+			t.Errorf("expandEnv(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"istio.io/istio/istioctl/pkg/util/handlers"
 	"istio.io/istio/istioctl/pkg/writer/envoy/clusters"
 	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
+	"istio.io/istio/istioctl/pkg/writer/pilot"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/pkg/log"
@@ -932,13 +933,62 @@ func secretConfigCmd() *cobra.Command {
 	return secretConfigCmd
 }
 
+func vipConfigCmd() *cobra.Command {
+	var podName, podNamespace string
+
+	vipConfigCmd := &cobra.Command{
+		Use:   "vip [<type>/]<name>[.<namespace>]",
+		Short: "Retrieves the addresses auto allocated to ServiceEntry hosts for the DNS proxy in the specified pod",
+		Long: `Retrieve the addresses Istiod automatically allocated to ServiceEntry hosts without an address, and that the DNS
+proxy of the specified pod resolves them to. Addresses are only allocated for pods with ISTIO_META_DNS_CAPTURE and
+ISTIO_META_DNS_AUTO_ALLOCATE enabled.`,
+		Example: `  # Retrieve the auto allocated addresses resolved by a given pod.
+  istioctl proxy-config vip <pod-name[.namespace]>`,
+		Aliases: []string{"vips"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("vip requires pod name")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var err error
+			if podName, podNamespace, err = getPodName(args[0]); err != nil {
+				return err
+			}
+			if outputFormat != summaryOutput {
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+			kubeClient, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %w", err)
+			}
+			path := fmt.Sprintf("/debug/ndsz?proxyID=%s.%s", podName, podNamespace)
+			nameTables, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, path)
+			if err != nil {
+				return err
+			}
+			registries, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, "/debug/registryz")
+			if err != nil {
+				return err
+			}
+			vw := pilot.VIPWriter{Writer: c.OutOrStdout()}
+			return vw.PrintVIPs(nameTables, registries)
+		},
+	}
+
+	vipConfigCmd.Long += "\n\n" + ExperimentalMsg
+	return vipConfigCmd
+}
+
 func proxyConfig() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "proxy-config",
 		Short: "Retrieve information about proxy configuration from Envoy [kube only]",
 		Long:  `A group of commands used to retrieve information about proxy configuration from the Envoy config dump`,
 		Example: `  # Retrieve information about proxy configuration from an Envoy instance.
-  istioctl proxy-config <clusters|listeners|routes|endpoints|bootstrap|log|secret> <pod-name[.namespace]>`,
+  istioctl proxy-config <clusters|listeners|routes|endpoints|bootstrap|log|secret|vip> <pod-name[.namespace]>`,
 		Aliases: []string{"pc"},
 	}
 
@@ -952,6 +1002,7 @@ func proxyConfig() *cobra.Command {
 	configCmd.AddCommand(bootstrapConfigCmd())
 	configCmd.AddCommand(endpointConfigCmd())
 	configCmd.AddCommand(secretConfigCmd())
+	configCmd.AddCommand(vipConfigCmd())
 
 	return configCmd
 }
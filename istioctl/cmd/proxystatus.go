@@ -17,11 +17,14 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 
 	xdsapi "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/istio/istioctl/pkg/clioptions"
 	"istio.io/istio/istioctl/pkg/multixds"
@@ -35,6 +38,8 @@ import (
 
 func statusCommand() *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
+	var drift bool
+	var driftConcurrency int
 
 	statusCmd := &cobra.Command{
 		Use:   "proxy-status [<type>/]<name>[.<namespace>]",
@@ -56,6 +61,9 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
   kubectl port-forward -n istio-system istio-egressgateway-59585c5b9c-ndc59 15000 &
   curl localhost:15000/config_dump > cd.json
   istioctl proxy-status istio-egressgateway-59585c5b9c-ndc59.istio-system --file cd.json
+
+  # Group all Envoys in the mesh by config drift (stale, nacked, diverged), as JSON
+  istioctl proxy-status --drift
 `,
 		Aliases: []string{"ps"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -63,6 +71,10 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 				cmd.Println(cmd.UsageString())
 				return fmt.Errorf("--file can only be used when pod-name is specified")
 			}
+			if (len(args) > 0) && drift {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("--drift cannot be used when pod-name is specified")
+			}
 			return nil
 		},
 		RunE: func(c *cobra.Command, args []string) error {
@@ -70,6 +82,9 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 			if err != nil {
 				return err
 			}
+			if drift {
+				return printDrift(c.OutOrStdout(), kubeClient, driftConcurrency)
+			}
 			if len(args) > 0 {
 				podName, ns, err := handlers.InferPodInfoFromTypedResource(args[0],
 					handlers.HandleNamespace(namespace, defaultNamespace),
@@ -111,10 +126,45 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 	opts.AttachControlPlaneFlags(statusCmd)
 	statusCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
+	statusCmd.PersistentFlags().BoolVar(&drift, "drift", false,
+		"Compare the config of all Envoys in the mesh to the Istiod they are connected to and output them grouped by "+
+			"drift category, as JSON. Envoys not running in pods, such as on VMs, are skipped")
+	statusCmd.PersistentFlags().IntVar(&driftConcurrency, "drift-concurrency", 10,
+		"The maximum number of Envoys whose config is compared at the same time with --drift")
 
 	return statusCmd
 }
 
+// printDrift writes the drift report of all proxies connected to Istiod.
+func printDrift(w io.Writer, kubeClient kube.ExtendedClient, concurrency int) error {
+	statuses, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, "/debug/syncz")
+	if err != nil {
+		return err
+	}
+	d := &compare.DriftDetector{
+		EnvoyConfigDump: func(proxyID string) ([]byte, error) {
+			podName, ns := handlers.InferPodInfo(proxyID, defaultNamespace)
+			// Proxies that are not backed by a pod, such as VMs, cannot be reached through the API server.
+			if _, err := kubeClient.CoreV1().Pods(ns).Get(context.TODO(), podName, metav1.GetOptions{}); err != nil {
+				if errors.IsNotFound(err) {
+					return nil, fmt.Errorf("%w: no pod %s.%s", compare.ErrEnvoyUnreachable, podName, ns)
+				}
+				return nil, err
+			}
+			return kubeClient.EnvoyDo(context.TODO(), podName, ns, "GET", "config_dump", nil)
+		},
+		IstiodConfigDump: func(istiod, proxyID string) ([]byte, error) {
+			return kubeClient.DiscoveryDo(context.TODO(), istiod, istioNamespace, "/debug/config_dump?proxyID="+proxyID)
+		},
+		Concurrency: concurrency,
+	}
+	report, err := d.Detect(statuses)
+	if err != nil {
+		return err
+	}
+	return report.Write(w)
+}
+
 func readConfigFile(filename string) ([]byte, error) {
 	file := os.Stdin
 	if filename != "-" {
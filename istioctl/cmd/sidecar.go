@@ -0,0 +1,387 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	// clusterStatsFilter selects the request and connection counters of the outbound clusters,
+	// and of the cluster traffic to unknown destinations goes through.
+	clusterStatsFilter = `^cluster\.(outbound\|.*|PassthroughCluster)\.upstream_(rq|cx)_total$`
+
+	passthroughCluster = "PassthroughCluster"
+)
+
+func sidecarCommand() *cobra.Command {
+	sidecarCmd := &cobra.Command{
+		Use:   "sidecar",
+		Short: "Commands to assist in scoping the configuration of sidecars with Sidecar resources",
+	}
+	sidecarCmd.AddCommand(sidecarGenerateCommand())
+	return sidecarCmd
+}
+
+func sidecarGenerateCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var selector, sidecarName string
+	var window time.Duration
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generates a Sidecar resource limiting the egress of workloads to the hosts they call",
+		Long: `Generates a Sidecar resource limiting the egress of the selected workloads to the hosts they were observed
+calling. Calls are read from the upstream_rq_total and upstream_cx_total stats of the outbound clusters of the
+Envoy of every selected pod, over the given time window. With a window of 0, all the calls since the proxies
+started are considered.
+
+The Sidecar is written to the standard output, and can be piped into 'kubectl apply -f -'. A report comparing
+the number of services every proxy currently receives with the number of hosts the Sidecar lets it receive,
+which the size of its configuration is roughly proportional to, is written to the standard error.
+
+Hosts that were not called during the window, and destinations reached through the PassthroughCluster, are
+not part of the Sidecar. Review it before applying it.`,
+		Example: `  # Generate a Sidecar for the reviews workloads, from the traffic observed for 10 minutes
+  istioctl x sidecar generate -n bookinfo -l app=reviews --window 10m
+
+  # Generate a namespace wide Sidecar from all the calls since the proxies started
+  istioctl x sidecar generate -n bookinfo --window 0 | kubectl apply -f -`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %w", err)
+			}
+			ns := handlers.HandleNamespace(namespace, defaultNamespace)
+			workloadSelector, err := sidecarWorkloadSelector(selector)
+			if err != nil {
+				return err
+			}
+			pods, err := sidecarPods(client, ns, selector)
+			if err != nil {
+				return err
+			}
+
+			proxies := make([]*observedProxy, 0, len(pods))
+			for _, pod := range pods {
+				stats, err := outboundClusterStats(client, pod)
+				if err != nil {
+					return err
+				}
+				proxies = append(proxies, &observedProxy{pod: pod.Name, namespace: pod.Namespace, before: stats})
+			}
+			if window > 0 {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Observing the traffic of %d pods for %v...\n", len(proxies), window)
+				time.Sleep(window)
+			}
+			for i, p := range proxies {
+				if p.after, err = outboundClusterStats(client, pods[i]); err != nil {
+					return err
+				}
+				if window <= 0 {
+					p.before = nil
+				}
+				p.services, p.connected = proxyVisibleServices(client, p.pod+"."+p.namespace)
+			}
+
+			sidecar, err := generateSidecarYAML(sidecarName, ns, workloadSelector, proxies)
+			if err != nil {
+				return err
+			}
+			if _, err := cmd.OutOrStdout().Write(sidecar); err != nil {
+				return err
+			}
+			return printSidecarReport(cmd.ErrOrStderr(), proxies)
+		},
+	}
+
+	opts.AttachControlPlaneFlags(generateCmd)
+	generateCmd.PersistentFlags().StringVarP(&selector, "selector", "l", "",
+		"Label selector of the pods to observe. The Sidecar applies to the whole namespace when it is not set")
+	generateCmd.PersistentFlags().StringVar(&sidecarName, "name", "default", "The name of the generated Sidecar")
+	generateCmd.PersistentFlags().DurationVar(&window, "window", time.Minute,
+		"How long to observe the traffic of the pods for. With 0, all the calls since the proxies started are considered")
+	return generateCmd
+}
+
+// observedProxy holds what was observed of the proxy of a pod.
+type observedProxy struct {
+	pod       string
+	namespace string
+	// before and after are the outbound cluster counters at the start and the end of the window.
+	before map[string]uint64
+	after  map[string]uint64
+	// services are the services the proxy currently receives, by hostname, with their namespace.
+	services  map[string]string
+	connected bool
+}
+
+// calledHosts returns the hostnames of the outbound clusters whose counters increased during the window.
+func (p *observedProxy) calledHosts() []string {
+	hosts := map[string]struct{}{}
+	for cluster, count := range p.after {
+		if count <= p.before[cluster] {
+			continue
+		}
+		if h := clusterHostname(cluster); h != "" {
+			hosts[h] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(hosts))
+	for h := range hosts {
+		out = append(out, h)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// passthroughCalls returns how many calls went to unknown destinations during the window.
+func (p *observedProxy) passthroughCalls() uint64 {
+	if p.after[passthroughCluster] <= p.before[passthroughCluster] {
+		return 0
+	}
+	return p.after[passthroughCluster] - p.before[passthroughCluster]
+}
+
+// clusterHostname returns the hostname of an outbound cluster, in the outbound|port|subset|hostname format.
+func clusterHostname(cluster string) string {
+	parts := strings.Split(cluster, "|")
+	if len(parts) != 4 || parts[0] != "outbound" {
+		return ""
+	}
+	return parts[3]
+}
+
+// sidecarWorkloadSelector converts a label selector to the labels of a Sidecar workload selector.
+func sidecarWorkloadSelector(selector string) (map[string]string, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	labels, err := k8s_labels.ConvertSelectorToLabelsMap(selector)
+	if err != nil {
+		return nil, fmt.Errorf("selector %q can not be used as a Sidecar workload selector, only equality "+
+			"requirements are supported: %v", selector, err)
+	}
+	return labels, nil
+}
+
+func sidecarPods(client kube.ExtendedClient, ns, selector string) ([]v1.Pod, error) {
+	podList, err := client.PodsForSelector(context.TODO(), ns, selector)
+	if err != nil {
+		return nil, fmt.Errorf("not able to locate pods with selector %q: %v", selector, err)
+	}
+	pods := make([]v1.Pod, 0, len(podList.Items))
+	for _, pod := range podList.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			if c.Name == proxyContainerName {
+				pods = append(pods, pod)
+				break
+			}
+		}
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("no running pods with a sidecar found in namespace %s with selector %q", ns, selector)
+	}
+	return pods, nil
+}
+
+func outboundClusterStats(client kube.ExtendedClient, pod v1.Pod) (map[string]uint64, error) {
+	path := "stats?usedonly&filter=" + url.QueryEscape(clusterStatsFilter)
+	out, err := client.EnvoyDo(context.TODO(), pod.Name, pod.Namespace, "GET", path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the stats of %s.%s: %v", pod.Name, pod.Namespace, err)
+	}
+	return parseClusterStats(string(out))
+}
+
+// parseClusterStats sums the upstream_rq_total and upstream_cx_total counters of every cluster
+// in the text output of the Envoy stats endpoint.
+func parseClusterStats(stats string) (map[string]uint64, error) {
+	out := map[string]uint64{}
+	scanner := bufio.NewScanner(strings.NewReader(stats))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndex(line, ": ")
+		if i < 0 || !strings.HasPrefix(line, "cluster.") {
+			continue
+		}
+		name, value := line[:i], line[i+2:]
+		var cluster string
+		switch {
+		case strings.HasSuffix(name, ".upstream_rq_total"):
+			cluster = strings.TrimSuffix(name, ".upstream_rq_total")
+		case strings.HasSuffix(name, ".upstream_cx_total"):
+			cluster = strings.TrimSuffix(name, ".upstream_cx_total")
+		default:
+			continue
+		}
+		count, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of stat %s: %v", name, err)
+		}
+		out[strings.TrimPrefix(cluster, "cluster.")] += count
+	}
+	return out, scanner.Err()
+}
+
+// proxyVisibleServices returns the services Istiod currently sends to the proxy, by hostname, with their namespace.
+func proxyVisibleServices(client kube.ExtendedClient, proxyID string) (map[string]string, bool) {
+	responses, err := client.AllDiscoveryDo(context.TODO(), istioNamespace, "/debug/sidecarz?proxyID="+proxyID)
+	if err != nil {
+		return nil, false
+	}
+	for _, response := range responses {
+		if services, err := parseSidecarScopeServices(response); err == nil {
+			return services, true
+		}
+	}
+	return nil, false
+}
+
+// parseSidecarScopeServices reads the services of a sidecar scope, as returned by the Istiod sidecarz debug endpoint.
+func parseSidecarScopeServices(sidecarz []byte) (map[string]string, error) {
+	scope := struct {
+		Services []struct {
+			Hostname   string `json:"hostname"`
+			Attributes struct {
+				Namespace string
+			}
+		} `json:"services"`
+	}{}
+	if err := json.Unmarshal(sidecarz, &scope); err != nil {
+		return nil, err
+	}
+	services := make(map[string]string, len(scope.Services))
+	for _, svc := range scope.Services {
+		services[svc.Hostname] = svc.Attributes.Namespace
+	}
+	return services, nil
+}
+
+// sidecarEgressHosts returns the egress hosts of a Sidecar letting the proxies reach the hosts they called.
+// Hosts are qualified with the namespace of their service when Istiod knows it, and with * otherwise.
+func sidecarEgressHosts(proxies []*observedProxy) []string {
+	hosts := map[string]struct{}{}
+	for _, p := range proxies {
+		for _, h := range p.calledHosts() {
+			ns, f := p.services[h]
+			if !f || ns == "" {
+				ns = "*"
+			}
+			hosts[ns+"/"+h] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(hosts))
+	for h := range hosts {
+		out = append(out, h)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func generateSidecarYAML(name, ns string, selector map[string]string, proxies []*observedProxy) ([]byte, error) {
+	spec := &networking.Sidecar{
+		Egress: []*networking.IstioEgressListener{{
+			Hosts: sidecarEgressHosts(proxies),
+		}},
+	}
+	if len(selector) > 0 {
+		spec.WorkloadSelector = &networking.WorkloadSelector{Labels: selector}
+	}
+	iSpec, err := unstructureIstioType(spec)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": collections.IstioNetworkingV1Alpha3Sidecars.Resource().APIVersion(),
+			"kind":       collections.IstioNetworkingV1Alpha3Sidecars.Resource().Kind(),
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": ns,
+			},
+			"spec": iSpec,
+		},
+	}
+	return yaml.Marshal(u.Object)
+}
+
+// printSidecarReport writes, for every proxy, how many services it receives today and how many hosts it would
+// receive with the generated Sidecar, along with the estimated reduction of its configuration size.
+func printSidecarReport(writer io.Writer, proxies []*observedProxy) error {
+	egressHosts := len(sidecarEgressHosts(proxies))
+	w := new(tabwriter.Writer).Init(writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "POD\tSERVICES\tCALLED HOSTS\tSIDECAR HOSTS\tREDUCTION")
+	current, connected, passthrough := 0, 0, uint64(0)
+	for _, p := range proxies {
+		passthrough += p.passthroughCalls()
+		if !p.connected {
+			_, _ = fmt.Fprintf(w, "%s.%s\t-\t%d\t%d\t-\n", p.pod, p.namespace, len(p.calledHosts()), egressHosts)
+			continue
+		}
+		current += len(p.services)
+		connected++
+		_, _ = fmt.Fprintf(w, "%s.%s\t%d\t%d\t%d\t%s\n", p.pod, p.namespace, len(p.services), len(p.calledHosts()),
+			egressHosts, reduction(len(p.services), egressHosts))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if connected > 0 {
+		_, _ = fmt.Fprintf(writer, "\nEstimated config size reduction: %s (%d services across %d proxies, %d with the Sidecar)\n",
+			reduction(current, egressHosts*connected), current, connected, egressHosts*connected)
+	}
+	if passthrough > 0 {
+		_, _ = fmt.Fprintf(writer, "\nWarning: %d calls went to destinations unknown to Istio through the %s, "+
+			"they are not covered by the Sidecar\n", passthrough, passthroughCluster)
+	}
+	return nil
+}
+
+func reduction(before, after int) string {
+	if before == 0 || after >= before {
+		return "0%"
+	}
+	return fmt.Sprintf("%d%%", (before-after)*100/before)
+}
@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const clusterStats = `cluster.PassthroughCluster.upstream_cx_total: 4
+cluster.outbound|9080||details.bookinfo.svc.cluster.local.upstream_cx_total: 2
+cluster.outbound|9080||details.bookinfo.svc.cluster.local.upstream_rq_total: 10
+cluster.outbound|9080|v1|reviews.bookinfo.svc.cluster.local.upstream_rq_total: 3
+cluster.outbound|443||api.example.com.upstream_cx_total: 1
+`
+
+func TestParseClusterStats(t *testing.T) {
+	got, err := parseClusterStats(clusterStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{
+		"PassthroughCluster": 4,
+		"outbound|9080||details.bookinfo.svc.cluster.local":   12,
+		"outbound|9080|v1|reviews.bookinfo.svc.cluster.local": 3,
+		"outbound|443||api.example.com":                       1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseClusterStats() = %v, want %v", got, want)
+	}
+
+	if _, err := parseClusterStats("cluster.outbound|80||a.com.upstream_rq_total: NaN"); err == nil {
+		t.Errorf("expected an error for an invalid counter")
+	}
+}
+
+func TestSidecarGenerate(t *testing.T) {
+	after, err := parseClusterStats(clusterStats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxies := []*observedProxy{
+		{
+			pod:       "productpage-1",
+			namespace: "bookinfo",
+			before: map[string]uint64{
+				"PassthroughCluster": 1,
+				// Not called during the window
+				"outbound|443||api.example.com": 1,
+			},
+			after: after,
+			services: map[string]string{
+				"details.bookinfo.svc.cluster.local":    "bookinfo",
+				"reviews.bookinfo.svc.cluster.local":    "bookinfo",
+				"ratings.bookinfo.svc.cluster.local":    "bookinfo",
+				"api.example.com":                       "external",
+				"other.example.com":                     "external",
+				"httpbin.default.svc.cluster.local":     "default",
+				"sleep.default.svc.cluster.local":       "default",
+				"istiod.istio-system.svc.cluster.local": "istio-system",
+			},
+			connected: true,
+		},
+		{
+			pod:       "productpage-2",
+			namespace: "bookinfo",
+			after: map[string]uint64{
+				"outbound|443||unknown.example.com": 1,
+			},
+		},
+	}
+
+	if got, want := sidecarEgressHosts(proxies), []string{
+		"*/unknown.example.com",
+		"bookinfo/details.bookinfo.svc.cluster.local",
+		"bookinfo/reviews.bookinfo.svc.cluster.local",
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sidecarEgressHosts() = %v, want %v", got, want)
+	}
+
+	selector, err := sidecarWorkloadSelector("app=productpage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sidecar, err := generateSidecarYAML("productpage", "bookinfo", selector, proxies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSidecar := `apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: productpage
+  namespace: bookinfo
+spec:
+  egress:
+  - hosts:
+    - '*/unknown.example.com'
+    - bookinfo/details.bookinfo.svc.cluster.local
+    - bookinfo/reviews.bookinfo.svc.cluster.local
+  workloadSelector:
+    labels:
+      app: productpage
+`
+	if string(sidecar) != wantSidecar {
+		t.Errorf("generateSidecarYAML() = \n%s\nwant\n%s", sidecar, wantSidecar)
+	}
+
+	report := &bytes.Buffer{}
+	if err := printSidecarReport(report, proxies); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"productpage-1.bookinfo     8            2                3                 62%",
+		"productpage-2.bookinfo     -            1                3                 -",
+		"Estimated config size reduction: 62% (8 services across 1 proxies, 3 with the Sidecar)",
+		"Warning: 3 calls went to destinations unknown to Istio through the PassthroughCluster",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("expected the report to contain %q, got\n%s", want, report.String())
+		}
+	}
+
+	if _, err := sidecarWorkloadSelector("app in (a, b)"); err == nil {
+		t.Errorf("expected an error for a set based selector")
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"istio.io/istio/operator/pkg/apis/istio/v1alpha1"
 	"istio.io/istio/operator/pkg/controlplane"
 	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/postrender"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/istio/operator/pkg/util"
 	"istio.io/istio/operator/pkg/util/clog"
@@ -213,8 +214,12 @@ func (v *StatusVerifier) verifyInstall() error {
 
 func (v *StatusVerifier) verifyPostInstallIstioOperator(iop *v1alpha1.IstioOperator, filename string) (int, int, error) {
 	t := translate.NewTranslator()
+	hooks, err := postrender.HooksFor()
+	if err != nil {
+		return 0, 0, err
+	}
 
-	cp, err := controlplane.NewIstioControlPlane(iop.Spec, t)
+	cp, err := controlplane.NewIstioControlPlane(iop.Spec, t, hooks)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -0,0 +1,277 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// Config types compared by drift detection.
+const (
+	ClusterType  = "clusters"
+	ListenerType = "listeners"
+	RouteType    = "routes"
+)
+
+// Drift categories. Each proxy is put in exactly one category; NACKed and stale proxies are not compared.
+const (
+	// DriftNacked means the proxy rejected the last config Istiod sent.
+	DriftNacked = "nacked"
+	// DriftStale means the proxy has not acknowledged the last config Istiod sent yet.
+	DriftStale = "stale"
+	// DriftDiverged means the proxy acknowledged the last config, but its config differs from what Istiod generates.
+	DriftDiverged = "diverged"
+	// DriftUnknown means the config of the proxy could not be compared.
+	DriftUnknown = "unknown"
+	// DriftSkipped means the config of the proxy was not compared because its Envoy cannot be reached, as
+	// for proxies running on VMs.
+	DriftSkipped = "skipped"
+	// DriftSynced means the config of the proxy matches what Istiod generates.
+	DriftSynced = "synced"
+)
+
+// ErrEnvoyUnreachable is returned by DriftDetector.EnvoyConfigDump for proxies whose Envoy cannot be reached,
+// such as proxies running on VMs.
+var ErrEnvoyUnreachable = errors.New("envoy cannot be reached")
+
+// ProxyDrift describes the drift of a single proxy.
+type ProxyDrift struct {
+	ProxyID string `json:"proxy"`
+	Istiod  string `json:"istiod"`
+	// Types lists the config types that are NACKed, stale or diverged.
+	Types []string `json:"types,omitempty"`
+	// IstiodHashes and EnvoyHashes hold the hash of each config type, for diverged proxies.
+	IstiodHashes map[string]string `json:"istiodHashes,omitempty"`
+	EnvoyHashes  map[string]string `json:"envoyHashes,omitempty"`
+	// Error is the reason the config of the proxy could not be compared.
+	Error string `json:"error,omitempty"`
+
+	category string
+}
+
+// DriftReport groups the proxies connected to Istiod by drift category.
+type DriftReport struct {
+	// Summary holds the number of proxies in each category.
+	Summary map[string]int          `json:"summary"`
+	Proxies map[string][]ProxyDrift `json:"proxies"`
+}
+
+// DriftDetector computes the drift of all proxies connected to Istiod.
+type DriftDetector struct {
+	// EnvoyConfigDump returns the config dump of the proxy with the given ID. It returns ErrEnvoyUnreachable
+	// for proxies whose Envoy cannot be reached.
+	EnvoyConfigDump func(proxyID string) ([]byte, error)
+	// IstiodConfigDump returns the config dump the given Istiod generates for the proxy with the given ID.
+	IstiodConfigDump func(istiod, proxyID string) ([]byte, error)
+	// Concurrency is the maximum number of proxies compared at the same time.
+	Concurrency int
+}
+
+// Detect computes the drift of all proxies in the given Istiod syncz responses. Only the config of proxies
+// that acknowledged the last push is compared, as the config of the others is known to differ.
+func (d *DriftDetector) Detect(statuses map[string][]byte) (*DriftReport, error) {
+	var drifts []*ProxyDrift
+	var compare []*ProxyDrift
+	for istiod, status := range statuses {
+		var ss []xds.SyncStatus
+		if err := json.Unmarshal(status, &ss); err != nil {
+			return nil, fmt.Errorf("could not unmarshal sync status of %s: %v", istiod, err)
+		}
+		for _, s := range ss {
+			drift := &ProxyDrift{ProxyID: s.ProxyID, Istiod: istiod}
+			drifts = append(drifts, drift)
+			if drift.Types = nackedTypes(s); len(drift.Types) > 0 {
+				drift.category = DriftNacked
+				continue
+			}
+			if drift.Types = staleTypes(s); len(drift.Types) > 0 {
+				drift.category = DriftStale
+				continue
+			}
+			compare = append(compare, drift)
+		}
+	}
+
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, drift := range compare {
+		drift := drift
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			switch err := d.compare(drift); {
+			case errors.Is(err, ErrEnvoyUnreachable):
+				drift.category = DriftSkipped
+				drift.Error = err.Error()
+			case err != nil:
+				drift.category = DriftUnknown
+				drift.Error = err.Error()
+			case len(drift.Types) > 0:
+				drift.category = DriftDiverged
+			default:
+				drift.category = DriftSynced
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := &DriftReport{
+		Summary: map[string]int{},
+		Proxies: map[string][]ProxyDrift{},
+	}
+	for _, drift := range drifts {
+		report.Summary[drift.category]++
+		report.Proxies[drift.category] = append(report.Proxies[drift.category], *drift)
+	}
+	for _, proxies := range report.Proxies {
+		sort.Slice(proxies, func(i, j int) bool {
+			return proxies[i].ProxyID < proxies[j].ProxyID
+		})
+	}
+	return report, nil
+}
+
+// compare fills in the diverged types of the proxy.
+func (d *DriftDetector) compare(drift *ProxyDrift) error {
+	envoyResponse, err := d.EnvoyConfigDump(drift.ProxyID)
+	if err != nil {
+		return fmt.Errorf("could not get config dump from envoy: %w", err)
+	}
+	envoyDump := &configdump.Wrapper{}
+	if err := json.Unmarshal(envoyResponse, envoyDump); err != nil {
+		return fmt.Errorf("could not unmarshal config dump from envoy: %v", err)
+	}
+	// Only the Istiod the proxy is connected to generates its config.
+	istiodResponse, err := d.IstiodConfigDump(drift.Istiod, drift.ProxyID)
+	if err != nil {
+		return fmt.Errorf("could not get config dump from istiod %s: %v", drift.Istiod, err)
+	}
+	istiodDump := &configdump.Wrapper{}
+	if err := json.Unmarshal(istiodResponse, istiodDump); err != nil {
+		return fmt.Errorf("could not unmarshal config dump from istiod: %v", err)
+	}
+
+	istiodHashes, err := ConfigHashes(istiodDump)
+	if err != nil {
+		return err
+	}
+	envoyHashes, err := ConfigHashes(envoyDump)
+	if err != nil {
+		return err
+	}
+	for _, t := range []string{ClusterType, ListenerType, RouteType} {
+		if istiodHashes[t] != envoyHashes[t] {
+			drift.Types = append(drift.Types, t)
+		}
+	}
+	if len(drift.Types) > 0 {
+		drift.IstiodHashes = istiodHashes
+		drift.EnvoyHashes = envoyHashes
+	}
+	return nil
+}
+
+// ConfigHashes returns a hash of the dynamic clusters, listeners and routes in the config dump, keyed by
+// config type. Config is normalized the same way as for the diff, so equal hashes mean an empty diff.
+func ConfigHashes(dump *configdump.Wrapper) (map[string]string, error) {
+	out := map[string]string{}
+	clusters, err := dump.GetDynamicClusterDump(true)
+	if out[ClusterType], err = hashConfig(clusters, err); err != nil {
+		return nil, err
+	}
+	listeners, err := dump.GetDynamicListenerDump(true)
+	if out[ListenerType], err = hashConfig(listeners, err); err != nil {
+		return nil, err
+	}
+	routes, err := dump.GetDynamicRouteDump(true)
+	if out[RouteType], err = hashConfig(routes, err); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// hashConfig hashes the config, or the error getting it, like the diff shows the error.
+func hashConfig(config proto.Message, getErr error) (string, error) {
+	buf := &bytes.Buffer{}
+	if getErr != nil {
+		buf.WriteString(getErr.Error())
+	} else if err := (&jsonpb.Marshaler{}).Marshal(buf, config); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(buf.Bytes())), nil
+}
+
+// nackedTypes returns the config types whose last push the proxy rejected.
+func nackedTypes(s xds.SyncStatus) []string {
+	var out []string
+	for t, nacked := range map[string]string{
+		ClusterType:  s.ClusterNacked,
+		ListenerType: s.ListenerNacked,
+		RouteType:    s.RouteNacked,
+	} {
+		if nacked != "" {
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// staleTypes returns the config types whose last push the proxy has not acknowledged yet.
+func staleTypes(s xds.SyncStatus) []string {
+	var out []string
+	for t, nonces := range map[string][2]string{
+		ClusterType:  {s.ClusterSent, s.ClusterAcked},
+		ListenerType: {s.ListenerSent, s.ListenerAcked},
+		RouteType:    {s.RouteSent, s.RouteAcked},
+	} {
+		if nonces[0] != nonces[1] {
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Write writes the report as indented JSON.
+func (r *DriftReport) Write(w io.Writer) error {
+	out, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+func clusterDump(clusterName string) []byte {
+	return []byte(fmt.Sprintf(`{"configs": [{
+  "@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+  "dynamic_active_clusters": [{
+    "version_info": "v1",
+    "cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster", "name": %q}
+  }]
+}]}`, clusterName))
+}
+
+func TestDriftDetector(t *testing.T) {
+	statuses, err := json.Marshal([]xds.SyncStatus{
+		{ProxyID: "synced.default", ClusterSent: "1", ClusterAcked: "1"},
+		{ProxyID: "diverged.default", ClusterSent: "1", ClusterAcked: "1"},
+		{ProxyID: "stale.default", ClusterSent: "2", ClusterAcked: "1", RouteSent: "2", RouteAcked: "1"},
+		{ProxyID: "nacked.default", ClusterSent: "2", ClusterAcked: "1", ListenerNacked: "2"},
+		{ProxyID: "unreachable.default", ClusterSent: "1", ClusterAcked: "1"},
+		{ProxyID: "vm.default", ClusterSent: "1", ClusterAcked: "1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &DriftDetector{
+		EnvoyConfigDump: func(proxyID string) ([]byte, error) {
+			switch proxyID {
+			case "unreachable.default":
+				return nil, fmt.Errorf("connection refused")
+			case "vm.default":
+				return nil, ErrEnvoyUnreachable
+			case "diverged.default":
+				return clusterDump("outbound|80||old.default.svc.cluster.local"), nil
+			}
+			return clusterDump("outbound|80||new.default.svc.cluster.local"), nil
+		},
+		IstiodConfigDump: func(istiod, proxyID string) ([]byte, error) {
+			if istiod != "istiod-1" {
+				return nil, fmt.Errorf("proxy %s is not connected to %s", proxyID, istiod)
+			}
+			return clusterDump("outbound|80||new.default.svc.cluster.local"), nil
+		},
+		Concurrency: 2,
+	}
+	report, err := d.Detect(map[string][]byte{"istiod-1": statuses, "istiod-2": []byte("[]")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSummary := map[string]int{DriftSynced: 1, DriftDiverged: 1, DriftStale: 1, DriftNacked: 1, DriftUnknown: 1, DriftSkipped: 1}
+	if !reflect.DeepEqual(report.Summary, wantSummary) {
+		t.Fatalf("got summary %v, want %v", report.Summary, wantSummary)
+	}
+	if got := report.Proxies[DriftDiverged][0]; got.ProxyID != "diverged.default" || !reflect.DeepEqual(got.Types, []string{ClusterType}) ||
+		got.IstiodHashes[ClusterType] == got.EnvoyHashes[ClusterType] {
+		t.Errorf("unexpected diverged proxy: %+v", got)
+	}
+	if got := report.Proxies[DriftStale][0]; !reflect.DeepEqual(got.Types, []string{ClusterType, RouteType}) {
+		t.Errorf("unexpected stale types: %v", got.Types)
+	}
+	if got := report.Proxies[DriftNacked][0]; !reflect.DeepEqual(got.Types, []string{ListenerType}) {
+		t.Errorf("unexpected nacked types: %v", got.Types)
+	}
+	if got := report.Proxies[DriftUnknown][0]; got.Error == "" {
+		t.Errorf("expected an error for the unreachable proxy")
+	}
+	if got := report.Proxies[DriftSkipped][0]; got.ProxyID != "vm.default" {
+		t.Errorf("unexpected skipped proxy: %+v", got)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"text/tabwriter"
+)
+
+// VIPWriter enables printing of the addresses auto allocated to ServiceEntry hosts using Istiod ndsz and registryz responses
+type VIPWriter struct {
+	Writer io.Writer
+}
+
+type nameTable struct {
+	Table map[string]struct {
+		Ips       []string `json:"ips"`
+		Namespace string   `json:"namespace"`
+	} `json:"table"`
+}
+
+type registryService struct {
+	Hostname             string `json:"hostname"`
+	AutoAllocatedAddress string `json:"autoAllocatedAddress"`
+}
+
+type vip struct {
+	address   string
+	hostname  string
+	namespace string
+}
+
+// PrintVIPs takes the ndsz responses of Istiod for a proxy and its registryz responses, and outputs the
+// auto allocated addresses the DNS proxy resolves, along with their host, using a tabwriter
+func (v *VIPWriter) PrintVIPs(nameTables, registries map[string][]byte) error {
+	allocated := map[string]string{}
+	for _, registry := range registries {
+		var services []registryService
+		if err := json.Unmarshal(registry, &services); err != nil {
+			continue
+		}
+		for _, svc := range services {
+			if svc.AutoAllocatedAddress != "" {
+				allocated[svc.AutoAllocatedAddress] = svc.Hostname
+			}
+		}
+	}
+
+	var table *nameTable
+	for _, response := range nameTables {
+		nt := &nameTable{}
+		// Istiods the proxy is not connected to do not return a name table.
+		if err := json.Unmarshal(response, nt); err == nil {
+			table = nt
+			break
+		}
+	}
+	if table == nil {
+		return errors.New("no Istiod returned a name table for the proxy, it may not be connected or not use the DNS proxy")
+	}
+
+	vips := make([]vip, 0)
+	for hostname, info := range table.Table {
+		for _, ip := range info.Ips {
+			if allocated[ip] == hostname {
+				vips = append(vips, vip{address: ip, hostname: hostname, namespace: info.Namespace})
+			}
+		}
+	}
+	sort.Slice(vips, func(i, j int) bool {
+		if c := bytes.Compare(net.ParseIP(vips[i].address).To16(), net.ParseIP(vips[j].address).To16()); c != 0 {
+			return c < 0
+		}
+		return vips[i].hostname < vips[j].hostname
+	})
+
+	w := new(tabwriter.Writer).Init(v.Writer, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VIP\tHOST\tNAMESPACE")
+	for _, vip := range vips {
+		_, _ = fmt.Fprintf(w, "%v\t%v\t%v\n", vip.address, vip.hostname, vip.namespace)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pilot
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"istio.io/istio/tests/util"
+)
+
+const (
+	vipNameTable = `{
+  "@type": "type.googleapis.com/istio.networking.nds.v1.NameTable",
+  "table": {
+    "b.example.com": {"ips": ["240.240.0.10"], "namespace": "ns"},
+    "a.example.com": {"ips": ["240.240.0.2"], "namespace": "default"},
+    "explicit.example.com": {"ips": ["1.2.3.4"], "namespace": "default"},
+    "productpage.default.svc.cluster.local": {"ips": ["10.0.0.1"], "registry": "Kubernetes", "namespace": "default"}
+  }
+}`
+	vipRegistry = `[
+{"hostname": "a.example.com", "address": "0.0.0.0", "autoAllocatedAddress": "240.240.0.2"},
+{"hostname": "b.example.com", "address": "0.0.0.0", "autoAllocatedAddress": "240.240.0.10"},
+{"hostname": "explicit.example.com", "address": "1.2.3.4"},
+{"hostname": "productpage.default.svc.cluster.local", "address": "10.0.0.1"},
+{}]`
+)
+
+func TestVIPWriter_PrintVIPs(t *testing.T) {
+	tests := []struct {
+		name       string
+		nameTables map[string][]byte
+		registries map[string][]byte
+		want       string
+		wantErr    bool
+	}{
+		{
+			name: "prints the auto allocated addresses of the name table by address",
+			nameTables: map[string][]byte{
+				"istiod1": []byte("Proxy not connected to this Pilot instance. It may be connected to another instance."),
+				"istiod2": []byte(vipNameTable),
+			},
+			registries: map[string][]byte{
+				"istiod1": []byte(vipRegistry),
+				"istiod2": []byte(vipRegistry),
+			},
+			want: "testdata/vips.txt",
+		},
+		{
+			name: "error if no istiod returns a name table",
+			nameTables: map[string][]byte{
+				"istiod1": []byte("Proxy not connected to this Pilot instance. It may be connected to another instance."),
+			},
+			registries: map[string][]byte{
+				"istiod1": []byte(vipRegistry),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			vw := VIPWriter{Writer: got}
+			err := vw.PrintVIPs(tt.nameTables, tt.registries)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			want, _ := ioutil.ReadFile(tt.want)
+			if err := util.Compare(got.Bytes(), want); err != nil {
+				t.Errorf(err.Error())
+			}
+		})
+	}
+}
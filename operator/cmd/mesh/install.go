@@ -125,6 +125,8 @@ func InstallCmd(logOpts *log.Options) *cobra.Command {
 
 	addFlags(ic, rootArgs)
 	addInstallFlags(ic, iArgs)
+	addChartFlags(ic)
+	addPostRenderFlags(ic)
 	return ic
 }
 
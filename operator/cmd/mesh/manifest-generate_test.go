@@ -141,6 +141,34 @@ func TestManifestGenerateComponentHubTag(t *testing.T) {
 	}
 }
 
+func TestManifestGeneratePostRender(t *testing.T) {
+	g := NewWithT(t)
+
+	// The post-render command is set by the environment, for all manifest commands.
+	os.Setenv("ISTIO_POST_RENDER_COMMAND", `["sh", "-c", "sed \"s/app: istiod$/app: post rendered/\""]`)
+	defer os.Unsetenv("ISTIO_POST_RENDER_COMMAND")
+	objss, err := runManifestCommands("post_render", "", liveCharts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, objs := range objss {
+		d := mustGetDeployment(g, objs, "istiod")
+		g.Expect(d.Unstructured()).Should(HavePathValueEqual(PathValue{"metadata.labels.app", "post rendered"}))
+	}
+
+	// Flags override the environment.
+	m, _, err := generateManifest("post_render", "--post-renderer cat", liveCharts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := parseObjectSetFromManifest(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := mustGetDeployment(g, objs, "istiod")
+	g.Expect(d.Unstructured()).Should(HavePathValueEqual(PathValue{"metadata.labels.app", "istiod"}))
+}
+
 func TestManifestGenerateGateways(t *testing.T) {
 	g := NewWithT(t)
 
@@ -42,6 +42,8 @@ func ManifestCmd(logOpts *log.Options) *cobra.Command {
 	addFlags(mdc, args)
 
 	addManifestGenerateFlags(mgc, mgcArgs)
+	addChartFlags(mgc)
+	addPostRenderFlags(mgc)
 	addManifestDiffFlags(mdc, mdcArgs)
 
 	mc.AddCommand(mgc)
@@ -19,6 +19,8 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"istio.io/istio/operator/pkg/helm"
+	"istio.io/istio/operator/pkg/postrender"
 	binversion "istio.io/istio/operator/version"
 	"istio.io/istio/pkg/url"
 	"istio.io/pkg/log"
@@ -57,6 +59,15 @@ could be secret list separated by comma, eg. '--imagePullSecrets imagePullSecret
 	OperatorRevFlagHelpStr   = `Target revision for the operator.`
 	ComponentFlagHelpStr     = "Specify which component to generate manifests for."
 	VerifyCRInstallHelpStr   = "Verify the Istio control plane after installation/in-place upgrade"
+	ChartKeyringFlagHelpStr  = `Path to a PGP keyring used to verify the provenance files of the chart archives
+passed in with --manifests. Defaults to the ISTIO_CHART_KEYRING environment variable.`
+	ChartVersionFlagHelpStr = `Version, or semver constraint, of the charts to use when --manifests is a chart
+repository. Defaults to the latest version in the repository index.`
+	PostRendererFlagHelpStr = `Path to a command that every rendered component manifest is piped through. It
+overrides the ISTIO_POST_RENDER_COMMAND environment variable.`
+	PostRendererArgsFlagHelpStr   = `An argument of the --post-renderer command. Can be specified multiple times.`
+	PostRenderPatchDirFlagHelpStr = `Path to a directory of strategic merge patches applied to every rendered
+component manifest. It overrides the ISTIO_POST_RENDER_PATCH_DIR environment variable.`
 )
 
 type rootArgs struct {
@@ -69,6 +80,19 @@ func addFlags(cmd *cobra.Command, rootArgs *rootArgs) {
 		false, "Console/log output only, make no changes.")
 }
 
+// addChartFlags adds the flags used to select and verify the charts loaded from chart archives and repositories.
+func addChartFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&helm.ChartKeyring, "chart-keyring", helm.ChartKeyring, ChartKeyringFlagHelpStr)
+	cmd.PersistentFlags().StringVar(&helm.ChartVersion, "chart-version", "", ChartVersionFlagHelpStr)
+}
+
+// addPostRenderFlags adds the flags configuring the hooks run on every rendered component manifest.
+func addPostRenderFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&postrender.Overrides.Command, "post-renderer", "", PostRendererFlagHelpStr)
+	cmd.PersistentFlags().StringArrayVar(&postrender.Overrides.Args, "post-renderer-args", nil, PostRendererArgsFlagHelpStr)
+	cmd.PersistentFlags().StringVar(&postrender.Overrides.PatchDir, "post-render-patch-dir", "", PostRenderPatchDirFlagHelpStr)
+}
+
 // GetRootCmd returns the root of the cobra command-tree.
 func GetRootCmd(args []string) *cobra.Command {
 	rootCmd := &cobra.Command{
@@ -128,6 +128,8 @@ func UpgradeCmd() *cobra.Command {
 	}
 	addFlags(cmd, rootArgs)
 	addUpgradeFlags(cmd, macArgs)
+	addChartFlags(cmd)
+	addPostRenderFlags(cmd)
 	return cmd
 }
 
@@ -28,7 +28,9 @@ import (
 	"istio.io/istio/operator/pkg/helm"
 	"istio.io/istio/operator/pkg/metrics"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
 	"istio.io/istio/operator/pkg/patch"
+	"istio.io/istio/operator/pkg/postrender"
 	"istio.io/istio/operator/pkg/tpath"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/pkg/log"
@@ -50,6 +52,8 @@ type Options struct {
 	Translator *translate.Translator
 	// Namespace is the namespace for this component.
 	Namespace string
+	// PostRenderHooks are run on the fully rendered manifest of the component.
+	PostRenderHooks []postrender.Hook
 }
 
 // IstioComponent defines the interface for a component.
@@ -421,6 +425,12 @@ func renderManifest(c IstioComponent, cf *CommonComponentFields) (string, error)
 		metrics.CountManifestRenderError(c.ComponentName(), metrics.HelmChartRenderError)
 		return "", err
 	}
+	if d, ok := cf.renderer.(helm.ChartDigester); ok && d.ChartDigest() != "" {
+		if my, err = addChartDigest(my, d.ChartDigest()); err != nil {
+			metrics.CountManifestRenderError(c.ComponentName(), metrics.HelmChartRenderError)
+			return "", err
+		}
+	}
 	my += helm.YAMLSeparator + "\n"
 	scope.Debugf("Initial manifest with merged values:\n%s\n", my)
 
@@ -448,8 +458,7 @@ func renderManifest(c IstioComponent, cf *CommonComponentFields) (string, error)
 	}
 	if !found {
 		scope.Debugf("Manifest after resources: \n%s\n", my)
-		metrics.CountManifestRender(cf.ComponentName)
-		return my, nil
+		return postRender(c, cf, my)
 	}
 	kyo, err := yaml.Marshal(overlays)
 	if err != nil {
@@ -463,10 +472,52 @@ func renderManifest(c IstioComponent, cf *CommonComponentFields) (string, error)
 	}
 
 	scope.Debugf("Manifest after resources and overlay: \n%s\n", ret)
+	return postRender(c, cf, ret)
+}
+
+// postRender runs the fully rendered manifest of the component through the configured post-render hooks.
+func postRender(c IstioComponent, cf *CommonComponentFields, manifest string) (string, error) {
+	if len(cf.PostRenderHooks) == 0 {
+		metrics.CountManifestRender(cf.ComponentName)
+		return manifest, nil
+	}
+	ret, err := postrender.Run(cf.PostRenderHooks, string(cf.ComponentName), manifest)
+	if err != nil {
+		metrics.CountManifestRenderError(c.ComponentName(), metrics.PostRenderHookError)
+		return "", err
+	}
+	scope.Debugf("Manifest after post-render hooks: \n%s\n", ret)
 	metrics.CountManifestRender(cf.ComponentName)
 	return ret, nil
 }
 
+// addChartDigest records the digest of the chart archive the manifest was rendered from in the labels and annotations
+// of every object in the manifest. Label values cannot hold a full digest, so the label only has a prefix of it.
+func addChartDigest(manifest, digest string) (string, error) {
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+	if err != nil {
+		return "", err
+	}
+	out := make(object.K8sObjects, 0, len(objs))
+	for _, o := range objs {
+		u := o.UnstructuredObject()
+		labels := u.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[helm.ChartDigestLabel] = helm.ChartDigestLabelValue(digest)
+		u.SetLabels(labels)
+		annotations := u.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[helm.ChartDigestLabel] = digest
+		u.SetAnnotations(annotations)
+		out = append(out, object.NewK8sObject(u, nil, nil))
+	}
+	return out.YAMLManifest()
+}
+
 // createHelmRenderer creates a helm renderer for the component defined by c and returns a ptr to it.
 // If a helm subdir is not found in ComponentMap translations, it is assumed to be "addon/<component name>.
 func createHelmRenderer(c *CommonComponentFields) helm.TemplateRenderer {
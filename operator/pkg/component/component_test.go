@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package component
+
+import (
+	"testing"
+
+	"istio.io/istio/operator/pkg/helm"
+	"istio.io/istio/operator/pkg/object"
+)
+
+func TestAddChartDigest(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: labeled
+  namespace: istio-system
+  labels:
+    app: istiod
+  annotations:
+    existing: annotation
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: unlabeled
+  namespace: istio-system
+`
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	got, err := addChartDigest(manifest, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objs) != 2 {
+		t.Fatalf("expected 2 objects, got %d:\n%s", len(objs), got)
+	}
+	for _, o := range objs {
+		u := o.UnstructuredObject()
+		if got, want := u.GetLabels()[helm.ChartDigestLabel], "0123456789abcdef0123456789abcdef"; got != want {
+			t.Errorf("%s: got digest label %q, want %q", o.Name, got, want)
+		}
+		if got := u.GetAnnotations()[helm.ChartDigestLabel]; got != digest {
+			t.Errorf("%s: got digest annotation %q, want %q", o.Name, got, digest)
+		}
+	}
+	labeled := objs[0].UnstructuredObject()
+	if labeled.GetLabels()["app"] != "istiod" || labeled.GetAnnotations()["existing"] != "annotation" {
+		t.Errorf("existing labels and annotations not preserved: %v %v", labeled.GetLabels(), labeled.GetAnnotations())
+	}
+}
@@ -22,6 +22,7 @@ import (
 	iop "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
 	"istio.io/istio/operator/pkg/component"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/postrender"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/istio/operator/pkg/util"
 )
@@ -33,12 +34,15 @@ type IstioControlPlane struct {
 	started    bool
 }
 
-// NewIstioControlPlane creates a new IstioControlPlane and returns a pointer to it.
-func NewIstioControlPlane(installSpec *v1alpha1.IstioOperatorSpec, translator *translate.Translator) (*IstioControlPlane, error) {
+// NewIstioControlPlane creates a new IstioControlPlane and returns a pointer to it. The post-render hooks are run on the
+// manifest of every component.
+func NewIstioControlPlane(installSpec *v1alpha1.IstioOperatorSpec, translator *translate.Translator,
+	postRenderHooks []postrender.Hook) (*IstioControlPlane, error) {
 	out := &IstioControlPlane{}
 	opts := &component.Options{
-		InstallSpec: installSpec,
-		Translator:  translator,
+		InstallSpec:     installSpec,
+		Translator:      translator,
+		PostRenderHooks: postRenderHooks,
 	}
 	for _, c := range name.AllCoreComponentNames {
 		o := *opts
@@ -64,9 +64,18 @@ type TemplateRenderer interface {
 // NewHelmRenderer creates a new helm renderer with the given parameters and returns an interface to it.
 // The format of helmBaseDir and profile strings determines the type of helm renderer returned (compiled-in, file,
 // HTTP etc.)
+// If the charts dir of operatorDataDir is a local chart repository, the chart is loaded from its archive in the
+// repository instead. If operatorDataDir was extracted from an install package archive, the renderer reports the
+// digest of the archive.
 func NewHelmRenderer(operatorDataDir, helmSubdir, componentName, namespace string) TemplateRenderer {
+	if IsChartRepo(operatorDataDir) {
+		repoDir := filepath.Join(operatorDataDir, ChartsSubdirName)
+		return NewRepoRenderer(repoDir, filepath.Base(helmSubdir), componentName, namespace)
+	}
 	dir := filepath.Join(ChartsSubdirName, helmSubdir)
-	return NewGenericRenderer(manifests.BuiltinOrDir(operatorDataDir), dir, componentName, namespace)
+	r := NewGenericRenderer(manifests.BuiltinOrDir(operatorDataDir), dir, componentName, namespace)
+	r.digest = installPackageDigest(operatorDataDir)
+	return r
 }
 
 // ReadProfileYAML reads the YAML values associated with the given profile. It uses an appropriate reader for the
@@ -43,6 +43,8 @@ type Renderer struct {
 	started       bool
 	files         fs.FS
 	dir           string
+	// digest is the digest of the chart archive, or install package archive, the chart is loaded from.
+	digest string
 }
 
 // NewFileTemplateRenderer creates a TemplateRenderer with the given parameters and returns a pointer to it.
@@ -66,6 +68,11 @@ func (h *Renderer) Run() error {
 	return nil
 }
 
+// ChartDigest implements the ChartDigester interface.
+func (h *Renderer) ChartDigest() string {
+	return h.digest
+}
+
 // RenderManifest renders the current helm templates with the current values and returns the resulting YAML manifest string.
 func (h *Renderer) RenderManifest(values string) (string, error) {
 	if !h.started {
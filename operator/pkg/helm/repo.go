@@ -0,0 +1,246 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mholt/archiver/v3"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
+
+	"istio.io/pkg/env"
+)
+
+const (
+	// RepoIndexFilename is the name of the index file of a local chart repository.
+	RepoIndexFilename = "index.yaml"
+	// ProvenanceFileSuffix is appended to a chart archive path to get the path of its provenance file.
+	ProvenanceFileSuffix = ".prov"
+	// ChartDigestPrefix is the prefix of digests returned by ChartDigest.
+	ChartDigestPrefix = "sha256:"
+	// ChartDigestLabel is the label and annotation recording the digest of the chart archive a resource was
+	// rendered from.
+	ChartDigestLabel = "install.operator.istio.io/chart-digest"
+
+	// chartDigestLabelLen is the number of hex digits of the digest kept in ChartDigestLabel.
+	chartDigestLabelLen = 32
+)
+
+var (
+	// ChartKeyring is the path of a PGP keyring used to verify chart archive provenance files. If it is set, every
+	// chart archive must have a provenance file signed by a key in the keyring. It defaults to ISTIO_CHART_KEYRING and
+	// is set by the --chart-keyring flag.
+	ChartKeyring = env.RegisterStringVar("ISTIO_CHART_KEYRING", "",
+		"Path to a PGP keyring used to verify the provenance of chart archives passed in with --manifests.").Get()
+
+	// ChartVersion is the version, or semver constraint, of the charts loaded from a chart repository. The latest
+	// version in the repository index is used if it is empty. It is set by the --chart-version flag.
+	ChartVersion string
+
+	// installPackageDigests holds the digests of the extracted install package archives, keyed by extraction dir.
+	installPackageDigests   = map[string]string{}
+	installPackageDigestsMu sync.RWMutex
+)
+
+// ChartDigester is implemented by renderers that know the digest of the chart archive they render.
+type ChartDigester interface {
+	// ChartDigest returns the digest of the chart archive, in the form sha256:<hex>, or "" if it is not known.
+	ChartDigest() string
+}
+
+// ChartDigestLabelValue returns the value of ChartDigestLabel for the given digest. The full digest does not fit in a
+// label value, so only its first 32 hex digits are kept.
+func ChartDigestLabelValue(digest string) string {
+	hex := strings.TrimPrefix(digest, ChartDigestPrefix)
+	if len(hex) > chartDigestLabelLen {
+		hex = hex[:chartDigestLabelLen]
+	}
+	return hex
+}
+
+// IsChartArchive reports whether path is a local chart or install package archive.
+func IsChartArchive(path string) bool {
+	if !strings.HasSuffix(path, ".tgz") && !strings.HasSuffix(path, ".tar.gz") {
+		return false
+	}
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
+
+// IsChartRepo reports whether the charts subdir of the given install package dir is a local chart repository, i.e.
+// it contains a chart index with archived charts rather than unpacked chart dirs.
+func IsChartRepo(operatorDataDir string) bool {
+	if operatorDataDir == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(operatorDataDir, ChartsSubdirName, RepoIndexFilename))
+	return err == nil
+}
+
+// RepoRenderer is a helm template renderer for a chart loaded from an archive in a local chart repository.
+type RepoRenderer struct {
+	*Renderer
+	repoDir   string
+	chartName string
+	version   string
+}
+
+// NewRepoRenderer creates a renderer for the chart with the given name from the chart repository in repoDir. The
+// version of the chart is selected by ChartVersion, and defaults to the latest version listed in the repository index.
+func NewRepoRenderer(repoDir, chartName, componentName, namespace string) *RepoRenderer {
+	return &RepoRenderer{
+		Renderer:  NewGenericRenderer(nil, "", componentName, namespace),
+		repoDir:   repoDir,
+		chartName: chartName,
+		version:   ChartVersion,
+	}
+}
+
+// Run implements the TemplateRenderer interface.
+func (h *RepoRenderer) Run() error {
+	if err := h.loadChart(); err != nil {
+		return err
+	}
+
+	h.started = true
+	return nil
+}
+
+func (h *RepoRenderer) loadChart() error {
+	index, err := repo.LoadIndexFile(filepath.Join(h.repoDir, RepoIndexFilename))
+	if err != nil {
+		return fmt.Errorf("load chart repository index: %v", err)
+	}
+	cv, err := index.Get(h.chartName, h.version)
+	if err != nil {
+		return fmt.Errorf("component %q: chart %q version %q not found in repository %s: %v", h.componentName,
+			h.chartName, h.version, h.repoDir, err)
+	}
+	if len(cv.URLs) == 0 {
+		return fmt.Errorf("chart %s-%s has no archive URL in repository %s", cv.Name, cv.Version, h.repoDir)
+	}
+	archive := cv.URLs[0]
+	if strings.Contains(archive, "://") {
+		return fmt.Errorf("chart %s-%s: remote archive %s is not supported, only archives local to the repository",
+			cv.Name, cv.Version, archive)
+	}
+	if !filepath.IsAbs(archive) {
+		archive = filepath.Join(h.repoDir, archive)
+	}
+
+	digest, err := VerifyChartArchive(archive)
+	if err != nil {
+		return err
+	}
+	if cv.Digest != "" && ChartDigestPrefix+cv.Digest != digest {
+		return fmt.Errorf("chart %s-%s: digest %s does not match repository index digest %s", cv.Name, cv.Version,
+			digest, cv.Digest)
+	}
+
+	h.chart, err = loader.LoadFile(archive)
+	if err != nil {
+		return fmt.Errorf("load chart archive %s: %v", archive, err)
+	}
+	h.digest = digest
+	scope.Debugf("Chart loaded from %s (%s)", archive, digest)
+	return nil
+}
+
+// VerifyChartArchive computes the digest of the given archive and, if a provenance file exists next to it or a
+// keyring is configured through ChartKeyring, verifies the archive against its provenance file.
+// It returns the digest of the archive in the form sha256:<hex>.
+func VerifyChartArchive(archive string) (string, error) {
+	sum, err := provenance.DigestFile(archive)
+	if err != nil {
+		return "", fmt.Errorf("digest chart archive %s: %v", archive, err)
+	}
+	digest := ChartDigestPrefix + sum
+
+	provFile := archive + ProvenanceFileSuffix
+	_, statErr := os.Stat(provFile)
+	keyring := ChartKeyring
+	switch {
+	case keyring == "" && statErr == nil:
+		scope.Warnf("Provenance file %s found but no chart keyring is set, skipping verification", provFile)
+		return digest, nil
+	case keyring == "":
+		return digest, nil
+	case statErr != nil:
+		return "", fmt.Errorf("chart archive %s has no provenance file %s: %v", archive, provFile, statErr)
+	}
+
+	sig, err := provenance.NewFromKeyring(keyring, "")
+	if err != nil {
+		return "", fmt.Errorf("load keyring %s: %v", keyring, err)
+	}
+	ver, err := sig.Verify(archive, provFile)
+	if err != nil {
+		return "", fmt.Errorf("verify provenance of chart archive %s: %v", archive, err)
+	}
+	if ver.FileHash != digest {
+		return "", fmt.Errorf("chart archive %s: provenance hash %s does not match digest %s", archive, ver.FileHash, digest)
+	}
+	for name := range ver.SignedBy.Identities {
+		scope.Infof("Chart archive %s (%s) signed by %s", archive, digest, name)
+	}
+	return digest, nil
+}
+
+// ExtractInstallPackage verifies the install package archive at archivePath and extracts it into a subdir of
+// destDirRoot named after the archive. It returns the path of the extracted dir. The digest of the archive is recorded
+// for the charts rendered from the extracted dir.
+func ExtractInstallPackage(archivePath, destDirRoot string) (string, error) {
+	digest, err := VerifyChartArchive(archivePath)
+	if err != nil {
+		return "", err
+	}
+	if destDirRoot == "" {
+		destDirRoot = filepath.Join(os.TempDir(), InstallationDirectory)
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(archivePath), ".tgz"), ".tar.gz")
+	destDir := filepath.Join(destDirRoot, name)
+	targz := archiver.TarGz{Tar: &archiver.Tar{OverwriteExisting: true, MkdirAll: true}}
+	if err := targz.Unarchive(archivePath, destDir); err != nil {
+		return "", fmt.Errorf("extract install package %s: %v", archivePath, err)
+	}
+
+	installPackageDigestsMu.Lock()
+	installPackageDigests[destDir] = digest
+	installPackageDigestsMu.Unlock()
+	return destDir, nil
+}
+
+// installPackageDigest returns the digest of the install package archive the given dir was extracted from, or "" if
+// it was not extracted from an archive.
+func installPackageDigest(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	installPackageDigestsMu.RLock()
+	defer installPackageDigestsMu.RUnlock()
+	for destDir, digest := range installPackageDigests {
+		rel, err := filepath.Rel(destDir, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return digest
+		}
+	}
+	return ""
+}
@@ -0,0 +1,291 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+const testChartYAML = `apiVersion: v1
+name: istio-discovery
+version: 1.0.0
+`
+
+const testChartTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  description: {{ .Values.description }}
+`
+
+// writeTarGz writes a gzipped tar archive with the given files, keyed by path, to archive.
+func writeTarGz(t *testing.T, archive string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gw, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeChartRepo writes a chart repository with a single istio-discovery chart archive into dir and returns the
+// sha256 digest of the archive. If indexDigest is not empty, it is written to the index instead of the real digest.
+func writeChartRepo(t *testing.T, dir, indexDigest string) string {
+	t.Helper()
+	archive := filepath.Join(dir, "istio-discovery-1.0.0.tgz")
+	writeTarGz(t, archive, map[string]string{
+		"istio-discovery/Chart.yaml":          testChartYAML,
+		"istio-discovery/templates/test.yaml": testChartTemplate,
+	})
+
+	b, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(b)
+	digest := hex.EncodeToString(sum[:])
+	if indexDigest == "" {
+		indexDigest = digest
+	}
+	index := fmt.Sprintf(`apiVersion: v1
+entries:
+  istio-discovery:
+  - apiVersion: v1
+    name: istio-discovery
+    version: 1.0.0
+    digest: %s
+    urls:
+    - istio-discovery-1.0.0.tgz
+`, indexDigest)
+	if err := ioutil.WriteFile(filepath.Join(dir, RepoIndexFilename), []byte(index), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return digest
+}
+
+func TestRepoRenderer(t *testing.T) {
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, ChartsSubdirName)
+	if err := os.Mkdir(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	digest := writeChartRepo(t, repoDir, "")
+
+	if !IsChartRepo(dir) {
+		t.Fatalf("expected %s to be a chart repository", dir)
+	}
+	r, ok := NewHelmRenderer(dir, "istio-control/istio-discovery", "Pilot", "istio-system").(*RepoRenderer)
+	if !ok {
+		t.Fatalf("expected a RepoRenderer for a chart repository")
+	}
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.ChartDigest(), ChartDigestPrefix+digest; got != want {
+		t.Errorf("got digest %s, want %s", got, want)
+	}
+	got, err := r.RenderManifest("description: forked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "description: forked") {
+		t.Errorf("unexpected manifest:\n%s", got)
+	}
+}
+
+func TestRepoRendererDigestMismatch(t *testing.T) {
+	dir := t.TempDir()
+	writeChartRepo(t, dir, strings.Repeat("0", 64))
+
+	r := NewRepoRenderer(dir, "istio-discovery", "Pilot", "istio-system")
+	err := r.Run()
+	if err == nil || !strings.Contains(err.Error(), "does not match repository index digest") {
+		t.Fatalf("expected digest mismatch error, got %v", err)
+	}
+}
+
+func TestChartDigestLabelValue(t *testing.T) {
+	digest := ChartDigestPrefix + strings.Repeat("ab", 32)
+	got := ChartDigestLabelValue(digest)
+	if len(got) != chartDigestLabelLen || !strings.HasPrefix(digest, ChartDigestPrefix+got) {
+		t.Errorf("unexpected label value %q for digest %q", got, digest)
+	}
+}
+
+func TestRepoRendererVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeChartRepo(t, dir, "")
+	defer func(v string) { ChartVersion = v }(ChartVersion)
+
+	for _, tt := range []struct {
+		version string
+		wantErr bool
+	}{
+		{version: "", wantErr: false},
+		{version: "1.0.0", wantErr: false},
+		{version: "~1.0", wantErr: false},
+		{version: "2.0.0", wantErr: true},
+	} {
+		ChartVersion = tt.version
+		err := NewRepoRenderer(dir, "istio-discovery", "Pilot", "istio-system").Run()
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Errorf("version %q: got error %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+	}
+}
+
+// writeKeyring writes a keyring with a new signing key to dir and returns its path.
+func writeKeyring(t *testing.T, dir string) string {
+	t.Helper()
+	entity, err := openpgp.NewEntity("Istio Test", "", "test@istio.io", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := filepath.Join(dir, "secring.gpg")
+	f, err := os.Create(keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := entity.SerializePrivate(f, nil); err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestVerifyChartArchive(t *testing.T) {
+	dir := t.TempDir()
+	digest := ChartDigestPrefix + writeChartRepo(t, dir, "")
+	archive := filepath.Join(dir, "istio-discovery-1.0.0.tgz")
+	keyring := writeKeyring(t, t.TempDir())
+	otherKeyring := writeKeyring(t, t.TempDir())
+
+	signer, err := provenance.NewFromKeyring(keyring, "Istio Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov, err := signer.ClearSign(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(k string) { ChartKeyring = k }(ChartKeyring)
+
+	tests := []struct {
+		name    string
+		keyring string
+		prov    string
+		wantErr string
+	}{
+		{name: "no keyring, no provenance"},
+		{name: "no keyring, provenance not verified", prov: "invalid"},
+		{name: "signed", keyring: keyring, prov: prov},
+		{name: "missing provenance", keyring: keyring, wantErr: "has no provenance file"},
+		{name: "unknown signer", keyring: otherKeyring, prov: prov, wantErr: "verify provenance"},
+		{name: "tampered provenance", keyring: keyring, prov: strings.Replace(prov, "1.0.0", "1.0.1", 1), wantErr: "verify provenance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provFile := archive + ProvenanceFileSuffix
+			os.Remove(provFile)
+			if tt.prov != "" {
+				if err := ioutil.WriteFile(provFile, []byte(tt.prov), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ChartKeyring = tt.keyring
+
+			got, err := VerifyChartArchive(archive)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != digest {
+				t.Errorf("got digest %s, want %s", got, digest)
+			}
+		})
+	}
+}
+
+func TestExtractInstallPackage(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "istio-test.tgz")
+	writeTarGz(t, archive, map[string]string{
+		"manifests/charts/istio-control/istio-discovery/Chart.yaml":          testChartYAML,
+		"manifests/charts/istio-control/istio-discovery/templates/test.yaml": testChartTemplate,
+	})
+	sum, err := provenance.DigestFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destDir, err := ExtractInstallPackage(archive, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := filepath.Base(destDir), "istio-test"; got != want {
+		t.Errorf("got extraction dir %s, want %s", got, want)
+	}
+	manifestsDir := filepath.Join(destDir, "manifests")
+	r := NewHelmRenderer(manifestsDir, "istio-control/istio-discovery", "Pilot", "istio-system")
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.RenderManifest("description: extracted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "description: extracted") {
+		t.Errorf("unexpected manifest:\n%s", got)
+	}
+	if got, want := r.(ChartDigester).ChartDigest(), ChartDigestPrefix+sum; got != want {
+		t.Errorf("got digest %s, want %s", got, want)
+	}
+
+	// Charts that are not extracted from an archive have no digest.
+	if got := NewHelmRenderer(t.TempDir(), "istio-control/istio-discovery", "Pilot", "istio-system").(ChartDigester).ChartDigest(); got != "" {
+		t.Errorf("expected no digest, got %s", got)
+	}
+}
@@ -19,6 +19,7 @@ import (
 
 	"istio.io/istio/operator/pkg/controlplane"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/postrender"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/istio/operator/pkg/validate"
 )
@@ -34,8 +35,12 @@ func (h *HelmReconciler) RenderCharts() (name.ManifestMap, error) {
 	}
 
 	t := translate.NewTranslator()
+	hooks, err := postrender.HooksFor()
+	if err != nil {
+		return nil, err
+	}
 
-	cp, err := controlplane.NewIstioControlPlane(iopSpec, t)
+	cp, err := controlplane.NewIstioControlPlane(iopSpec, t, hooks)
 	if err != nil {
 		return nil, err
 	}
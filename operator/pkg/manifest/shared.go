@@ -33,6 +33,7 @@ import (
 	"istio.io/istio/operator/pkg/controlplane"
 	"istio.io/istio/operator/pkg/helm"
 	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/postrender"
 	"istio.io/istio/operator/pkg/tpath"
 	"istio.io/istio/operator/pkg/translate"
 	"istio.io/istio/operator/pkg/util"
@@ -63,8 +64,12 @@ func GenManifests(inFilename []string, setFlags []string, force bool,
 	}
 
 	t := translate.NewTranslator()
+	hooks, err := postrender.HooksFor()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	cp, err := controlplane.NewIstioControlPlane(mergedIOPS.Spec, t)
+	cp, err := controlplane.NewIstioControlPlane(mergedIOPS.Spec, t, hooks)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -343,9 +348,23 @@ func fetchExtractInstallPackageHTTP(releaseTarURL string) (string, error) {
 	return uf.DestDir(), nil
 }
 
+// extractInstallPackageArchive verifies and extracts a local install package archive, which is an archive of a
+// manifests dir containing charts and profiles. If successful, it returns the path of the extracted manifests dir.
+func extractInstallPackageArchive(archivePath string) (string, error) {
+	destDir, err := helm.ExtractInstallPackage(archivePath, "")
+	if err != nil {
+		return "", err
+	}
+	// Archives created from a release or a checkout have a top level manifests dir.
+	if _, err := os.Stat(filepath.Join(destDir, helm.OperatorSubdirFilePath)); err == nil {
+		return filepath.Join(destDir, helm.OperatorSubdirFilePath), nil
+	}
+	return destDir, nil
+}
+
 // rewriteURLToLocalInstallPath checks installPackagePath and if it is a URL, it tries to download and extract the
 // Istio release tar at the URL to a local file path. If successful, it returns the resulting local paths to the
-// installation charts and profile file.
+// installation charts and profile file. A local install package archive is verified and extracted the same way.
 // If installPackagePath is not a URL, it returns installPackagePath and profileOrPath unmodified.
 func rewriteURLToLocalInstallPath(installPackagePath, profileOrPath string, skipValidation bool) (string, string, error) {
 	isURL, err := util.IsHTTPURL(installPackagePath)
@@ -367,6 +386,11 @@ func rewriteURLToLocalInstallPath(installPackagePath, profileOrPath string, skip
 		profileOrPath = filepath.Join(baseDir, "profiles", profileOrPath+".yaml")
 		// Rewrite installPackagePath to the local file path for further processing.
 		installPackagePath = baseDir
+	} else if helm.IsChartArchive(installPackagePath) {
+		installPackagePath, err = extractInstallPackageArchive(installPackagePath)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
 	return installPackagePath, profileOrPath, nil
@@ -89,6 +89,9 @@ const (
 
 	// K8SManifestPatchError describes errors while patching generated manifest.
 	K8SManifestPatchError RenderErrorType = "k8s_manifest_patch"
+
+	// PostRenderHookError describes errors while running post-render hooks on the generated manifest.
+	PostRenderHookError RenderErrorType = "post_render_hook"
 )
 
 var (
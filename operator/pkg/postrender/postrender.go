@@ -0,0 +1,276 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+/*
+Package postrender implements hooks that post-process the fully rendered manifest of a component, after helm
+rendering, K8s settings and K8s overlays have been applied.
+
+Two kinds of hooks are supported:
+
+  - a patch dir: a directory of strategic merge patches. Each YAML document in the directory is a partial K8s object
+    identified by apiVersion, kind, metadata.name and optionally metadata.namespace. A name of "*" matches every
+    object of the given kind. Patches are applied in file name order.
+  - a command: an external command which receives the manifest on stdin and must write the modified manifest to
+    stdout, in the same way as a helm post-renderer. The component name is passed in the ISTIO_COMPONENT environment
+    variable.
+
+Hooks are configured with the istioctl --post-renderer, --post-renderer-args and --post-render-patch-dir flags, or
+the ISTIO_POST_RENDER_COMMAND and ISTIO_POST_RENDER_PATCH_DIR environment variables, which take a JSON list of the
+command and its arguments and a directory. The in-cluster operator controller only runs the hooks set by the
+environment variables of its own deployment; they are never read from the IstioOperator, as anyone able to edit it
+could otherwise run any command, or read any file, in the operator pod.
+
+If a patch dir and a command are both set, patches are applied before the command is run.
+*/
+package postrender
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/pkg/env"
+	"istio.io/pkg/log"
+)
+
+const (
+	// ComponentEnvVar is the environment variable holding the component name for a post-render command.
+	ComponentEnvVar = "ISTIO_COMPONENT"
+	// matchAllName is a patch name that matches all objects of a kind.
+	matchAllName = "*"
+)
+
+var (
+	scope = log.RegisterScope("installer", "installer", 0)
+
+	commandVar = env.RegisterStringVar("ISTIO_POST_RENDER_COMMAND", "",
+		"JSON list of the command and arguments that every rendered component manifest is piped through.")
+	patchDirVar = env.RegisterStringVar("ISTIO_POST_RENDER_PATCH_DIR", "",
+		"Directory of strategic merge patches applied to every rendered component manifest.")
+
+	// Overrides is the config set by istioctl flags, which takes precedence over the environment variables.
+	Overrides Config
+)
+
+// Config selects the post-render hooks.
+type Config struct {
+	// Command is the post-render command, run with Args.
+	Command string
+	Args    []string
+	// PatchDir is the directory of post-render patches.
+	PatchDir string
+}
+
+// Hook post-processes the rendered manifest of a component.
+type Hook interface {
+	// Run returns the manifest of the component with the given name after post-processing.
+	Run(componentName, manifest string) (string, error)
+}
+
+// HooksFor returns the configured hooks, in the order they must be run. Patches are loaded once, so the hooks must be
+// created again to pick up changes to the patch dir.
+func HooksFor() ([]Hook, error) {
+	cfg := Config{PatchDir: patchDirVar.Get()}
+	var err error
+	if cfg.Command, cfg.Args, err = parseCommand(commandVar.Get()); err != nil {
+		return nil, fmt.Errorf("invalid ISTIO_POST_RENDER_COMMAND: %v", err)
+	}
+	if Overrides.Command != "" {
+		cfg.Command, cfg.Args = Overrides.Command, Overrides.Args
+	}
+	if Overrides.PatchDir != "" {
+		cfg.PatchDir = Overrides.PatchDir
+	}
+
+	var hooks []Hook
+	if cfg.PatchDir != "" {
+		h, err := NewPatchDirHook(cfg.PatchDir)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, h)
+	}
+	if cfg.Command != "" {
+		hooks = append(hooks, &CommandHook{Command: append([]string{cfg.Command}, cfg.Args...)})
+	}
+	return hooks, nil
+}
+
+// parseCommand parses a JSON list of a command and its arguments.
+func parseCommand(s string) (string, []string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil, nil
+	}
+	var argv []string
+	if err := json.Unmarshal([]byte(s), &argv); err != nil {
+		return "", nil, fmt.Errorf("expected a JSON list of the command and its arguments: %v", err)
+	}
+	if len(argv) == 0 || argv[0] == "" {
+		return "", nil, nil
+	}
+	return argv[0], argv[1:], nil
+}
+
+// Run runs the manifest of the component with the given name through all hooks, in order.
+func Run(hooks []Hook, componentName, manifest string) (string, error) {
+	var err error
+	for _, h := range hooks {
+		if manifest, err = h.Run(componentName, manifest); err != nil {
+			return "", err
+		}
+	}
+	return manifest, nil
+}
+
+// CommandHook pipes the manifest through an external command.
+type CommandHook struct {
+	// Command is the command and its arguments.
+	Command []string
+}
+
+// Run implements the Hook interface.
+func (h *CommandHook) Run(componentName, manifest string) (string, error) {
+	cmd := exec.Command(h.Command[0], h.Command[1:]...)
+	cmd.Env = append(os.Environ(), ComponentEnvVar+"="+componentName)
+	cmd.Stdin = strings.NewReader(manifest)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("post-render command %q failed for component %s: %v: %s",
+			strings.Join(h.Command, " "), componentName, err, strings.TrimSpace(stderr.String()))
+	}
+	scope.Debugf("Post-render command %q applied to component %s", strings.Join(h.Command, " "), componentName)
+	return stdout.String(), nil
+}
+
+// PatchDirHook applies the strategic merge patches in a directory to the manifest.
+type PatchDirHook struct {
+	// Dir is the directory holding the patches.
+	Dir     string
+	patches object.K8sObjects
+}
+
+// NewPatchDirHook creates a hook for the patches in dir, which are read once.
+func NewPatchDirHook(dir string) (*PatchDirHook, error) {
+	patches, err := readPatches(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &PatchDirHook{Dir: dir, patches: patches}, nil
+}
+
+// Run implements the Hook interface.
+func (h *PatchDirHook) Run(componentName, manifest string) (string, error) {
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+	if err != nil {
+		return "", err
+	}
+	for i, o := range objs {
+		for _, p := range h.patches {
+			if !matches(p, o) {
+				continue
+			}
+			if o, err = applyPatch(o, p); err != nil {
+				return "", fmt.Errorf("component %s: %v", componentName, err)
+			}
+			scope.Debugf("Post-render patch %s applied to %s in component %s", p.HashNameKind(), o.Hash(), componentName)
+		}
+		objs[i] = o
+	}
+	return objs.YAMLManifest()
+}
+
+// readPatches reads all patches from the YAML and JSON files in dir, sorted by file name.
+func readPatches(dir string) (object.K8sObjects, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read post-render patch dir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var patches object.K8sObjects
+	for _, name := range names {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		objs, err := object.ParseK8sObjectsFromYAMLManifest(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("post-render patch file %s: %v", name, err)
+		}
+		patches = append(patches, objs...)
+	}
+	return patches, nil
+}
+
+// matches reports whether the patch p targets the object o.
+func matches(p, o *object.K8sObject) bool {
+	if p.GroupVersionKind().GroupKind() != o.GroupVersionKind().GroupKind() {
+		return false
+	}
+	if p.Name != matchAllName && p.Name != o.Name {
+		return false
+	}
+	return p.Namespace == "" || p.Namespace == o.Namespace
+}
+
+// applyPatch applies p to o as a strategic merge patch if the type of o is known, or as a JSON merge patch otherwise.
+func applyPatch(o, p *object.K8sObject) (*object.K8sObject, error) {
+	// The patch must not rename the object when matching by wildcard.
+	pu := p.UnstructuredObject().DeepCopy()
+	pu.SetName(o.Name)
+	pu.SetNamespace(o.Namespace)
+	patchJSON, err := pu.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	baseJSON, err := o.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var newJSON []byte
+	var versionedObject runtime.Object
+	if versionedObject, err = scheme.Scheme.New(o.GroupVersionKind()); err == nil {
+		newJSON, err = strategicpatch.StrategicMergePatch(baseJSON, patchJSON, versionedObject)
+	} else {
+		newJSON, err = jsonpatch.MergePatch(baseJSON, patchJSON)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply post-render patch to %s: %v", o.Hash(), err)
+	}
+	return object.ParseJSONToK8sObject(newJSON)
+}
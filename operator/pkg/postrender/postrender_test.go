@@ -0,0 +1,241 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postrender
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"istio.io/istio/operator/pkg/object"
+)
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: pilot
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istio-ingressgateway
+  namespace: istio-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: istio-proxy
+        image: proxyv2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: stats
+  namespace: istio-system
+spec:
+  priority: 1
+`
+
+func TestPatchDirHook(t *testing.T) {
+	dir := t.TempDir()
+	patches := map[string]string{
+		"01-all-deployments.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "*"
+spec:
+  template:
+    spec:
+      securityContext:
+        runAsNonRoot: true
+`,
+		"02-istiod.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: discovery
+        securityContext:
+          readOnlyRootFilesystem: true
+`,
+		"03-filter.yaml": `apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: stats
+spec:
+  priority: 10
+`,
+		"README.md": `not a patch`,
+	}
+	for name, content := range patches {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := NewPatchDirHook(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Patches are read once, when the hook is created.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Run([]Hook{h}, "Pilot", testManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := objs.ToNameKindMap()
+
+	for _, name := range []string{"istiod", "istio-ingressgateway"} {
+		d := byName[object.HashNameKind("Deployment", name)].Unstructured()
+		if !strings.Contains(toString(d), "runAsNonRoot:true") {
+			t.Errorf("deployment %s: expected wildcard patch to be applied, got %v", name, d)
+		}
+	}
+	istiod := toString(byName[object.HashNameKind("Deployment", "istiod")].Unstructured())
+	if !strings.Contains(istiod, "readOnlyRootFilesystem:true") || !strings.Contains(istiod, "image:pilot") {
+		t.Errorf("expected container patch to be merged by name, got %v", istiod)
+	}
+	ingress := toString(byName[object.HashNameKind("Deployment", "istio-ingressgateway")].Unstructured())
+	if strings.Contains(ingress, "readOnlyRootFilesystem") {
+		t.Errorf("istiod patch applied to ingress gateway: %v", ingress)
+	}
+	filter := toString(byName[object.HashNameKind("EnvoyFilter", "stats")].Unstructured())
+	if !strings.Contains(filter, "priority:10") {
+		t.Errorf("expected merge patch on custom resource, got %v", filter)
+	}
+}
+
+func TestCommandHook(t *testing.T) {
+	h := &CommandHook{Command: []string{"sh", "-c", `sed "s/image: pilot/image: $ISTIO_COMPONENT/"`}}
+	got, err := h.Run("Pilot", testManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "image: Pilot") {
+		t.Errorf("expected command output, got:\n%s", got)
+	}
+
+	h = &CommandHook{Command: []string{"sh", "-c", "echo bad patch >&2; exit 1"}}
+	if _, err := h.Run("Pilot", testManifest); err == nil || !strings.Contains(err.Error(), "bad patch") {
+		t.Errorf("expected error with command stderr, got %v", err)
+	}
+}
+
+func TestHooksFor(t *testing.T) {
+	patchDir := t.TempDir()
+	defer func(o Config) { Overrides = o }(Overrides)
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		overrides   Config
+		wantCommand []string
+		wantPatches string
+		wantErr     string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:        "env",
+			env:         map[string]string{"ISTIO_POST_RENDER_COMMAND": `["sh", "-c", "sed 's/a b/c/'"]`, "ISTIO_POST_RENDER_PATCH_DIR": patchDir},
+			wantCommand: []string{"sh", "-c", "sed 's/a b/c/'"},
+			wantPatches: patchDir,
+		},
+		{
+			name:        "flags override env",
+			env:         map[string]string{"ISTIO_POST_RENDER_COMMAND": `["kustomize"]`, "ISTIO_POST_RENDER_PATCH_DIR": "/does/not/exist"},
+			overrides:   Config{Command: "/bin/post render", Args: []string{"--name", "a b"}, PatchDir: patchDir},
+			wantCommand: []string{"/bin/post render", "--name", "a b"},
+			wantPatches: patchDir,
+		},
+		{
+			name:    "invalid env",
+			env:     map[string]string{"ISTIO_POST_RENDER_COMMAND": `kustomize build`},
+			wantErr: "invalid ISTIO_POST_RENDER_COMMAND",
+		},
+		{
+			name:    "missing patch dir",
+			env:     map[string]string{"ISTIO_POST_RENDER_PATCH_DIR": "/does/not/exist"},
+			wantErr: "read post-render patch dir",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"ISTIO_POST_RENDER_COMMAND", "ISTIO_POST_RENDER_PATCH_DIR"} {
+				if v, ok := tt.env[k]; ok {
+					os.Setenv(k, v)
+				} else {
+					os.Unsetenv(k)
+				}
+				defer os.Unsetenv(k)
+			}
+			Overrides = tt.overrides
+
+			hooks, err := HooksFor()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotCommand []string
+			var gotPatches string
+			for _, h := range hooks {
+				switch h := h.(type) {
+				case *CommandHook:
+					gotCommand = h.Command
+				case *PatchDirHook:
+					gotPatches = h.Dir
+				}
+			}
+			if !reflect.DeepEqual(gotCommand, tt.wantCommand) {
+				t.Errorf("got command %q, want %q", gotCommand, tt.wantCommand)
+			}
+			if gotPatches != tt.wantPatches {
+				t.Errorf("got patch dir %q, want %q", gotPatches, tt.wantPatches)
+			}
+		})
+	}
+}
+
+// toString formats the object so that nested fields read as key:value.
+func toString(m map[string]interface{}) string {
+	return fmt.Sprint(m)
+}
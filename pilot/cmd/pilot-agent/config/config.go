@@ -53,14 +53,6 @@ func ConstructProxyConfig(meshConfigFile, serviceCluster, proxyConfigEnv string,
 		}
 		fileMeshContents = string(contents)
 	}
-	meshConfig, err := getMeshConfig(fileMeshContents, annotations[annotation.ProxyConfig.Name], proxyConfigEnv, role.Type == model.SidecarProxy)
-	if err != nil {
-		return nil, err
-	}
-	proxyConfig := mesh.DefaultProxyConfig()
-	if meshConfig.DefaultConfig != nil {
-		proxyConfig = *meshConfig.DefaultConfig
-	}
 
 	// If concurrency is unset, we will automatically set this based on CPU requests/limits for sidecars.
 	// For gateways, this will use all available CPUs.
@@ -68,11 +60,27 @@ func ConstructProxyConfig(meshConfigFile, serviceCluster, proxyConfigEnv string,
 	if concurrency == 0 && role.Type == model.SidecarProxy {
 		byResources := determineConcurrencyOption()
 		if byResources != nil {
-			proxyConfig.Concurrency = byResources
+			concurrency = int(byResources.Value)
 		}
-	} else if concurrency != 0 {
-		// If --concurrency is explicitly set, we will use that. Otherwise, use source determined by
-		// proxy config.
+	}
+	return ProxyConfigFor(fileMeshContents, annotations, serviceCluster, proxyConfigEnv, concurrency, role.Type == model.SidecarProxy)
+}
+
+// ProxyConfigFor builds the proxy config from the mesh config file contents, the pod annotations and the
+// PROXY_CONFIG environment variable, as the agent does at startup. A zero concurrency keeps the concurrency
+// of the proxy config. This allows tools to reproduce the proxy config of a pod without running it.
+func ProxyConfigFor(fileMeshContents string, annotations map[string]string, serviceCluster, proxyConfigEnv string,
+	concurrency int, isSidecar bool) (*meshconfig.ProxyConfig, error) {
+	meshConfig, err := getMeshConfig(fileMeshContents, annotations[annotation.ProxyConfig.Name], proxyConfigEnv, isSidecar)
+	if err != nil {
+		return nil, err
+	}
+	proxyConfig := mesh.DefaultProxyConfig()
+	if meshConfig.DefaultConfig != nil {
+		proxyConfig = *meshConfig.DefaultConfig
+	}
+
+	if concurrency != 0 {
 		proxyConfig.Concurrency = &types.Int32Value{Value: int32(concurrency)}
 	}
 	proxyConfig.ServiceCluster = serviceCluster
@@ -94,21 +102,30 @@ func ConstructProxyConfig(meshConfigFile, serviceCluster, proxyConfigEnv string,
 
 // determineConcurrencyOption determines the correct setting for --concurrency based on CPU requests/limits
 func determineConcurrencyOption() *types.Int32Value {
-	// If limit is set, us that
 	// The format in the file is a plain integer. `100` in the file is equal to `100m` (based on `divisor: 1m`
 	// in the pod spec).
+	limit, _ := readPodCPULimits()
+	requests, _ := readPodCPURequests()
+	if concurrency := ConcurrencyForCPU(int64(limit), int64(requests)); concurrency > 0 {
+		return &types.Int32Value{Value: int32(concurrency)}
+	}
+	return nil
+}
+
+// ConcurrencyForCPU returns the concurrency of a sidecar with the given CPU limit and requests, in millicores,
+// or 0 if neither is set.
+func ConcurrencyForCPU(limit, requests int64) int {
+	// If limit is set, us that
 	// With the resource setting, we round up to single integer number; for example, if we have a 500m limit
 	// the pod will get concurrency=1. With 6500m, it will get concurrency=7.
-	limit, err := readPodCPULimits()
-	if err == nil && limit > 0 {
-		return &types.Int32Value{Value: int32(math.Ceil(float64(limit) / 1000))}
+	if limit > 0 {
+		return int(math.Ceil(float64(limit) / 1000))
 	}
 	// If limit is unset, use requests instead, with the same logic.
-	requests, err := readPodCPURequests()
-	if err == nil && requests > 0 {
-		return &types.Int32Value{Value: int32(math.Ceil(float64(requests) / 1000))}
+	if requests > 0 {
+		return int(math.Ceil(float64(requests) / 1000))
 	}
-	return nil
+	return 0
 }
 
 // getMeshConfig gets the mesh config to use for proxy configuration
@@ -132,3 +132,24 @@ proxyStatsMatcher:
 		})
 	}
 }
+
+func TestConcurrencyForCPU(t *testing.T) {
+	cases := []struct {
+		name     string
+		limit    int64
+		requests int64
+		expect   int
+	}{
+		{"unset", 0, 0, 0},
+		{"limit rounded up", 500, 0, 1},
+		{"limit preferred", 6500, 1000, 7},
+		{"requests", 0, 2000, 2},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConcurrencyForCPU(tt.limit, tt.requests); got != tt.expect {
+				t.Fatalf("got concurrency %v, expected %v", got, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/security/pkg/stsservice/tokenmanager"
+)
+
+// ProxyArgs are the arguments of the pilot-agent proxy command.
+type ProxyArgs struct {
+	DNSDomain          string
+	StsPort            int
+	TokenManagerPlugin string
+
+	MeshConfigFile string
+
+	// proxy config flags (named identically)
+	ServiceCluster         string
+	ProxyLogLevel          string
+	ProxyComponentLogLevel string
+	Concurrency            int
+	TemplateFile           string
+	OutlierLogPath         string
+}
+
+// AttachFlags registers the pilot-agent proxy command flags on the flag set. Tools parsing the arguments of
+// an istio-proxy container use it to interpret them as the agent does.
+func (a *ProxyArgs) AttachFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&a.DNSDomain, "domain", "",
+		"DNS domain suffix. If not provided uses ${POD_NAMESPACE}.svc.cluster.local")
+	fs.StringVar(&a.MeshConfigFile, "meshConfig", "./etc/istio/config/mesh",
+		"File name for Istio mesh configuration. If not specified, a default mesh will be used. This may be overridden by "+
+			"PROXY_CONFIG environment variable or proxy.istio.io/config annotation.")
+	fs.IntVar(&a.StsPort, "stsPort", 0,
+		"HTTP Port on which to serve Security Token Service (STS). If zero, STS service will not be provided.")
+	fs.StringVar(&a.TokenManagerPlugin, "tokenManagerPlugin", tokenmanager.GoogleTokenExchange,
+		"Token provider specific plugin name.")
+	// Flags for proxy configuration
+	fs.StringVar(&a.ServiceCluster, "serviceCluster", constants.ServiceClusterName, "Service cluster")
+	// Log levels are provided by the library https://github.com/gabime/spdlog, used by Envoy.
+	fs.StringVar(&a.ProxyLogLevel, "proxyLogLevel", "warning",
+		fmt.Sprintf("The log level used to start the Envoy proxy (choose from {%s, %s, %s, %s, %s, %s, %s})",
+			"trace", "debug", "info", "warning", "error", "critical", "off"))
+	fs.IntVar(&a.Concurrency, "concurrency", 0, "number of worker threads to run")
+	// See https://www.envoyproxy.io/docs/envoy/latest/operations/cli#cmdoption-component-log-level
+	fs.StringVar(&a.ProxyComponentLogLevel, "proxyComponentLogLevel", "misc:error",
+		"The component log level used to start the Envoy proxy")
+	fs.StringVar(&a.TemplateFile, "templateFile", "",
+		"Go template bootstrap config")
+	fs.StringVar(&a.OutlierLogPath, "outlierLogPath", "",
+		"The log path for outlier detection")
+}
@@ -40,7 +40,6 @@ import (
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/util/gogoprotomarshal"
 	stsserver "istio.io/istio/security/pkg/stsservice/server"
-	"istio.io/istio/security/pkg/stsservice/tokenmanager"
 	cleaniptables "istio.io/istio/tools/istio-clean-iptables/pkg/cmd"
 	iptables "istio.io/istio/tools/istio-iptables/pkg/cmd"
 	"istio.io/pkg/collateral"
@@ -56,20 +55,8 @@ const (
 // TODO: Move most of this to pkg options.
 
 var (
-	dnsDomain          string
-	stsPort            int
-	tokenManagerPlugin string
-
-	meshConfigFile string
-
-	// proxy config flags (named identically)
-	serviceCluster         string
-	proxyLogLevel          string
-	proxyComponentLogLevel string
-	concurrency            int
-	templateFile           string
-	loggingOptions         = log.DefaultOptions()
-	outlierLogPath         string
+	proxyArgs      config.ProxyArgs
+	loggingOptions = log.DefaultOptions()
 
 	rootCmd = &cobra.Command{
 		Use:          "pilot-agent",
@@ -98,7 +85,8 @@ var (
 			if err != nil {
 				return err
 			}
-			proxyConfig, err := config.ConstructProxyConfig(meshConfigFile, serviceCluster, options.ProxyConfigEnv, concurrency, proxy)
+			proxyConfig, err := config.ConstructProxyConfig(proxyArgs.MeshConfigFile, proxyArgs.ServiceCluster, options.ProxyConfigEnv,
+				proxyArgs.Concurrency, proxy)
 			if err != nil {
 				return fmt.Errorf("failed to get proxy config: %v", err)
 			}
@@ -108,7 +96,7 @@ var (
 				log.Infof("Effective config: %s", out)
 			}
 
-			secOpts, err := options.NewSecurityOptions(proxyConfig, stsPort, tokenManagerPlugin)
+			secOpts, err := options.NewSecurityOptions(proxyConfig, proxyArgs.StsPort, proxyArgs.TokenManagerPlugin)
 			if err != nil {
 				return err
 			}
@@ -117,7 +105,7 @@ var (
 			// listen on STS port for STS requests. For STS, see
 			// https://tools.ietf.org/html/draft-ietf-oauth-token-exchange-16.
 			// STS is used for stackdriver or other Envoy services using google gRPC.
-			if stsPort > 0 {
+			if proxyArgs.StsPort > 0 {
 				stsServer, err := initStsServer(proxy, secOpts.TokenManager)
 				if err != nil {
 					return err
@@ -140,8 +128,8 @@ var (
 			}
 
 			// If we are using a custom template file (for control plane proxy, for example), configure this.
-			if templateFile != "" && proxyConfig.CustomConfigFile == "" {
-				proxyConfig.ProxyBootstrapTemplatePath = templateFile
+			if proxyArgs.TemplateFile != "" && proxyConfig.CustomConfigFile == "" {
+				proxyConfig.ProxyBootstrapTemplatePath = proxyArgs.TemplateFile
 			}
 
 			ctx, cancel := context.WithCancel(context.Background())
@@ -154,23 +142,17 @@ var (
 				}
 			}
 
-			provCert := agent.FindRootCAForXDS()
-			if provCert == "" {
-				// Envoy only supports load from file. If we want to use system certs, use best guess
-				// To be more correct this could lookup all the "well known" paths but this is extremely \
-				// unlikely to run on a non-debian based machine, and if it is it can be explicitly configured
-				provCert = "/etc/ssl/certs/ca-certificates.crt"
-			}
+			provCert := istio_agent.BootstrapRootCA(agent.FindRootCAForXDS())
 			node, err := bootstrap.GetNodeMetaData(bootstrap.MetadataOptions{
 				ID:                  proxy.ServiceNode(),
 				Envs:                os.Environ(),
 				Platform:            platform.Discover(),
 				InstanceIPs:         proxy.IPAddresses,
-				StsPort:             stsPort,
+				StsPort:             proxyArgs.StsPort,
 				ProxyConfig:         proxyConfig,
 				ProxyViaAgent:       agentOptions.ProxyXDSViaAgent,
 				PilotSubjectAltName: pilotSAN,
-				OutlierLogPath:      outlierLogPath,
+				OutlierLogPath:      proxyArgs.OutlierLogPath,
 				PilotCertProvider:   secOpts.PilotCertProvider,
 				ProvCert:            provCert,
 			})
@@ -180,8 +162,8 @@ var (
 			}
 			envoyProxy := envoy.NewProxy(envoy.ProxyConfig{
 				Node:              node,
-				LogLevel:          proxyLogLevel,
-				ComponentLogLevel: proxyComponentLogLevel,
+				LogLevel:          proxyArgs.ProxyLogLevel,
+				ComponentLogLevel: proxyArgs.ProxyComponentLogLevel,
 				LogAsJSON:         loggingOptions.JSONEncoding,
 				NodeIPs:           proxy.IPAddresses,
 				Sidecar:           proxy.Type == model.SidecarProxy,
@@ -198,29 +180,7 @@ var (
 )
 
 func init() {
-	proxyCmd.PersistentFlags().StringVar(&dnsDomain, "domain", "",
-		"DNS domain suffix. If not provided uses ${POD_NAMESPACE}.svc.cluster.local")
-	proxyCmd.PersistentFlags().StringVar(&meshConfigFile, "meshConfig", "./etc/istio/config/mesh",
-		"File name for Istio mesh configuration. If not specified, a default mesh will be used. This may be overridden by "+
-			"PROXY_CONFIG environment variable or proxy.istio.io/config annotation.")
-	proxyCmd.PersistentFlags().IntVar(&stsPort, "stsPort", 0,
-		"HTTP Port on which to serve Security Token Service (STS). If zero, STS service will not be provided.")
-	proxyCmd.PersistentFlags().StringVar(&tokenManagerPlugin, "tokenManagerPlugin", tokenmanager.GoogleTokenExchange,
-		"Token provider specific plugin name.")
-	// Flags for proxy configuration
-	proxyCmd.PersistentFlags().StringVar(&serviceCluster, "serviceCluster", constants.ServiceClusterName, "Service cluster")
-	// Log levels are provided by the library https://github.com/gabime/spdlog, used by Envoy.
-	proxyCmd.PersistentFlags().StringVar(&proxyLogLevel, "proxyLogLevel", "warning",
-		fmt.Sprintf("The log level used to start the Envoy proxy (choose from {%s, %s, %s, %s, %s, %s, %s})",
-			"trace", "debug", "info", "warning", "error", "critical", "off"))
-	proxyCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 0, "number of worker threads to run")
-	// See https://www.envoyproxy.io/docs/envoy/latest/operations/cli#cmdoption-component-log-level
-	proxyCmd.PersistentFlags().StringVar(&proxyComponentLogLevel, "proxyComponentLogLevel", "misc:error",
-		"The component log level used to start the Envoy proxy")
-	proxyCmd.PersistentFlags().StringVar(&templateFile, "templateFile", "",
-		"Go template bootstrap config")
-	proxyCmd.PersistentFlags().StringVar(&outlierLogPath, "outlierLogPath", "",
-		"The log path for outlier detection")
+	proxyArgs.AttachFlags(proxyCmd.PersistentFlags())
 
 	// Attach the Istio logging options to the command.
 	loggingOptions.AttachCobraFlags(rootCmd)
@@ -257,7 +217,7 @@ func initStsServer(proxy *model.Proxy, tokenManager security.TokenManager) (*sts
 	}
 	stsServer, err := stsserver.NewServer(stsserver.Config{
 		LocalHostAddr: localHostAddr,
-		LocalPort:     stsPort,
+		LocalPort:     proxyArgs.StsPort,
 	}, tokenManager)
 	if err != nil {
 		return nil, err
@@ -323,7 +283,7 @@ func initProxy(args []string) (*model.Proxy, error) {
 
 	// If not set, set a default based on platform - podNamespace.svc.cluster.local for
 	// K8S
-	proxy.DNSDomain = getDNSDomain(podNamespace, dnsDomain)
+	proxy.DNSDomain = getDNSDomain(podNamespace, proxyArgs.DNSDomain)
 	log.WithLabels("ips", proxy.IPAddresses, "type", proxy.Type, "id", proxy.ID, "domain", proxy.DNSDomain).Info("Proxy role")
 
 	return proxy, nil
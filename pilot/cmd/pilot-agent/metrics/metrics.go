@@ -42,6 +42,16 @@ var (
 	AppScrapeErrors   = scrapeErrors.With(typeTag.Value(ScrapeTypeApp))
 	AgentScrapeErrors = scrapeErrors.With(typeTag.Value(ScrapeTypeAgent))
 
+	// mergeErrors records the metrics dropped because they conflict with the metrics of another scrape.
+	mergeErrors = monitoring.NewSum(
+		"scrape_merge_errors_total",
+		"The total number of metric families and series dropped because they conflict with another scrape.",
+		monitoring.WithLabels(typeTag),
+	)
+	EnvoyMergeErrors = mergeErrors.With(typeTag.Value(ScrapeTypeEnvoy))
+	AppMergeErrors   = mergeErrors.With(typeTag.Value(ScrapeTypeApp))
+	AgentMergeErrors = mergeErrors.With(typeTag.Value(ScrapeTypeAgent))
+
 	// ScrapeTotals records total number of scrapes.
 	ScrapeTotals = monitoring.NewSum(
 		"scrapes_total",
@@ -67,6 +77,7 @@ func init() {
 	monitoring.MustRegister(
 		ScrapeTotals,
 		scrapeErrors,
+		mergeErrors,
 		startupTime,
 	)
 }
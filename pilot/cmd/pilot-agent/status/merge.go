@@ -0,0 +1,320 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+const (
+	// scrapeAcceptHeader lists the formats we can parse, preferring OpenMetrics like Prometheus does.
+	scrapeAcceptHeader = `application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5,*/*;q=0.1`
+
+	openMetricsType = "application/openmetrics-text"
+)
+
+// metricsSource is a scrape to merge, with the metric counting its families and series dropped in the merge.
+type metricsSource struct {
+	families    map[string]*dto.MetricFamily
+	mergeErrors monitoring.Metric
+}
+
+// mergeMetrics merges the metric families of the sources. Sources are in precedence order: a family with
+// a different type than the family of the same name of a previous source is dropped, as well as a series
+// with the same labels as the series of a previous source.
+func mergeMetrics(sources ...metricsSource) []*dto.MetricFamily {
+	merged := map[string]*dto.MetricFamily{}
+	series := map[string]struct{}{}
+	for _, src := range sources {
+		for name, mf := range src.families {
+			out, f := merged[name]
+			if !f {
+				out = &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type}
+				merged[name] = out
+			} else if out.GetType() != mf.GetType() {
+				log.Debugf("dropping metric family %s: type %v conflicts with type %v", name, mf.GetType(), out.GetType())
+				src.mergeErrors.Increment()
+				continue
+			}
+			for _, m := range mf.Metric {
+				key := seriesKey(name, m.Label)
+				if _, dup := series[key]; dup {
+					log.Debugf("dropping duplicate series of metric %s", name)
+					src.mergeErrors.Increment()
+					continue
+				}
+				series[key] = struct{}{}
+				out.Metric = append(out.Metric, m)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		out = append(out, merged[name])
+	}
+	return out
+}
+
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetName()+"\xff"+l.GetValue())
+	}
+	sort.Strings(pairs)
+	return name + "\xfe" + strings.Join(pairs, "\xfe")
+}
+
+// parseMetrics parses a scrape in the Prometheus text, protobuf or OpenMetrics format, according to its
+// content type. The families that parse are returned even on error, rather than dropping the whole scrape
+// for a single malformed family.
+func parseMetrics(body []byte, contentType string) (map[string]*dto.MetricFamily, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	format := expfmt.ResponseFormat(header)
+	if format == expfmt.FmtProtoDelim {
+		families := map[string]*dto.MetricFamily{}
+		dec := expfmt.NewDecoder(bytes.NewReader(body), format)
+		for {
+			mf := &dto.MetricFamily{}
+			if err := dec.Decode(mf); err == io.EOF {
+				return families, nil
+			} else if err != nil {
+				// Delimited messages cannot be resynchronized after an error.
+				return families, err
+			}
+			families[mf.GetName()] = mf
+		}
+	}
+	if mediatype, _, _ := mime.ParseMediaType(contentType); mediatype == openMetricsType {
+		body = openMetricsToText(body)
+	}
+	parser := expfmt.TextParser{}
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err == nil {
+		return families, nil
+	}
+	return parseTextFamilies(body)
+}
+
+// parseTextFamilies parses each metric family of a scrape in the text format on its own, skipping the
+// families that fail to parse. A family starts at a HELP or TYPE comment for a new metric name.
+func parseTextFamilies(body []byte) (map[string]*dto.MetricFamily, error) {
+	families := map[string]*dto.MetricFamily{}
+	var errs error
+	parse := func(chunk []byte) {
+		if len(bytes.TrimSpace(chunk)) == 0 {
+			return
+		}
+		parser := expfmt.TextParser{}
+		parsed, err := parser.TextToMetricFamilies(bytes.NewReader(chunk))
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			return
+		}
+		for name, mf := range parsed {
+			families[name] = mf
+		}
+	}
+
+	var chunk []byte
+	current := ""
+	for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+		if f := strings.Fields(string(line)); len(f) >= 3 && f[0] == "#" && (f[1] == "HELP" || f[1] == "TYPE") {
+			if f[2] != current {
+				parse(chunk)
+				chunk, current = nil, f[2]
+			}
+		}
+		chunk = append(chunk, line...)
+	}
+	parse(chunk)
+	return families, errs
+}
+
+// openMetricsToText converts the OpenMetrics format to the Prometheus text format, the only one the text
+// parser understands. What the text format cannot express is dropped: units, exemplars and _created series.
+func openMetricsToText(in []byte) []byte {
+	lines := strings.Split(string(in), "\n")
+	types := map[string]string{}
+	for _, l := range lines {
+		if f := strings.Fields(l); len(f) == 4 && f[0] == "#" && f[1] == "TYPE" {
+			types[f[2]] = f[3]
+		}
+	}
+
+	out := &bytes.Buffer{}
+	for _, l := range lines {
+		if strings.HasPrefix(l, "#") {
+			f := strings.SplitN(l, " ", 4)
+			if len(f) < 3 {
+				// # EOF
+				continue
+			}
+			name, typ := textMetadata(f[2], types[f[2]])
+			switch {
+			case f[1] == "TYPE" && typ != "":
+				fmt.Fprintf(out, "# TYPE %s %s\n", name, typ)
+			case f[1] == "HELP" && len(f) == 4:
+				// Quotes are escaped in OpenMetrics, but not in the text format
+				fmt.Fprintf(out, "# HELP %s %s\n", name, strings.ReplaceAll(f[3], `\"`, `"`))
+			}
+			continue
+		}
+		if sample := textSample(l, types); sample != "" {
+			out.WriteString(sample)
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes()
+}
+
+// textMetadata returns the text format name and type of an OpenMetrics family, or an empty type if the
+// family has no equivalent and its samples are left untyped.
+func textMetadata(name, typ string) (string, string) {
+	switch typ {
+	case "counter":
+		// Counter samples have a _total suffix in OpenMetrics, but not in the family name
+		return name + "_total", typ
+	case "info":
+		return name + "_info", "gauge"
+	case "stateset":
+		return name, "gauge"
+	case "unknown":
+		return name, "untyped"
+	case "gaugehistogram":
+		return name, ""
+	}
+	return name, typ
+}
+
+// textSample converts an OpenMetrics sample line to the text format. It returns an empty string for the
+// samples to drop.
+func textSample(line string, types map[string]string) string {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return ""
+	}
+	end := strings.IndexAny(line, "{ ")
+	if end < 0 {
+		return ""
+	}
+	name := line[:end]
+	if base := strings.TrimSuffix(name, "_created"); base != name {
+		switch types[base] {
+		case "counter", "histogram", "summary":
+			return ""
+		}
+	}
+	if line[end] == '{' {
+		end = labelsEnd(line, end)
+		if end < 0 {
+			return line
+		}
+	}
+	rest := line[end:]
+	// Drop the exemplar
+	if i := strings.Index(rest, " # "); i >= 0 {
+		rest = rest[:i]
+	}
+	f := strings.Fields(rest)
+	if len(f) == 2 {
+		// Timestamps are in seconds in OpenMetrics, and in milliseconds in the text format
+		if ts, err := strconv.ParseFloat(f[1], 64); err == nil {
+			f[1] = strconv.FormatInt(int64(math.Round(ts*1000)), 10)
+		}
+	}
+	return line[:end] + " " + strings.Join(f, " ")
+}
+
+// labelsEnd returns the index following the closing brace of the labels starting at start, or -1.
+func labelsEnd(line string, start int) int {
+	quoted := false
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case '}':
+			if !quoted {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// writeMetrics writes the metric families in the format negotiated with the Accept header, gzip compressed
+// if the scraper accepts it.
+func writeMetrics(w http.ResponseWriter, r *http.Request, mfs []*dto.MetricFamily) error {
+	format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+	w.Header().Set("Content-Type", string(format))
+
+	var out io.Writer = w
+	if gzipAccepted(r.Header) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+
+	var errs error
+	enc := expfmt.NewEncoder(out, format)
+	for _, mf := range mfs {
+		if err := enc.Encode(mf); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if format == expfmt.FmtOpenMetrics {
+		if _, err := expfmt.FinalizeOpenMetrics(out); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+func gzipAccepted(header http.Header) bool {
+	for _, part := range strings.Split(header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.Split(part, ";")[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
@@ -15,7 +15,6 @@
 package status
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -34,9 +33,8 @@ import (
 	"time"
 
 	ocprom "contrib.go.opencensus.io/exporter/prometheus"
-	"github.com/hashicorp/go-multierror"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/common/expfmt"
+	dto "github.com/prometheus/client_model/go"
 	"go.opencensus.io/stats/view"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -375,60 +373,58 @@ type PrometheusScrapeConfiguration struct {
 
 // handleStats handles prometheus stats scraping. This will scrape envoy metrics, and, if configured,
 // the application metrics and merge them together.
-// The merge here is a simple string concatenation. This works for almost all cases, assuming the application
-// is not exposing the same metrics as Envoy.
+// All scrapes are parsed and merged by metric family. When the same metric is exposed by several scrapes, Envoy
+// metrics take precedence over the agent ones, which take precedence over the application ones: a family
+// with a conflicting type or a series with the same labels is dropped, and counted in the merge errors metric.
 // Note that we do not return any errors here. If we do, we will drop metrics. For example, the app may be having issues,
 // but we still want Envoy metrics. Instead, errors are tracked in the failed scrape metrics/logs.
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	metrics.ScrapeTotals.Increment()
-	var envoy, application, agent []byte
-	var err error
+	var envoy, application, agent map[string]*dto.MetricFamily
 	// Gather all the metrics we will merge
-	if envoy, err = s.scrape(fmt.Sprintf("http://localhost:%d/stats/prometheus", s.envoyStatsPort), r.Header); err != nil {
+	if body, contentType, err := s.scrape(fmt.Sprintf("http://localhost:%d/stats/prometheus", s.envoyStatsPort), r.Header); err != nil {
 		log.Errorf("failed scraping envoy metrics: %v", err)
 		metrics.EnvoyScrapeErrors.Increment()
+	} else if envoy, err = parseMetrics(body, contentType); err != nil {
+		log.Errorf("failed parsing envoy metrics: %v", err)
+		metrics.EnvoyScrapeErrors.Increment()
 	}
 	if s.prometheus != nil {
 		url := fmt.Sprintf("http://localhost:%s%s", s.prometheus.Port, s.prometheus.Path)
-		if application, err = s.scrape(url, r.Header); err != nil {
+		if body, contentType, err := s.scrape(url, r.Header); err != nil {
 			log.Errorf("failed scraping application metrics: %v", err)
 			metrics.AppScrapeErrors.Increment()
+		} else if application, err = parseMetrics(body, contentType); err != nil {
+			log.Errorf("failed parsing application metrics: %v", err)
+			metrics.AppScrapeErrors.Increment()
 		}
 	}
-	if agent, err = scrapeAgentMetrics(); err != nil {
+	agent, err := scrapeAgentMetrics()
+	if err != nil {
 		log.Errorf("failed scraping agent metrics: %v", err)
 		metrics.AgentScrapeErrors.Increment()
 	}
 
+	merged := mergeMetrics(
+		metricsSource{families: envoy, mergeErrors: metrics.EnvoyMergeErrors},
+		metricsSource{families: agent, mergeErrors: metrics.AgentMergeErrors},
+		metricsSource{families: application, mergeErrors: metrics.AppMergeErrors},
+	)
+
 	// Write out the metrics
-	if _, err := w.Write(envoy); err != nil {
-		log.Errorf("failed to write envoy metrics: %v", err)
-		metrics.EnvoyScrapeErrors.Increment()
-	}
-	if _, err := w.Write(application); err != nil {
-		log.Errorf("failed to write application metrics: %v", err)
-		metrics.AppScrapeErrors.Increment()
-	}
-	if _, err := w.Write(agent); err != nil {
-		log.Errorf("failed to write agent metrics: %v", err)
-		metrics.AgentScrapeErrors.Increment()
+	if err := writeMetrics(w, r, merged); err != nil {
+		log.Errorf("failed to write metrics: %v", err)
 	}
 }
 
-func scrapeAgentMetrics() ([]byte, error) {
-	buf := &bytes.Buffer{}
+// scrapeAgentMetrics gathers the agent metrics. Metrics gathered successfully are returned even on error.
+func scrapeAgentMetrics() (map[string]*dto.MetricFamily, error) {
 	mfs, err := promRegistry.Gather()
-	enc := expfmt.NewEncoder(buf, expfmt.FmtText)
-	if err != nil {
-		return nil, err
-	}
-	var errs error
+	families := make(map[string]*dto.MetricFamily, len(mfs))
 	for _, mf := range mfs {
-		if err := enc.Encode(mf); err != nil {
-			errs = multierror.Append(errs, err)
-		}
+		families[mf.GetName()] = mf
 	}
-	return buf.Bytes(), errs
+	return families, err
 }
 
 func applyHeaders(into http.Header, from http.Header, keys ...string) {
@@ -453,7 +449,7 @@ func getHeaderTimeout(timeout string) (time.Duration, error) {
 // scrape will send a request to the provided url to scrape metrics from
 // This will attempt to mimic some of Prometheus functionality by passing some of the headers through
 // such as timeout and user agent
-func (s *Server) scrape(url string, header http.Header) ([]byte, error) {
+func (s *Server) scrape(url string, header http.Header) ([]byte, string, error) {
 	ctx := context.Background()
 	if timeoutString := header.Get("X-Prometheus-Scrape-Timeout-Seconds"); timeoutString != "" {
 		timeout, err := getHeaderTimeout(timeoutString)
@@ -468,27 +464,30 @@ func (s *Server) scrape(url string, header http.Header) ([]byte, error) {
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	applyHeaders(req.Header, header, "Accept",
+	applyHeaders(req.Header, header,
 		"User-Agent",
 		"X-Prometheus-Scrape-Timeout-Seconds",
 	)
+	// The metrics are parsed before being merged, so only ask for the formats we can parse, regardless of the
+	// format the scraper asked for.
+	req.Header.Set("Accept", scrapeAcceptHeader)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error scraping %s: %v", url, err)
+		return nil, "", fmt.Errorf("error scraping %s: %v", url, err)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error scraping %s, status code: %v", url, resp.StatusCode)
+		return nil, "", fmt.Errorf("error scraping %s, status code: %v", url, resp.StatusCode)
 	}
 	defer resp.Body.Close()
 	metrics, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error reading %s: %v", url, err)
+		return nil, "", fmt.Errorf("error reading %s: %v", url, err)
 	}
 
-	return metrics, nil
+	return metrics, resp.Header.Get("Content-Type"), nil
 }
 
 func (s *Server) handleQuit(w http.ResponseWriter, r *http.Request) {
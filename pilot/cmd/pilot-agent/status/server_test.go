@@ -15,10 +15,12 @@
 package status
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -175,11 +177,12 @@ func TestPprof(t *testing.T) {
 
 func TestStats(t *testing.T) {
 	cases := []struct {
-		name             string
-		envoy            string
-		app              string
-		output           string
-		expectParseError bool
+		name           string
+		envoy          string
+		app            string
+		appContentType string
+		output         string
+		missing        string
 	}{
 		{
 			name: "envoy metric only",
@@ -189,9 +192,9 @@ my_metric{} 0
 my_other_metric{} 0
 `,
 			output: `# TYPE my_metric counter
-my_metric{} 0
+my_metric 0
 # TYPE my_other_metric counter
-my_other_metric{} 0
+my_other_metric 0
 `,
 		},
 		{
@@ -202,9 +205,9 @@ my_metric{} 0
 my_other_metric{} 0
 `,
 			output: `# TYPE my_metric counter
-my_metric{} 0
+my_metric 0
 # TYPE my_other_metric counter
-my_other_metric{} 0
+my_other_metric 0
 `,
 		},
 		{
@@ -216,9 +219,9 @@ my_metric{} 0
 my_other_metric{} 0
 `,
 			output: `# TYPE my_metric counter
-my_metric{} 0
+my_metric 0
 # TYPE my_other_metric counter
-my_other_metric{} 0
+my_other_metric 0
 `,
 		},
 		{
@@ -230,8 +233,7 @@ my_other_metric{} 0
 # TYPE istio_agent_scrapes_total counter
 istio_agent_scrapes_total`,
 		},
-		// When the application and envoy share a metric, Prometheus will fail. This negative check validates this
-		// assumption.
+		// When the application and envoy share a series, the application one is dropped.
 		{
 			name: "conflict metric",
 			envoy: `# TYPE my_metric counter
@@ -240,17 +242,16 @@ my_metric{} 0
 my_other_metric{} 0
 `,
 			app: `# TYPE my_metric counter
-my_metric{} 0
+my_metric{} 1
 `,
 			output: `# TYPE my_metric counter
-my_metric{} 0
+my_metric 0
 # TYPE my_other_metric counter
-my_other_metric{} 0
-# TYPE my_metric counter
-my_metric{} 0
+my_other_metric 0
 `,
-			expectParseError: true,
+			missing: "my_metric 1",
 		},
+		// Series of the same family with different labels are merged.
 		{
 			name: "conflict metric labeled",
 			envoy: `# TYPE my_metric counter
@@ -261,10 +262,44 @@ my_metric{app="bar"} 0
 `,
 			output: `# TYPE my_metric counter
 my_metric{app="foo"} 0
-# TYPE my_metric counter
 my_metric{app="bar"} 0
 `,
-			expectParseError: true,
+		},
+		{
+			name: "conflict metric type",
+			envoy: `# TYPE my_metric counter
+my_metric{app="foo"} 0
+`,
+			app: `# TYPE my_metric gauge
+my_metric{app="bar"} 1
+`,
+			output: `# TYPE my_metric counter
+my_metric{app="foo"} 0
+`,
+			missing: `my_metric{app="bar"}`,
+		},
+		{
+			name: "openmetrics app",
+			envoy: `# TYPE my_metric counter
+my_metric{} 0
+`,
+			app: `# HELP my_app_requests The \"requests\".
+# TYPE my_app_requests counter
+# UNIT my_app_requests requests
+my_app_requests_total{code="200"} 5 1600000000.5 # {trace_id="abc"} 1 1600000000.1
+my_app_requests_created{code="200"} 1600000000
+# TYPE my_app_build info
+my_app_build_info{version="1.0"} 1
+# EOF
+`,
+			appContentType: "application/openmetrics-text; version=0.0.1; charset=utf-8",
+			output: `# TYPE my_app_build_info gauge
+my_app_build_info{version="1.0"} 1
+# HELP my_app_requests_total The "requests".
+# TYPE my_app_requests_total counter
+my_app_requests_total{code="200"} 5 1600000000500
+`,
+			missing: "my_app_requests_created",
 		},
 	}
 	for _, tt := range cases {
@@ -277,6 +312,9 @@ my_metric{app="bar"} 0
 			}))
 			defer envoy.Close()
 			app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.appContentType != "" {
+					w.Header().Set("Content-Type", tt.appContentType)
+				}
 				if _, err := w.Write([]byte(tt.app)); err != nil {
 					t.Fatalf("write failed: %v", err)
 				}
@@ -300,18 +338,87 @@ my_metric{app="bar"} 0
 			if !strings.Contains(rec.Body.String(), tt.output) {
 				t.Fatalf("handleStats() => %v; want %v", rec.Body.String(), tt.output)
 			}
+			if tt.missing != "" && strings.Contains(rec.Body.String(), tt.missing) {
+				t.Fatalf("handleStats() => %v; should not contain %v", rec.Body.String(), tt.missing)
+			}
 
 			parser := expfmt.TextParser{}
-			mfMap, err := parser.TextToMetricFamilies(strings.NewReader(rec.Body.String()))
-			if err != nil && !tt.expectParseError {
+			if _, err := parser.TextToMetricFamilies(strings.NewReader(rec.Body.String())); err != nil {
 				t.Fatalf("failed to parse metrics: %v", err)
-			} else if err == nil && tt.expectParseError {
-				t.Fatalf("expected a prse error, got %+v", mfMap)
 			}
 		})
 	}
 }
 
+func TestStatsNegotiation(t *testing.T) {
+	envoy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("# TYPE my_metric counter\nmy_metric 1\n"))
+	}))
+	defer envoy.Close()
+	envoyPort, err := strconv.Atoi(strings.Split(envoy.URL, ":")[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{envoyStatsPort: envoyPort}
+
+	req := &http.Request{Header: http.Header{}}
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	server.handleStats(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Fatalf("unexpected content type %v", ct)
+	}
+	if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("unexpected content encoding %v", ce)
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "my_metric 1.0\n") || !strings.HasSuffix(string(body), "# EOF\n") {
+		t.Fatalf("unexpected OpenMetrics scrape %v", string(body))
+	}
+}
+
+func TestStatsMalformedApp(t *testing.T) {
+	envoy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("# TYPE envoy_metric counter\nenvoy_metric 1\n"))
+	}))
+	defer envoy.Close()
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("# TYPE good_metric counter\ngood_metric 2\n# TYPE bad_metric counter\nbad_metric{ 3\n" +
+			"# TYPE other_metric gauge\nother_metric 4\n"))
+	}))
+	defer app.Close()
+	envoyPort, err := strconv.Atoi(strings.Split(envoy.URL, ":")[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &Server{
+		prometheus:     &PrometheusScrapeConfiguration{Port: strings.Split(app.URL, ":")[2]},
+		envoyStatsPort: envoyPort,
+	}
+	rec := httptest.NewRecorder()
+	server.handleStats(rec, &http.Request{Header: http.Header{}})
+
+	// Only the malformed family of the application is dropped
+	body := rec.Body.String()
+	for _, want := range []string{"envoy_metric 1", "good_metric 2", "other_metric 4"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in the merged scrape %v", want, body)
+		}
+	}
+	if strings.Contains(body, "bad_metric") {
+		t.Errorf("expected the malformed family to be dropped: %v", body)
+	}
+}
+
 func TestStatsError(t *testing.T) {
 	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
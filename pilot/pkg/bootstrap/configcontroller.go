@@ -16,7 +16,10 @@ package bootstrap
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
@@ -54,6 +57,12 @@ const (
 	// k8s:// - load in-cluster k8s controller
 	// example k8s://
 	Kubernetes ConfigSourceAddressScheme = "k8s"
+	// git://HOST/PATH?branch=BRANCH - load the files of a branch of a git repository, fetched periodically.
+	// The repository can also be a local path, or use another transport prefixed with git+.
+	// example git:///var/lib/istio/config.git?branch=main
+	// example git+https://github.com/example/mesh-config.git?branch=main
+	// A commit with invalid config is rejected, and the config of the previous commit keeps being served.
+	Git ConfigSourceAddressScheme = "git"
 )
 
 // initConfigController creates the config controller in the pilotConfig.
@@ -183,6 +192,9 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 			return fmt.Errorf("invalid config URL %s %v", configSource.Address, err)
 		}
 		scheme := ConfigSourceAddressScheme(srcAddress.Scheme)
+		if strings.HasPrefix(srcAddress.Scheme, string(Git)+"+") {
+			scheme = Git
+		}
 		switch scheme {
 		case File:
 			if srcAddress.Path == "" {
@@ -215,6 +227,18 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 			}
 			s.ConfigStores = append(s.ConfigStores, configController)
 			log.Warn("Started XDS config ", s.ConfigStores)
+		case Git:
+			store := memory.Make(collections.Pilot)
+			configController := memory.NewController(store)
+
+			err := s.makeGitMonitor(srcAddress, args.RegistryOptions.KubeOptions.DomainSuffix, configController)
+			if err != nil {
+				// The address may hold the credentials of the repository
+				redacted := *srcAddress
+				redacted.User = nil
+				return fmt.Errorf("invalid git config source %s: %v", redacted.String(), err)
+			}
+			s.ConfigStores = append(s.ConfigStores, configController)
 		case Kubernetes:
 			if srcAddress.Path == "" || srcAddress.Path == "/" {
 				err2 := s.initK8SConfigStore(args)
@@ -306,6 +330,9 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 				AddRunFunction(func(stop <-chan struct{}) {
 					s.statusReporter.SetController(controller)
 					controller.Start(stop)
+				}).
+				AddRunFunction(func(stop <-chan struct{}) {
+					s.serviceEntryStore.WriteAllocatedAddresses(s.RWConfigStore, stop)
 				}).Run(stop)
 			return nil
 		})
@@ -323,6 +350,7 @@ func (s *Server) makeKubeConfigController(args *PilotArgs) (model.ConfigStoreCac
 func (s *Server) makeFileMonitor(fileDir string, domainSuffix string, configController model.ConfigStore) error {
 	fileSnapshot := configmonitor.NewFileSnapshot(fileDir, collections.Pilot, domainSuffix)
 	fileMonitor := configmonitor.NewMonitor("file-monitor", configController, fileSnapshot.ReadConfigFiles, fileDir)
+	s.XDSServer.FileConfigSources = append(s.XDSServer.FileConfigSources, fileSnapshot)
 
 	// Defer starting the file monitor until after the service is created.
 	s.addStartFunc(func(stop <-chan struct{}) error {
@@ -332,3 +360,45 @@ func (s *Server) makeFileMonitor(fileDir string, domainSuffix string, configCont
 
 	return nil
 }
+
+func (s *Server) makeGitMonitor(srcAddress *url.URL, domainSuffix string, configController model.ConfigStore) error {
+	repo := *srcAddress
+	repo.RawQuery = ""
+	repo.Scheme = strings.TrimPrefix(repo.Scheme, string(Git)+"+")
+	repository := repo.String()
+	if repo.Scheme == string(Git) && repo.Host == "" {
+		// Local repository
+		repository = repo.Path
+	}
+	if repository == "" {
+		return fmt.Errorf("no repository")
+	}
+
+	gitDir, err := ioutil.TempDir("", "istiod-git-config")
+	if err != nil {
+		return err
+	}
+	gitSnapshot, err := configmonitor.NewGitSnapshot(repository, srcAddress.Query().Get("branch"), gitDir,
+		features.GitConfigPollInterval, collections.Pilot, domainSuffix)
+	if err != nil {
+		_ = os.RemoveAll(gitDir)
+		return err
+	}
+	gitMonitor := configmonitor.NewPollingMonitor("git-monitor", configController, gitSnapshot.ReadConfig,
+		features.GitConfigPollInterval)
+	s.XDSServer.GitConfigSources = append(s.XDSServer.GitConfigSources, gitSnapshot)
+
+	// Defer starting the git monitor until after the service is created.
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		gitMonitor.Start(stop)
+		go func() {
+			<-stop
+			if err := os.RemoveAll(gitDir); err != nil {
+				log.Warnf("failed to remove git config directory %s: %v", gitDir, err)
+			}
+		}()
+		return nil
+	})
+
+	return nil
+}
@@ -53,6 +53,7 @@ import (
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
 	"istio.io/istio/pilot/pkg/status"
+	"istio.io/istio/pilot/pkg/tracing"
 	tb "istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
@@ -260,6 +261,12 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		return nil, err
 	}
 
+	s.initProxyConfigOverrides()
+
+	if err := s.initPushTracing(); err != nil {
+		return nil, err
+	}
+
 	// Initialize workloadTrustBundle after CA has been initialized
 	if err := s.initWorkloadTrustBundle(args); err != nil {
 		return nil, err
@@ -511,7 +518,7 @@ func (s *Server) initSDSServer(args *PilotArgs) {
 					Reason: []model.TriggerReason{model.SecretTrigger},
 				})
 			})
-			s.XDSServer.Generators[v3.SecretType] = xds.NewSecretGen(sc, s.XDSServer.Cache)
+			s.XDSServer.Generators[v3.SecretType] = xds.NewSecretGen(sc, s.XDSServer.Cache, s.XDSServer)
 		}
 	}
 }
@@ -1071,6 +1078,11 @@ func (s *Server) fetchCARoot() map[string]string {
 // initMeshHandlers initializes mesh and network handlers.
 func (s *Server) initMeshHandlers() {
 	log.Info("initializing mesh handlers")
+	if features.EnableMeshConfigRollback {
+		if w, ok := s.environment.Watcher.(mesh.StagedWatcher); ok {
+			w.EnableStagedApply()
+		}
+	}
 	// When the mesh config or networks change, do a full push.
 	s.environment.AddMeshHandler(func() {
 		spiffe.SetTrustDomain(s.environment.Mesh().GetTrustDomain())
@@ -1106,6 +1118,44 @@ func (s *Server) addIstioCAToTrustBundle(args *PilotArgs) error {
 	return nil
 }
 
+// initProxyConfigOverrides watches the namespaces and ConfigMaps overriding the mesh default ProxyConfig.
+func (s *Server) initProxyConfigOverrides() {
+	if !features.EnableProxyConfigOverrides || s.kubeClient == nil {
+		return
+	}
+	c := kubecontroller.NewProxyConfigController(s.kubeClient, s.XDSServer)
+	s.environment.ProxyConfigSource = c
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go c.Run(stop)
+		return nil
+	})
+}
+
+// initPushTracing reports the spans of the push pipeline to a Zipkin collector or a local file, if configured.
+func (s *Server) initPushTracing() error {
+	var reporter *tracing.ZipkinReporter
+	switch {
+	case features.PushTracingZipkinAddress != "":
+		reporter = tracing.NewZipkinReporter("istiod", "http://"+features.PushTracingZipkinAddress+"/api/v2/spans")
+	case features.PushTracingFile != "":
+		f, err := os.OpenFile(features.PushTracingFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open push tracing file: %v", err)
+		}
+		// The file is kept open until Istiod exits, the reporter flushes the last spans when stopped.
+		reporter = tracing.NewZipkinFileReporter("istiod", f)
+	default:
+		return nil
+	}
+	log.Infof("Tracing %v%% of the pushes", features.PushTracingSampling)
+	tracing.Start(reporter, features.PushTracingSampling/100)
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go reporter.Run(stop)
+		return nil
+	})
+	return nil
+}
+
 func (s *Server) initWorkloadTrustBundle(args *PilotArgs) error {
 	var err error
 
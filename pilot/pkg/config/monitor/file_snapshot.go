@@ -15,16 +15,23 @@
 package monitor
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
 )
 
 var supportedExtensions = map[string]bool{
@@ -32,12 +39,49 @@ var supportedExtensions = map[string]bool{
 	".yml":  true,
 }
 
+var (
+	fileTag = monitoring.MustCreateLabel("file")
+
+	rejectedFiles = monitoring.NewGauge(
+		"pilot_config_file_rejected",
+		"Set to 1 for the config files rejected because of invalid config, and to 0 once they are fixed.",
+		monitoring.WithLabels(fileTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(rejectedFiles)
+}
+
+// FileError is an error in a config file.
+type FileError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e FileError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
 // FileSnapshot holds a reference to a file directory that contains crd
 // config and filter criteria for which of those configs will be parsed.
 type FileSnapshot struct {
 	root             string
 	domainSuffix     string
 	configTypeFilter map[config.GroupVersionKind]bool
+
+	mu    sync.RWMutex
+	files map[string]*snapshotFile
+}
+
+// snapshotFile is a parsed config file. A file is only parsed again when its content changes.
+type snapshotFile struct {
+	hash [sha256.Size]byte
+	// configs are the configs of the last valid content of the file.
+	configs []*config.Config
+	// errors are the errors of the current content of the file, which is rejected if there are any.
+	errors []FileError
 }
 
 // NewFileSnapshot returns a snapshotter.
@@ -47,6 +91,7 @@ func NewFileSnapshot(root string, schemas collection.Schemas, domainSuffix strin
 		root:             root,
 		domainSuffix:     domainSuffix,
 		configTypeFilter: make(map[config.GroupVersionKind]bool),
+		files:            map[string]*snapshotFile{},
 	}
 
 	ss := schemas.All()
@@ -65,9 +110,13 @@ func NewFileSnapshot(root string, schemas collection.Schemas, domainSuffix strin
 
 // ReadConfigFiles parses files in the root directory and returns a sorted slice of
 // eligible model.Config. This can be used as a configFunc when creating a Monitor.
+// Only the files that changed since the previous call are parsed. A file with invalid
+// config is rejected: the config of its last valid content is returned instead.
 func (f *FileSnapshot) ReadConfigFiles() ([]*config.Config, error) {
-	var result []*config.Config
+	f.mu.Lock()
+	defer f.mu.Unlock()
 
+	seen := map[string]bool{}
 	err := filepath.Walk(f.root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -79,23 +128,34 @@ func (f *FileSnapshot) ReadConfigFiles() ([]*config.Config, error) {
 			log.Warnf("Failed to read %s: %v", path, err)
 			return err
 		}
-		configs, err := parseInputs(data, f.domainSuffix)
-		if err != nil {
-			log.Warnf("Failed to parse %s: %v", path, err)
-			return err
+		seen[path] = true
+		f.updateFile(path, data)
+		return nil
+	})
+	if err != nil {
+		log.Warnf("failure during filepath.Walk: %v", err)
+	} else {
+		for path, file := range f.files {
+			if !seen[path] {
+				if len(file.errors) > 0 {
+					rejectedFiles.With(fileTag.Value(f.name(path))).Record(0)
+				}
+				delete(f.files, path)
+			}
 		}
+	}
 
+	var result []*config.Config
+	for _, file := range f.files {
 		// Filter any unsupported types before appending to the result.
-		for _, cfg := range configs {
+		for _, cfg := range file.configs {
 			if !f.configTypeFilter[cfg.GroupVersionKind] {
 				continue
 			}
-			result = append(result, cfg)
+			// The monitor modifies the configs it is given
+			cpy := cfg.DeepCopy()
+			result = append(result, &cpy)
 		}
-		return nil
-	})
-	if err != nil {
-		log.Warnf("failure during filepath.Walk: %v", err)
 	}
 
 	// Sort by the config IDs.
@@ -103,6 +163,113 @@ func (f *FileSnapshot) ReadConfigFiles() ([]*config.Config, error) {
 	return result, err
 }
 
+// updateFile parses the file at path, unless its content did not change.
+func (f *FileSnapshot) updateFile(path string, data []byte) {
+	hash := sha256.Sum256(data)
+	file := f.files[path]
+	if file == nil {
+		file = &snapshotFile{}
+		f.files[path] = file
+	} else if file.hash == hash {
+		return
+	}
+	file.hash = hash
+
+	name := f.name(path)
+	wasRejected := len(file.errors) > 0
+	configs, errs := parseFile(name, data, f.domainSuffix)
+	file.errors = errs
+	if len(errs) > 0 {
+		for _, e := range errs {
+			log.Warnf("Rejecting config file: %v", e)
+		}
+		rejectedFiles.With(fileTag.Value(name)).Record(1)
+		return
+	}
+	if wasRejected {
+		rejectedFiles.With(fileTag.Value(name)).Record(0)
+	}
+	file.configs = configs
+}
+
+// name returns the name files are reported with, relative to the root directory.
+func (f *FileSnapshot) name(path string) string {
+	if rel, err := filepath.Rel(f.root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// RejectedFiles returns the errors of the files rejected because of invalid config, sorted by file and line.
+func (f *FileSnapshot) RejectedFiles() []FileError {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	out := make([]FileError, 0)
+	for _, file := range f.files {
+		out = append(out, file.errors...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		return out[i].Line < out[j].Line
+	})
+	return out
+}
+
+// yamlLineRegexp matches the line of YAML syntax errors, relative to the document.
+var yamlLineRegexp = regexp.MustCompile(`yaml: line (\d+)`)
+
+// parseFile parses and validates the documents of a config file. Errors are reported with the line of
+// the file they occur at: the line of the syntax error, or the first line of the invalid document.
+func parseFile(name string, data []byte, domainSuffix string) ([]*config.Config, []FileError) {
+	var configs []*config.Config
+	var errs []FileError
+	for _, doc := range splitDocuments(string(data)) {
+		parsed, err := parseInputs([]byte(doc.content), domainSuffix)
+		if err != nil {
+			line := doc.line
+			if m := yamlLineRegexp.FindStringSubmatch(err.Error()); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				line += n - 1
+			}
+			errs = append(errs, FileError{File: name, Line: line, Message: err.Error()})
+			continue
+		}
+		configs = append(configs, parsed...)
+	}
+	return configs, errs
+}
+
+type document struct {
+	// line is the line of the file the document starts at
+	line    int
+	content string
+}
+
+// splitDocuments splits a YAML stream into its documents.
+func splitDocuments(in string) []document {
+	var docs []document
+	lines := strings.Split(in, "\n")
+	add := func(start, end int) {
+		// Errors of the document as a whole are reported at its first non-empty line
+		line := start
+		for line < end-1 && strings.TrimSpace(lines[line]) == "" {
+			line++
+		}
+		docs = append(docs, document{line: line + 1, content: strings.Join(lines[line:end], "\n")})
+	}
+	start := 0
+	for i, l := range lines {
+		if strings.TrimRight(l, " \t\r") == "---" {
+			add(start, i)
+			start = i + 1
+		}
+	}
+	add(start, len(lines))
+	return docs
+}
+
 // parseInputs is identical to crd.ParseInputs, except that it returns an array of config pointers.
 func parseInputs(data []byte, domainSuffix string) ([]*config.Config, error) {
 	configs, _, err := crd.ParseInputs(string(data))
@@ -126,6 +126,73 @@ func TestFileSnapshotSorting(t *testing.T) {
 	g.Expect(configs[1].Spec).To(gomega.BeAssignableToTypeOf(&networking.VirtualService{}))
 }
 
+func TestFileSnapshotRejectedFiles(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	ts := &testState{
+		ConfigFiles: map[string][]byte{
+			"gateway.yml":         []byte(gatewayYAML),
+			"virtual_service.yml": []byte(virtualServiceYAML),
+		},
+	}
+
+	ts.testSetup(t)
+	defer ts.testTeardown(t)
+
+	fileWatcher := monitor.NewFileSnapshot(ts.rootPath, collection.SchemasFor(), "")
+	configs, err := fileWatcher.ReadConfigFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configs).To(gomega.HaveLen(2))
+	g.Expect(fileWatcher.RejectedFiles()).To(gomega.BeEmpty())
+
+	// An invalid resource rejects the file, whose last valid config is kept
+	ts.writeFile(t, "gateway.yml", "# comment\n---"+invalidGatewayYAML)
+	// A syntax error is reported at its line
+	ts.writeFile(t, "virtual_service.yml", virtualServiceYAML+"---\nkind: Gateway\nmetadata: [\n")
+	configs, err = fileWatcher.ReadConfigFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configs).To(gomega.HaveLen(2))
+	gateway := configs[0].Spec.(*networking.Gateway)
+	g.Expect(gateway.Servers[0].Hosts).To(gomega.Equal([]string{"*.example.com"}))
+
+	rejected := fileWatcher.RejectedFiles()
+	g.Expect(rejected).To(gomega.HaveLen(2))
+	g.Expect(rejected[0].File).To(gomega.Equal("gateway.yml"))
+	g.Expect(rejected[0].Line).To(gomega.Equal(3))
+	g.Expect(rejected[1].File).To(gomega.Equal("virtual_service.yml"))
+	g.Expect(rejected[1].Line).To(gomega.Equal(17))
+
+	// Fixed and removed files are no longer rejected
+	ts.writeFile(t, "gateway.yml", gatewayYAML)
+	g.Expect(os.Remove(filepath.Join(ts.rootPath, "virtual_service.yml"))).To(gomega.Succeed())
+	configs, err = fileWatcher.ReadConfigFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configs).To(gomega.HaveLen(1))
+	g.Expect(fileWatcher.RejectedFiles()).To(gomega.BeEmpty())
+}
+
+func TestFileSnapshotReturnsCopies(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	ts := &testState{
+		ConfigFiles: map[string][]byte{"gateway.yml": []byte(gatewayYAML)},
+	}
+
+	ts.testSetup(t)
+	defer ts.testTeardown(t)
+
+	fileWatcher := monitor.NewFileSnapshot(ts.rootPath, collection.SchemasFor(), "")
+	configs, err := fileWatcher.ReadConfigFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	configs[0].ResourceVersion = "modified"
+
+	// The unchanged file is not parsed again, and its cached config is not modified
+	configs, err = fileWatcher.ReadConfigFiles()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(configs).To(gomega.HaveLen(1))
+	g.Expect(configs[0].ResourceVersion).To(gomega.BeEmpty())
+}
+
 type testState struct {
 	ConfigFiles map[string][]byte
 	rootPath    string
@@ -153,3 +220,9 @@ func (ts *testState) testTeardown(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func (ts *testState) writeFile(t *testing.T, name, content string) {
+	if err := ioutil.WriteFile(filepath.Join(ts.rootPath, name), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+const (
+	// localRef is the ref the branch of the repository is fetched to.
+	localRef = "refs/istio/config"
+
+	// minGitTimeout is the minimum time a git command may run before it is killed.
+	minGitTimeout = 10 * time.Second
+)
+
+var (
+	repositoryTag = monitoring.MustCreateLabel("repository")
+
+	// The active commit itself is shown by /debug/config_sourcez, as a label would create a series per commit.
+	gitActiveCommitTimestamp = monitoring.NewGauge(
+		"pilot_config_git_active_commit_timestamp_seconds",
+		"Unix time at which istiod started serving the config of the active commit of a git config source.",
+		monitoring.WithLabels(repositoryTag),
+	)
+
+	gitRejectedCommits = monitoring.NewSum(
+		"pilot_config_git_rejected_commits_total",
+		"Number of commits of a git config source rejected because of invalid config.",
+		monitoring.WithLabels(repositoryTag),
+	)
+
+	gitFetchErrors = monitoring.NewSum(
+		"pilot_config_git_fetch_errors_total",
+		"Number of failed fetches of a git config source.",
+		monitoring.WithLabels(repositoryTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(gitActiveCommitTimestamp, gitRejectedCommits, gitFetchErrors)
+}
+
+// GitStatus is the status of a git config source.
+type GitStatus struct {
+	Repository string `json:"repository"`
+	Branch     string `json:"branch,omitempty"`
+	// ActiveCommit is the commit the served config comes from.
+	ActiveCommit string    `json:"activeCommit,omitempty"`
+	ActiveSince  time.Time `json:"activeSince"`
+	// RejectedCommit is the last commit rejected because of invalid config, if more recent than the active commit.
+	RejectedCommit string    `json:"rejectedCommit,omitempty"`
+	RejectionError string    `json:"rejectionError,omitempty"`
+	LastFetch      time.Time `json:"lastFetch"`
+	FetchError     string    `json:"fetchError,omitempty"`
+}
+
+// GitSnapshot reads the config of a branch of a git repository. The config of a commit is validated as a whole:
+// if any resource is invalid, the commit is rejected and the config of the previous commit keeps being served.
+type GitSnapshot struct {
+	repository string
+	// name is the repository without the credentials it may hold. It is used in metrics, logs and the status.
+	name         string
+	branch       string
+	gitDir       string
+	timeout      time.Duration
+	domainSuffix string
+	schemas      collection.Schemas
+
+	mu      sync.RWMutex
+	status  GitStatus
+	configs []*config.Config
+}
+
+// NewGitSnapshot returns a snapshotter of the given branch of a git repository, which can be a URL or the path of
+// a local repository. If the branch is empty, the default branch of the repository is used. The repository is
+// fetched to gitDir. Git commands are killed if they run longer than the interval the snapshot is polled at.
+func NewGitSnapshot(repository, branch, gitDir string, pollInterval time.Duration, schemas collection.Schemas,
+	domainSuffix string) (*GitSnapshot, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git config sources require the git command: %v", err)
+	}
+	if len(schemas.All()) == 0 {
+		schemas = collections.Pilot
+	}
+	timeout := pollInterval
+	if timeout < minGitTimeout {
+		timeout = minGitTimeout
+	}
+	name := redactRepository(repository)
+	g := &GitSnapshot{
+		repository:   repository,
+		name:         name,
+		branch:       branch,
+		gitDir:       gitDir,
+		timeout:      timeout,
+		domainSuffix: domainSuffix,
+		schemas:      schemas,
+		status:       GitStatus{Repository: name, Branch: branch},
+	}
+	if _, err := g.git(nil, "init", "--quiet", "--bare"); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Status returns the status of the config source.
+func (g *GitSnapshot) Status() GitStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.status
+}
+
+// ReadConfig fetches the branch and returns the config of its latest valid commit, sorted.
+// This can be used as a configFunc when creating a Monitor.
+func (g *GitSnapshot) ReadConfig() ([]*config.Config, error) {
+	commit, err := g.fetch()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status.LastFetch = time.Now()
+	if err != nil {
+		gitFetchErrors.With(repositoryTag.Value(g.name)).Increment()
+		g.status.FetchError = err.Error()
+		return nil, err
+	}
+	g.status.FetchError = ""
+	if commit == g.status.ActiveCommit || commit == g.status.RejectedCommit {
+		return g.snapshot(), nil
+	}
+
+	configs, err := g.readCommit(commit)
+	if err != nil {
+		log.Warnf("Rejecting commit %s of %s: %v", commit, g.name, err)
+		gitRejectedCommits.With(repositoryTag.Value(g.name)).Increment()
+		g.status.RejectedCommit = commit
+		g.status.RejectionError = err.Error()
+		if g.status.ActiveCommit == "" {
+			// Nothing was ever served, let the monitor report the error
+			return nil, err
+		}
+		return g.snapshot(), nil
+	}
+
+	log.Infof("Serving config of commit %s of %s", commit, g.name)
+	g.configs = configs
+	g.status.ActiveCommit = commit
+	g.status.ActiveSince = time.Now()
+	gitActiveCommitTimestamp.With(repositoryTag.Value(g.name)).Record(float64(g.status.ActiveSince.Unix()))
+	g.status.RejectedCommit = ""
+	g.status.RejectionError = ""
+	return g.snapshot(), nil
+}
+
+// snapshot returns a copy of the configs of the active commit, as the monitor modifies the configs it is given.
+func (g *GitSnapshot) snapshot() []*config.Config {
+	out := make([]*config.Config, 0, len(g.configs))
+	for _, cfg := range g.configs {
+		cpy := cfg.DeepCopy()
+		out = append(out, &cpy)
+	}
+	return out
+}
+
+// fetch fetches the branch and returns its latest commit.
+func (g *GitSnapshot) fetch() (string, error) {
+	src := "HEAD"
+	if g.branch != "" {
+		src = "refs/heads/" + g.branch
+	}
+	if _, err := g.git(nil, "fetch", "--quiet", "--force", "--no-tags",
+		g.repository, "+"+src+":"+localRef); err != nil {
+		return "", err
+	}
+	out, err := g.git(nil, "rev-parse", "--verify", localRef+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// readCommit parses and validates the config of all the YAML files of a commit.
+func (g *GitSnapshot) readCommit(commit string) ([]*config.Config, error) {
+	archive := &bytes.Buffer{}
+	if _, err := g.git(archive, "archive", "--format=tar", commit); err != nil {
+		return nil, err
+	}
+
+	var errs error
+	var result []*config.Config
+	definedIn := map[string]string{}
+	tr := tar.NewReader(archive)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !supportedExtensions[filepath.Ext(hdr.Name)] {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		configs, fileErrs := parseFile(hdr.Name, data, g.domainSuffix)
+		for _, e := range fileErrs {
+			errs = multierror.Append(errs, e)
+		}
+		for _, cfg := range configs {
+			s, f := g.schemas.FindByGroupVersionKind(cfg.GroupVersionKind)
+			if !f {
+				continue
+			}
+			if prev, dup := definedIn[cfg.Key()]; dup {
+				errs = multierror.Append(errs, fmt.Errorf("%s: %s %s/%s is also defined in %s",
+					hdr.Name, cfg.GroupVersionKind.Kind, cfg.Namespace, cfg.Name, prev))
+				continue
+			}
+			definedIn[cfg.Key()] = hdr.Name
+			if _, err := s.Resource().ValidateConfig(*cfg); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("%s: invalid %s %s/%s: %v",
+					hdr.Name, cfg.GroupVersionKind.Kind, cfg.Namespace, cfg.Name, err))
+				continue
+			}
+			cfg.ResourceVersion = commit
+			result = append(result, cfg)
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+
+	sort.Sort(byKey(result))
+	return result, nil
+}
+
+// git runs a git command on the local repository. Its output is written to stdout, or returned if stdout is nil.
+func (g *GitSnapshot) git(stdout io.Writer, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--git-dir", g.gitDir}, args...)...)
+	// Fail instead of waiting for credentials that will never be entered
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out := &bytes.Buffer{}
+	cmd.Stdout = out
+	if stdout != nil {
+		cmd.Stdout = stdout
+	}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %v", g.timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if g.name != g.repository {
+			msg = strings.ReplaceAll(msg, g.repository, g.name)
+		}
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, msg)
+	}
+	return out.Bytes(), nil
+}
+
+// redactRepository removes the credentials from the URL of a repository.
+func redactRepository(repository string) string {
+	u, err := url.Parse(repository)
+	if err != nil || u.User == nil {
+		return repository
+	}
+	u.User = nil
+	return u.String()
+}
@@ -34,6 +34,8 @@ type Monitor struct {
 	store           model.ConfigStore
 	configs         []*config.Config
 	getSnapshotFunc func() ([]*config.Config, error)
+	// pollInterval is the interval getSnapshotFunc is polled at, for sources that cannot be watched
+	pollInterval time.Duration
 	// channel to trigger updates on
 	// generally set to a file watch, but used in tests as well
 	updateCh chan struct{}
@@ -52,6 +54,15 @@ func NewMonitor(name string, delegateStore model.ConfigStore, getSnapshotFunc fu
 	return monitor
 }
 
+// NewPollingMonitor creates a Monitor polling the getSnapshotFunc at the given interval, instead of watching
+// a file.
+func NewPollingMonitor(name string, delegateStore model.ConfigStore, getSnapshotFunc func() ([]*config.Config, error),
+	interval time.Duration) *Monitor {
+	monitor := NewMonitor(name, delegateStore, getSnapshotFunc, "")
+	monitor.pollInterval = interval
+	return monitor
+}
+
 const watchDebounceDelay = 50 * time.Millisecond
 
 // Trigger notifications when a file is mutated
@@ -87,6 +98,23 @@ func fileTrigger(path string, ch chan struct{}, stop <-chan struct{}) error {
 	return nil
 }
 
+// Trigger notifications periodically
+func pollTrigger(interval time.Duration, ch chan struct{}, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
 // Start starts a new Monitor. Immediately checks the Monitor getSnapshotFunc
 // and updates the controller. It then kicks off an asynchronous event loop that
 // periodically polls the getSnapshotFunc for changes until a close event is sent.
@@ -95,7 +123,9 @@ func (m *Monitor) Start(stop <-chan struct{}) {
 
 	c := make(chan struct{}, 1)
 	m.updateCh = c
-	if err := fileTrigger(m.root, m.updateCh, stop); err != nil {
+	if m.pollInterval > 0 {
+		go pollTrigger(m.pollInterval, m.updateCh, stop)
+	} else if err := fileTrigger(m.root, m.updateCh, stop); err != nil {
 		log.Errorf("Unable to setup FileTrigger for %s: %v", m.root, err)
 	}
 	// Run the close loop asynchronously.
@@ -103,7 +133,9 @@ func (m *Monitor) Start(stop <-chan struct{}) {
 		for {
 			select {
 			case <-c:
-				log.Infof("Triggering reload of file configuration")
+				if m.pollInterval == 0 {
+					log.Infof("Triggering reload of file configuration")
+				}
 				m.checkAndUpdate()
 			case <-stop:
 				return
@@ -194,3 +194,25 @@ func TestMonitorForError(t *testing.T) {
 		return nil
 	}).Should(gomega.Succeed())
 }
+
+func TestPollingMonitor(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	store := memory.Make(collection.SchemasFor(collections.IstioNetworkingV1Alpha3Gateways))
+
+	// The config is only found after the initial check, by polling
+	var configs []*config.Config
+	someConfigFunc := func() ([]*config.Config, error) {
+		c := configs
+		configs = createConfigSet
+		return c, nil
+	}
+	mon := NewPollingMonitor("", store, someConfigFunc, 10*time.Millisecond)
+	stop := make(chan struct{})
+	defer close(stop)
+	mon.Start(stop)
+
+	g.Eventually(func() ([]config.Config, error) {
+		return store.List(gvk.Gateway, "")
+	}).Should(gomega.HaveLen(1))
+}
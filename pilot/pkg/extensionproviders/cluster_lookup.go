@@ -16,9 +16,11 @@ package extensionproviders
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pkg/config/host"
 )
 
@@ -34,6 +36,7 @@ func LookupCluster(push *model.PushContext, service string, port int) (hostname
 		if svc := push.ServiceIndex.HostnameAndNamespace[host.Name(name)][namespace]; svc != nil {
 			hostname = string(svc.Hostname)
 			cluster = model.BuildSubsetKey(model.TrafficDirectionOutbound, "", svc.Hostname, port)
+			err = checkServiceEntryPort(svc, port)
 			return
 		}
 	} else {
@@ -43,10 +46,14 @@ func LookupCluster(push *model.PushContext, service string, port int) (hostname
 			namespaces = append(namespaces, k)
 		}
 		// If namespace is omitted, return successfully if there is only one such host name in the service index.
-		if len(namespaces) == 1 {
+		// External collectors are often declared by a ServiceEntry in each namespace using them, these all
+		// share the same cluster.
+		if len(namespaces) == 1 || (len(namespaces) > 1 && allServiceEntries(namespaceToServices)) {
+			sort.Strings(namespaces)
 			svc := namespaceToServices[namespaces[0]]
 			hostname = string(svc.Hostname)
 			cluster = model.BuildSubsetKey(model.TrafficDirectionOutbound, "", svc.Hostname, port)
+			err = checkServiceEntryPort(svc, port)
 			return
 		} else if len(namespaces) > 1 {
 			err = fmt.Errorf("found %s in multiple namespaces %v, specify the namespace explicitly in "+
@@ -58,3 +65,24 @@ func LookupCluster(push *model.PushContext, service string, port int) (hostname
 	err = fmt.Errorf("could not find service %s in Istio service registry", service)
 	return
 }
+
+func allServiceEntries(services map[string]*model.Service) bool {
+	for _, svc := range services {
+		if svc.Attributes.ServiceRegistry != string(serviceregistry.External) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkServiceEntryPort verifies a ServiceEntry declares the port of the provider, as no cluster is generated
+// for the other ports.
+func checkServiceEntryPort(svc *model.Service, port int) error {
+	if svc.Attributes.ServiceRegistry != string(serviceregistry.External) {
+		return nil
+	}
+	if _, f := svc.Ports.GetByPort(port); !f {
+		return fmt.Errorf("port %d is not declared by the ServiceEntry of %s", port, svc.Hostname)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensionproviders
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pkg/config/host"
+)
+
+func TestLookupCluster(t *testing.T) {
+	service := func(hostname, namespace string, registry serviceregistry.ProviderID) *model.Service {
+		return &model.Service{
+			Hostname: host.Name(hostname),
+			Ports:    model.PortList{{Name: "http", Port: 9411}},
+			Attributes: model.ServiceAttributes{
+				Namespace:       namespace,
+				ServiceRegistry: string(registry),
+			},
+		}
+	}
+	push := model.NewPushContext()
+	for _, svc := range []*model.Service{
+		service("zipkin.istio-system.svc.cluster.local", "istio-system", serviceregistry.Kubernetes),
+		service("zipkin.foo.svc.cluster.local", "foo", serviceregistry.Kubernetes),
+		service("zipkin.foo.svc.cluster.local", "bar", serviceregistry.Kubernetes),
+		service("collector.example.com", "foo", serviceregistry.External),
+		service("collector.example.com", "bar", serviceregistry.External),
+	} {
+		if push.ServiceIndex.HostnameAndNamespace[svc.Hostname] == nil {
+			push.ServiceIndex.HostnameAndNamespace[svc.Hostname] = map[string]*model.Service{}
+		}
+		push.ServiceIndex.HostnameAndNamespace[svc.Hostname][svc.Attributes.Namespace] = svc
+	}
+
+	cases := []struct {
+		name    string
+		service string
+		port    int
+		cluster string
+		err     bool
+	}{
+		{"service", "zipkin.istio-system.svc.cluster.local", 9411, "outbound|9411||zipkin.istio-system.svc.cluster.local", false},
+		{"namespaced service", "foo/zipkin.foo.svc.cluster.local", 9411, "outbound|9411||zipkin.foo.svc.cluster.local", false},
+		{"ambiguous service", "zipkin.foo.svc.cluster.local", 9411, "", true},
+		{"service entries", "collector.example.com", 9411, "outbound|9411||collector.example.com", false},
+		{"namespaced service entry", "bar/collector.example.com", 9411, "outbound|9411||collector.example.com", false},
+		{"service entry undeclared port", "collector.example.com", 4317, "", true},
+		{"unknown", "unknown.example.com", 9411, "", true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, cluster, err := LookupCluster(push, tt.service, tt.port)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && cluster != tt.cluster {
+				t.Fatalf("expected cluster %v, got %v", tt.cluster, cluster)
+			}
+		})
+	}
+}
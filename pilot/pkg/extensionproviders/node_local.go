@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensionproviders
+
+import (
+	"sort"
+	"strconv"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+)
+
+// HostIPService is the service of the tracing providers running on the node of each proxy, for example a
+// collector deployed as a DaemonSet and only reachable on the node IP. Istiod generates a static cluster per
+// proxy for these providers, pointing to the host IP the proxy reports in its node metadata.
+const HostIPService = "$(HOST_IP)"
+
+// NodeLocalPort is a port of a node-local provider.
+type NodeLocalPort struct {
+	Port uint32
+	// HTTP2 is set for providers using gRPC.
+	HTTP2 bool
+}
+
+// NodeLocalCluster returns the name of the cluster of a node-local provider port.
+func NodeLocalCluster(port uint32) string {
+	return "node-local|" + strconv.Itoa(int(port))
+}
+
+// NodeLocalPorts returns the ports of the node-local tracing providers of the mesh, sorted.
+func NodeLocalPorts(mesh *meshconfig.MeshConfig) []NodeLocalPort {
+	ports := map[uint32]bool{}
+	add := func(service string, port uint32, h2 bool) {
+		if service == HostIPService {
+			ports[port] = ports[port] || h2
+		}
+	}
+	for _, p := range mesh.GetExtensionProviders() {
+		switch provider := p.Provider.(type) {
+		case *meshconfig.MeshConfig_ExtensionProvider_Zipkin:
+			add(provider.Zipkin.GetService(), provider.Zipkin.GetPort(), false)
+		case *meshconfig.MeshConfig_ExtensionProvider_Datadog:
+			add(provider.Datadog.GetService(), provider.Datadog.GetPort(), false)
+		case *meshconfig.MeshConfig_ExtensionProvider_Lightstep:
+			add(provider.Lightstep.GetService(), provider.Lightstep.GetPort(), true)
+		}
+	}
+	out := make([]NodeLocalPort, 0, len(ports))
+	for port, h2 := range ports {
+		out = append(out, NodeLocalPort{Port: port, HTTP2: h2})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Port < out[j].Port
+	})
+	return out
+}
@@ -0,0 +1,51 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package extensionproviders
+
+import (
+	"reflect"
+	"testing"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+)
+
+func TestNodeLocalPorts(t *testing.T) {
+	mesh := &meshconfig.MeshConfig{
+		ExtensionProviders: []*meshconfig.MeshConfig_ExtensionProvider{
+			{
+				Name: "zipkin",
+				Provider: &meshconfig.MeshConfig_ExtensionProvider_Zipkin{
+					Zipkin: &meshconfig.MeshConfig_ExtensionProvider_ZipkinTracingProvider{Service: HostIPService, Port: 9411},
+				},
+			},
+			{
+				Name: "lightstep",
+				Provider: &meshconfig.MeshConfig_ExtensionProvider_Lightstep{
+					Lightstep: &meshconfig.MeshConfig_ExtensionProvider_LightstepTracingProvider{Service: HostIPService, Port: 8360},
+				},
+			},
+			{
+				Name: "datadog",
+				Provider: &meshconfig.MeshConfig_ExtensionProvider_Datadog{
+					Datadog: &meshconfig.MeshConfig_ExtensionProvider_DatadogTracingProvider{Service: "datadog.istio-system", Port: 8126},
+				},
+			},
+		},
+	}
+	want := []NodeLocalPort{{Port: 8360, HTTP2: true}, {Port: 9411}}
+	if got := NodeLocalPorts(mesh); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -63,6 +63,35 @@ var (
 		"Limits the number of concurrent pushes allowed. On larger machines this can be increased for faster pushes",
 	).Get()
 
+	EnablePushPriority = env.RegisterBoolVar(
+		"PILOT_ENABLE_PUSH_PRIORITY",
+		false,
+		"If enabled, pending pushes to gateways, to proxies whose config depends on the updated config, and to all "+
+			"other proxies are interleaved with 4:2:1 weights, so higher priority proxies are pushed sooner without "+
+			"starving the others. Otherwise, pushes are sent in the order they were queued.",
+	).Get()
+
+	GatewayPushThrottle = env.RegisterIntVar(
+		"PILOT_PUSH_THROTTLE_GATEWAY",
+		0,
+		"Limits the number of concurrent pushes to gateways. If unset, only PILOT_PUSH_THROTTLE applies.",
+	).Get()
+
+	AffectedPushThrottle = env.RegisterIntVar(
+		"PILOT_PUSH_THROTTLE_AFFECTED",
+		0,
+		"Limits the number of concurrent pushes to proxies whose config depends on the updated config. "+
+			"If unset, only PILOT_PUSH_THROTTLE applies.",
+	).Get()
+
+	DefaultPushThrottle = env.RegisterIntVar(
+		"PILOT_PUSH_THROTTLE_DEFAULT",
+		0,
+		"Limits the number of concurrent pushes to proxies that are neither gateways nor depend on the updated "+
+			"config. Setting it below PILOT_PUSH_THROTTLE reserves the remaining pushes for the other proxies. "+
+			"If unset, only PILOT_PUSH_THROTTLE applies.",
+	).Get()
+
 	// MaxRecvMsgSize The max receive buffer size of gRPC received channel of Pilot in bytes.
 	MaxRecvMsgSize = env.RegisterIntVar(
 		"ISTIO_GPRC_MAXRECVMSGSIZE",
@@ -75,6 +104,14 @@ var (
 	// Currently this may cause a bug when we go from N clusters -> 0 clusters -> N clusters
 	FilterGatewayClusterConfig = env.RegisterBoolVar("PILOT_FILTER_GATEWAY_CLUSTER_CONFIG", false, "").Get()
 
+	ScopeGatewayPushes = env.RegisterBoolVar(
+		"PILOT_SCOPE_GATEWAY_PUSHES",
+		true,
+		"If enabled, gateways are only pushed VirtualService changes for the VirtualServices bound to them. "+
+			"If PILOT_FILTER_GATEWAY_CLUSTER_CONFIG is also enabled, they are only pushed service and DestinationRule "+
+			"changes for the hosts they route to.",
+	).Get()
+
 	DebounceAfter = env.RegisterDurationVar(
 		"PILOT_DEBOUNCE_AFTER",
 		100*time.Millisecond,
@@ -90,6 +127,21 @@ var (
 			"for this time, we'll trigger a push.",
 	).Get()
 
+	EnableAdaptiveDebounce = env.RegisterBoolVar(
+		"PILOT_DEBOUNCE_ADAPTIVE",
+		false,
+		"If enabled, the delay added to config/registry events for debouncing starts at PILOT_DEBOUNCE_AFTER, "+
+			"is doubled when pushes take longer than it or proxies are still waiting for the previous push, and "+
+			"is halved when pushes are quick, up to PILOT_DEBOUNCE_ADAPTIVE_MAX.",
+	).Get()
+
+	AdaptiveDebounceMax = env.RegisterDurationVar(
+		"PILOT_DEBOUNCE_ADAPTIVE_MAX",
+		time.Second,
+		"The maximum delay added to config/registry events for debouncing when PILOT_DEBOUNCE_ADAPTIVE is enabled. "+
+			"Pushes are still triggered after PILOT_DEBOUNCE_MAX if events keep showing up.",
+	).Get()
+
 	EnableEDSDebounce = env.RegisterBoolVar(
 		"PILOT_ENABLE_EDS_DEBOUNCE",
 		true,
@@ -220,6 +272,22 @@ var (
 		"If enabled, Pilot will generate MCS ServiceExport objects for every non cluster-local service in the cluster",
 	).Get()
 
+	EnableMCSServiceDiscovery = env.RegisterBoolVar(
+		"PILOT_ENABLE_MCS_SERVICE_DISCOVERY",
+		false,
+		"If enabled, Pilot will watch MCS ServiceImport objects and generate a clusterset.local host for every imported "+
+			"service, with the endpoints of all the clusters exporting it. The cluster.local host of a service then only "+
+			"reaches the endpoints in the same cluster. This requires the MCS CRDs to be installed in every cluster",
+	).Get()
+
+	ServiceEntryAutoAllocateCIDR = env.RegisterStringVar(
+		"PILOT_AUTO_ALLOCATE_CIDR",
+		"240.240.0.0/16",
+		"The IPv4 range from which addresses are automatically allocated to ServiceEntry hosts without an address, "+
+			"for proxies that enable ISTIO_META_DNS_AUTO_ALLOCATE. Allocations are persisted in the ServiceEntry "+
+			"status when PILOT_ENABLE_STATUS is enabled",
+	).Get()
+
 	EnableSDSServer = env.RegisterBoolVar(
 		"ISTIOD_ENABLE_SDS_SERVER",
 		true,
@@ -227,6 +295,38 @@ var (
 			"To ensure proper security, PILOT_ENABLE_XDS_IDENTITY_CHECK=true is required as well.",
 	).Get()
 
+	EnableSidecarCredentialName = env.RegisterBoolVar(
+		"PILOT_ENABLE_SIDECAR_CREDENTIAL_NAME",
+		false,
+		"If enabled, DestinationRule credentialName will be honored for sidecars. The sidecar's agent fetches "+
+			"the credential from Istiod's SDS server, so ISTIOD_ENABLE_SDS_SERVER must be enabled as well. "+
+			"The agent only fetches credentials if this is also set in its environment, for example "+
+			"through proxyMetadata.",
+	).Get()
+
+	EnableMeshConfigRollback = env.RegisterBoolVar(
+		"PILOT_ENABLE_MESH_CONFIG_ROLLBACK",
+		false,
+		"If enabled, a mesh config update is rolled back to the previous mesh config if pushing it fails, "+
+			"either because the push context cannot be built or because generators return errors for "+
+			"PILOT_MESH_CONFIG_ROLLBACK_FAILED_PROXY_RATIO of the connected proxies.",
+	).Get()
+
+	MeshConfigRollbackFailedProxyRatio = env.RegisterFloatVar(
+		"PILOT_MESH_CONFIG_ROLLBACK_FAILED_PROXY_RATIO",
+		0.1,
+		"With PILOT_ENABLE_MESH_CONFIG_ROLLBACK, the ratio of connected proxies for which generating config "+
+			"from a mesh config update must fail, within the rollback window, for the update to be rolled back. "+
+			"Errors for proxies that also failed with the previous mesh config are not counted.",
+	).Get()
+
+	MeshConfigRollbackWindow = env.RegisterDurationVar(
+		"PILOT_MESH_CONFIG_ROLLBACK_WINDOW",
+		30*time.Second,
+		"With PILOT_ENABLE_MESH_CONFIG_ROLLBACK, the time after a mesh config update was pushed during which "+
+			"push failures roll it back. After this, the update is committed.",
+	).Get()
+
 	EnableAnalysis = env.RegisterBoolVar(
 		"PILOT_ENABLE_ANALYSIS",
 		false,
@@ -339,6 +439,16 @@ var (
 	XDSCacheMaxSize = env.RegisterIntVar("PILOT_XDS_CACHE_SIZE", 20000,
 		"The maximum number of cache entries for the XDS cache.").Get()
 
+	EnablePushCostAttribution = env.RegisterBoolVar("PILOT_PUSH_COST_ATTRIBUTION", false,
+		"If true, Pilot will track the size, generation time and cache hit ratio of the pushes to each connection, "+
+			"and decode the pushed clusters, endpoints, routes and listeners to attribute their size to the services "+
+			"and VirtualServices they were built from, reported on /debug/push_cost. "+
+			"This adds CPU cost to every push.").Get()
+
+	EnablePushCostMetrics = env.RegisterBoolVar("PILOT_PUSH_COST_METRICS", false,
+		"If true, Pilot will export the bytes attributed to config by PILOT_PUSH_COST_ATTRIBUTION as metrics, "+
+			"labeled by rank and kind rather than by config name to bound their cardinality.").Get()
+
 	// EnableLegacyFSGroupInjection has first-party-jwt as allowed because we only
 	// need the fsGroup configuration for the projected service account volume mount,
 	// which is only used by first-party-jwt. The installer will automatically
@@ -360,6 +470,64 @@ var (
 			"Setting the timeout to 0 disables this behavior.",
 	).Get()
 
+	RemoteClusterHealthCheckInterval = env.RegisterDurationVar(
+		"PILOT_REMOTE_CLUSTER_HEALTH_CHECK_INTERVAL",
+		30*time.Second,
+		"The interval at which remote clusters added via remote-secrets are checked for API server reachability "+
+			"and informer sync. Clients whose credentials are rejected are rebuilt from their secret. "+
+			"Setting the interval to 0 disables health checking.",
+	).Get()
+
+	RemoteClusterExecPlugins = env.RegisterStringVar(
+		"PILOT_REMOTE_CLUSTER_EXEC_PLUGINS",
+		"",
+		"Comma separated list of the exec credential plugin commands that kubeconfigs of remote-secrets may use. "+
+			"If set, kubeconfigs using any other exec plugin are rejected. By default all exec plugins are allowed.",
+	).Get()
+
+	EnableProxyConfigOverrides = env.RegisterBoolVar(
+		"PILOT_ENABLE_PROXY_CONFIG_OVERRIDES",
+		false,
+		"If enabled, the mesh default ProxyConfig is overridden by ConfigMaps labeled with istio.io/proxy-config in "+
+			"the root namespace, then by the proxy.istio.io/config annotation of namespaces and by such ConfigMaps in "+
+			"the namespace of the workload. Sidecars are injected with the effective ProxyConfig, which the agent "+
+			"reads on startup, so changes take effect once the proxy is re-injected.",
+	).Get()
+
+	PushTracingZipkinAddress = env.RegisterStringVar(
+		"PILOT_PUSH_TRACING_ZIPKIN_ADDRESS",
+		"",
+		"If set, Istiod traces its push pipeline (debounce, push context initialization, generation, send and ACK) "+
+			"and reports the spans to this Zipkin v2 collector, for example zipkin.istio-system:9411.",
+	).Get()
+
+	PushTracingFile = env.RegisterStringVar(
+		"PILOT_PUSH_TRACING_FILE",
+		"",
+		"If set, Istiod traces its push pipeline and appends the spans to this file, one Zipkin v2 JSON span per line.",
+	).Get()
+
+	pushTracingSamplingVar = env.RegisterFloatVar(
+		"PILOT_PUSH_TRACING_SAMPLING",
+		100.0,
+		"Sets the percentage of pushes traced when push tracing is enabled. Should be 0.0 - 100.0.",
+	)
+
+	PushTracingSampling = func() float64 {
+		f := pushTracingSamplingVar.Get()
+		if f < 0.0 || f > 100.0 {
+			log.Warnf("PILOT_PUSH_TRACING_SAMPLING out of range: %v", f)
+			return 100.0
+		}
+		return f
+	}()
+
+	GitConfigPollInterval = env.RegisterDurationVar(
+		"PILOT_GIT_CONFIG_POLL_INTERVAL",
+		30*time.Second,
+		"The interval at which git config sources are fetched for new commits.",
+	).Get()
+
 	EndpointTelemetryLabel = env.RegisterBoolVar("PILOT_ENDPOINT_TELEMETRY_LABEL", true,
 		"If true, pilot will add telemetry related metadata to Endpoint resource, which will be consumed by telemetry filter.",
 	).Get()
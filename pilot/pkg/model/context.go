@@ -34,6 +34,7 @@ import (
 	structpb "github.com/golang/protobuf/ptypes/struct"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
@@ -83,6 +84,10 @@ type Environment struct {
 	// TrustBundle: List of Mesh TrustAnchors
 	TrustBundle *trustbundle.TrustBundle
 
+	// ProxyConfigSource provides the overrides of the mesh default ProxyConfig. It is nil unless
+	// overrides are enabled.
+	ProxyConfigSource
+
 	clusterLocalServices ClusterLocalProvider
 }
 
@@ -228,6 +233,12 @@ type Proxy struct {
 	// The merged gateways associated with the proxy if this is a Router
 	MergedGateway *MergedGateway
 
+	// the config the merged gateways depend on, if this is a Router
+	GatewayDependencies *GatewayDependencies
+
+	// the config the merged gateways depended on previously
+	PrevGatewayDependencies *GatewayDependencies
+
 	// service instances associated with the proxy
 	ServiceInstances []*ServiceInstance
 
@@ -485,6 +496,9 @@ type NodeMetadata struct {
 	// InstanceIPs is the set of IPs attached to this proxy
 	InstanceIPs StringList `json:"INSTANCE_IPS,omitempty"`
 
+	// HostIP is the IP of the node running this proxy. It is used to reach node-local extension providers.
+	HostIP string `json:"HOST_IP,omitempty"`
+
 	// Namespace is the namespace in which the workload instance is running.
 	Namespace string `json:"NAMESPACE,omitempty"`
 
@@ -743,6 +757,11 @@ func (node *Proxy) SetGatewaysForProxy(ps *PushContext) {
 		return
 	}
 	node.MergedGateway = ps.mergeGateways(node)
+	if !features.ScopeGatewayPushes {
+		return
+	}
+	node.PrevGatewayDependencies = node.GatewayDependencies
+	node.GatewayDependencies = ps.gatewayDependencies(node)
 }
 
 func (node *Proxy) SetServiceInstances(serviceDiscovery ServiceDiscovery) {
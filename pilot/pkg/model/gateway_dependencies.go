@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	networking "istio.io/api/networking/v1alpha3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// GatewayDependencies indexes the config a gateway proxy is built from: the VirtualServices bound to its merged
+// Gateways, including their delegates, the hosts they route to and the DestinationRules of those hosts.
+type GatewayDependencies struct {
+	// configs are the hash codes of the VirtualServices and DestinationRules the gateway depends on.
+	configs map[uint32]struct{}
+
+	// hosts are the hosts of the VirtualServices bound to the gateway, and the hosts they route to.
+	hosts map[host.Name]struct{}
+	// wildcardHosts is true if some of the hosts are wildcards, which are matched against the updated hosts.
+	wildcardHosts bool
+
+	// scopeServices is set if the gateway is only sent clusters for the hosts it routes to. Otherwise, the
+	// gateway depends on all services and DestinationRules.
+	scopeServices bool
+}
+
+// gatewayDependencies builds the dependencies of the gateway proxy, from its merged Gateways.
+func (ps *PushContext) gatewayDependencies(proxy *Proxy) *GatewayDependencies {
+	out := &GatewayDependencies{
+		configs: map[uint32]struct{}{},
+		hosts:   map[host.Name]struct{}{},
+		// Clusters are built for all services unless filtered, and always for the SNI-DNAT router.
+		scopeServices: features.FilterGatewayClusterConfig && proxy.GetRouterMode() != SniDnatRouter,
+	}
+	if proxy.MergedGateway == nil {
+		return out
+	}
+
+	gateways := map[string]struct{}{}
+	for _, gw := range proxy.MergedGateway.GatewayNameForServer {
+		gateways[gw] = struct{}{}
+	}
+	for gw := range gateways {
+		virtualServices := ps.VirtualServicesForGateway(proxy, gw)
+		for _, delegate := range ps.DelegateVirtualServicesConfigKey(virtualServices) {
+			out.configs[delegate.HashCode()] = struct{}{}
+		}
+		for _, vsConfig := range virtualServices {
+			out.configs[ConfigKey{Kind: gvk.VirtualService, Name: vsConfig.Name, Namespace: vsConfig.Namespace}.HashCode()] = struct{}{}
+			vs, ok := vsConfig.Spec.(*networking.VirtualService)
+			if !ok {
+				continue
+			}
+			// Delegates are merged into the VirtualServices, so their destinations are included.
+			for _, h := range vs.Hosts {
+				out.addHost(host.Name(h))
+			}
+			for _, h := range virtualServiceDestinationHosts(vs) {
+				out.addHost(host.Name(h))
+			}
+		}
+	}
+
+	for h := range out.hosts {
+		for _, svc := range ps.ServiceIndex.HostnameAndNamespace[h] {
+			if dr := ps.DestinationRule(proxy, svc); dr != nil {
+				out.configs[ConfigKey{Kind: gvk.DestinationRule, Name: dr.Name, Namespace: dr.Namespace}.HashCode()] = struct{}{}
+			}
+		}
+	}
+	return out
+}
+
+func (d *GatewayDependencies) addHost(h host.Name) {
+	d.hosts[h] = struct{}{}
+	if h.IsWildCarded() {
+		d.wildcardHosts = true
+	}
+}
+
+// DependsOnConfig determines if the gateway depends on the given config. Gateways depend on the VirtualServices
+// bound to them and, when they are only sent the clusters they route to, on the services and DestinationRules of
+// those hosts. They are assumed to depend on any other config.
+func (d *GatewayDependencies) DependsOnConfig(config ConfigKey) bool {
+	if d == nil {
+		return true
+	}
+
+	switch config.Kind {
+	case gvk.VirtualService:
+		_, exists := d.configs[config.HashCode()]
+		return exists
+	case gvk.DestinationRule:
+		if !d.scopeServices {
+			return true
+		}
+		_, exists := d.configs[config.HashCode()]
+		return exists
+	case gvk.ServiceEntry:
+		if !d.scopeServices {
+			return true
+		}
+		return d.dependsOnHost(host.Name(config.Name))
+	default:
+		return true
+	}
+}
+
+func (d *GatewayDependencies) dependsOnHost(h host.Name) bool {
+	if _, exists := d.hosts[h]; exists {
+		return true
+	}
+	if !d.wildcardHosts && !h.IsWildCarded() {
+		return false
+	}
+	for dep := range d.hosts {
+		if dep.Matches(h) {
+			return true
+		}
+	}
+	return false
+}
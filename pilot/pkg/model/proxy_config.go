@@ -0,0 +1,172 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+// ProxyConfigOverride overrides the mesh default ProxyConfig for the proxies in a namespace. Overrides come
+// from the proxy.istio.io/config annotation of the namespace, and from ProxyConfig ConfigMaps, which may
+// restrict them to the workloads matching a selector. A ConfigMap without a selector in the root namespace
+// applies to the whole mesh.
+type ProxyConfigOverride struct {
+	// Name is the name of the ConfigMap, or empty for the namespace annotation.
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace"`
+	// Selector restricts the override to workloads with these labels. An empty selector applies to the
+	// whole namespace.
+	Selector labels.Instance `json:"selector,omitempty"`
+	// ProxyConfig is the ProxyConfig YAML merged over the lower layers.
+	ProxyConfig string `json:"proxyConfig"`
+
+	CreationTimestamp time.Time `json:"-"`
+}
+
+// ProxyConfigSource provides the ProxyConfig overrides of all namespaces.
+type ProxyConfigSource interface {
+	// ProxyConfigs returns the overrides. The result is shared and must not be modified.
+	ProxyConfigs() *ProxyConfigs
+}
+
+// ProxyConfigs organizes ProxyConfig overrides by namespace.
+type ProxyConfigs struct {
+	// Maps from namespace to the overrides, namespace annotation first, then ConfigMaps by creation time.
+	NamespaceToOverrides map[string][]ProxyConfigOverride `json:"namespace_to_overrides"`
+}
+
+// GetProxyConfigs returns the ProxyConfig overrides for the given environment.
+func GetProxyConfigs(env *Environment) *ProxyConfigs {
+	if env.ProxyConfigSource == nil {
+		return NewProxyConfigs(nil)
+	}
+	return env.ProxyConfigs()
+}
+
+// NewProxyConfigs organizes the given overrides by namespace.
+func NewProxyConfigs(overrides []ProxyConfigOverride) *ProxyConfigs {
+	pcs := &ProxyConfigs{
+		NamespaceToOverrides: map[string][]ProxyConfigOverride{},
+	}
+	overrides = append([]ProxyConfigOverride{}, overrides...)
+	sort.SliceStable(overrides, func(i, j int) bool {
+		// The namespace annotation is applied before the ConfigMaps.
+		if (overrides[i].Name == "") != (overrides[j].Name == "") {
+			return overrides[i].Name == ""
+		}
+		if overrides[i].CreationTimestamp.Equal(overrides[j].CreationTimestamp) {
+			return overrides[i].Name+"."+overrides[i].Namespace < overrides[j].Name+"."+overrides[j].Namespace
+		}
+		return overrides[i].CreationTimestamp.Before(overrides[j].CreationTimestamp)
+	})
+	for _, o := range overrides {
+		pcs.NamespaceToOverrides[o.Namespace] = append(pcs.NamespaceToOverrides[o.Namespace], o)
+	}
+	return pcs
+}
+
+// Overrides returns the overrides applying to a workload in the given namespace, in the order they are
+// merged: the oldest ConfigMap without a selector of the root namespace, the namespace annotation, the
+// oldest namespace-wide ConfigMap, then the oldest ConfigMap whose selector matches the workload.
+func (p *ProxyConfigs) Overrides(namespace, rootNamespace string, workload labels.Instance) []ProxyConfigOverride {
+	if p == nil {
+		return nil
+	}
+
+	var root, annotation, namespaceWide, selected *ProxyConfigOverride
+	if rootNamespace != "" && namespace != rootNamespace {
+		root = p.namespaceWide(rootNamespace)
+	}
+	overrides := p.NamespaceToOverrides[namespace]
+	for i := range overrides {
+		o := &overrides[i]
+		switch {
+		case o.Name == "":
+			annotation = o
+		case len(o.Selector) == 0:
+			if namespaceWide == nil {
+				namespaceWide = o
+			}
+		case selected == nil && o.Selector.SubsetOf(workload):
+			selected = o
+		}
+	}
+	var out []ProxyConfigOverride
+	for _, o := range []*ProxyConfigOverride{root, annotation, namespaceWide, selected} {
+		if o != nil {
+			out = append(out, *o)
+		}
+	}
+	return out
+}
+
+// namespaceWide returns the oldest ConfigMap without a selector in the namespace.
+func (p *ProxyConfigs) namespaceWide(namespace string) *ProxyConfigOverride {
+	overrides := p.NamespaceToOverrides[namespace]
+	for i := range overrides {
+		if overrides[i].Name != "" && len(overrides[i].Selector) == 0 {
+			return &overrides[i]
+		}
+	}
+	return nil
+}
+
+// EffectiveMeshConfig returns a copy of the mesh config whose default ProxyConfig has the overrides
+// applying to a workload in the given namespace merged in.
+func (p *ProxyConfigs) EffectiveMeshConfig(namespace string, workload labels.Instance,
+	mc *meshconfig.MeshConfig) (*meshconfig.MeshConfig, error) {
+	out := mc
+	for _, o := range p.Overrides(namespace, mc.GetRootNamespace(), workload) {
+		var err error
+		if out, err = mesh.ApplyProxyConfig(o.ProxyConfig, *out); err != nil {
+			return nil, fmt.Errorf("invalid proxy config override %s: %v", o.key(), err)
+		}
+	}
+	return out, nil
+}
+
+// EffectiveProxyConfig returns the ProxyConfig of a workload in the given namespace, resolved from the
+// mesh default and the overrides applying to it.
+func (p *ProxyConfigs) EffectiveProxyConfig(namespace string, workload labels.Instance,
+	mc *meshconfig.MeshConfig) (*meshconfig.ProxyConfig, error) {
+	out, err := p.EffectiveMeshConfig(namespace, workload, mc)
+	if err != nil {
+		return nil, err
+	}
+	return out.GetDefaultConfig(), nil
+}
+
+func (o ProxyConfigOverride) key() string {
+	if o.Name == "" {
+		return "namespace/" + o.Namespace
+	}
+	return "configmap/" + o.Namespace + "/" + o.Name
+}
+
+// EffectiveProxyConfig returns the ProxyConfig of the proxy, resolved from the mesh default and the
+// overrides applying to its namespace and workload.
+func (ps *PushContext) EffectiveProxyConfig(proxy *Proxy) (*meshconfig.ProxyConfig, error) {
+	var workload labels.Instance
+	if proxy.Metadata != nil {
+		workload = proxy.Metadata.Labels
+	}
+	return ps.ProxyConfigs.EffectiveProxyConfig(proxy.ConfigNamespace, workload, ps.Mesh)
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+type fakeProxyConfigSource []ProxyConfigOverride
+
+func (f fakeProxyConfigSource) ProxyConfigs() *ProxyConfigs {
+	return NewProxyConfigs(f)
+}
+
+func TestProxyConfigs_EffectiveProxyConfig(t *testing.T) {
+	now := time.Now()
+	env := &Environment{
+		ProxyConfigSource: fakeProxyConfigSource{
+			{
+				Name:              "selected",
+				Namespace:         "foo",
+				Selector:          labels.Instance{"app": "foo"},
+				ProxyConfig:       "drainDuration: 30s",
+				CreationTimestamp: now,
+			},
+			{
+				Name:              "namespace-wide",
+				Namespace:         "foo",
+				ProxyConfig:       "concurrency: 4\ndrainDuration: 20s",
+				CreationTimestamp: now,
+			},
+			{
+				Name:              "newer-namespace-wide",
+				Namespace:         "foo",
+				ProxyConfig:       "concurrency: 8",
+				CreationTimestamp: now.Add(time.Minute),
+			},
+			{
+				Namespace:   "foo",
+				ProxyConfig: "concurrency: 2\nstatNameLength: 100",
+			},
+			{
+				Name:        "other",
+				Namespace:   "bar",
+				ProxyConfig: "concurrency: 16",
+			},
+			{
+				Name:        "mesh-wide",
+				Namespace:   "istio-system",
+				ProxyConfig: "statNameLength: 50",
+			},
+			{
+				Name:        "root-selected",
+				Namespace:   "istio-system",
+				Selector:    labels.Instance{"app": "foo"},
+				ProxyConfig: "statNameLength: 60",
+			},
+		},
+	}
+	pcs := GetProxyConfigs(env)
+	mc := mesh.DefaultMeshConfig()
+
+	cases := []struct {
+		name           string
+		namespace      string
+		labels         labels.Instance
+		concurrency    int32
+		drainDuration  time.Duration
+		statNameLength int32
+		overrides      []string
+	}{
+		{
+			name:           "selected workload",
+			namespace:      "foo",
+			labels:         labels.Instance{"app": "foo", "version": "v1"},
+			concurrency:    4,
+			drainDuration:  30 * time.Second,
+			statNameLength: 100,
+			overrides:      []string{"mesh-wide", "", "namespace-wide", "selected"},
+		},
+		{
+			name:           "other workload",
+			namespace:      "foo",
+			labels:         labels.Instance{"app": "bar"},
+			concurrency:    4,
+			drainDuration:  20 * time.Second,
+			statNameLength: 100,
+			overrides:      []string{"mesh-wide", "", "namespace-wide"},
+		},
+		{
+			name:           "other namespace",
+			namespace:      "bar",
+			concurrency:    16,
+			drainDuration:  time.Duration(mesh.DefaultProxyConfig().DrainDuration.Seconds) * time.Second,
+			statNameLength: 50,
+			overrides:      []string{"mesh-wide", "other"},
+		},
+		{
+			name:           "mesh-wide only",
+			namespace:      "baz",
+			labels:         labels.Instance{"app": "foo"},
+			concurrency:    mesh.DefaultProxyConfig().Concurrency.GetValue(),
+			drainDuration:  time.Duration(mesh.DefaultProxyConfig().DrainDuration.Seconds) * time.Second,
+			statNameLength: 50,
+			overrides:      []string{"mesh-wide"},
+		},
+		{
+			name:           "root namespace",
+			namespace:      "istio-system",
+			labels:         labels.Instance{"app": "foo"},
+			concurrency:    mesh.DefaultProxyConfig().Concurrency.GetValue(),
+			drainDuration:  time.Duration(mesh.DefaultProxyConfig().DrainDuration.Seconds) * time.Second,
+			statNameLength: 60,
+			overrides:      []string{"mesh-wide", "root-selected"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, o := range pcs.Overrides(tt.namespace, mc.GetRootNamespace(), tt.labels) {
+				names = append(names, o.Name)
+			}
+			if !reflect.DeepEqual(names, tt.overrides) {
+				t.Fatalf("got overrides %v, want %v", names, tt.overrides)
+			}
+
+			pc, err := pcs.EffectiveProxyConfig(tt.namespace, tt.labels, &mc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pc.GetConcurrency().GetValue(); got != tt.concurrency {
+				t.Errorf("got concurrency %v, want %v", got, tt.concurrency)
+			}
+			if got := time.Duration(pc.GetDrainDuration().Seconds) * time.Second; got != tt.drainDuration {
+				t.Errorf("got drain duration %v, want %v", got, tt.drainDuration)
+			}
+			if got := pc.GetStatNameLength(); got != tt.statNameLength {
+				t.Errorf("got stat name length %v, want %v", got, tt.statNameLength)
+			}
+		})
+	}
+
+	// The mesh config itself is never modified.
+	if mc.DefaultConfig.Concurrency.GetValue() != mesh.DefaultProxyConfig().Concurrency.GetValue() {
+		t.Fatalf("mesh config was modified")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/tracing"
 	"istio.io/istio/pilot/pkg/util/sets"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
@@ -183,6 +184,9 @@ type PushContext struct {
 	// Telemetry stores the existing Telemetry resources for the cluster.
 	Telemetry *Telemetries `json:"-"`
 
+	// ProxyConfigs stores the overrides of the mesh default ProxyConfig.
+	ProxyConfigs *ProxyConfigs `json:"-"`
+
 	// The following data is either a global index or used in the inbound path.
 	// Namespace specific views do not apply here.
 
@@ -219,6 +223,11 @@ type Gateway struct {
 	Addr string
 	// gateway port
 	Port uint32
+	// Weight is the relative capacity of the gateway, used to split the traffic to its network between its
+	// gateways. Registries set it to zero if the capacity is unknown.
+	Weight uint32
+	// Unhealthy is set for gateways without ready endpoints, which do not receive traffic.
+	Unhealthy bool
 }
 
 type processedDestRules struct {
@@ -305,6 +314,11 @@ type PushRequest struct {
 	// There should only be multiple reasons if the push request is the result of two distinct triggers, rather than
 	// classifying a single trigger as having multiple reasons.
 	Reason []TriggerReason
+
+	// Span is the root span of the push trace. It is nil unless push tracing is enabled and the push is sampled.
+	// It is held while the push of each connection is pending, so it ends once the last connection is pushed.
+	// Merging releases the span of the newer request.
+	Span *tracing.Span
 }
 
 type TriggerReason string
@@ -356,6 +370,15 @@ func (pr *PushRequest) Merge(other *PushRequest) *PushRequest {
 
 		// Merge the two reasons. Note that we shouldn't deduplicate here, or we would under count
 		Reason: reason,
+
+		// Keep the first (older) trace
+		Span: pr.Span,
+	}
+	if merged.Span == nil {
+		merged.Span = other.Span
+	} else if other.Span != nil && other.Span != merged.Span {
+		// The newer trace ends here, its push is carried out by the older one.
+		other.Span.Tag("merged", "true").Release()
 	}
 
 	// Do not merge when any one is empty
@@ -979,6 +1002,13 @@ func (ps *PushContext) InitContext(env *Environment, oldPushContext *PushContext
 	ps.initMeshNetworks(env.Networks())
 
 	ps.clusterLocalHosts = env.ClusterLocal().GetClusterLocalHosts()
+	if features.EnableMCSServiceDiscovery {
+		// With MCS, the cluster.local host of a service only reaches the endpoints in the same cluster. The
+		// clusterset.local host reaches the endpoints of all the clusters exporting the service.
+		hosts := append(ClusterLocalHosts{host.Name("*.svc." + env.DomainSuffix)}, ps.clusterLocalHosts...)
+		sort.Sort(host.Names(hosts))
+		ps.clusterLocalHosts = hosts
+	}
 
 	ps.initDone.Store(true)
 	return nil
@@ -1010,6 +1040,8 @@ func (ps *PushContext) createNewContext(env *Environment) error {
 		return err
 	}
 
+	ps.initProxyConfigs(env)
+
 	if err := ps.initEnvoyFilters(env); err != nil {
 		return err
 	}
@@ -1030,7 +1062,8 @@ func (ps *PushContext) updateContext(
 	oldPushContext *PushContext,
 	pushReq *PushRequest) error {
 	var servicesChanged, virtualServicesChanged, destinationRulesChanged, gatewayChanged,
-		authnChanged, authzChanged, envoyFiltersChanged, sidecarsChanged, telemetryChanged bool
+		authnChanged, authzChanged, envoyFiltersChanged, sidecarsChanged, telemetryChanged,
+		proxyConfigsChanged bool
 
 	for conf := range pushReq.ConfigsUpdated {
 		switch conf.Kind {
@@ -1056,6 +1089,8 @@ func (ps *PushContext) updateContext(
 			gatewayChanged = true
 		case gvk.Telemetry:
 			telemetryChanged = true
+		case gvk.Namespace, gvk.ConfigMap:
+			proxyConfigsChanged = true
 		}
 	}
 
@@ -1111,6 +1146,12 @@ func (ps *PushContext) updateContext(
 		ps.Telemetry = oldPushContext.Telemetry
 	}
 
+	if proxyConfigsChanged {
+		ps.initProxyConfigs(env)
+	} else {
+		ps.ProxyConfigs = oldPushContext.ProxyConfigs
+	}
+
 	if envoyFiltersChanged {
 		if err := ps.initEnvoyFilters(env); err != nil {
 			return err
@@ -1591,6 +1632,10 @@ func (ps *PushContext) initAuthorizationPolicies(env *Environment) error {
 	return nil
 }
 
+func (ps *PushContext) initProxyConfigs(env *Environment) {
+	ps.ProxyConfigs = GetProxyConfigs(env)
+}
+
 func (ps *PushContext) initTelemetry(env *Environment) (err error) {
 	if ps.Telemetry, err = GetTelemetries(env); err != nil {
 		telemetryLog.Errorf("failed to initialize telemetry: %v", err)
@@ -1752,7 +1797,7 @@ func (ps *PushContext) initMeshNetworks(meshNetworks *meshconfig.MeshNetworks) {
 			gws := networkConf.Gateways
 			for _, gw := range gws {
 				if gwIP := net.ParseIP(gw.GetAddress()); gwIP != nil {
-					ps.networkGateways[network] = append(ps.networkGateways[network], &Gateway{gw.GetAddress(), gw.Port})
+					ps.networkGateways[network] = append(ps.networkGateways[network], &Gateway{Addr: gw.GetAddress(), Port: gw.Port})
 				}
 			}
 
@@ -1763,7 +1808,36 @@ func (ps *PushContext) initMeshNetworks(meshNetworks *meshconfig.MeshNetworks) {
 	for network, gateways := range ps.ServiceDiscovery.NetworkGateways() {
 		// - the internal map of label gateways - these get deleted if the service is deleted, updated if the ip changes etc.
 		// - the computed map from meshNetworks (triggered by reloadNetworkLookup, the ported logic from getGatewayAddresses)
-		ps.networkGateways[network] = append(ps.networkGateways[network], gateways...)
+		for _, gw := range gateways {
+			// Copy, as the weight may be set below
+			gwCopy := *gw
+			ps.networkGateways[network] = append(ps.networkGateways[network], &gwCopy)
+		}
+	}
+
+	for _, gateways := range ps.networkGateways {
+		setDefaultGatewayWeights(gateways)
+	}
+}
+
+// setDefaultGatewayWeights sets the weight of the gateways of unknown capacity to the average weight of the
+// healthy gateways of the network, or to 1 if none of them has a known capacity.
+func setDefaultGatewayWeights(gateways []*Gateway) {
+	var total, known uint32
+	for _, gw := range gateways {
+		if gw.Weight > 0 && !gw.Unhealthy {
+			total += gw.Weight
+			known++
+		}
+	}
+	weight := uint32(1)
+	if known > 0 {
+		weight = (total + known/2) / known
+	}
+	for _, gw := range gateways {
+		if gw.Weight == 0 {
+			gw.Weight = weight
+		}
 	}
 }
 
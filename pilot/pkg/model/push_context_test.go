@@ -34,6 +34,7 @@ import (
 	securityBeta "istio.io/api/security/v1beta1"
 	selectorpb "istio.io/api/type/v1beta1"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/tracing"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
@@ -126,6 +127,38 @@ func TestMergeUpdateRequest(t *testing.T) {
 	}
 }
 
+type spanRecorder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (r *spanRecorder) Report(s *tracing.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, s.Name+":"+s.Tags["merged"])
+}
+
+func TestMergeUpdateRequestSpan(t *testing.T) {
+	recorder := &spanRecorder{}
+	tracing.Start(recorder, 1)
+	defer tracing.Stop()
+
+	older := &PushRequest{Span: tracing.StartSpan("older", time.Now())}
+	newer := &PushRequest{Span: tracing.StartSpan("newer", time.Now())}
+	older.Span.Hold()
+	newer.Span.Hold()
+
+	merged := older.Merge(newer)
+	if merged.Span != older.Span {
+		t.Fatalf("expected the older span to be kept")
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if !reflect.DeepEqual(recorder.names, []string{"newer:true"}) {
+		t.Fatalf("expected only the newer span to be finished, got %v", recorder.names)
+	}
+}
+
 func TestConcurrentMerge(t *testing.T) {
 	reqA := &PushRequest{Reason: make([]TriggerReason, 0, 100)}
 	reqB := &PushRequest{Reason: []TriggerReason{ServiceUpdate, ProxyUpdate}}
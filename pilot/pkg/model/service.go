@@ -75,15 +75,12 @@ type Service struct {
 	Address string `json:"address,omitempty"`
 
 	// AutoAllocatedAddress specifies the automatically allocated
-	// IPv4 address out of the reserved Class E subnet
-	// (240.240.0.0/16) for service entries with non-wildcard
-	// hostnames. The IPs assigned to services are not
-	// synchronized across istiod replicas as the DNS resolution
-	// for these service entries happens completely inside a pod
-	// whose proxy is managed by one istiod. That said, the algorithm
-	// to allocate IPs is pretty deterministic that at stable state, two
-	// istiods will allocate the exact same set of IPs for a given set of
-	// service entries.
+	// IPv4 address out of the range configured by PILOT_AUTO_ALLOCATE_CIDR
+	// (the reserved Class E subnet 240.240.0.0/16 by default) for service
+	// entries with non-wildcard hostnames. An allocated address does not
+	// change for as long as the service exists. When status writing is
+	// enabled, the allocations are persisted in the ServiceEntry status so
+	// that all istiod replicas agree on them, including across restarts.
 	AutoAllocatedAddress string `json:"autoAllocatedAddress,omitempty"`
 
 	// Protect concurrent ClusterVIPs read/write
@@ -31,6 +31,8 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/extensionproviders"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
@@ -482,6 +484,41 @@ func (cb *ClusterBuilder) buildBlackHoleCluster() *cluster.Cluster {
 	return c
 }
 
+// buildNodeLocalClusters generates a static cluster for each port of the node-local extension providers, such
+// as tracing collectors deployed as a DaemonSet, sending traffic to the IP of the node of the proxy.
+func (cb *ClusterBuilder) buildNodeLocalClusters() []*cluster.Cluster {
+	hostIP := cb.proxy.Metadata.HostIP
+	if hostIP == "" {
+		return nil
+	}
+	ports := extensionproviders.NodeLocalPorts(cb.push.Mesh)
+	clusters := make([]*cluster.Cluster, 0, len(ports))
+	for _, port := range ports {
+		name := extensionproviders.NodeLocalCluster(port.Port)
+		mc := NewMutableCluster(&cluster.Cluster{
+			Name:                 name,
+			ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STATIC},
+			ConnectTimeout:       gogo.DurationToProtoDuration(cb.push.Mesh.ConnectTimeout),
+			LbPolicy:             cluster.Cluster_ROUND_ROBIN,
+			LoadAssignment: &endpoint.ClusterLoadAssignment{
+				ClusterName: name,
+				Endpoints: []*endpoint.LocalityLbEndpoints{{
+					LbEndpoints: []*endpoint.LbEndpoint{{
+						HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+							Endpoint: &endpoint.Endpoint{Address: util.BuildAddress(hostIP, port.Port)},
+						},
+					}},
+				}},
+			},
+		})
+		if port.HTTP2 {
+			cb.setH2Options(mc)
+		}
+		clusters = append(clusters, mc.build())
+	}
+	return clusters
+}
+
 // generates a cluster that sends traffic to the original destination.
 // This cluster is used to catch all traffic to unknown listener ports
 func (cb *ClusterBuilder) buildDefaultPassthroughCluster() *cluster.Cluster {
@@ -720,8 +757,8 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 	proxy := opts.proxy
 
 	// Hack to avoid egress sds cluster config generation for sidecar when
-	// CredentialName is set in DestinationRule
-	if tls.CredentialName != "" && proxy.Type == model.SidecarProxy {
+	// CredentialName is set in DestinationRule, unless the sidecar's agent can fetch the credential
+	if tls.CredentialName != "" && proxy.Type == model.SidecarProxy && !features.EnableSidecarCredentialName {
 		if tls.Mode == networking.ClientTLSSettings_SIMPLE || tls.Mode == networking.ClientTLSSettings_MUTUAL {
 			return nil, nil
 		}
@@ -774,7 +811,7 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 			}
 			// If  credential name is specified at Destination Rule config and originating node is egress gateway, create
 			// SDS config for egress gateway to fetch key/cert at gateway agent.
-			authn_model.ApplyCustomSDSToClientCommonTLSContext(tlsContext.CommonTlsContext, tls)
+			applyCredentialSDSToClientCommonTLSContext(tlsContext.CommonTlsContext, tls, proxy)
 		} else {
 			// If CredentialName is not set fallback to files specified in DR.
 			res := model.SdsCertificateConfig{
@@ -807,7 +844,7 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 		if tls.CredentialName != "" {
 			// If  credential name is specified at Destination Rule config and originating node is egress gateway, create
 			// SDS config for egress gateway to fetch key/cert at gateway agent.
-			authn_model.ApplyCustomSDSToClientCommonTLSContext(tlsContext.CommonTlsContext, tls)
+			applyCredentialSDSToClientCommonTLSContext(tlsContext.CommonTlsContext, tls, proxy)
 		} else {
 			// If CredentialName is not set fallback to file based approach
 			if tls.ClientCertificate == "" || tls.PrivateKey == "" {
@@ -957,3 +994,13 @@ func maybeApplyEdsConfig(c *cluster.Cluster) {
 		},
 	}
 }
+
+// applyCredentialSDSToClientCommonTLSContext applies the credentialName SDS config. Gateways fetch the credential
+// from Istiod over ADS, while sidecars fetch it from their agent, which in turn fetches it from Istiod.
+func applyCredentialSDSToClientCommonTLSContext(tlsContext *auth.CommonTlsContext, tls *networking.ClientTLSSettings, proxy *model.Proxy) {
+	if proxy.Type == model.SidecarProxy {
+		authn_model.ApplyAgentCredentialSDSToClientCommonTLSContext(tlsContext, tls, proxy)
+		return
+	}
+	authn_model.ApplyCustomSDSToClientCommonTLSContext(tlsContext, tls)
+}
@@ -35,6 +35,7 @@ import (
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
@@ -2114,6 +2115,51 @@ func TestBuildUpstreamClusterTLSContext(t *testing.T) {
 	}
 }
 
+func TestBuildUpstreamClusterTLSContextSidecarCredentialName(t *testing.T) {
+	defer func(old bool) { features.EnableSidecarCredentialName = old }(features.EnableSidecarCredentialName)
+	features.EnableSidecarCredentialName = true
+
+	proxy := &model.Proxy{
+		Metadata: &model.NodeMetadata{},
+		Type:     model.SidecarProxy,
+	}
+	opts := &buildClusterOpts{
+		mutable: newTestCluster(),
+		proxy:   proxy,
+	}
+	tlsSettings := &networking.ClientTLSSettings{
+		Mode:            networking.ClientTLSSettings_MUTUAL,
+		CredentialName:  "fake-cred",
+		SubjectAltNames: []string{"SAN"},
+	}
+	expected := &tls.UpstreamTlsContext{
+		CommonTlsContext: &tls.CommonTlsContext{
+			TlsCertificateSdsSecretConfigs: []*tls.SdsSecretConfig{
+				authn_model.ConstructSdsSecretConfig("kubernetes://fake-cred", proxy),
+			},
+			ValidationContextType: &tls.CommonTlsContext_CombinedValidationContext{
+				CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
+					DefaultValidationContext: &tls.CertificateValidationContext{
+						MatchSubjectAltNames: util.StringToExactMatch([]string{"SAN"}),
+					},
+					ValidationContextSdsSecretConfig: authn_model.ConstructSdsSecretConfig("kubernetes://fake-cred"+authn_model.SdsCaSuffix, proxy),
+				},
+			},
+		},
+	}
+
+	ret, err := NewClusterBuilder(nil, nil).buildUpstreamClusterTLSContext(opts, tlsSettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, ret, protocmp.Transform()); diff != "" {
+		t.Errorf("got diff: `%v", diff)
+	}
+	if got := ret.CommonTlsContext.TlsCertificateSdsSecretConfigs[0].SdsConfig.GetApiConfigSource().GetGrpcServices()[0].GetEnvoyGrpc().GetClusterName(); got != authn_model.SDSClusterName {
+		t.Errorf("expected credential to be served by the agent SDS cluster, got %q", got)
+	}
+}
+
 func newTestCluster() *MutableCluster {
 	return NewMutableCluster(&cluster.Cluster{
 		Name: "test-cluster",
@@ -16,6 +16,7 @@ package v1alpha3
 
 import (
 	"fmt"
+	"net"
 	"sort"
 	"strconv"
 	"strings"
@@ -118,17 +119,15 @@ func configureTracingFromSpec(spec *telemetrypb.Telemetry, opts buildListenerOpt
 	}
 }
 
-// TODO: follow-on work to enable bootstrapping of clusters for $(HOST_IP):PORT addresses.
-
 func configureFromProviderConfig(pushCtx *model.PushContext, meta *model.NodeMetadata,
 	providerCfg *meshconfig.MeshConfig_ExtensionProvider) (*hpb.HttpConnectionManager_Tracing, error) {
 	switch provider := providerCfg.Provider.(type) {
 	case *meshconfig.MeshConfig_ExtensionProvider_Zipkin:
-		return buildHCMTracing(pushCtx, providerCfg.Name, provider.Zipkin.Service, provider.Zipkin.Port, provider.Zipkin.MaxTagLength, zipkinConfigGen)
+		return buildHCMTracing(pushCtx, meta, providerCfg.Name, provider.Zipkin.Service, provider.Zipkin.Port, provider.Zipkin.MaxTagLength, zipkinConfigGen)
 	case *meshconfig.MeshConfig_ExtensionProvider_Datadog:
-		return buildHCMTracing(pushCtx, providerCfg.Name, provider.Datadog.Service, provider.Datadog.Port, provider.Datadog.MaxTagLength, datadogConfigGen)
+		return buildHCMTracing(pushCtx, meta, providerCfg.Name, provider.Datadog.Service, provider.Datadog.Port, provider.Datadog.MaxTagLength, datadogConfigGen)
 	case *meshconfig.MeshConfig_ExtensionProvider_Lightstep:
-		return buildHCMTracing(pushCtx, providerCfg.Name, provider.Lightstep.Service, provider.Lightstep.Port, provider.Lightstep.MaxTagLength,
+		return buildHCMTracing(pushCtx, meta, providerCfg.Name, provider.Lightstep.Service, provider.Lightstep.Port, provider.Lightstep.MaxTagLength,
 			func(clusterName string) (*anypb.Any, error) {
 				lc := &tracingcfg.LightstepConfig{
 					CollectorCluster: clusterName,
@@ -139,8 +138,15 @@ func configureFromProviderConfig(pushCtx *model.PushContext, meta *model.NodeMet
 
 	case *meshconfig.MeshConfig_ExtensionProvider_Opencensus:
 		return buildHCMTracingOpenCensus(providerCfg.Name, provider.Opencensus.MaxTagLength, func() (*anypb.Any, error) {
+			address := provider.Opencensus.Service
+			if address == extensionproviders.HostIPService {
+				if meta.HostIP == "" {
+					return nil, fmt.Errorf("the proxy did not report the IP of its node")
+				}
+				address = meta.HostIP
+			}
 			oc := &tracingcfg.OpenCensusConfig{
-				OcagentAddress:         fmt.Sprintf("%s:%d", provider.Opencensus.Service, provider.Opencensus.Port),
+				OcagentAddress:         net.JoinHostPort(address, strconv.Itoa(int(provider.Opencensus.Port))),
 				OcagentExporterEnabled: true,
 				IncomingTraceContext:   convert(provider.Opencensus.Context),
 				OutgoingTraceContext:   convert(provider.Opencensus.Context),
@@ -247,13 +253,22 @@ func datadogConfigGen(cluster string) (*anypb.Any, error) {
 
 type typedConfigGenFn func() (*anypb.Any, error)
 
-func buildHCMTracing(pushCtx *model.PushContext, provider, svc string, port, maxTagLen uint32,
+func buildHCMTracing(pushCtx *model.PushContext, meta *model.NodeMetadata, provider, svc string, port, maxTagLen uint32,
 	anyFn typedConfigGenFromClusterFn) (*hpb.HttpConnectionManager_Tracing, error) {
 	config := &hpb.HttpConnectionManager_Tracing{}
 
-	_, cluster, err := clusterLookupFn(pushCtx, svc, int(port))
-	if err != nil {
-		return config, fmt.Errorf("could not find cluster for tracing provider %q: %v", provider, err)
+	var cluster string
+	if svc == extensionproviders.HostIPService {
+		// The node-local cluster is only generated for proxies reporting the IP of their node
+		if meta.HostIP == "" {
+			return config, fmt.Errorf("could not configure node-local tracing provider %q: the proxy did not report the IP of its node", provider)
+		}
+		cluster = extensionproviders.NodeLocalCluster(port)
+	} else {
+		var err error
+		if _, cluster, err = clusterLookupFn(pushCtx, svc, int(port)); err != nil {
+			return config, fmt.Errorf("could not find cluster for tracing provider %q: %v", provider, err)
+		}
 	}
 
 	any, err := anyFn(cluster)
@@ -101,6 +101,30 @@ func TestConfigureTracing(t *testing.T) {
 	}
 }
 
+func TestConfigureNodeLocalTracing(t *testing.T) {
+	opts := fakeOptsOnlyTelemetryAPI()
+	opts.push.Mesh.ExtensionProviders[0].GetZipkin().Service = extensionproviders.HostIPService
+	spec := fakeTracingSpec(fakeProviders([]string{"foo"}), 99.999, false)
+
+	// Without the IP of its node, the proxy cannot use the provider
+	hcm := &hpb.HttpConnectionManager{}
+	configureTracingFromSpec(spec, opts, hcm)
+	if hcm.Tracing.GetProvider() != nil {
+		t.Fatalf("expected no provider, got %v", hcm.Tracing.GetProvider())
+	}
+
+	opts.proxy.Metadata.HostIP = "10.0.0.1"
+	hcm = &hpb.HttpConnectionManager{}
+	configureTracingFromSpec(spec, opts, hcm)
+	zc := &tracingcfg.ZipkinConfig{}
+	if err := hcm.Tracing.GetProvider().GetTypedConfig().UnmarshalTo(zc); err != nil {
+		t.Fatal(err)
+	}
+	if zc.CollectorCluster != "node-local|9411" {
+		t.Fatalf("expected the node-local cluster, got %v", zc.CollectorCluster)
+	}
+}
+
 func fakeOptsNoTelemetryAPI() buildListenerOpts {
 	var opts buildListenerOpts
 	opts.push = &model.PushContext{
@@ -288,10 +288,28 @@ func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Prox
 // ApplyCustomSDSToClientCommonTLSContext applies the customized sds to CommonTlsContext
 // Used for building upstream TLS context for egress gateway's TLS/mTLS origination
 func ApplyCustomSDSToClientCommonTLSContext(tlsContext *tls.CommonTlsContext, tlsOpts *networking.ClientTLSSettings) {
+	applyCredentialSDSToClientCommonTLSContext(tlsContext, tlsOpts, ConstructSdsSecretConfigForCredential)
+}
+
+// ApplyAgentCredentialSDSToClientCommonTLSContext applies the credentialName sds to CommonTlsContext, to be
+// served by the proxy's agent rather than over ADS. The agent fetches the credential from Istiod.
+// Used for building upstream TLS context for sidecar's TLS/mTLS origination
+func ApplyAgentCredentialSDSToClientCommonTLSContext(tlsContext *tls.CommonTlsContext, tlsOpts *networking.ClientTLSSettings,
+	proxy *model.Proxy) {
+	applyCredentialSDSToClientCommonTLSContext(tlsContext, tlsOpts, func(name string) *tls.SdsSecretConfig {
+		if name == "" {
+			return nil
+		}
+		return ConstructSdsSecretConfig(KubernetesSecretTypeURI+name, proxy)
+	})
+}
+
+func applyCredentialSDSToClientCommonTLSContext(tlsContext *tls.CommonTlsContext, tlsOpts *networking.ClientTLSSettings,
+	sdsSecretConfig func(name string) *tls.SdsSecretConfig) {
 	if tlsOpts.Mode == networking.ClientTLSSettings_MUTUAL {
 		// create SDS config for gateway to fetch key/cert from agent.
 		tlsContext.TlsCertificateSdsSecretConfigs = []*tls.SdsSecretConfig{
-			ConstructSdsSecretConfigForCredential(tlsOpts.CredentialName),
+			sdsSecretConfig(tlsOpts.CredentialName),
 		}
 	}
 	// create SDS config for gateway to fetch certificate validation context
@@ -302,7 +320,7 @@ func ApplyCustomSDSToClientCommonTLSContext(tlsContext *tls.CommonTlsContext, tl
 	tlsContext.ValidationContextType = &tls.CommonTlsContext_CombinedValidationContext{
 		CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
 			DefaultValidationContext:         defaultValidationContext,
-			ValidationContextSdsSecretConfig: ConstructSdsSecretConfigForCredential(tlsOpts.CredentialName + SdsCaSuffix),
+			ValidationContextSdsSecretConfig: sdsSecretConfig(tlsOpts.CredentialName + SdsCaSuffix),
 		},
 	}
 }
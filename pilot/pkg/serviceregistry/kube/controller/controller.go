@@ -69,6 +69,11 @@ const (
 	// DefaultNetworkGatewayPort is the port used by default for cross-network traffic if not otherwise specified
 	// by meshNetworks or "networking.istio.io/gatewayPort"
 	DefaultNetworkGatewayPort = 15443
+	// GatewayCapacityAnnotation overrides the capacity of a multi-network gateway service, which is its number of
+	// ready endpoints by default. The cross-network traffic is split between the gateways of a network in proportion
+	// to their capacity.
+	// TODO move gatewayCapacity to api repo
+	GatewayCapacityAnnotation = "networking.istio.io/gatewayCapacity"
 )
 
 var log = istiolog.RegisterScope("kube", "kubernetes service registry controller", 0)
@@ -214,6 +219,9 @@ type Controller struct {
 
 	endpoints kubeEndpointsController
 
+	// imports creates the clusterset.local services of the MCS ServiceImports, if MCS service discovery is enabled.
+	imports *serviceImportCache
+
 	// Used to watch node accessible from remote cluster.
 	// In multi-cluster(shared control plane multi-networks) scenario, ingress gateway service can be of nodePort type.
 	// With this, we can populate mesh's gateway address with the node ips.
@@ -263,6 +271,8 @@ type Controller struct {
 	registryServiceNameGateways map[host.Name]uint32
 	// gateways for each network, indexed by the service that runs them so we clean them up later
 	networkGateways map[host.Name]map[string][]*model.Gateway
+	// gatewayWeightsPushPending is set while the push of changed network gateway weights is delayed.
+	gatewayWeightsPushPending bool
 
 	// informerInit is set to true once the controller is running successfully. This ensures we do not
 	// return HasSynced=true before we are running
@@ -364,6 +374,10 @@ func NewController(kubeClient kubelib.Client, options Options) *Controller {
 	})
 	c.registerHandlers(c.pods.informer, "Pods", c.pods.onEvent, nil)
 
+	if features.EnableMCSServiceDiscovery {
+		c.imports = newServiceImportCache(c, kubeClient)
+	}
+
 	return c
 }
 
@@ -401,6 +415,9 @@ func (c *Controller) Cleanup() error {
 		c.xdsUpdater.SvcUpdate(c.clusterID, string(name), s.Namespace, model.EventDelete)
 		// TODO(landow) do we need to notify service handlers?
 	}
+	if c.imports != nil {
+		c.imports.cleanup()
+	}
 	return nil
 }
 
@@ -616,7 +633,8 @@ func (c *Controller) informersSynced() bool {
 		!c.serviceInformer.HasSynced() ||
 		!c.endpoints.HasSynced() ||
 		!c.pods.informer.HasSynced() ||
-		!c.nodeInformer.HasSynced() {
+		!c.nodeInformer.HasSynced() ||
+		(c.imports != nil && !c.imports.HasSynced()) {
 		return false
 	}
 	return true
@@ -650,6 +668,9 @@ func (c *Controller) SyncAll() error {
 
 	err = multierror.Append(err, c.syncPods())
 	err = multierror.Append(err, c.syncEndpoints())
+	if c.imports != nil {
+		err = multierror.Append(err, c.imports.syncServiceImports())
+	}
 
 	return multierror.Flatten(err.ErrorOrNil())
 }
@@ -760,6 +781,9 @@ func (c *Controller) getPodLocality(pod *v1.Pod) string {
 
 // InstancesByPort implements a service catalog operation
 func (c *Controller) InstancesByPort(svc *model.Service, reqSvcPort int, labelsList labels.Collection) []*model.ServiceInstance {
+	if c.imports != nil && !c.imports.hasInstances(svc) {
+		return nil
+	}
 	// First get k8s standard service instances and the workload entry instances
 	outInstances := c.endpoints.InstancesByPort(c, svc, reqSvcPort, labelsList)
 	outInstances = append(outInstances, c.serviceInstancesFromWorkloadInstances(svc, reqSvcPort)...)
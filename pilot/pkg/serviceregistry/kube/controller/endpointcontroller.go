@@ -42,6 +42,8 @@ type kubeEndpointsController interface {
 	// forgetEndpoint does internal bookkeeping on a deleted endpoint
 	forgetEndpoint(endpoint interface{})
 	getServiceInfo(ep interface{}) (host.Name, string, string)
+	// readyAddresses returns the number of ready and not ready addresses of a service.
+	readyAddresses(name, namespace string) (ready int, notReady int)
 }
 
 // kubeEndpoints abstracts the common behavior across endpoint and endpoint slices.
@@ -110,6 +112,11 @@ func updateEDS(c *Controller, epc kubeEndpointsController, ep interface{}, event
 	}
 
 	c.xdsUpdater.EDSUpdate(c.clusterID, string(host), ns, endpoints)
+	// The weights of the network gateways depend on their ready endpoints
+	c.updateNetworkGatewayWeights(host)
+	if c.imports != nil {
+		c.imports.onEndpointsUpdate(svcName, ns, endpoints)
+	}
 }
 
 // getPod fetches a pod by name or IP address.
@@ -217,6 +217,19 @@ func (e *endpointsController) buildIstioEndpointsWithService(name, namespace str
 	return e.buildIstioEndpoints(ep, host)
 }
 
+func (e *endpointsController) readyAddresses(name, namespace string) (int, int) {
+	ep, err := listerv1.NewEndpointsLister(e.informer.GetIndexer()).Endpoints(namespace).Get(name)
+	if err != nil || ep == nil {
+		return 0, 0
+	}
+	ready, notReady := 0, 0
+	for _, ss := range ep.Subsets {
+		ready += len(ss.Addresses)
+		notReady += len(ss.NotReadyAddresses)
+	}
+	return ready, notReady
+}
+
 func (e *endpointsController) getServiceInfo(ep interface{}) (host.Name, string, string) {
 	endpoint := ep.(*v1.Endpoints)
 	return kube.ServiceHostname(endpoint.Name, endpoint.Namespace, e.c.domainSuffix), endpoint.Name, endpoint.Namespace
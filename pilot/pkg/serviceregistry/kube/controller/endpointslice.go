@@ -203,6 +203,25 @@ func (esc *endpointSliceController) buildIstioEndpointsWithService(name, namespa
 	return endpoints
 }
 
+func (esc *endpointSliceController) readyAddresses(name, namespace string) (int, int) {
+	esLabelSelector := klabels.Set(map[string]string{discovery.LabelServiceName: name}).AsSelectorPreValidated()
+	slices, err := discoverylister.NewEndpointSliceLister(esc.informer.GetIndexer()).EndpointSlices(namespace).List(esLabelSelector)
+	if err != nil {
+		return 0, 0
+	}
+	ready, notReady := 0, 0
+	for _, slice := range slices {
+		for _, e := range slice.Endpoints {
+			if e.Conditions.Ready != nil && !*e.Conditions.Ready {
+				notReady += len(e.Addresses)
+			} else {
+				ready += len(e.Addresses)
+			}
+		}
+	}
+	return ready, notReady
+}
+
 func (esc *endpointSliceController) getServiceInfo(es interface{}) (host.Name, string, string) {
 	slice := es.(*discovery.EndpointSlice)
 	svcName := slice.Labels[discovery.LabelServiceName]
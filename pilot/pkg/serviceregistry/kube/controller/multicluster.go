@@ -389,3 +389,11 @@ func (m *Multicluster) InitSecretController(stop <-chan struct{}) {
 func (m *Multicluster) HasSynced() bool {
 	return m.secretController.HasSynced()
 }
+
+// ClusterStatus returns the health of the remote clusters added via remote secrets.
+func (m *Multicluster) ClusterStatus() []secretcontroller.ClusterStatus {
+	if m.secretController == nil {
+		return nil
+	}
+	return m.secretController.ClusterStatus()
+}
@@ -16,7 +16,10 @@ package controller
 
 import (
 	"net"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/yl2chen/cidranger"
 
@@ -182,8 +185,9 @@ func (c *Controller) extractGatewaysInner(svc *model.Service) bool {
 			}
 		}
 		ips := svc.Attributes.ClusterExternalAddresses[c.clusterID]
+		weight, unhealthy := c.gatewayWeight(svc, len(ips))
 		for _, ip := range ips {
-			gws = append(gws, &model.Gateway{Addr: ip, Port: gwPort})
+			gws = append(gws, &model.Gateway{Addr: ip, Port: gwPort, Weight: weight, Unhealthy: unhealthy})
 		}
 	}
 
@@ -205,6 +209,97 @@ func (c *Controller) extractGatewaysInner(svc *model.Service) bool {
 	return gwsChanged
 }
 
+// gatewayWeight returns the weight of each address of a gateway service, and whether the gateway is unhealthy.
+// The capacity of the service, from its capacity annotation or its number of ready endpoints, is split between
+// its addresses. A service without endpoints, for example routing to a gateway outside of the cluster, is not
+// unhealthy: its weight is unknown, unless its capacity is annotated.
+func (c *Controller) gatewayWeight(svc *model.Service, addresses int) (uint32, bool) {
+	ready, notReady := c.endpoints.readyAddresses(svc.Attributes.Name, svc.Attributes.Namespace)
+	if ready == 0 && notReady > 0 {
+		return 0, true
+	}
+
+	capacity := uint32(ready)
+	if k8sSvc, _ := c.serviceLister.Services(svc.Attributes.Namespace).Get(svc.Attributes.Name); k8sSvc != nil {
+		if capacityStr := k8sSvc.Annotations[GatewayCapacityAnnotation]; capacityStr != "" {
+			if n, err := strconv.ParseUint(capacityStr, 10, 32); err == nil && n > 0 {
+				capacity = uint32(n)
+			} else {
+				log.Warnf("could not parse %q for %s on %s/%s; using the number of ready endpoints",
+					capacityStr, GatewayCapacityAnnotation, svc.Attributes.Namespace, svc.Attributes.Name)
+			}
+		}
+	}
+	if capacity == 0 || addresses == 0 {
+		return 0, false
+	}
+	if weight := capacity / uint32(addresses); weight > 0 {
+		return weight, false
+	}
+	return 1, false
+}
+
+// networkGatewayWeightsDelay delays the push of changes of the weights of network gateways, which follow their ready
+// endpoints, so that the changes during a rollout of a gateway are pushed together.
+var networkGatewayWeightsDelay = 5 * time.Second
+
+// updateNetworkGatewayWeights updates the gateways of a service after its endpoints change, and pushes the change.
+// Gateways becoming unhealthy or healthy again are pushed right away, weight changes after networkGatewayWeightsDelay.
+func (c *Controller) updateNetworkGatewayWeights(hostname host.Name) {
+	c.Lock()
+	if _, isGateway := c.networkGateways[hostname]; !isGateway {
+		c.Unlock()
+		return
+	}
+	svc := c.servicesMap[hostname]
+	if svc == nil {
+		c.Unlock()
+		return
+	}
+	wasUnhealthy := unhealthyGateways(c.networkGateways[hostname])
+	if !c.extractGatewaysInner(svc) {
+		c.Unlock()
+		return
+	}
+	healthChanged := unhealthyGateways(c.networkGateways[hostname]) != wasUnhealthy
+	if !healthChanged {
+		if c.gatewayWeightsPushPending {
+			c.Unlock()
+			return
+		}
+		c.gatewayWeightsPushPending = true
+	}
+	c.Unlock()
+
+	push := func() {
+		c.xdsUpdater.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.NetworksTrigger}})
+	}
+	if healthChanged {
+		push()
+		return
+	}
+	time.AfterFunc(networkGatewayWeightsDelay, func() {
+		c.Lock()
+		c.gatewayWeightsPushPending = false
+		c.Unlock()
+		push()
+	})
+}
+
+// unhealthyGateways returns the sorted addresses of the unhealthy gateways of each network.
+func unhealthyGateways(gws map[string][]*model.Gateway) string {
+	var out []string
+	for network, networkGws := range gws {
+		for _, gw := range networkGws {
+			if gw.Unhealthy {
+				out = append(out, network+"/"+gw.Addr)
+			}
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
+
 // getGatewayDetails finds the port and network to use for cross-network traffic on the given service.
 // Zero values are returned if the service is not a cross-network gateway.
 func (c *Controller) getGatewayDetails(svc *model.Service) (uint32, string) {
@@ -0,0 +1,112 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestNetworkGatewayWeights(t *testing.T) {
+	delay := networkGatewayWeightsDelay
+	networkGatewayWeightsDelay = time.Hour
+	defer func() { networkGatewayWeightsDelay = delay }()
+	controller, fx := NewFakeControllerWithOptions(FakeControllerOptions{Mode: EndpointsOnly})
+	defer controller.Stop()
+
+	gwService := &coreV1.Service{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:      "istio-eastwestgateway",
+			Namespace: "istio-system",
+			Labels:    map[string]string{label.TopologyNetwork.Name: "network-1"},
+		},
+		Spec: coreV1.ServiceSpec{
+			ClusterIP:   "10.0.0.1",
+			Ports:       []coreV1.ServicePort{{Name: "tls", Port: 15443}},
+			Type:        coreV1.ServiceTypeClusterIP,
+			ExternalIPs: []string{"2.2.2.2"},
+		},
+	}
+	if _, err := controller.client.CoreV1().Services("istio-system").Create(context.TODO(), gwService, metaV1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectGateway := func(want model.Gateway) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			gws := controller.NetworkGateways()["network-1"]
+			if len(gws) != 1 || *gws[0] != want {
+				return fmt.Errorf("expected gateway %+v, got %v", want, gws)
+			}
+			return nil
+		}, serviceExportTimeout)
+	}
+	setAddresses := func(ready, notReady []string) {
+		t.Helper()
+		ss := coreV1.EndpointSubset{Ports: []coreV1.EndpointPort{{Name: "tls", Port: 15443}}}
+		for _, ip := range ready {
+			ss.Addresses = append(ss.Addresses, coreV1.EndpointAddress{IP: ip})
+		}
+		for _, ip := range notReady {
+			ss.NotReadyAddresses = append(ss.NotReadyAddresses, coreV1.EndpointAddress{IP: ip})
+		}
+		ep := &coreV1.Endpoints{
+			ObjectMeta: metaV1.ObjectMeta{Name: gwService.Name, Namespace: gwService.Namespace},
+			Subsets:    []coreV1.EndpointSubset{ss},
+		}
+		endpoints := controller.client.CoreV1().Endpoints(gwService.Namespace)
+		if _, err := endpoints.Get(context.TODO(), ep.Name, metaV1.GetOptions{}); err == nil {
+			_, err = endpoints.Update(context.TODO(), ep, metaV1.UpdateOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := endpoints.Create(context.TODO(), ep, metaV1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without endpoints, the capacity of the gateway is unknown
+	expectGateway(model.Gateway{Addr: "2.2.2.2", Port: 15443})
+
+	// Weight changes are pushed after a delay
+	fx.Clear()
+	setAddresses([]string{"10.1.0.1", "10.1.0.2"}, []string{"10.1.0.3"})
+	expectGateway(model.Gateway{Addr: "2.2.2.2", Port: 15443, Weight: 2})
+	if ev := fx.Wait("xds"); ev != nil {
+		t.Fatalf("unexpected push for a gateway weight change: %v", ev)
+	}
+
+	// A draining gateway is unhealthy, which is pushed right away
+	setAddresses(nil, []string{"10.1.0.1", "10.1.0.2"})
+	expectGateway(model.Gateway{Addr: "2.2.2.2", Port: 15443, Unhealthy: true})
+	if ev := fx.Wait("xds"); ev == nil {
+		t.Fatalf("expected a push for an unhealthy gateway")
+	}
+
+	setAddresses([]string{"10.1.0.1"}, nil)
+	gwService.Annotations = map[string]string{GatewayCapacityAnnotation: "10"}
+	if _, err := controller.client.CoreV1().Services("istio-system").Update(context.TODO(), gwService, metaV1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectGateway(model.Gateway{Addr: "2.2.2.2", Port: 15443, Weight: 10})
+}
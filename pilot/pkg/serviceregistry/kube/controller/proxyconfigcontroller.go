@@ -0,0 +1,231 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	// ProxyConfigLabel marks the ConfigMaps holding ProxyConfig overrides.
+	ProxyConfigLabel = "istio.io/proxy-config"
+	// ProxyConfigKey is the ConfigMap key holding the ProxyConfig YAML.
+	ProxyConfigKey = "proxyConfig"
+	// ProxyConfigSelectorKey is the ConfigMap key holding the workload selector, as a list of labels
+	// (app=foo,version=v1). Without a selector, the override applies to the whole namespace.
+	ProxyConfigSelectorKey = "selector"
+)
+
+// ProxyConfigController watches the overrides of the mesh default ProxyConfig: the proxy.istio.io/config
+// annotation of namespaces and the ConfigMaps labeled with istio.io/proxy-config. Changes trigger a full push to
+// the proxies in the namespace of the override, or to all proxies for the root namespace.
+type ProxyConfigController struct {
+	xdsUpdater model.XDSUpdater
+
+	configMapFactory   informers.SharedInformerFactory
+	configMapInformer  cache.SharedIndexInformer
+	namespacesInformer cache.SharedIndexInformer
+
+	// proxyConfigs caches the overrides until they change, as they are read for every injection and push.
+	mutex        sync.Mutex
+	proxyConfigs *model.ProxyConfigs
+}
+
+var _ model.ProxyConfigSource = &ProxyConfigController{}
+
+// NewProxyConfigController returns a pointer to a newly constructed ProxyConfigController instance.
+func NewProxyConfigController(kubeClient kube.Client, xdsUpdater model.XDSUpdater) *ProxyConfigController {
+	c := &ProxyConfigController{
+		xdsUpdater: xdsUpdater,
+		configMapFactory: informers.NewSharedInformerFactoryWithOptions(kubeClient.Kube(), 0,
+			informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+				opts.LabelSelector = ProxyConfigLabel
+			})),
+		namespacesInformer: kubeClient.KubeInformer().Core().V1().Namespaces().Informer(),
+	}
+	c.configMapInformer = c.configMapFactory.Core().V1().ConfigMaps().Informer()
+
+	c.configMapInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.configMapChanged(obj)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			// Only the override keys matter; other changes to the ConfigMap do not affect proxies.
+			oldCM, curCM := old.(*v1.ConfigMap), cur.(*v1.ConfigMap)
+			if oldCM.Data[ProxyConfigKey] != curCM.Data[ProxyConfigKey] ||
+				oldCM.Data[ProxyConfigSelectorKey] != curCM.Data[ProxyConfigSelectorKey] {
+				c.configMapChanged(cur)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.configMapChanged(obj)
+		},
+	})
+	c.namespacesInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if proxyConfigAnnotation(obj) != "" {
+				c.namespaceChanged(obj)
+			}
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			if proxyConfigAnnotation(old) != proxyConfigAnnotation(cur) {
+				c.namespaceChanged(cur)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if proxyConfigAnnotation(obj) != "" {
+				c.namespaceChanged(obj)
+			}
+		},
+	})
+	return c
+}
+
+// Run starts the ProxyConfigController until a value is sent to stopCh.
+func (c *ProxyConfigController) Run(stopCh <-chan struct{}) {
+	c.configMapFactory.Start(stopCh)
+	cache.WaitForCacheSync(stopCh, c.namespacesInformer.HasSynced, c.configMapInformer.HasSynced)
+	log.Infof("ProxyConfig controller started")
+}
+
+// ProxyConfigs returns the overrides of all namespaces. Invalid overrides are skipped.
+func (c *ProxyConfigController) ProxyConfigs() *model.ProxyConfigs {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.proxyConfigs == nil {
+		c.proxyConfigs = model.NewProxyConfigs(c.listOverrides())
+	}
+	return c.proxyConfigs
+}
+
+func (c *ProxyConfigController) listOverrides() []model.ProxyConfigOverride {
+	var out []model.ProxyConfigOverride
+	for _, obj := range c.namespacesInformer.GetStore().List() {
+		ns := obj.(*v1.Namespace)
+		if pc := ns.Annotations[annotation.ProxyConfig.Name]; pc != "" {
+			o := model.ProxyConfigOverride{
+				Namespace:         ns.Name,
+				ProxyConfig:       pc,
+				CreationTimestamp: ns.CreationTimestamp.Time,
+			}
+			if validateProxyConfigOverride(o) == nil {
+				out = append(out, o)
+			}
+		}
+	}
+	for _, obj := range c.configMapInformer.GetStore().List() {
+		if o, err := proxyConfigOverrideFromConfigMap(obj.(*v1.ConfigMap)); err == nil {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func (c *ProxyConfigController) configMapChanged(obj interface{}) {
+	cm, err := convertToConfigMap(obj)
+	if err != nil {
+		log.Errorf("failed to convert to configmap: %v", err)
+		return
+	}
+	if _, err := proxyConfigOverrideFromConfigMap(cm); err != nil {
+		log.Warnf("ignoring ProxyConfig override in configmap %s/%s: %v", cm.Namespace, cm.Name, err)
+	}
+	c.push(model.ConfigKey{Kind: gvk.ConfigMap, Name: cm.Name, Namespace: cm.Namespace})
+}
+
+func (c *ProxyConfigController) namespaceChanged(obj interface{}) {
+	ns, err := convertToNamespace(obj)
+	if err != nil {
+		log.Errorf("failed to convert to namespace: %v", err)
+		return
+	}
+	if pc := ns.Annotations[annotation.ProxyConfig.Name]; pc != "" {
+		if err := validateProxyConfigOverride(model.ProxyConfigOverride{ProxyConfig: pc}); err != nil {
+			log.Warnf("ignoring ProxyConfig override in namespace %s: %v", ns.Name, err)
+		}
+	}
+	c.push(model.ConfigKey{Kind: gvk.Namespace, Name: ns.Name, Namespace: ns.Name})
+}
+
+func (c *ProxyConfigController) push(key model.ConfigKey) {
+	c.mutex.Lock()
+	c.proxyConfigs = nil
+	c.mutex.Unlock()
+	c.xdsUpdater.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: map[model.ConfigKey]struct{}{key: {}},
+		Reason:         []model.TriggerReason{model.ConfigUpdate},
+	})
+}
+
+func proxyConfigAnnotation(obj interface{}) string {
+	ns, err := convertToNamespace(obj)
+	if err != nil {
+		return ""
+	}
+	return ns.Annotations[annotation.ProxyConfig.Name]
+}
+
+func convertToNamespace(obj interface{}) (*v1.Namespace, error) {
+	ns, ok := obj.(*v1.Namespace)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return nil, fmt.Errorf("couldn't get object from tombstone %#v", obj)
+		}
+		ns, ok = tombstone.Obj.(*v1.Namespace)
+		if !ok {
+			return nil, fmt.Errorf("tombstone contained object that is not a Namespace %#v", obj)
+		}
+	}
+	return ns, nil
+}
+
+// proxyConfigOverrideFromConfigMap parses and validates the ProxyConfig override in a ConfigMap.
+func proxyConfigOverrideFromConfigMap(cm *v1.ConfigMap) (model.ProxyConfigOverride, error) {
+	o := model.ProxyConfigOverride{
+		Name:              cm.Name,
+		Namespace:         cm.Namespace,
+		ProxyConfig:       cm.Data[ProxyConfigKey],
+		CreationTimestamp: cm.CreationTimestamp.Time,
+	}
+	if selector := cm.Data[ProxyConfigSelectorKey]; selector != "" {
+		set, err := klabels.ConvertSelectorToLabelsMap(selector)
+		if err != nil {
+			return o, err
+		}
+		o.Selector = labels.Instance(set)
+	}
+	return o, validateProxyConfigOverride(o)
+}
+
+func validateProxyConfigOverride(o model.ProxyConfigOverride) error {
+	_, err := mesh.ApplyProxyConfig(o.ProxyConfig, mesh.DefaultMeshConfig())
+	return err
+}
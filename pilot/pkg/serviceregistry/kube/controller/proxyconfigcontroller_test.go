@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestProxyConfigController(t *testing.T) {
+	client := kube.NewFakeClient()
+	xdsUpdater := NewFakeXDS()
+	c := NewProxyConfigController(client, xdsUpdater)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	client.RunAndWait(stop)
+	c.Run(stop)
+
+	if _, err := client.CoreV1().Namespaces().Create(context.TODO(), &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Annotations: map[string]string{annotation.ProxyConfig.Name: "concurrency: 2"},
+		},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := xdsUpdater.Wait("xds"); ev == nil || ev.ID != "foo" {
+		t.Fatalf("expected a push for namespace foo, got %v", ev)
+	}
+
+	for _, cm := range []*v1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "selected", Namespace: "foo", Labels: map[string]string{ProxyConfigLabel: "true"}},
+			Data:       map[string]string{ProxyConfigKey: "concurrency: 4", ProxyConfigSelectorKey: "app=foo"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "foo", Labels: map[string]string{ProxyConfigLabel: "true"}},
+			Data:       map[string]string{ProxyConfigKey: "concurrency: [4]"},
+		},
+	} {
+		if _, err := client.CoreV1().ConfigMaps(cm.Namespace).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	retry.UntilSuccessOrFail(t, func() error {
+		got := map[string]model.ProxyConfigOverride{}
+		for _, overrides := range c.ProxyConfigs().NamespaceToOverrides {
+			for _, o := range overrides {
+				got[o.Name] = o
+			}
+		}
+		if len(got) != 2 {
+			return fmt.Errorf("expected the namespace and selected overrides, got %v", got)
+		}
+		if got[""].Namespace != "foo" || got[""].ProxyConfig != "concurrency: 2" {
+			return fmt.Errorf("unexpected namespace override %+v", got[""])
+		}
+		if !got["selected"].Selector.Equals(labels.Instance{"app": "foo"}) {
+			return fmt.Errorf("unexpected selector %v", got["selected"].Selector)
+		}
+		return nil
+	})
+
+	// Changes to other ConfigMap keys do not trigger a push
+	xdsUpdater.Clear()
+	for _, cm := range []*v1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "selected", Namespace: "foo", Labels: map[string]string{ProxyConfigLabel: "true"}},
+			Data:       map[string]string{ProxyConfigKey: "concurrency: 4", ProxyConfigSelectorKey: "app=foo", "unrelated": "true"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "foo", Labels: map[string]string{ProxyConfigLabel: "true"}},
+			Data:       map[string]string{ProxyConfigKey: "concurrency: 8"},
+		},
+	} {
+		if _, err := client.CoreV1().ConfigMaps(cm.Namespace).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ev := xdsUpdater.Wait("xds"); ev == nil || ev.ID != "invalid" {
+		t.Fatalf("expected a push for configmap invalid only, got %v", ev)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/client-go/tools/cache"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller/filter"
+	"istio.io/istio/pkg/config/host"
+	kubelib "istio.io/istio/pkg/kube"
+)
+
+// serviceImportCache creates a service with the clusterset.local hostname for every MCS ServiceImport of the
+// cluster. The endpoints of the cluster are only added to these services if the cluster exports the service
+// with a ServiceExport: the aggregate registry merges the endpoints of all the clusters exporting it.
+type serviceImportCache struct {
+	c *Controller
+
+	importInformer filter.FilteredSharedIndexInformer
+	exportInformer filter.FilteredSharedIndexInformer
+}
+
+func newServiceImportCache(c *Controller, kubeClient kubelib.Client) *serviceImportCache {
+	informers := kubeClient.MCSApisInformer().Multicluster().V1alpha1()
+	ic := &serviceImportCache{
+		c: c,
+		importInformer: filter.NewFilteredSharedIndexInformer(c.discoveryNamespacesFilter.Filter,
+			informers.ServiceImports().Informer()),
+		exportInformer: filter.NewFilteredSharedIndexInformer(c.discoveryNamespacesFilter.Filter,
+			informers.ServiceExports().Informer()),
+	}
+	c.registerHandlers(ic.importInformer, "ServiceImports", ic.onServiceImportEvent, nil)
+	c.registerHandlers(ic.exportInformer, "ServiceExports", ic.onServiceExportEvent, nil)
+	return ic
+}
+
+func (ic *serviceImportCache) HasSynced() bool {
+	return ic.importInformer.HasSynced() && ic.exportInformer.HasSynced()
+}
+
+func (ic *serviceImportCache) syncServiceImports() error {
+	var err *multierror.Error
+	imports := ic.importInformer.GetIndexer().List()
+	log.Debugf("initializing %d service imports", len(imports))
+	for _, si := range imports {
+		err = multierror.Append(err, ic.onServiceImportEvent(si, model.EventAdd))
+	}
+	return err.ErrorOrNil()
+}
+
+func (ic *serviceImportCache) onServiceImportEvent(obj interface{}, event model.Event) error {
+	si, err := convertToServiceImport(obj)
+	if err != nil {
+		log.Errorf(err)
+		return nil
+	}
+
+	log.Debugf("Handle event %s for service import %s in namespace %s", event, si.Name, si.Namespace)
+
+	c := ic.c
+	svcConv := kube.ConvertServiceImport(*si, c.clusterID)
+	switch event {
+	case model.EventDelete:
+		c.Lock()
+		delete(c.servicesMap, svcConv.Hostname)
+		c.Unlock()
+	default:
+		c.Lock()
+		c.servicesMap[svcConv.Hostname] = svcConv
+		c.Unlock()
+
+		if endpoints := ic.buildIstioEndpoints(si.Name, si.Namespace); len(endpoints) > 0 {
+			c.xdsUpdater.EDSCacheUpdate(c.clusterID, string(svcConv.Hostname), si.Namespace, endpoints)
+		}
+	}
+
+	c.xdsUpdater.SvcUpdate(c.clusterID, string(svcConv.Hostname), si.Namespace, event)
+	// Notify service handlers.
+	for _, f := range c.serviceHandlers {
+		f(svcConv, event)
+	}
+
+	return nil
+}
+
+// onServiceExportEvent adds or removes the endpoints of the cluster to the clusterset.local service.
+func (ic *serviceImportCache) onServiceExportEvent(obj interface{}, _ model.Event) error {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Errorf(err)
+		return nil
+	}
+	namespace, name, err := kube.SplitKey(key)
+	if err != nil {
+		log.Errorf(err)
+		return nil
+	}
+
+	hostname := clusterSetHostname(name, namespace)
+	if !ic.isImported(hostname) {
+		return nil
+	}
+	// The endpoints are empty if the service is no longer exported
+	ic.c.xdsUpdater.EDSUpdate(ic.c.clusterID, string(hostname), namespace, ic.buildIstioEndpoints(name, namespace))
+	return nil
+}
+
+// onEndpointsUpdate propagates the endpoints of the cluster.local service to the clusterset.local service,
+// if the service is imported and exported.
+func (ic *serviceImportCache) onEndpointsUpdate(name, namespace string, endpoints []*model.IstioEndpoint) {
+	hostname := clusterSetHostname(name, namespace)
+	if !ic.isImported(hostname) || !ic.isExported(name, namespace) {
+		return
+	}
+	ic.c.xdsUpdater.EDSUpdate(ic.c.clusterID, string(hostname), namespace, endpoints)
+}
+
+// buildIstioEndpoints returns the endpoints of the cluster for the clusterset.local service, which are the
+// endpoints of the cluster.local service if the cluster exports it.
+func (ic *serviceImportCache) buildIstioEndpoints(name, namespace string) []*model.IstioEndpoint {
+	if !ic.isExported(name, namespace) {
+		return nil
+	}
+	return ic.c.endpoints.buildIstioEndpointsWithService(name, namespace,
+		kube.ServiceHostname(name, namespace, ic.c.domainSuffix))
+}
+
+// hasInstances returns false for the clusterset.local services the cluster does not export.
+func (ic *serviceImportCache) hasInstances(svc *model.Service) bool {
+	if svc.Hostname != clusterSetHostname(svc.Attributes.Name, svc.Attributes.Namespace) {
+		return true
+	}
+	return ic.isExported(svc.Attributes.Name, svc.Attributes.Namespace)
+}
+
+func (ic *serviceImportCache) isImported(hostname host.Name) bool {
+	ic.c.RLock()
+	defer ic.c.RUnlock()
+	_, f := ic.c.servicesMap[hostname]
+	return f
+}
+
+func (ic *serviceImportCache) isExported(name, namespace string) bool {
+	_, exists, err := ic.exportInformer.GetIndexer().GetByKey(kube.KeyFunc(name, namespace))
+	return err == nil && exists
+}
+
+// cleanup removes the clusterset.local services.
+func (ic *serviceImportCache) cleanup() {
+	for _, obj := range ic.importInformer.GetIndexer().List() {
+		si := obj.(*mcsapi.ServiceImport)
+		hostname := clusterSetHostname(si.Name, si.Namespace)
+		ic.c.xdsUpdater.SvcUpdate(ic.c.clusterID, string(hostname), si.Namespace, model.EventDelete)
+	}
+}
+
+func clusterSetHostname(name, namespace string) host.Name {
+	return kube.ServiceHostname(name, namespace, kube.ClusterSetDomainSuffix)
+}
+
+func convertToServiceImport(obj interface{}) (*mcsapi.ServiceImport, error) {
+	si, ok := obj.(*mcsapi.ServiceImport)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return nil, fmt.Errorf("couldn't get object from tombstone %#v", obj)
+		}
+		si, ok = tombstone.Obj.(*mcsapi.ServiceImport)
+		if !ok {
+			return nil, fmt.Errorf("tombstone contained object that is not a ServiceImport %#v", obj)
+		}
+	}
+	return si, nil
+}
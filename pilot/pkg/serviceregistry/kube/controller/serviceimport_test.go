@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/labels"
+	kubelib "istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestServiceImport(t *testing.T) {
+	defer func(v bool) { features.EnableMCSServiceDiscovery = v }(features.EnableMCSServiceDiscovery)
+	features.EnableMCSServiceDiscovery = true
+
+	for mode, name := range EndpointModeNames {
+		mode := mode
+		t.Run(name, func(t *testing.T) {
+			client := kubelib.NewFakeClient()
+			controller, fx := NewFakeControllerWithOptions(FakeControllerOptions{Client: client, Mode: mode, ClusterID: "cluster1"})
+			defer controller.Stop()
+			mcs := client.MCSApis().MulticlusterV1alpha1()
+			const hostname = "svc1.nsA.svc.clusterset.local"
+
+			createService(controller, "svc1", "nsA", nil, []int32{8080}, map[string]string{"app": "prod-app"}, t)
+			if ev := fx.Wait("service"); ev == nil {
+				t.Fatal("Timeout creating service")
+			}
+			createEndpoints(controller, "svc1", "nsA", []string{"tcp-port"}, []string{"10.10.1.1"}, nil, t)
+			if ev := fx.Wait("eds"); ev == nil {
+				t.Fatal("Timeout creating endpoints")
+			}
+
+			si := &mcsapi.ServiceImport{
+				ObjectMeta: metaV1.ObjectMeta{Name: "svc1", Namespace: "nsA"},
+				Spec: mcsapi.ServiceImportSpec{
+					Type:  mcsapi.ClusterSetIP,
+					IPs:   []string{"240.0.0.1"},
+					Ports: []mcsapi.ServicePort{{Name: "tcp-port", Port: 8080, Protocol: coreV1.ProtocolTCP}},
+				},
+			}
+			if _, err := mcs.ServiceImports("nsA").Create(context.TODO(), si, metaV1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if ev := fx.Wait("service"); ev == nil || ev.ID != hostname {
+				t.Fatalf("Expected the clusterset.local service, got %v", ev)
+			}
+			svc, _ := controller.GetService(hostname)
+			if svc == nil || svc.ClusterVIPs["cluster1"] != "240.0.0.1" {
+				t.Fatalf("Unexpected clusterset.local service %v", svc)
+			}
+
+			// The endpoints of the cluster are only used if it exports the service
+			if instances := controller.InstancesByPort(svc, 8080, labels.Collection{}); len(instances) != 0 {
+				t.Fatalf("Expected no instances of the service not exported by the cluster, got %v", instances)
+			}
+			if _, err := mcs.ServiceExports("nsA").Create(context.TODO(), &mcsapi.ServiceExport{
+				ObjectMeta: metaV1.ObjectMeta{Name: "svc1", Namespace: "nsA"},
+			}, metaV1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if ev := fx.Wait("eds"); ev == nil || ev.ID != hostname || len(ev.Endpoints) != 1 || ev.Endpoints[0].Address != "10.10.1.1" {
+				t.Fatalf("Expected the endpoints of the exported service, got %v", ev)
+			}
+			retry.UntilSuccessOrFail(t, func() error {
+				if instances := controller.InstancesByPort(svc, 8080, labels.Collection{}); len(instances) != 1 {
+					return fmt.Errorf("expected the instances of the exported service, got %v", instances)
+				}
+				return nil
+			}, serviceExportTimeout)
+
+			// Endpoint updates are propagated to the clusterset.local service
+			createEndpoints(controller, "svc1", "nsA", []string{"tcp-port"}, []string{"10.10.1.1", "10.10.1.2"}, nil, t)
+			retry.UntilSuccessOrFail(t, func() error {
+				ev := fx.Wait("eds")
+				if ev == nil || ev.ID != hostname || len(ev.Endpoints) != 2 {
+					return fmt.Errorf("expected the updated endpoints of the clusterset.local service, got %v", ev)
+				}
+				return nil
+			}, serviceExportTimeout)
+
+			if err := mcs.ServiceImports("nsA").Delete(context.TODO(), "svc1", metaV1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			retry.UntilSuccessOrFail(t, func() error {
+				if svc, _ := controller.GetService(hostname); svc != nil {
+					return fmt.Errorf("expected the clusterset.local service to be removed")
+				}
+				return nil
+			}, serviceExportTimeout)
+		})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	coreV1 "k8s.io/api/core/v1"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
 
 	"istio.io/api/annotation"
 	"istio.io/istio/pilot/pkg/model"
@@ -36,6 +37,10 @@ const (
 	// responsible for it
 	IngressClassAnnotation = "kubernetes.io/ingress.class"
 
+	// ClusterSetDomainSuffix is the domain suffix of the hostnames of multi-cluster services (MCS), which reach
+	// the endpoints of all the clusters exporting the service.
+	ClusterSetDomainSuffix = "clusterset.local"
+
 	// TODO: move to API
 	// The value for this annotation is a set of key value pairs (node labels)
 	// that can be used to select a subset of nodes from the pool of k8s nodes
@@ -161,6 +166,42 @@ func ConvertService(svc coreV1.Service, domainSuffix string, clusterID string) *
 	return istioService
 }
 
+// ConvertServiceImport converts a MCS ServiceImport to the service of its clusterset.local hostname.
+func ConvertServiceImport(si mcsapi.ServiceImport, clusterID string) *model.Service {
+	addr := constants.UnspecifiedIP
+	resolution := model.Passthrough
+	if si.Spec.Type == mcsapi.ClusterSetIP && len(si.Spec.IPs) > 0 {
+		addr = si.Spec.IPs[0]
+		resolution = model.ClientSideLB
+	}
+
+	ports := make([]*model.Port, 0, len(si.Spec.Ports))
+	for _, port := range si.Spec.Ports {
+		ports = append(ports, &model.Port{
+			Name:     port.Name,
+			Port:     int(port.Port),
+			Protocol: kube.ConvertProtocol(port.Port, port.Name, port.Protocol, port.AppProtocol),
+		})
+	}
+
+	return &model.Service{
+		Hostname:        ServiceHostname(si.Name, si.Namespace, ClusterSetDomainSuffix),
+		Ports:           ports,
+		Address:         addr,
+		ServiceAccounts: make([]string, 0),
+		Resolution:      resolution,
+		CreationTime:    si.CreationTimestamp.Time,
+		ClusterVIPs:     map[string]string{clusterID: addr},
+		Attributes: model.ServiceAttributes{
+			ServiceRegistry: string(serviceregistry.Kubernetes),
+			Name:            si.Name,
+			Namespace:       si.Namespace,
+			Labels:          si.Labels,
+			UID:             formatUID(si.Namespace, si.Name),
+		},
+	}
+}
+
 func ExternalNameServiceInstances(k8sSvc *coreV1.Service, svc *model.Service) []*model.ServiceInstance {
 	if k8sSvc.Spec.Type != coreV1.ServiceTypeExternalName || k8sSvc.Spec.ExternalName == "" {
 		return nil
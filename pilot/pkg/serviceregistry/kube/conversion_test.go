@@ -23,8 +23,11 @@ import (
 
 	coreV1 "k8s.io/api/core/v1"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	mcsapi "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
 
 	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/kube"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/spiffe"
@@ -406,6 +409,40 @@ func TestLBServiceConversion(t *testing.T) {
 	}
 }
 
+func TestServiceImportConversion(t *testing.T) {
+	si := mcsapi.ServiceImport{
+		ObjectMeta: metaV1.ObjectMeta{Name: "service1", Namespace: "default"},
+		Spec: mcsapi.ServiceImportSpec{
+			Type: mcsapi.ClusterSetIP,
+			IPs:  []string{"10.0.0.1"},
+			Ports: []mcsapi.ServicePort{
+				{Name: "http", Port: 8080, Protocol: coreV1.ProtocolTCP},
+			},
+		},
+	}
+
+	service := ConvertServiceImport(si, clusterID)
+	if service.Hostname != "service1.default.svc.clusterset.local" {
+		t.Fatalf("service hostname incorrect => %q", service.Hostname)
+	}
+	if service.Address != "10.0.0.1" || service.ClusterVIPs[clusterID] != "10.0.0.1" || service.Resolution != model.ClientSideLB {
+		t.Fatalf("service address incorrect => %q, %v, %v", service.Address, service.ClusterVIPs, service.Resolution)
+	}
+	if len(service.Ports) != 1 || service.Ports[0].Protocol != protocol.HTTP {
+		t.Fatalf("service ports incorrect => %v", service.Ports)
+	}
+	if service.Attributes.Name != "service1" || service.Attributes.Namespace != "default" {
+		t.Fatalf("service attributes incorrect => %+v", service.Attributes)
+	}
+
+	si.Spec.Type = mcsapi.Headless
+	si.Spec.IPs = nil
+	service = ConvertServiceImport(si, clusterID)
+	if service.Address != constants.UnspecifiedIP || service.Resolution != model.Passthrough {
+		t.Fatalf("headless service should not be load balanced => %q, %v", service.Address, service.Resolution)
+	}
+}
+
 func TestSecureNamingSAN(t *testing.T) {
 	pod := &coreV1.Pod{}
 
@@ -0,0 +1,348 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+
+	"istio.io/api/meta/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/queue"
+	"istio.io/pkg/log"
+)
+
+const defaultAutoAllocateCIDR = "240.240.0.0/16"
+
+// autoAllocator hands out the addresses of the range configured by PILOT_AUTO_ALLOCATE_CIDR.
+var autoAllocator = func() *addressAllocator {
+	a, err := newAddressAllocator(features.ServiceEntryAutoAllocateCIDR)
+	if err != nil {
+		log.Errorf("invalid auto allocation range %q, using %s: %v",
+			features.ServiceEntryAutoAllocateCIDR, defaultAutoAllocateCIDR, err)
+		a, _ = newAddressAllocator(defaultAutoAllocateCIDR)
+	}
+	return a
+}()
+
+// addressAllocator allocates IPv4 addresses out of a range. Addresses ending in .0 or .255 are
+// never handed out, as some clients refuse to connect to them.
+type addressAllocator struct {
+	base uint32
+	size uint32
+}
+
+func newAddressAllocator(cidr string) (*addressAllocator, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	ip := ipNet.IP.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("%s is not an IPv4 range", cidr)
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones < 2 {
+		return nil, fmt.Errorf("%s is too small to allocate addresses from", cidr)
+	}
+	return &addressAllocator{
+		base: binary.BigEndian.Uint32(ip),
+		size: uint32(1) << uint(bits-ones),
+	}, nil
+}
+
+// offset returns the position of the address in the range, if it is an address the allocator could hand out.
+func (a *addressAllocator) offset(address string) (uint32, bool) {
+	ip := net.ParseIP(address).To4()
+	if ip == nil {
+		return 0, false
+	}
+	v := binary.BigEndian.Uint32(ip)
+	if v < a.base || v-a.base >= a.size || !usableAddress(v) {
+		return 0, false
+	}
+	return v - a.base, true
+}
+
+func (a *addressAllocator) address(offset uint32) string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, a.base+offset)
+	return ip.String()
+}
+
+// next returns the lowest free offset above from.
+func (a *addressAllocator) next(from uint32, used map[uint32]*model.Service) (uint32, bool) {
+	for o := from + 1; o < a.size; o++ {
+		if _, f := used[o]; !f && usableAddress(a.base+o) {
+			return o, true
+		}
+	}
+	return 0, false
+}
+
+func usableAddress(v uint32) bool {
+	last := v & 0xff
+	return last != 0 && last != 0xff
+}
+
+// allocate sets AutoAllocatedAddress on every service that needs one.
+// A service that already carries an address, restored from the ServiceEntry status or from a previous
+// allocation, keeps it as long as it is in the range and not used by another service. On a collision the
+// oldest service keeps the address and the others are reallocated. New addresses are the lowest free ones,
+// so creating or deleting a ServiceEntry never moves the addresses of the others.
+func (a *addressAllocator) allocate(services []*model.Service) []*model.Service {
+	used := map[uint32]*model.Service{}
+	candidates := make([]*model.Service, 0, len(services))
+	for _, svc := range services {
+		if needsAutoAllocation(svc) {
+			candidates = append(candidates, svc)
+		} else if o, ok := a.offset(svc.Address); ok {
+			// Addresses set explicitly in a ServiceEntry are never handed out again.
+			used[o] = svc
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].CreationTime.Equal(candidates[j].CreationTime) {
+			return candidates[i].CreationTime.Before(candidates[j].CreationTime)
+		}
+		if candidates[i].Attributes.Namespace != candidates[j].Attributes.Namespace {
+			return candidates[i].Attributes.Namespace < candidates[j].Attributes.Namespace
+		}
+		return candidates[i].Hostname < candidates[j].Hostname
+	})
+
+	pending := make([]*model.Service, 0, len(candidates))
+	for _, svc := range candidates {
+		if svc.AutoAllocatedAddress != "" {
+			o, ok := a.offset(svc.AutoAllocatedAddress)
+			if !ok {
+				log.Warnf("auto allocated address %s of %s/%s is outside of %s, reallocating",
+					svc.AutoAllocatedAddress, svc.Attributes.Namespace, svc.Hostname, features.ServiceEntryAutoAllocateCIDR)
+			} else if owner, f := used[o]; f {
+				log.Warnf("auto allocated address %s of %s/%s collides with %s/%s, reallocating",
+					svc.AutoAllocatedAddress, svc.Attributes.Namespace, svc.Hostname, owner.Attributes.Namespace, owner.Hostname)
+			} else {
+				used[o] = svc
+				continue
+			}
+		}
+		pending = append(pending, svc)
+	}
+
+	o := uint32(0)
+	for _, svc := range pending {
+		var ok bool
+		if o, ok = a.next(o, used); !ok {
+			log.Errorf("out of IPs to allocate for service entries")
+			svc.AutoAllocatedAddress = ""
+			continue
+		}
+		used[o] = svc
+		svc.AutoAllocatedAddress = a.address(o)
+	}
+	return services
+}
+
+// we can allocate IPs only if
+//  1. the service has resolution set to static/dns. We cannot allocate
+//     for NONE because we will not know the original DST IP that the application requested.
+//  2. the address is not set (0.0.0.0)
+//  3. the hostname is not a wildcard
+func needsAutoAllocation(svc *model.Service) bool {
+	return svc.Address == constants.UnspecifiedIP && !svc.Hostname.IsWildCarded() &&
+		svc.Resolution != model.Passthrough
+}
+
+// allocationState tracks the addresses auto allocated to the services of the ServiceEntryStore.
+type allocationState struct {
+	// pending is set when the services have been refreshed but not allocated addresses yet.
+	pending bool
+	// addresses holds the last address allocated to every host, so that it is kept across refreshes.
+	addresses map[instancesKey]string
+	// entries holds the allocations of every ServiceEntry.
+	entries map[configKey]*entryAllocation
+}
+
+// entryAllocation holds the services of a ServiceEntry along with their allocated addresses, formatted as in
+// the message of the ConditionAddressAllocated condition.
+type entryAllocation struct {
+	services []*model.Service
+	// persisted is read from the ServiceEntry status.
+	persisted string
+	// current is computed by this istiod.
+	current string
+}
+
+func newEntryAllocation(cfg config.Config, services []*model.Service) *entryAllocation {
+	e := &entryAllocation{services: services}
+	if cond := status.GetConditionFromSpec(cfg, status.ConditionAddressAllocated); cond != nil {
+		e.persisted = cond.Message
+	}
+	return e
+}
+
+// maybeAllocateAddresses allocates addresses to the services, if they were refreshed since the last allocation.
+// Addresses persisted in the ServiceEntry status take precedence over the ones previously allocated by this istiod,
+// so that all istiods converge to the allocation of the one persisting them.
+func (s *ServiceEntryStore) maybeAllocateAddresses() {
+	s.storeMutex.Lock()
+	defer s.storeMutex.Unlock()
+	if !s.allocations.pending {
+		return
+	}
+	s.allocations.pending = false
+
+	for _, e := range s.allocations.entries {
+		persisted := parseAllocationMessage(e.persisted)
+		for _, svc := range e.services {
+			if !needsAutoAllocation(svc) {
+				continue
+			}
+			if addr, f := persisted[string(svc.Hostname)]; f {
+				svc.AutoAllocatedAddress = addr
+			} else {
+				svc.AutoAllocatedAddress = s.allocations.addresses[instancesKey{svc.Hostname, svc.Attributes.Namespace}]
+			}
+		}
+	}
+	autoAllocateIPs(s.services)
+
+	addresses := make(map[instancesKey]string, len(s.services))
+	for _, svc := range s.services {
+		if svc.AutoAllocatedAddress != "" {
+			addresses[instancesKey{svc.Hostname, svc.Attributes.Namespace}] = svc.AutoAllocatedAddress
+		}
+	}
+	s.allocations.addresses = addresses
+
+	for key, e := range s.allocations.entries {
+		e.current = allocationMessage(e.services)
+		s.queueAllocationStatus(key, e)
+	}
+}
+
+// queueAllocationStatus schedules a status update of the ServiceEntry if the persisted allocation is stale.
+// It must be called with storeMutex held.
+func (s *ServiceEntryStore) queueAllocationStatus(key configKey, e *entryAllocation) {
+	if s.statusQueue == nil || e.current == e.persisted {
+		return
+	}
+	name, namespace := key.name, key.namespace
+	s.statusQueue.Push(func() error {
+		return s.writeAllocationStatus(name, namespace)
+	})
+}
+
+// parseAllocationMessage returns the host to address allocations of a ConditionAddressAllocated message.
+func parseAllocationMessage(message string) map[string]string {
+	if message == "" {
+		return nil
+	}
+	out := map[string]string{}
+	for _, pair := range strings.Split(message, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		out[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return out
+}
+
+// allocationMessage formats the auto allocated addresses of the services of a ServiceEntry for its status.
+func allocationMessage(services []*model.Service) string {
+	pairs := make([]string, 0, len(services))
+	for _, svc := range services {
+		if svc.AutoAllocatedAddress != "" {
+			pairs = append(pairs, string(svc.Hostname)+"="+svc.AutoAllocatedAddress)
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// WriteAllocatedAddresses persists the auto allocated addresses of ServiceEntry hosts in the ServiceEntry
+// status until stop is closed, so that they survive restarts and are shared by all istiod instances.
+// Only one istiod should write them at a time.
+func (s *ServiceEntryStore) WriteAllocatedAddresses(store model.ConfigStore, stop <-chan struct{}) {
+	q := queue.NewQueue(time.Second)
+	s.storeMutex.Lock()
+	s.statusStore = store
+	s.statusQueue = q
+	// Allocations made before becoming the writer are persisted right away, later ones as they are made.
+	if !s.allocations.pending {
+		for key, e := range s.allocations.entries {
+			s.queueAllocationStatus(key, e)
+		}
+	}
+	s.storeMutex.Unlock()
+
+	q.Run(stop)
+
+	s.storeMutex.Lock()
+	s.statusStore = nil
+	s.statusQueue = nil
+	s.storeMutex.Unlock()
+}
+
+func (s *ServiceEntryStore) writeAllocationStatus(name, namespace string) error {
+	s.storeMutex.RLock()
+	store := s.statusStore
+	e, f := s.allocations.entries[configKey{kind: serviceEntryConfigType, name: name, namespace: namespace}]
+	message := ""
+	if f {
+		message = e.current
+	}
+	s.storeMutex.RUnlock()
+	if store == nil || !f {
+		// No longer the writer, or the ServiceEntry was deleted.
+		return nil
+	}
+	cfg := store.Get(gvk.ServiceEntry, name, namespace)
+	if cfg == nil {
+		return nil
+	}
+	cond := status.GetConditionFromSpec(*cfg, status.ConditionAddressAllocated)
+	if cond == nil && message == "" || cond != nil && cond.Message == message {
+		return nil
+	}
+	condStatus := status.StatusTrue
+	if message == "" {
+		condStatus = status.StatusFalse
+	}
+	updated := status.UpdateConfigCondition(*cfg, &v1alpha1.IstioCondition{
+		Type:               status.ConditionAddressAllocated,
+		Status:             condStatus,
+		Reason:             "AutoAllocated",
+		Message:            message,
+		LastTransitionTime: types.TimestampNow(),
+	})
+	if _, err := store.UpdateStatus(updated); err != nil {
+		return fmt.Errorf("error while updating allocated addresses of ServiceEntry %s/%s: %v", namespace, name, err)
+	}
+	log.Debugf("updated allocated addresses of ServiceEntry %s/%s to %q", namespace, name, message)
+	return nil
+}
@@ -15,7 +15,6 @@
 package serviceentry
 
 import (
-	"fmt"
 	"reflect"
 	"strconv"
 	"sync"
@@ -29,10 +28,10 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/util/informermetric"
 	"istio.io/istio/pkg/config"
-	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/queue"
 	"istio.io/pkg/log"
 )
 
@@ -86,6 +85,12 @@ type ServiceEntryStore struct { // nolint:golint
 	refreshIndexes   *atomic.Bool
 	workloadHandlers []func(*model.WorkloadInstance, model.Event)
 
+	// allocations keeps track of the addresses auto allocated to services, see autoAllocateIPs.
+	allocations allocationState
+	// statusStore and statusQueue are set while this istiod persists the allocated addresses.
+	statusStore model.ConfigStore
+	statusQueue queue.Instance
+
 	processServiceEntry bool
 }
 
@@ -496,13 +501,14 @@ func (s *ServiceEntryStore) Services() ([]*model.Service, error) {
 		return nil, nil
 	}
 	s.maybeRefreshIndexes()
+	s.maybeAllocateAddresses()
 	s.storeMutex.RLock()
 	defer s.storeMutex.RUnlock()
-	return autoAllocateIPs(s.services), nil
+	return s.services, nil
 }
 
 // GetService retrieves a service by host name if it exists.
-// NOTE: This does not auto allocate IPs. The service entry implementation is used only for tests.
+// NOTE: The service entry implementation is used only for tests.
 func (s *ServiceEntryStore) GetService(hostname host.Name) (*model.Service, error) {
 	if !s.processServiceEntry {
 		return nil, nil
@@ -658,6 +664,7 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 	// First refresh service entry
 	seWithSelectorByNamespace := map[string][]servicesWithEntry{}
 	allServices := []*model.Service{}
+	allocations := map[configKey]*entryAllocation{}
 	if s.processServiceEntry {
 		for _, cfg := range s.store.ServiceEntries() {
 			key := configKey{
@@ -667,6 +674,7 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 			}
 			updateInstances(key, convertServiceEntryToInstances(cfg, nil), instanceMap, ip2instances)
 			services := convertServices(cfg)
+			allocations[key] = newEntryAllocation(cfg, services)
 
 			se := cfg.Spec.(*networking.ServiceEntry)
 			// If we have a workload selector, we will add all instances from WorkloadEntries. Otherwise, we continue
@@ -727,6 +735,8 @@ func (s *ServiceEntryStore) maybeRefreshIndexes() {
 
 	s.seWithSelectorByNamespace = seWithSelectorByNamespace
 	s.services = allServices
+	s.allocations.entries = allocations
+	s.allocations.pending = true
 	s.instances = instanceMap
 	s.ip2instance = ip2instances
 }
@@ -869,62 +879,19 @@ func selectorChanged(old, curr config.Config) bool {
 
 // Automatically allocates IPs for service entry services WITHOUT an
 // address field if the hostname is not a wildcard, or when resolution
-// is not NONE. The IPs are allocated from the reserved Class E subnet
-// (240.240.0.0/16) that is not reachable outside the pod. When DNS
-// capture is enabled, Envoy will resolve the DNS to these IPs. The
-// listeners for TCP services will also be set up on these IPs. The
-// IPs allocated to a service entry may differ from istiod to istiod
-// but it does not matter because these IPs only affect the listener
-// IPs on a given proxy managed by a given istiod.
-//
+// is not NONE. The IPs are allocated from the range configured by
+// PILOT_AUTO_ALLOCATE_CIDR (240.240.0.0/16 by default, a reserved Class E
+// subnet that is not reachable outside the pod). When DNS capture is
+// enabled, Envoy will resolve the DNS to these IPs. The listeners for
+// TCP services will also be set up on these IPs.
 // NOTE: If DNS capture is not enabled by the proxy, the automatically
 // allocated IP addresses do not take effect.
 //
-// The current algorithm to allocate IPs is deterministic across all istiods.
-// At stable state, given two istiods with exact same set of services, there should
-// be no change in XDS as the algorithm is just a dumb iterative one that allocates sequentially.
-//
-// TODO: Rather than sequentially allocate IPs, switch to a hash based allocation mechanism so that
-// deletion of the oldest service entry does not cause change of IPs for all other service entries.
-// Currently, the sequential allocation will result in unnecessary XDS reloads (lds/rds) when a
-// service entry with auto allocated IP is deleted. We are trading off a perf problem (xds reload)
-// for a usability problem (e.g., multiple cloud SQL or AWS RDS tcp services with no VIPs end up having
-// the same port, causing traffic to go to the wrong place). Once we move to a deterministic hash-based
-// allocation with deterministic collision resolution, the perf problem will go away. If the collision guarantee
-// cannot be made within the IP address space we have (which is about 64K services), then we may need to
-// have the sequential allocation algorithm as a fallback when too many collisions take place.
+// Allocations are stable: a service keeps the address it was given for as
+// long as it exists, and the elected istiod persists them in the ServiceEntry
+// status so that they survive restarts and are the same on every istiod.
 func autoAllocateIPs(services []*model.Service) []*model.Service {
-	// i is everything from 240.240.0.(j) to 240.240.255.(j)
-	// j is everything from 240.240.(i).1 to 240.240.(i).254
-	// we can capture this in one integer variable.
-	// given X, we can compute i by X/255, and j is X%255
-	// To avoid allocating 240.240.(i).255, if X % 255 is 0, increment X.
-	// For example, when X=510, the resulting IP would be 240.240.2.0 (invalid)
-	// So we bump X to 511, so that the resulting IP is 240.240.2.1
-	maxIPs := 255 * 255 // are we going to exceeed this limit by processing 64K services?
-	x := 0
-	for _, svc := range services {
-		// we can allocate IPs only if
-		// 1. the service has resolution set to static/dns. We cannot allocate
-		//   for NONE because we will not know the original DST IP that the application requested.
-		// 2. the address is not set (0.0.0.0)
-		// 3. the hostname is not a wildcard
-		if svc.Address == constants.UnspecifiedIP && !svc.Hostname.IsWildCarded() &&
-			svc.Resolution != model.Passthrough {
-			x++
-			if x%255 == 0 {
-				x++
-			}
-			if x >= maxIPs {
-				log.Errorf("out of IPs to allocate for service entries")
-				return services
-			}
-			thirdOctet := x / 255
-			fourthOctet := x % 255
-			svc.AutoAllocatedAddress = fmt.Sprintf("240.240.%d.%d", thirdOctet, fourthOctet)
-		}
-	}
-	return services
+	return autoAllocator.allocate(services)
 }
 
 func makeConfigKey(svc *model.Service) model.ConfigKey {
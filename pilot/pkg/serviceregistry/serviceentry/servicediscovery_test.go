@@ -23,9 +23,11 @@ import (
 	"time"
 
 	"istio.io/api/label"
+	"istio.io/api/meta/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/memory"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/model/status"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
@@ -1390,6 +1392,153 @@ func Test_autoAllocateIP_values(t *testing.T) {
 	}
 }
 
+func Test_autoAllocateIP_stable(t *testing.T) {
+	svc := func(hostname string, age int, address string) *model.Service {
+		return &model.Service{
+			Hostname:             host.Name(hostname),
+			Resolution:           model.ClientSideLB,
+			Address:              constants.UnspecifiedIP,
+			CreationTime:         time.Unix(int64(1000-age), 0),
+			AutoAllocatedAddress: address,
+		}
+	}
+	cases := []struct {
+		name       string
+		inServices []*model.Service
+		want       map[host.Name]string
+	}{
+		{
+			name: "existing allocations are kept",
+			inServices: []*model.Service{
+				svc("a.com", 3, "240.240.0.1"),
+				svc("new.com", 0, ""),
+				svc("c.com", 1, "240.240.0.3"),
+			},
+			want: map[host.Name]string{"a.com": "240.240.0.1", "new.com": "240.240.0.2", "c.com": "240.240.0.3"},
+		},
+		{
+			name: "older service wins a collision",
+			inServices: []*model.Service{
+				svc("young.com", 1, "240.240.0.1"),
+				svc("old.com", 2, "240.240.0.1"),
+			},
+			want: map[host.Name]string{"old.com": "240.240.0.1", "young.com": "240.240.0.2"},
+		},
+		{
+			name: "addresses outside of the range are reallocated",
+			inServices: []*model.Service{
+				svc("outside.com", 1, "10.0.0.1"),
+				svc("broadcast.com", 1, "240.240.0.255"),
+			},
+			want: map[host.Name]string{"broadcast.com": "240.240.0.1", "outside.com": "240.240.0.2"},
+		},
+		{
+			name: "explicit addresses are not allocated",
+			inServices: []*model.Service{
+				{Hostname: "explicit.com", Resolution: model.ClientSideLB, Address: "240.240.0.1"},
+				svc("auto.com", 1, "240.240.0.1"),
+			},
+			want: map[host.Name]string{"explicit.com": "", "auto.com": "240.240.0.2"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[host.Name]string{}
+			for _, svc := range autoAllocateIPs(tt.inServices) {
+				got[svc.Hostname] = svc.AutoAllocatedAddress
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("autoAllocateIPs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddressAllocatorRange(t *testing.T) {
+	if _, err := newAddressAllocator("240.240.0.1/32"); err == nil {
+		t.Errorf("expected an error for a range too small")
+	}
+	if _, err := newAddressAllocator("fd00::/64"); err == nil {
+		t.Errorf("expected an error for an IPv6 range")
+	}
+	a, err := newAddressAllocator("10.20.0.0/23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	services := make([]*model.Service, 510)
+	for i := range services {
+		services[i] = &model.Service{Hostname: host.Name(fmt.Sprintf("svc%03d.com", i)), Address: constants.UnspecifiedIP}
+	}
+	a.allocate(services)
+	if got := services[254].AutoAllocatedAddress; got != "10.20.1.1" {
+		t.Errorf("expected the 255th address to be 10.20.1.1, got %s", got)
+	}
+	if got := services[507].AutoAllocatedAddress; got != "10.20.1.254" {
+		t.Errorf("expected the last address to be 10.20.1.254, got %s", got)
+	}
+	if got := services[508].AutoAllocatedAddress; got != "" {
+		t.Errorf("expected the range to be exhausted, got %s", got)
+	}
+}
+
+func TestAutoAllocatedAddressStatus(t *testing.T) {
+	store, sd, _, stopFn := initServiceDiscovery()
+	defer stopFn()
+
+	entry := func(name string, age int, hosts ...string) *config.Config {
+		return &config.Config{
+			Meta: config.Meta{
+				GroupVersionKind:  gvk.ServiceEntry,
+				Name:              name,
+				Namespace:         "auto",
+				CreationTimestamp: time.Unix(int64(1000-age), 0),
+			},
+			Spec: &networking.ServiceEntry{
+				Hosts:      hosts,
+				Ports:      []*networking.Port{{Number: 80, Name: "http", Protocol: "http"}},
+				Resolution: networking.ServiceEntry_DNS,
+			},
+		}
+	}
+	persisted := entry("persisted", 2, "a.com", "b.com")
+	createConfigs([]*config.Config{persisted, entry("fresh", 1, "c.com")}, store, t)
+	persisted.Status = &v1alpha1.IstioStatus{Conditions: []*v1alpha1.IstioCondition{{
+		Type:    status.ConditionAddressAllocated,
+		Status:  status.StatusTrue,
+		Message: "a.com=240.240.0.7,b.com=240.240.0.3",
+	}}}
+	if _, err := store.UpdateStatus(*persisted); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go sd.WriteAllocatedAddresses(store, stop)
+
+	want := map[string]string{
+		"persisted": "a.com=240.240.0.7,b.com=240.240.0.3",
+		"fresh":     "c.com=240.240.0.1",
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		services, _ := sd.Services()
+		got := map[host.Name]string{}
+		for _, svc := range services {
+			got[svc.Hostname] = svc.AutoAllocatedAddress
+		}
+		if want := (map[host.Name]string{"a.com": "240.240.0.7", "b.com": "240.240.0.3", "c.com": "240.240.0.1"}); !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("got allocations %v, want %v", got, want)
+		}
+		for name, message := range want {
+			cfg := store.Get(gvk.ServiceEntry, name, "auto")
+			cond := status.GetConditionFromSpec(*cfg, status.ConditionAddressAllocated)
+			if cond == nil || cond.Message != message {
+				return fmt.Errorf("got condition %v for %s, want message %q", cond, name, message)
+			}
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+}
+
 func TestWorkloadEntryOnlyMode(t *testing.T) {
 	store, registry, _, cleanup := initServiceDiscoveryWithOpts(DisableServiceEntryProcessing())
 	defer cleanup()
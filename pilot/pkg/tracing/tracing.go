@@ -0,0 +1,206 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records spans of Istiod's own push pipeline, and reports them in the Zipkin v2 format.
+//
+// Tracing is disabled until Start is called. All span methods are safe to call on a nil span, which is
+// what StartSpan returns when tracing is disabled or the trace is not sampled, so callers never need to
+// check whether tracing is enabled.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// Span is a timed operation of a trace.
+type Span struct {
+	traceID  string
+	id       string
+	parentID string
+	name     string
+	start    time.Time
+
+	mu       sync.Mutex
+	tags     map[string]string
+	finished bool
+	// holds counts the Hold calls not released yet.
+	holds int
+}
+
+var (
+	reporterMu sync.RWMutex
+	reporter   Reporter
+
+	sampling = atomic.NewFloat64(1)
+)
+
+// Reporter receives finished spans.
+type Reporter interface {
+	Report(s *SpanData)
+}
+
+// SpanData is a finished span.
+type SpanData struct {
+	TraceID  string
+	ID       string
+	ParentID string
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Tags     map[string]string
+}
+
+// Start enables tracing, reporting a sampled fraction of the traces, between 0 and 1, to the reporter.
+func Start(r Reporter, samplingRate float64) {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	reporter = r
+	sampling.Store(samplingRate)
+}
+
+// Stop disables tracing. Spans started before are still reported when finished.
+func Stop() {
+	reporterMu.Lock()
+	defer reporterMu.Unlock()
+	reporter = nil
+}
+
+func enabled() bool {
+	reporterMu.RLock()
+	defer reporterMu.RUnlock()
+	return reporter != nil
+}
+
+func report(s *SpanData) {
+	reporterMu.RLock()
+	r := reporter
+	reporterMu.RUnlock()
+	if r != nil {
+		r.Report(s)
+	}
+}
+
+// StartSpan starts the root span of a new trace. It returns nil if tracing is disabled or the trace is not
+// sampled.
+func StartSpan(name string, start time.Time) *Span {
+	if !enabled() || randomFloat() >= sampling.Load() {
+		return nil
+	}
+	return &Span{
+		traceID: newID(16),
+		id:      newID(8),
+		name:    name,
+		start:   start,
+	}
+}
+
+// StartChild starts a child span. It returns nil if the span is nil.
+func (s *Span) StartChild(name string, start time.Time) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{
+		traceID:  s.traceID,
+		id:       newID(8),
+		parentID: s.id,
+		name:     name,
+		start:    start,
+	}
+}
+
+// Tag sets a tag of the span, and returns the span.
+func (s *Span) Tag(key, value string) *Span {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tags == nil {
+		s.tags = map[string]string{}
+	}
+	s.tags[key] = value
+	return s
+}
+
+// Hold keeps the span open until a matching Release, for spans whose children are finished asynchronously.
+func (s *Span) Hold() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.holds++
+}
+
+// Release undoes a Hold, finishing the span once it is no longer held.
+func (s *Span) Release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.holds--
+	done := s.holds <= 0
+	s.mu.Unlock()
+	if done {
+		s.Finish()
+	}
+}
+
+// Finish ends the span now and reports it.
+func (s *Span) Finish() {
+	s.FinishAt(time.Now())
+}
+
+// FinishAt ends the span at the given time and reports it. Spans are only reported once.
+func (s *Span) FinishAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.finished = true
+	data := &SpanData{
+		TraceID:  s.traceID,
+		ID:       s.id,
+		ParentID: s.parentID,
+		Name:     s.name,
+		Start:    s.start,
+		Duration: end.Sub(s.start),
+		Tags:     s.tags,
+	}
+	s.mu.Unlock()
+	report(data)
+}
+
+// newID returns a random hex encoded ID of the given number of bytes.
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func randomFloat() float64 {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return float64(binary.BigEndian.Uint64(b)>>11) / (1 << 53)
+}
@@ -0,0 +1,158 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDisabled(t *testing.T) {
+	Stop()
+	span := StartSpan("push", time.Now())
+	if span != nil {
+		t.Fatalf("expected no span with tracing disabled")
+	}
+	// All methods are safe on nil spans
+	span.StartChild("child", time.Now()).Tag("key", "value").Finish()
+}
+
+type fakeReporter struct {
+	mu    sync.Mutex
+	spans []*SpanData
+}
+
+func (r *fakeReporter) Report(s *SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func (r *fakeReporter) reported() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.spans)
+}
+
+func TestHold(t *testing.T) {
+	reporter := &fakeReporter{}
+	Start(reporter, 1)
+	defer Stop()
+
+	span := StartSpan("push", time.Now())
+	span.Hold()
+	span.Hold()
+	span.Release()
+	if reporter.reported() != 0 {
+		t.Fatalf("expected a held span not to be finished")
+	}
+	span.Release()
+	if reporter.reported() != 1 {
+		t.Fatalf("expected the span to be finished once released")
+	}
+	span.Hold()
+	span.Release()
+	if reporter.reported() != 1 {
+		t.Fatalf("expected the span to be reported once")
+	}
+}
+
+func TestZipkinReporter(t *testing.T) {
+	var mu sync.Mutex
+	var received []zipkinSpan
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var spans []zipkinSpan
+		if r.URL.Path != "/api/v2/spans" || json.NewDecoder(r.Body).Decode(&spans) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, spans...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer collector.Close()
+
+	reporter := NewZipkinReporter("istiod", collector.URL+"/api/v2/spans")
+	Start(reporter, 1)
+	defer Stop()
+
+	start := time.Now()
+	root := StartSpan("push", start).Tag("reason", "config")
+	child := root.StartChild("debounce", start)
+	child.FinishAt(start.Add(100 * time.Millisecond))
+	child.FinishAt(start.Add(time.Second))
+	root.FinishAt(start.Add(200 * time.Millisecond))
+	if err := reporter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("expected each span to be reported once, got %+v", received)
+	}
+	debounce, push := received[0], received[1]
+	if debounce.Name != "debounce" || push.Name != "push" {
+		t.Fatalf("unexpected spans %+v", received)
+	}
+	if debounce.TraceID != push.TraceID || debounce.ParentID != push.ID || push.ParentID != "" {
+		t.Fatalf("expected debounce to be a child of push: %+v", received)
+	}
+	if debounce.Duration != 100000 || push.Duration != 200000 {
+		t.Fatalf("unexpected durations %+v", received)
+	}
+	if push.Tags["reason"] != "config" || push.LocalEndpoint.ServiceName != "istiod" {
+		t.Fatalf("unexpected push span %+v", push)
+	}
+}
+
+func TestZipkinFileReporter(t *testing.T) {
+	out := &bytes.Buffer{}
+	reporter := NewZipkinFileReporter("istiod", out)
+	Start(reporter, 1)
+	defer Stop()
+
+	StartSpan("push", time.Now()).Finish()
+	StartSpan("push", time.Now()).Finish()
+	if err := reporter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(out)
+	for i := 0; i < 2; i++ {
+		var s zipkinSpan
+		if err := dec.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Name != "push" || len(s.TraceID) != 32 || len(s.ID) != 16 {
+			t.Fatalf("unexpected span %+v", s)
+		}
+	}
+}
+
+func TestSampling(t *testing.T) {
+	Start(NewZipkinFileReporter("istiod", &bytes.Buffer{}), 0)
+	defer Stop()
+	for i := 0; i < 100; i++ {
+		if StartSpan("push", time.Now()) != nil {
+			t.Fatalf("expected no sampled traces")
+		}
+	}
+}
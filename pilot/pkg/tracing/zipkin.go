@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("tracing", "Istiod push tracing", 0)
+
+const (
+	// FlushInterval is the maximum time a span is buffered before being reported.
+	FlushInterval = time.Second
+	// maxBufferedSpans is the number of buffered spans that triggers a flush. Spans are dropped when
+	// twice as many are buffered, for example while the collector is unreachable.
+	maxBufferedSpans = 1000
+)
+
+// ZipkinReporter reports spans in batches, in the Zipkin v2 JSON format. Spans are either sent to a Zipkin
+// compatible collector, or written to a local file, one span per line.
+type ZipkinReporter struct {
+	serviceName string
+	endpoint    string
+	out         io.Writer
+	client      *http.Client
+
+	mu      sync.Mutex
+	spans   []zipkinSpan
+	flushCh chan struct{}
+}
+
+var _ Reporter = &ZipkinReporter{}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinSpan struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint zipkinEndpoint    `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+// NewZipkinReporter creates a reporter sending spans to the given Zipkin v2 collector endpoint,
+// for example http://zipkin.istio-system:9411/api/v2/spans.
+func NewZipkinReporter(serviceName, endpoint string) *ZipkinReporter {
+	return &ZipkinReporter{
+		serviceName: serviceName,
+		endpoint:    endpoint,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		flushCh:     make(chan struct{}, 1),
+	}
+}
+
+// NewZipkinFileReporter creates a reporter writing spans to out, one JSON encoded span per line.
+func NewZipkinFileReporter(serviceName string, out io.Writer) *ZipkinReporter {
+	return &ZipkinReporter{
+		serviceName: serviceName,
+		out:         out,
+		flushCh:     make(chan struct{}, 1),
+	}
+}
+
+// Report buffers a finished span.
+func (r *ZipkinReporter) Report(s *SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.spans) >= 2*maxBufferedSpans {
+		return
+	}
+	r.spans = append(r.spans, zipkinSpan{
+		TraceID:       s.TraceID,
+		ID:            s.ID,
+		ParentID:      s.ParentID,
+		Name:          s.Name,
+		Timestamp:     s.Start.UnixNano() / int64(time.Microsecond),
+		Duration:      s.Duration.Microseconds(),
+		LocalEndpoint: zipkinEndpoint{ServiceName: r.serviceName},
+		Tags:          s.Tags,
+	})
+	if len(r.spans) >= maxBufferedSpans {
+		select {
+		case r.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run flushes the buffered spans periodically, until the stop channel is closed.
+func (r *ZipkinReporter) Run(stop <-chan struct{}) {
+	t := time.NewTicker(FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			if err := r.Flush(); err != nil {
+				log.Warnf("failed to report spans: %v", err)
+			}
+			return
+		case <-t.C:
+		case <-r.flushCh:
+		}
+		if err := r.Flush(); err != nil {
+			log.Warnf("failed to report spans: %v", err)
+		}
+	}
+}
+
+// Flush reports the buffered spans.
+func (r *ZipkinReporter) Flush() error {
+	r.mu.Lock()
+	spans := r.spans
+	r.spans = nil
+	r.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	if r.out != nil {
+		buf := &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		for _, s := range spans {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		_, err := r.out.Write(buf.Bytes())
+		return err
+	}
+
+	body, err := json.Marshal(spans)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(r.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%d spans rejected by %s: %s", len(spans), r.endpoint, resp.Status)
+	}
+	return nil
+}
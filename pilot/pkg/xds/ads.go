@@ -33,6 +33,7 @@ import (
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/tracing"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/spiffe"
@@ -106,6 +107,18 @@ type Connection struct {
 	// (last push not ACKed). When we get an ACK from Envoy, if the type is populated here, we will trigger
 	// the push.
 	blockedPushes map[string]*model.PushRequest
+
+	// generateFailed records whether generating config for this connection failed with the last committed
+	// mesh config, so that such failures are not blamed on a pending mesh config update. It is only accessed
+	// from the goroutine handling the connection.
+	generateFailed bool
+
+	// pushSpan traces the push currently sent to this connection, if it is sampled.
+	pushSpan *tracing.Span
+
+	// ackSpans is a map of TypeUrl to the span waiting for the ACK of the last traced response. It is only
+	// accessed from the goroutine handling the connection.
+	ackSpans map[string]*tracing.Span
 }
 
 // Event represents a config or registry event that results in a push.
@@ -204,6 +217,9 @@ func (s *DiscoveryServer) processRequest(req *discovery.DiscoveryRequest, con *C
 	request, haveBlockedPush := con.blockedPushes[req.TypeUrl]
 	delete(con.blockedPushes, req.TypeUrl)
 	con.proxy.Unlock()
+	if haveBlockedPush {
+		defer request.Span.Release()
+	}
 
 	if shouldRespond {
 		// This is a request, trigger a full push for this type
@@ -348,6 +364,7 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 		con.proxy.Lock()
 		con.proxy.WatchedResources[request.TypeUrl].NonceNacked = request.ResponseNonce
 		con.proxy.Unlock()
+		con.finishAckSpan(request, errCode.String()+": "+request.ErrorDetail.GetMessage())
 		return false
 	}
 
@@ -407,6 +424,7 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 	con.proxy.WatchedResources[request.TypeUrl].ResourceNames = request.ResourceNames
 	con.proxy.WatchedResources[request.TypeUrl].LastRequest = request
 	con.proxy.Unlock()
+	con.finishAckSpan(request, "")
 
 	// Envoy can send two DiscoveryRequests with same version and nonce
 	// when it detects a new resource. We should respond if they change.
@@ -420,6 +438,34 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 	return true
 }
 
+// startAckSpan starts waiting for the ACK of a traced response, replacing the span of the previous response
+// of the same type, which will never be ACKed.
+func (conn *Connection) startAckSpan(parent *tracing.Span, resp *discovery.DiscoveryResponse) {
+	if old := conn.ackSpans[resp.TypeUrl]; old != nil {
+		old.Tag("superseded", "true").Finish()
+		delete(conn.ackSpans, resp.TypeUrl)
+	}
+	if span := parent.StartChild("ack "+v3.GetShortType(resp.TypeUrl), time.Now()); span != nil {
+		if conn.ackSpans == nil {
+			conn.ackSpans = map[string]*tracing.Span{}
+		}
+		conn.ackSpans[resp.TypeUrl] = span.Tag("nonce", resp.Nonce)
+	}
+}
+
+// finishAckSpan ends the span waiting for the ACK or NACK of a response, if it is traced.
+func (conn *Connection) finishAckSpan(request *discovery.DiscoveryRequest, nackErr string) {
+	span := conn.ackSpans[request.TypeUrl]
+	if span == nil {
+		return
+	}
+	delete(conn.ackSpans, request.TypeUrl)
+	if nackErr != "" {
+		span.Tag("error", nackErr)
+	}
+	span.Tag("ack_nonce", request.ResponseNonce).Finish()
+}
+
 // shouldUnsubscribe checks if we should unsubscribe. This is done when Envoy is
 // no longer watching. For example, we remove all RDS references, we will
 // unsubscribe from RDS. NOTE: This may happen as part of the initial request. If
@@ -517,6 +563,11 @@ func (s *DiscoveryServer) closeConnection(con *Connection) {
 		return
 	}
 	s.removeCon(con.ConID)
+	con.proxy.Lock()
+	for _, blocked := range con.blockedPushes {
+		blocked.Span.Release()
+	}
+	con.proxy.Unlock()
 	if s.StatusGen != nil {
 		s.StatusGen.OnDisconnect(con)
 	}
@@ -647,6 +698,9 @@ func (s *DiscoveryServer) computeProxyState(proxy *model.Proxy, request *model.P
 			}
 		}
 	}
+	// The scopes are read by the PushQueue to prioritize pushes, so they are updated under the lock.
+	proxy.Lock()
+	defer proxy.Unlock()
 	switch {
 	case sidecar && proxy.Type == model.SidecarProxy:
 		proxy.SetSidecarScope(push)
@@ -695,6 +749,14 @@ func (s *DiscoveryServer) DeltaAggregatedResources(stream discovery.AggregatedDi
 func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 	pushRequest := pushEv.pushRequest
 
+	con.pushSpan = pushRequest.Span.StartChild("push "+con.ConID, pushRequest.Start)
+	con.pushSpan.StartChild("queue", pushRequest.Start).Finish()
+	defer func() {
+		con.pushSpan.Finish()
+		con.pushSpan = nil
+		pushRequest.Span.Release()
+	}()
+
 	if pushRequest.Full {
 		// Update Proxy with current information.
 		s.updateProxy(con.proxy, pushRequest)
@@ -702,6 +764,7 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 
 	if !s.ProxyNeedsPush(con.proxy, pushRequest) {
 		log.Debugf("Skipping push to %v, no updates required", con.ConID)
+		con.pushSpan.Tag("skipped", "true")
 		if pushRequest.Full {
 			// Only report for full versions, incremental pushes do not have a new version
 			reportAllEvents(s.StatusReporter, con.ConID, pushRequest.Push.LedgerVersion, nil)
@@ -743,6 +806,8 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 			totalDelayedPushes.With(typeTag.Value(v3.GetMetricType(w.TypeUrl))).Increment()
 			log.Debugf("%s: QUEUE for node:%s", v3.GetShortType(w.TypeUrl), con.proxy.ID)
 			con.proxy.Lock()
+			// Released once the blocked push is sent, or when the connection is closed.
+			pushEv.pushRequest.Span.Hold()
 			con.blockedPushes[w.TypeUrl] = con.blockedPushes[w.TypeUrl].Merge(pushEv.pushRequest)
 			con.proxy.Unlock()
 		}
@@ -893,6 +958,7 @@ func (s *DiscoveryServer) addCon(conID string, con *Connection) {
 	s.adsClientsMutex.Lock()
 	defer s.adsClientsMutex.Unlock()
 	s.adsClients[conID] = con
+	s.pushCost.connect(con)
 }
 
 func (s *DiscoveryServer) removeCon(conID string) {
@@ -905,6 +971,7 @@ func (s *DiscoveryServer) removeCon(conID string) {
 	} else {
 		delete(s.adsClients, conID)
 		recordXDSClients(con.proxy.Metadata.IstioVersion, -1)
+		s.pushCost.disconnect(con)
 	}
 }
 
@@ -971,6 +1038,16 @@ func (conn *Connection) NonceSent(typeUrl string) string {
 	return ""
 }
 
+// nolint
+func (conn *Connection) NonceNacked(typeUrl string) string {
+	conn.proxy.RLock()
+	defer conn.proxy.RUnlock()
+	if conn.proxy.WatchedResources != nil && conn.proxy.WatchedResources[typeUrl] != nil {
+		return conn.proxy.WatchedResources[typeUrl].NonceNacked
+	}
+	return ""
+}
+
 func (conn *Connection) Clusters() []string {
 	conn.proxy.RLock()
 	defer conn.proxy.RUnlock()
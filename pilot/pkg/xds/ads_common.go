@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -23,19 +24,53 @@ import (
 // configKindAffectedProxyTypes contains known config types which may affect certain node types.
 var configKindAffectedProxyTypes = map[config.GroupVersionKind][]model.NodeType{
 	gvk.Gateway: {model.Router},
-	gvk.Secret:  {model.Router},
+	gvk.Secret:  secretAffectedProxyTypes(),
 	gvk.Sidecar: {model.SidecarProxy},
 }
 
+// namespaceScopedConfigKinds are config kinds that only affect the proxies in their namespace: the namespaces
+// and ConfigMaps holding ProxyConfig overrides. ConfigMaps in the root namespace affect all proxies.
+var namespaceScopedConfigKinds = map[config.GroupVersionKind]struct{}{
+	gvk.Namespace: {},
+	gvk.ConfigMap: {},
+}
+
+// secretAffectedProxyTypes returns the node types that are served credentialName secrets.
+func secretAffectedProxyTypes() []model.NodeType {
+	if features.EnableSidecarCredentialName {
+		return []model.NodeType{model.Router, model.SidecarProxy}
+	}
+	return []model.NodeType{model.Router}
+}
+
 // ConfigAffectsProxy checks if a pushEv will affect a specified proxy. That means whether the push will be performed
 // towards the proxy.
 func ConfigAffectsProxy(req *model.PushRequest, proxy *model.Proxy) bool {
+	affected, _ := configAffectsProxy(req, proxy)
+	return affected
+}
+
+// configAffectsProxy is ConfigAffectsProxy, also reporting whether the gateway dependencies of the proxy were
+// checked against the updated config.
+func configAffectsProxy(req *model.PushRequest, proxy *model.Proxy) (bool, bool) {
 	// Empty changes means "all" to get a backward compatibility.
 	if len(req.ConfigsUpdated) == 0 {
-		return true
+		return true, false
 	}
 
+	gatewayScoped := false
+
 	for config := range req.ConfigsUpdated {
+		if _, f := namespaceScopedConfigKinds[config.Kind]; f {
+			if config.Namespace == proxy.ConfigNamespace {
+				return true, false
+			}
+			if config.Kind == gvk.ConfigMap && req.Push != nil && config.Namespace == req.Push.Mesh.GetRootNamespace() {
+				return true, false
+			}
+			continue
+		}
+
 		affected := true
 
 		// Some configKinds only affect specific proxy types
@@ -49,12 +84,18 @@ func ConfigAffectsProxy(req *model.PushRequest, proxy *model.Proxy) bool {
 			}
 		}
 
-		if affected && checkProxyDependencies(proxy, config) {
-			return true
+		if !affected {
+			continue
+		}
+		if checkProxyDependencies(proxy, config) {
+			return true, false
+		}
+		if proxy.Type == model.Router {
+			gatewayScoped = true
 		}
 	}
 
-	return false
+	return false, gatewayScoped
 }
 
 func checkProxyDependencies(proxy *model.Proxy, config model.ConfigKey) bool {
@@ -66,6 +107,15 @@ func checkProxyDependencies(proxy *model.Proxy, config model.ConfigKey) bool {
 		} else if proxy.PrevSidecarScope != nil && proxy.PrevSidecarScope.DependsOnConfig(config) {
 			return true
 		}
+	case model.Router:
+		if !features.ScopeGatewayPushes {
+			return true
+		}
+		if proxy.GatewayDependencies.DependsOnConfig(config) {
+			return true
+		} else if proxy.PrevGatewayDependencies != nil && proxy.PrevGatewayDependencies.DependsOnConfig(config) {
+			return true
+		}
 	default:
 		// TODO We'll add the check for other proxy types later.
 		return true
@@ -75,7 +125,8 @@ func checkProxyDependencies(proxy *model.Proxy, config model.ConfigKey) bool {
 
 // DefaultProxyNeedsPush check if a proxy needs push for this push event.
 func DefaultProxyNeedsPush(proxy *model.Proxy, req *model.PushRequest) bool {
-	if ConfigAffectsProxy(req, proxy) {
+	affected, gatewayScoped := configAffectsProxy(req, proxy)
+	if affected {
 		return true
 	}
 
@@ -91,5 +142,9 @@ func DefaultProxyNeedsPush(proxy *model.Proxy, req *model.PushRequest) bool {
 		}
 	}
 
+	if gatewayScoped {
+		// Only count the pushes skipped because the gateway does not depend on the updated config.
+		skippedGatewayPushes.Increment()
+	}
 	return false
 }
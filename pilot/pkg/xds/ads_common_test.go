@@ -19,6 +19,8 @@ import (
 	"strconv"
 	"testing"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	model "istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -47,7 +49,7 @@ func TestProxyNeedsPush(t *testing.T) {
 	}
 
 	sidecar := &model.Proxy{
-		Type: model.SidecarProxy, IPAddresses: []string{"127.0.0.1"}, Metadata: &model.NodeMetadata{},
+		Type: model.SidecarProxy, IPAddresses: []string{"127.0.0.1"}, Metadata: &model.NodeMetadata{}, ConfigNamespace: nsName,
 		SidecarScope: &model.SidecarScope{Name: generalName, Namespace: nsName, RootNamespace: nsRoot},
 	}
 	gateway := &model.Proxy{Type: model.Router}
@@ -108,6 +110,15 @@ func TestProxyNeedsPush(t *testing.T) {
 			{Kind: gvk.ServiceEntry, Name: svcName + invalidNameSuffix, Namespace: nsName}:   {},
 		}, false},
 		{"empty configsUpdated for sidecar", sidecar, nil, true},
+		{"proxy config override for sidecar in same namespace", sidecar, map[model.ConfigKey]struct{}{
+			{Kind: gvk.ConfigMap, Name: generalName, Namespace: nsName}: {},
+		}, true},
+		{"proxy config override for sidecar in different namespace", sidecar, map[model.ConfigKey]struct{}{
+			{Kind: gvk.Namespace, Name: nsRoot, Namespace: nsRoot}: {},
+		}, false},
+		{"proxy config override for sidecar in root namespace", sidecar, map[model.ConfigKey]struct{}{
+			{Kind: gvk.ConfigMap, Name: generalName, Namespace: nsRoot}: {},
+		}, true},
 	}
 
 	for kind, name := range sidecarScopeKindNames {
@@ -172,9 +183,10 @@ func TestProxyNeedsPush(t *testing.T) {
 		}
 	}
 
+	push := &model.PushContext{Mesh: &meshconfig.MeshConfig{RootNamespace: nsRoot}}
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
-			got := DefaultProxyNeedsPush(tt.proxy, &model.PushRequest{ConfigsUpdated: tt.configs})
+			got := DefaultProxyNeedsPush(tt.proxy, &model.PushRequest{ConfigsUpdated: tt.configs, Push: push})
 			if got != tt.want {
 				t.Fatalf("Got needs push = %v, expected %v", got, tt.want)
 			}
@@ -182,6 +194,188 @@ func TestProxyNeedsPush(t *testing.T) {
 	}
 }
 
+const gatewayDependenciesConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: gateway
+  namespace: default
+spec:
+  selector:
+    istio: ingressgateway
+  servers:
+  - port:
+      number: 80
+      name: http
+      protocol: HTTP
+    hosts:
+    - "*"
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: services
+  namespace: default
+spec:
+  hosts:
+  - routed.example.com
+  - other.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: bound
+  namespace: default
+spec:
+  hosts:
+  - public.example.com
+  gateways:
+  - gateway
+  http:
+  - route:
+    - destination:
+        host: routed.example.com
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: mesh
+  namespace: default
+spec:
+  hosts:
+  - other.example.com
+  http:
+  - route:
+    - destination:
+        host: other.example.com
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: routed
+  namespace: default
+spec:
+  host: routed.example.com
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: other
+  namespace: default
+spec:
+  host: other.example.com
+`
+
+func TestGatewayProxyNeedsPush(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: gatewayDependenciesConfig})
+	setupGateway := func() *model.Proxy {
+		return s.SetupProxy(&model.Proxy{
+			Type:     model.Router,
+			Metadata: &model.NodeMetadata{Labels: map[string]string{"istio": "ingressgateway"}},
+		})
+	}
+	needsPush := func(proxy *model.Proxy, kind config.GroupVersionKind, name string) bool {
+		return DefaultProxyNeedsPush(proxy, &model.PushRequest{
+			ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: kind, Name: name, Namespace: "default"}: {}},
+		})
+	}
+	type Case struct {
+		kind config.GroupVersionKind
+		name string
+		want bool
+	}
+	run := func(t *testing.T, proxy *model.Proxy, cases []Case) {
+		t.Helper()
+		for _, tt := range cases {
+			if got := needsPush(proxy, tt.kind, tt.name); got != tt.want {
+				t.Errorf("%s %s: got needs push = %v, expected %v", tt.kind.Kind, tt.name, got, tt.want)
+			}
+		}
+	}
+
+	t.Run("all clusters", func(t *testing.T) {
+		run(t, setupGateway(), []Case{
+			{gvk.VirtualService, "bound", true},
+			{gvk.VirtualService, "mesh", false},
+			{gvk.Gateway, "new", true},
+			// Gateways are sent the clusters of all services
+			{gvk.ServiceEntry, "routed.example.com", true},
+			{gvk.ServiceEntry, "other.example.com", true},
+			{gvk.DestinationRule, "other", true},
+		})
+	})
+
+	t.Run("filtered clusters", func(t *testing.T) {
+		original := features.FilterGatewayClusterConfig
+		features.FilterGatewayClusterConfig = true
+		defer func() {
+			features.FilterGatewayClusterConfig = original
+		}()
+		run(t, setupGateway(), []Case{
+			{gvk.VirtualService, "bound", true},
+			{gvk.VirtualService, "mesh", false},
+			{gvk.ServiceEntry, "routed.example.com", true},
+			{gvk.ServiceEntry, "public.example.com", true},
+			{gvk.ServiceEntry, "*.example.com", true},
+			{gvk.ServiceEntry, "other.example.com", false},
+			{gvk.DestinationRule, "routed", true},
+			{gvk.DestinationRule, "other", false},
+		})
+	})
+
+	t.Run("previous dependencies", func(t *testing.T) {
+		proxy := setupGateway()
+		// The VirtualService is no longer bound to the gateway, but it must be removed from its config
+		proxy.PrevGatewayDependencies = proxy.GatewayDependencies
+		proxy.GatewayDependencies = &model.GatewayDependencies{}
+		run(t, proxy, []Case{
+			{gvk.VirtualService, "bound", true},
+		})
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		original := features.ScopeGatewayPushes
+		features.ScopeGatewayPushes = false
+		defer func() {
+			features.ScopeGatewayPushes = original
+		}()
+		proxy := setupGateway()
+		if proxy.GatewayDependencies != nil {
+			t.Errorf("expected no gateway dependencies to be computed, got %+v", proxy.GatewayDependencies)
+		}
+		run(t, proxy, []Case{
+			{gvk.VirtualService, "mesh", true},
+		})
+	})
+
+	t.Run("scoped skips", func(t *testing.T) {
+		proxy := setupGateway()
+		for _, tt := range []struct {
+			key    model.ConfigKey
+			scoped bool
+		}{
+			// Skipped because the gateway does not depend on it
+			{model.ConfigKey{Kind: gvk.VirtualService, Name: "mesh", Namespace: "default"}, true},
+			// Pushed
+			{model.ConfigKey{Kind: gvk.VirtualService, Name: "bound", Namespace: "default"}, false},
+			// Skipped regardless of the gateway dependencies
+			{model.ConfigKey{Kind: gvk.Sidecar, Name: "sidecar", Namespace: "default"}, false},
+		} {
+			_, scoped := configAffectsProxy(&model.PushRequest{
+				ConfigsUpdated: map[model.ConfigKey]struct{}{tt.key: {}},
+			}, proxy)
+			if scoped != tt.scoped {
+				t.Errorf("%s %s: got gateway scoped = %v, expected %v", tt.key.Kind.Kind, tt.key.Name, scoped, tt.scoped)
+			}
+		}
+	})
+}
+
 func BenchmarkListEquals(b *testing.B) {
 	size := 100
 	var l []string
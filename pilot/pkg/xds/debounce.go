@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"time"
+)
+
+// adaptiveDebounceStep is the smallest non-zero window. The window is widened to it from zero, which is
+// allowed by PILOT_DEBOUNCE_AFTER=0, and shrunk to the minimum below it.
+const adaptiveDebounceStep = 10 * time.Millisecond
+
+// adaptiveDebounce adjusts the delay added to events for debouncing to the push load. The delay is doubled
+// when the previous push took longer than the delay, or proxies are still waiting for it in the push queue,
+// and halved when pushes are quick and the queue is drained. It stays within [min, max], and is reset to min
+// when no push happened for a while. It is only used by the debounce goroutine, so it is not locked.
+type adaptiveDebounce struct {
+	min time.Duration
+	max time.Duration
+	// window is the current delay added to events.
+	window time.Duration
+	// pending returns the number of proxies waiting in the push queue.
+	pending func() int
+}
+
+func newAdaptiveDebounce(min, max time.Duration, pending func() int) *adaptiveDebounce {
+	if max < min {
+		max = min
+	}
+	a := &adaptiveDebounce{
+		min:     min,
+		max:     max,
+		window:  min,
+		pending: pending,
+	}
+	debounceWindow.Record(min.Seconds())
+	return a
+}
+
+// update adjusts the window before a push, based on the duration of the previous push and the proxies
+// still waiting for it. The duration only covers the PushContext initialization and the enqueueing of the
+// proxies, not the delivery to them, which is accounted for by the proxies still in the queue.
+func (a *adaptiveDebounce) update(lastPushDuration time.Duration) {
+	pending := a.pending()
+	switch {
+	case lastPushDuration > a.window || pending > 0:
+		window := a.window * 2
+		if window < adaptiveDebounceStep {
+			window = adaptiveDebounceStep
+		}
+		a.set(window)
+	case lastPushDuration < a.window/2:
+		window := a.window / 2
+		if window < adaptiveDebounceStep {
+			window = a.min
+		}
+		a.set(window)
+	}
+}
+
+// reset shrinks the window back to its minimum, when the pushes are idle.
+func (a *adaptiveDebounce) reset() {
+	a.set(a.min)
+}
+
+func (a *adaptiveDebounce) set(window time.Duration) {
+	if window < a.min {
+		window = a.min
+	}
+	if window > a.max {
+		window = a.max
+	}
+	if window != a.window {
+		log.Infof("Push debounce window changed from %v to %v", a.window, window)
+	}
+	a.window = window
+	debounceWindow.Record(window.Seconds())
+}
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -32,6 +33,7 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 
 	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/config/monitor"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -40,7 +42,10 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/kube/secretcontroller"
 	istiolog "istio.io/pkg/log"
 )
 
@@ -113,6 +118,12 @@ type SyncStatus struct {
 	RouteAcked    string `json:"route_acked,omitempty"`
 	EndpointSent  string `json:"endpoint_sent,omitempty"`
 	EndpointAcked string `json:"endpoint_acked,omitempty"`
+	// The *Nacked fields hold the nonce of the last response the proxy rejected, if it has not ACKed a
+	// later one since.
+	ClusterNacked  string `json:"cluster_nacked,omitempty"`
+	ListenerNacked string `json:"listener_nacked,omitempty"`
+	RouteNacked    string `json:"route_nacked,omitempty"`
+	EndpointNacked string `json:"endpoint_nacked,omitempty"`
 }
 
 // SyncedVersions shows what resourceVersion of a given resource has been acked by Envoy.
@@ -177,7 +188,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling b
 	s.addDebugHandler(mux, "/debug/endpointz", "Debug support for endpoints", s.endpointz)
 	s.addDebugHandler(mux, "/debug/endpointShardz", "Info about the endpoint shards", s.endpointShardz)
 	s.addDebugHandler(mux, "/debug/cachez", "Info about the internal XDS caches", s.cachez)
-	s.addDebugHandler(mux, "/debug/configz", "Debug support for config", s.configz)
+	s.addDebugHandler(mux, "/debug/configz", "Debug support for config, with ?rejected=true listing the rejected config files", s.configz)
+	s.addDebugHandler(mux, "/debug/config_sourcez", "Status of the git config sources", s.configSourcez)
 	s.addDebugHandler(mux, "/debug/sidecarz", "Debug sidecar scope for a proxy", s.sidecarz)
 	s.addDebugHandler(mux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
@@ -190,8 +202,11 @@ func (s *DiscoveryServer) AddDebugHandlers(mux *http.ServeMux, enableProfiling b
 	s.addDebugHandler(mux, "/debug/connections", "Info about the connected XDS clients", s.ConnectionsHandler)
 
 	s.addDebugHandler(mux, "/debug/inject", "Active inject template", s.InjectTemplateHandler(webhook))
-	s.addDebugHandler(mux, "/debug/mesh", "Active mesh config", s.MeshHandler)
-	s.addDebugHandler(mux, "/debug/networkz", "List cross-network gateways", s.networkz)
+	s.addDebugHandler(mux, "/debug/mesh", "Active mesh config, with ?status=true including rejected updates", s.MeshHandler)
+	s.addDebugHandler(mux, "/debug/networkz", "List cross-network gateways and their weights", s.networkz)
+	s.addDebugHandler(mux, "/debug/clusterz", "Health of the remote clusters", s.clusterz)
+	s.addDebugHandler(mux, "/debug/proxyconfigz", "Effective ProxyConfig of a proxy and the overrides it is resolved from", s.proxyConfigz)
+	s.addDebugHandler(mux, "/debug/push_cost", "The connections and configs with the most pushed bytes, with ?top=N and ?proxyID=", s.pushCostz)
 
 	s.addDebugHandler(mux, "/debug/list", "List all supported debug commands in json", s.List)
 }
@@ -219,6 +234,11 @@ func (s *DiscoveryServer) Syncz(w http.ResponseWriter, _ *http.Request) {
 				RouteAcked:    con.NonceAcked(v3.RouteType),
 				EndpointSent:  con.NonceSent(v3.EndpointType),
 				EndpointAcked: con.NonceAcked(v3.EndpointType),
+
+				ClusterNacked:  con.NonceNacked(v3.ClusterType),
+				ListenerNacked: con.NonceNacked(v3.ListenerType),
+				RouteNacked:    con.NonceNacked(v3.RouteType),
+				EndpointNacked: con.NonceNacked(v3.EndpointType),
 			})
 		}
 	}
@@ -401,6 +421,10 @@ func (k kubernetesConfig) MarshalJSON() ([]byte, error) {
 
 // Config debugging.
 func (s *DiscoveryServer) configz(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Query().Get("rejected") == "true" {
+		s.rejectedConfigz(w)
+		return
+	}
 	configs := []kubernetesConfig{}
 	s.Env.IstioConfigStore.Schemas().ForEach(func(schema collection.Schema) bool {
 		cfg, _ := s.Env.IstioConfigStore.List(schema.Resource().GroupVersionKind(), "")
@@ -419,6 +443,38 @@ func (s *DiscoveryServer) configz(w http.ResponseWriter, req *http.Request) {
 	_, _ = w.Write(b)
 }
 
+// rejectedConfigz lists the errors of the config files rejected because of invalid config.
+func (s *DiscoveryServer) rejectedConfigz(w http.ResponseWriter) {
+	rejected := make([]monitor.FileError, 0)
+	for _, src := range s.FileConfigSources {
+		rejected = append(rejected, src.RejectedFiles()...)
+	}
+	b, err := json.MarshalIndent(rejected, "  ", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// Config source debugging.
+func (s *DiscoveryServer) configSourcez(w http.ResponseWriter, _ *http.Request) {
+	sources := make([]monitor.GitStatus, 0, len(s.GitConfigSources))
+	for _, src := range s.GitConfigSources {
+		sources = append(sources, src.Status())
+	}
+	b, err := json.MarshalIndent(sources, "  ", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
 // SidecarScope debugging
 func (s *DiscoveryServer) sidecarz(w http.ResponseWriter, req *http.Request) {
 	proxyID := req.URL.Query().Get("proxyID")
@@ -693,11 +749,42 @@ func (s *DiscoveryServer) InjectTemplateHandler(webhook func() map[string]string
 	}
 }
 
-// MeshHandler dumps the mesh config
+// MeshConfigStatus is the mesh config along with the state of its updates.
+type MeshConfigStatus struct {
+	Mesh json.RawMessage `json:"mesh"`
+	// Pending is set if the mesh config has been applied, but may still be rolled back.
+	Pending bool `json:"pending"`
+	// LastRejection is the most recently rejected or rolled back mesh config update.
+	LastRejection *mesh.Rejection `json:"lastRejection,omitempty"`
+}
+
+// MeshHandler dumps the mesh config. With ?status=true, the pending and last rejected updates are
+// included as well.
 func (s *DiscoveryServer) MeshHandler(w http.ResponseWriter, r *http.Request) {
-	if err := (&jsonpb.Marshaler{Indent: "  "}).Marshal(w, s.Env.Mesh()); err != nil {
+	if r.URL.Query().Get("status") != "true" {
+		if err := (&jsonpb.Marshaler{Indent: "  "}).Marshal(w, s.Env.Mesh()); err != nil {
+			w.WriteHeader(500)
+		}
+		return
+	}
+
+	meshJSON, err := (&jsonpb.Marshaler{}).MarshalToString(s.Env.Mesh())
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+	status := MeshConfigStatus{Mesh: json.RawMessage(meshJSON)}
+	if watcher, ok := s.Env.Watcher.(mesh.StagedWatcher); ok {
+		status.Pending = watcher.Pending()
+		status.LastRejection = watcher.LastRejection()
+	}
+	out, err := json.MarshalIndent(&status, "", "  ")
+	if err != nil {
 		w.WriteHeader(500)
+		return
 	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
 }
 
 // PushStatusHandler dumps the last PushContext
@@ -810,10 +897,6 @@ func (s *DiscoveryServer) Ndsz(w http.ResponseWriter, req *http.Request) {
 		_, _ = w.Write([]byte("You must provide a proxyID in the query string"))
 		return
 	}
-	if _, err := w.Write([]byte("[")); err != nil {
-		return
-	}
-
 	if s.Generators[v3.NameTableType] != nil {
 		nds, _ := s.Generators[v3.NameTableType].Generate(con.proxy, s.globalPushContext(), nil, nil)
 		if len(nds) == 0 {
@@ -914,8 +997,79 @@ func (s *DiscoveryServer) instancesz(w http.ResponseWriter, req *http.Request) {
 	_, _ = w.Write(by)
 }
 
+// clusterz dumps the health of the remote clusters.
+func (s *DiscoveryServer) clusterz(w http.ResponseWriter, req *http.Request) {
+	status := []secretcontroller.ClusterStatus{}
+	if s.RemoteClusterStatus != nil {
+		if out := s.RemoteClusterStatus(); out != nil {
+			status = out
+		}
+	}
+	by, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(by)
+}
+
+// ProxyConfigStatus is the effective ProxyConfig of a proxy and the overrides of the mesh default it is
+// resolved from, in the order they are merged.
+type ProxyConfigStatus struct {
+	ProxyConfig json.RawMessage             `json:"proxyConfig"`
+	Overrides   []model.ProxyConfigOverride `json:"overrides"`
+}
+
+// proxyConfigz shows the effective ProxyConfig of the proxy with the given ID. Proxies only pick up
+// changes when restarted, or re-injected for sidecars.
+func (s *DiscoveryServer) proxyConfigz(w http.ResponseWriter, req *http.Request) {
+	proxyID := req.URL.Query().Get("proxyID")
+	if proxyID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a proxyID in the query string"))
+		return
+	}
+	con := s.getProxyConnection(proxyID)
+	if con == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("Proxy not connected to this Pilot instance. It may be connected to another instance."))
+		return
+	}
+
+	var workload labels.Instance
+	if con.proxy.Metadata != nil {
+		workload = con.proxy.Metadata.Labels
+	}
+	push := s.globalPushContext()
+	pc, err := push.EffectiveProxyConfig(con.proxy)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprintf(w, "unable to resolve proxy config: %v", err)
+		return
+	}
+	pcJSON, err := (&jsonpb.Marshaler{}).MarshalToString(pc)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	status := ProxyConfigStatus{
+		ProxyConfig: json.RawMessage(pcJSON),
+		Overrides:   push.ProxyConfigs.Overrides(con.proxy.ConfigNamespace, push.Mesh.GetRootNamespace(), workload),
+	}
+	out, err := json.MarshalIndent(&status, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// networkz lists the cross-network gateways of each network, with the weights used to split the traffic
+// between them. Unhealthy gateways are listed, but do not receive traffic.
 func (s *DiscoveryServer) networkz(w http.ResponseWriter, req *http.Request) {
-	gws := s.Env.NetworkGateways()
+	gws := s.globalPushContext().NetworkGateways()
 	by, err := json.MarshalIndent(gws, "", "  ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -925,3 +1079,33 @@ func (s *DiscoveryServer) networkz(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+// pushCostz lists the connections with the most pushed bytes, with their generation time and cache hit ratio
+// per type, and the services and VirtualServices the most bytes were attributed to. It is only available if
+// PILOT_PUSH_COST_ATTRIBUTION is enabled. The number of connections and configs listed defaults to 10, and can be
+// set with ?top=N.
+func (s *DiscoveryServer) pushCostz(w http.ResponseWriter, req *http.Request) {
+	top := 10
+	if t := req.URL.Query().Get("top"); t != "" {
+		n, err := strconv.Atoi(t)
+		if err != nil || n <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "invalid top %q, it must be a positive number", t)
+			return
+		}
+		top = n
+	}
+	if s.pushCost == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprint(w, "push cost tracking is disabled, enable it with PILOT_PUSH_COST_ATTRIBUTION")
+		return
+	}
+	status := s.pushCost.status(top, req.URL.Query().Get("proxyID"))
+	out, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
@@ -17,6 +17,7 @@ package xds
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
@@ -145,6 +146,14 @@ func (s *DiscoveryServer) StreamDeltas(stream DeltaDiscoveryStream) error {
 func (s *DiscoveryServer) pushConnectionDelta(con *Connection, pushEv *Event) error {
 	pushRequest := pushEv.pushRequest
 
+	con.pushSpan = pushRequest.Span.StartChild("push "+con.ConID, pushRequest.Start)
+	con.pushSpan.StartChild("queue", pushRequest.Start).Finish()
+	defer func() {
+		con.pushSpan.Finish()
+		con.pushSpan = nil
+		pushRequest.Span.Release()
+	}()
+
 	if pushRequest.Full {
 		// Update Proxy with current information.
 		s.updateProxy(con.proxy, pushRequest)
@@ -152,6 +161,7 @@ func (s *DiscoveryServer) pushConnectionDelta(con *Connection, pushEv *Event) er
 
 	if !s.ProxyNeedsPush(con.proxy, pushRequest) {
 		log.Debugf("Skipping push to %v, no updates required", con.ConID)
+		con.pushSpan.Tag("skipped", "true")
 		if pushRequest.Full {
 			// Only report for full versions, incremental pushes do not have a new version
 			reportAllEvents(s.StatusReporter, con.ConID, pushRequest.Push.LedgerVersion, nil)
@@ -193,6 +203,8 @@ func (s *DiscoveryServer) pushConnectionDelta(con *Connection, pushEv *Event) er
 			totalDelayedPushes.With(typeTag.Value(v3.GetMetricType(w.TypeUrl))).Increment()
 			log.Debugf("%s: QUEUE for node:%s", v3.GetShortType(w.TypeUrl), con.proxy.ID)
 			con.proxy.Lock()
+			// Released once the blocked push is sent, or when the connection is closed.
+			pushEv.pushRequest.Span.Hold()
 			con.blockedPushes[w.TypeUrl] = con.blockedPushes[w.TypeUrl].Merge(pushEv.pushRequest)
 			con.proxy.Unlock()
 		}
@@ -303,6 +315,9 @@ func (s *DiscoveryServer) processDeltaRequest(req *discovery.DeltaDiscoveryReque
 	request, haveBlockedPush := con.blockedPushes[req.TypeUrl]
 	delete(con.blockedPushes, req.TypeUrl)
 	con.proxy.Unlock()
+	if haveBlockedPush {
+		defer request.Span.Release()
+	}
 
 	if shouldRespond {
 		debugRequest(req)
@@ -427,7 +442,18 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 
 	t0 := time.Now()
 
+	span := con.pushSpan
+	if span == nil {
+		span = req.Span
+	}
+	genSpan := span.StartChild("generate "+v3.GetShortType(w.TypeUrl), t0)
 	res, err := gen.Generate(con.proxy, push, w, req)
+	generation := time.Since(t0)
+	if err != nil {
+		genSpan.Tag("error", err.Error())
+	}
+	s.recordGenerateResult(con, push, err)
+	genSpan.Tag("resources", strconv.Itoa(len(res))).Finish()
 	if err != nil || res == nil {
 		// If we have nothing to send, report that we got an ACK for this version.
 		if s.StatusReporter != nil {
@@ -436,6 +462,7 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 		return err
 	}
 	defer func() { recordPushTime(w.TypeUrl, time.Since(t0)) }()
+	s.recordPushCost(con, push, w.TypeUrl, res, ResourceSize(res), generation)
 
 	deltaResponse := convertResponseToDelta(currentVersion, res)
 	originalResponse := deltaResponse
@@ -474,10 +501,13 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection, push *model.PushContext,
 		con.proxy.Unlock()
 	}
 
+	sendSpan := span.StartChild("send "+v3.GetShortType(w.TypeUrl), time.Now())
 	if err := con.sendDelta(resp); err != nil {
+		sendSpan.Tag("error", err.Error()).Finish()
 		recordSendError(w.TypeUrl, con.ConID, err)
 		return err
 	}
+	sendSpan.Finish()
 
 	// Some types handle logs inside Generate, skip them here
 	// TODO because we filter out after the fact, SkipLogTypes report wrong info
@@ -15,7 +15,9 @@
 package xds
 
 import (
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +26,7 @@ import (
 	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 
+	"istio.io/istio/pilot/pkg/config/monitor"
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
@@ -33,8 +36,10 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pilot/pkg/tracing"
 	"istio.io/istio/pilot/pkg/util/sets"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/kube/secretcontroller"
 	"istio.io/istio/pkg/security"
 )
 
@@ -64,6 +69,17 @@ type debounceOptions struct {
 
 	// enableEDSDebounce indicates whether EDS pushes should be debounced.
 	enableEDSDebounce bool
+
+	// adaptive, if set, replaces debounceAfter with a delay adjusted to the push load.
+	adaptive *adaptiveDebounce
+}
+
+// quietPeriod returns the delay added to events for debouncing.
+func (o debounceOptions) quietPeriod() time.Duration {
+	if o.adaptive != nil {
+		return o.adaptive.window
+	}
+	return o.debounceAfter
 }
 
 // DiscoveryServer is Pilot's gRPC implementation for Envoy's xds APIs
@@ -131,6 +147,12 @@ type DiscoveryServer struct {
 	StatusGen               *StatusGen
 	WorkloadEntryController *workloadentry.Controller
 
+	// GitConfigSources are the git config sources, reported on the debug interface.
+	GitConfigSources []*monitor.GitSnapshot
+
+	// FileConfigSources are the file config sources, whose rejected files are reported on the debug interface.
+	FileConfigSources []*monitor.FileSnapshot
+
 	// serverReady indicates caches have been synced up and server is ready to process requests.
 	serverReady atomic.Bool
 
@@ -141,8 +163,18 @@ type DiscoveryServer struct {
 	// Cache for XDS resources
 	Cache model.XdsCache
 
+	// pushCost keeps statistics of the pushes to each connection, reported on /debug/push_cost. It is nil unless
+	// PILOT_PUSH_COST_ATTRIBUTION or PILOT_PUSH_COST_METRICS is enabled, as it is updated on every push.
+	pushCost *pushCostTracker
+
 	// JwtKeyResolver holds a reference to the JWT key resolver instance.
 	JwtKeyResolver *model.JwksResolver
+
+	// meshCommitter commits mesh config updates that were pushed successfully.
+	meshCommitter meshConfigCommitter
+
+	// RemoteClusterStatus returns the health of the remote clusters, if there are any.
+	RemoteClusterStatus func() []secretcontroller.ClusterStatus
 }
 
 // EndpointShards holds the set of endpoint shards of a service. Registries update
@@ -188,6 +220,16 @@ func NewDiscoveryServer(env *model.Environment, plugins []string, instanceID str
 		instanceID: instanceID,
 	}
 
+	if features.EnablePushCostAttribution || features.EnablePushCostMetrics {
+		out.pushCost = newPushCostTracker()
+	}
+
+	if features.EnableAdaptiveDebounce {
+		out.debounceOptions.adaptive = newAdaptiveDebounce(features.DebounceAfter, features.AdaptiveDebounceMax, out.pushQueue.Pending)
+	} else {
+		debounceWindow.Record(features.DebounceAfter.Seconds())
+	}
+
 	out.initJwksResolver()
 
 	out.initGenerators(env, systemNameSpace)
@@ -289,6 +331,9 @@ func (s *DiscoveryServer) periodicRefreshMetrics(stopCh <-chan struct{}) {
 				}
 			}
 			model.LastPushMutex.Unlock()
+			if features.EnablePushCostMetrics {
+				s.pushCost.recordMetrics()
+			}
 		case <-stopCh:
 			return
 		}
@@ -298,6 +343,9 @@ func (s *DiscoveryServer) periodicRefreshMetrics(stopCh <-chan struct{}) {
 // Push is called to push changes on config updates using ADS. This is set in DiscoveryService.Push,
 // to avoid direct dependencies.
 func (s *DiscoveryServer) Push(req *model.PushRequest) {
+	// The trace ends once the push of every connection is done, or right away if none is pushed.
+	req.Span.Hold()
+	defer req.Span.Release()
 	if !req.Full {
 		req.Push = s.globalPushContext()
 		s.AdsPushAll(versionInfo(), req)
@@ -313,10 +361,16 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	t0 := time.Now()
 
 	versionLocal := time.Now().Format(time.RFC3339) + "/" + strconv.FormatUint(versionNum.Inc(), 10)
+	initSpan := req.Span.StartChild("init_push_context", t0).Tag("version", versionLocal)
 	push, err := s.initPushContext(req, oldPushContext, versionLocal)
 	if err != nil {
+		initSpan.Tag("error", err.Error()).Finish()
+		s.rollbackMeshConfig(s.Env.Mesh(), versionLocal, err)
 		return
 	}
+	s.scheduleMeshConfigCommit(push)
+	s.pushCost.prune(push, req.ConfigsUpdated)
+	initSpan.Finish()
 
 	initContextTime := time.Since(t0)
 	log.Debugf("InitContext %v for push took %s", versionLocal, initContextTime)
@@ -368,6 +422,10 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	var timeChan <-chan time.Time
 	var startDebounce time.Time
 	var lastConfigUpdateTime time.Time
+	// lastPushDuration is the duration of the last pushFn call. For full pushes, this is the PushContext
+	// initialization and the enqueueing of the proxies; the pushes to the proxies happen afterwards.
+	var lastPushDuration time.Duration
+	var lastPushTime time.Time
 
 	pushCounter := 0
 	debouncedEvents := 0
@@ -376,39 +434,48 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	var req *model.PushRequest
 
 	free := true
-	freeCh := make(chan struct{}, 1)
+	// freeCh receives the duration of the push when it is done.
+	freeCh := make(chan time.Duration, 1)
 
 	push := func(req *model.PushRequest, debouncedEvents int) {
+		t0 := time.Now()
 		pushFn(req)
 		updateSent.Add(int64(debouncedEvents))
-		freeCh <- struct{}{}
+		freeCh <- time.Since(t0)
 	}
 
 	pushWorker := func() {
 		eventDelay := time.Since(startDebounce)
 		quietTime := time.Since(lastConfigUpdateTime)
 		// it has been too long or quiet enough
-		if eventDelay >= opts.debounceMax || quietTime >= opts.debounceAfter {
+		if eventDelay >= opts.debounceMax || quietTime >= opts.quietPeriod() {
 			if req != nil {
+				if opts.adaptive != nil {
+					// The new window applies to the next pushes.
+					opts.adaptive.update(lastPushDuration)
+				}
 				pushCounter++
 				log.Infof("Push debounce stable[%d] %d: %v since last change, %v since last push, full=%v",
 					pushCounter, debouncedEvents,
 					quietTime, eventDelay, req.Full)
 
 				free = false
+				startPushSpan(req, startDebounce)
+				req.Span.StartChild("debounce", startDebounce).Tag("events", strconv.Itoa(debouncedEvents)).Finish()
 				go push(req, debouncedEvents)
 				req = nil
 				debouncedEvents = 0
 			}
 		} else {
-			timeChan = time.After(opts.debounceAfter - quietTime)
+			timeChan = time.After(opts.quietPeriod() - quietTime)
 		}
 	}
 
 	for {
 		select {
-		case <-freeCh:
+		case lastPushDuration = <-freeCh:
 			free = true
+			lastPushTime = time.Now()
 			pushWorker()
 		case r := <-ch:
 			// If reason is not set, record it as an unknown reason
@@ -417,13 +484,18 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 			}
 			if !opts.enableEDSDebounce && !r.Full {
 				// trigger push now, just for EDS
+				startPushSpan(r, time.Now())
 				go pushFn(r)
 				continue
 			}
 
 			lastConfigUpdateTime = time.Now()
 			if debouncedEvents == 0 {
-				timeChan = time.After(opts.debounceAfter)
+				if opts.adaptive != nil && free && lastConfigUpdateTime.Sub(lastPushTime) >= opts.debounceMax {
+					// Pushes have been idle, so the load of the last push no longer applies.
+					opts.adaptive.reset()
+				}
+				timeChan = time.After(opts.quietPeriod())
 				startDebounce = lastConfigUpdateTime
 			}
 			debouncedEvents++
@@ -439,6 +511,33 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	}
 }
 
+// maxTracedConfigs limits the number of config keys recorded in a push span.
+const maxTracedConfigs = 50
+
+// startPushSpan starts the trace of a push request, tagged with what triggered the push.
+func startPushSpan(req *model.PushRequest, start time.Time) {
+	req.Span = tracing.StartSpan("push", start)
+	if req.Span == nil {
+		return
+	}
+	reasons := make([]string, 0, len(req.Reason))
+	for _, r := range req.Reason {
+		reasons = append(reasons, string(r))
+	}
+	configs := make([]string, 0, len(req.ConfigsUpdated))
+	for key := range req.ConfigsUpdated {
+		if len(configs) == maxTracedConfigs {
+			configs = append(configs, "...")
+			break
+		}
+		configs = append(configs, key.Kind.Kind+"/"+key.Namespace+"/"+key.Name)
+	}
+	sort.Strings(configs)
+	req.Span.Tag("reason", strings.Join(reasons, ",")).
+		Tag("full", strconv.FormatBool(req.Full)).
+		Tag("configs", strings.Join(configs, ","))
+}
+
 func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQueue) {
 	for {
 		select {
@@ -461,7 +560,6 @@ func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQu
 				<-semaphore
 			}
 
-			proxiesQueueTime.Record(time.Since(push.Start).Seconds())
 			var closed <-chan struct{}
 			if client.stream != nil {
 				closed = client.stream.Context().Done()
@@ -479,6 +577,7 @@ func doSendPushes(stopCh <-chan struct{}, semaphore chan struct{}, queue *PushQu
 					return
 				case <-closed: // grpc stream was closed
 					doneFunc()
+					push.Span.Release()
 					log.Infof("Client closed connection %v", client.ConID)
 				}
 			}()
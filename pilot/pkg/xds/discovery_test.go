@@ -482,3 +482,53 @@ func TestShouldRespond(t *testing.T) {
 		})
 	}
 }
+
+func TestAdaptiveDebounce(t *testing.T) {
+	pending := 0
+	a := newAdaptiveDebounce(100*time.Millisecond, time.Second, func() int { return pending })
+	expect := func(want time.Duration) {
+		t.Helper()
+		if a.window != want {
+			t.Fatalf("expected a window of %v, got %v", want, a.window)
+		}
+	}
+	expect(100 * time.Millisecond)
+
+	// Slow pushes widen the window
+	a.update(300 * time.Millisecond)
+	expect(200 * time.Millisecond)
+
+	// Proxies waiting for the previous push widen the window, up to the max
+	pending = 5
+	a.update(0)
+	expect(400 * time.Millisecond)
+	a.update(0)
+	expect(800 * time.Millisecond)
+	a.update(0)
+	expect(time.Second)
+
+	// Quick pushes shrink the window
+	pending = 0
+	a.update(10 * time.Millisecond)
+	expect(500 * time.Millisecond)
+	// Pushes taking about the window keep it
+	a.update(400 * time.Millisecond)
+	expect(500 * time.Millisecond)
+
+	a.reset()
+	expect(100 * time.Millisecond)
+	a.update(10 * time.Millisecond)
+	expect(100 * time.Millisecond)
+
+	// A zero minimum, with PILOT_DEBOUNCE_AFTER=0, is still widened
+	a = newAdaptiveDebounce(0, time.Second, func() int { return pending })
+	expect(0)
+	a.update(50 * time.Millisecond)
+	expect(adaptiveDebounceStep)
+	a.update(50 * time.Millisecond)
+	expect(2 * adaptiveDebounceStep)
+	a.update(0)
+	expect(adaptiveDebounceStep)
+	a.update(0)
+	expect(0)
+}
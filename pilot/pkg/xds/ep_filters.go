@@ -15,6 +15,7 @@
 package xds
 
 import (
+	"math"
 	"net"
 
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
@@ -99,15 +100,23 @@ func (b *EndpointBuilder) EndpointsByNetworkFilter(endpoints []*LocLbEndpointsAn
 		for network, w := range remoteEps {
 			gateways := b.push.NetworkGatewaysByNetwork(network)
 
-			gatewayNum := len(gateways)
-			weight := w * uint32(multiples/gatewayNum)
+			// The traffic is split between the healthy gateways in proportion to their weight.
+			var totalWeight uint32
+			for _, gw := range gateways {
+				if isUsableGateway(gw) {
+					totalWeight += gw.Weight
+				}
+			}
 
 			// There may be multiples gateways for one network. Add each gateway as an endpoint.
 			for _, gw := range gateways {
-				if net.ParseIP(gw.Addr) == nil {
-					// this is a gateway with hostname in it. skip this gateway as EDS can't take hostnames
+				if !isUsableGateway(gw) {
 					continue
 				}
+				weight := uint32(math.Round(float64(w) * float64(multiples) * float64(gw.Weight) / float64(totalWeight)))
+				if weight == 0 {
+					weight = 1
+				}
 				epAddr := util.BuildAddress(gw.Addr, gw.Port)
 				gwEp := &endpoint.LbEndpoint{
 					HostIdentifier: &endpoint.LbEndpoint_Endpoint{
@@ -134,6 +143,12 @@ func (b *EndpointBuilder) EndpointsByNetworkFilter(endpoints []*LocLbEndpointsAn
 	return filtered
 }
 
+// isUsableGateway returns false for the unhealthy gateways and the gateways with hostname in them, as EDS can't
+// take hostnames.
+func isUsableGateway(gw *model.Gateway) bool {
+	return !gw.Unhealthy && gw.Weight > 0 && net.ParseIP(gw.Addr) != nil
+}
+
 // TODO: remove this, filtering should be done before generating the config, and
 // network metadata should not be included in output. A node only receives endpoints
 // in the same network as itself - so passing an network meta, with exactly
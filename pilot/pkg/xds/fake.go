@@ -59,12 +59,17 @@ type FakeOptions struct {
 	KubernetesObjects []runtime.Object
 	// If provided, the yaml string will be parsed and used as objects for the default cluster ("Kubernetes")
 	KubernetesObjectString string
+	// If provided, this client is used for the default cluster ("Kubernetes") instead of a new fake client
+	// holding the Kubernetes objects.
+	KubeClient kubelib.ExtendedClient
 	// Endpoint mode for the Kubernetes service registry
 	KubernetesEndpointMode kube.EndpointMode
 	// If provided, these configs will be used directly
 	Configs []config.Config
 	// If provided, the yaml string will be parsed and used as configs
 	ConfigString string
+	// If provided, the configs in these stores will be served in addition to Configs and ConfigString
+	ConfigStoreCaches []model.ConfigStoreCache
 	// If provided, the ConfigString will be treated as a go template, with this as input params
 	ConfigTemplateInput interface{}
 	// If provided, this mesh config will be used
@@ -135,6 +140,9 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	}
 	for cluster, objs := range k8sObjects {
 		client := kubelib.NewFakeClient(objs...)
+		if cluster == "Kubernetes" && opts.KubeClient != nil {
+			client = opts.KubeClient
+		}
 		k8s, _ := kube.NewFakeControllerWithOptions(kube.FakeControllerOptions{
 			ServiceHandler:  serviceHandler,
 			Client:          client,
@@ -158,7 +166,7 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 	}
 
 	sc := kubesecrets.NewMulticluster(defaultKubeClient, "", "", stop)
-	s.Generators[v3.SecretType] = NewSecretGen(sc, &model.DisabledCache{})
+	s.Generators[v3.SecretType] = NewSecretGen(sc, &model.DisabledCache{}, s)
 	defaultKubeClient.RunAndWait(stop)
 
 	ingr := ingress.NewController(defaultKubeClient, mesh.NewFixedWatcher(m), kube.Options{
@@ -174,7 +182,7 @@ func NewFakeDiscoveryServer(t test.Failer, opts FakeOptions) *FakeDiscoveryServe
 		NetworksWatcher:     opts.NetworksWatcher,
 		ServiceRegistries:   registries,
 		PushContextLock:     &s.updateMutex,
-		ConfigStoreCaches:   []model.ConfigStoreCache{ingr},
+		ConfigStoreCaches:   append([]model.ConfigStoreCache{ingr}, opts.ConfigStoreCaches...),
 		SkipRun:             true,
 	})
 	cg.ServiceEntryRegistry.AppendServiceHandler(serviceHandler)
@@ -16,12 +16,14 @@ package xds
 
 import (
 	"encoding/json"
+	"strconv"
 	"strings"
 	"time"
 
 	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
@@ -105,7 +107,19 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 
 	t0 := time.Now()
 
+	// Blocked pushes, sent when the previous response is ACKed, are traced directly under the push
+	span := con.pushSpan
+	if span == nil {
+		span = req.Span
+	}
+	genSpan := span.StartChild("generate "+v3.GetShortType(w.TypeUrl), t0)
 	res, err := gen.Generate(con.proxy, push, w, req)
+	generation := time.Since(t0)
+	if err != nil {
+		genSpan.Tag("error", err.Error())
+	}
+	s.recordGenerateResult(con, push, err)
+	genSpan.Tag("resources", strconv.Itoa(len(res))).Finish()
 	if err != nil || res == nil {
 		// If we have nothing to send, report that we got an ACK for this version.
 		if s.StatusReporter != nil {
@@ -125,11 +139,16 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 
 	configSize := ResourceSize(res)
 	configSizeBytes.With(typeTag.Value(w.TypeUrl)).Record(float64(configSize))
+	s.recordPushCost(con, push, w.TypeUrl, res, configSize, generation)
 
+	sendSpan := span.StartChild("send "+v3.GetShortType(w.TypeUrl), time.Now()).Tag("bytes", strconv.Itoa(configSize))
 	if err := con.send(resp); err != nil {
+		sendSpan.Tag("error", err.Error()).Finish()
 		recordSendError(w.TypeUrl, con.ConID, err)
 		return err
 	}
+	sendSpan.Finish()
+	con.startAckSpan(span, resp)
 
 	// Some types handle logs inside Generate, skip them here
 	if _, f := SkipLogTypes[w.TypeUrl]; !f {
@@ -146,6 +165,15 @@ func (s *DiscoveryServer) pushXds(con *Connection, push *model.PushContext,
 	return nil
 }
 
+// recordPushCost records the cost of the push to the connection for /debug/push_cost.
+func (s *DiscoveryServer) recordPushCost(con *Connection, push *model.PushContext, typeURL string,
+	res model.Resources, size int, generation time.Duration) {
+	s.pushCost.recordPush(con.proxy, typeURL, res, size, generation)
+	if features.EnablePushCostAttribution {
+		s.pushCost.attribute(con.proxy, push, typeURL, res)
+	}
+}
+
 func ResourceSize(r model.Resources) int {
 	// Approximate size by looking at the Any marshaled size. This avoids high cost
 	// proto.Size, at the expense of slightly under counting.
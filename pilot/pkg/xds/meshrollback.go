@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+// meshConfigCommitter commits pending mesh config updates once they have been pushed without errors for
+// features.MeshConfigRollbackWindow. Until then, push failures roll them back.
+type meshConfigCommitter struct {
+	mu sync.Mutex
+	// scheduled is the pending mesh config a commit is scheduled for.
+	scheduled *meshconfig.MeshConfig
+	// failed holds the connections generators returned errors for with the pending mesh config failedMesh.
+	failedMesh *meshconfig.MeshConfig
+	failed     map[string]struct{}
+}
+
+// pendingMeshConfig returns the mesh watcher if it holds a mesh config that can still be rolled back.
+func (s *DiscoveryServer) pendingMeshConfig() mesh.StagedWatcher {
+	if !features.EnableMeshConfigRollback || s.Env == nil {
+		return nil
+	}
+	w, ok := s.Env.Watcher.(mesh.StagedWatcher)
+	if !ok || !w.Pending() {
+		return nil
+	}
+	return w
+}
+
+// scheduleMeshConfigCommit commits the pending mesh config the push was built from, unless pushing it fails
+// within the rollback window.
+func (s *DiscoveryServer) scheduleMeshConfigCommit(push *model.PushContext) {
+	w := s.pendingMeshConfig()
+	if w == nil || push.Mesh != w.Mesh() {
+		return
+	}
+	c := &s.meshCommitter
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.scheduled == push.Mesh {
+		return
+	}
+	c.scheduled = push.Mesh
+	time.AfterFunc(features.MeshConfigRollbackWindow, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.scheduled != push.Mesh {
+			return
+		}
+		c.scheduled = nil
+		c.failedMesh, c.failed = nil, nil
+		// The mesh config may have been rolled back or replaced in the meantime; a newer update is
+		// committed by its own push.
+		if w.Mesh() == push.Mesh {
+			w.Commit()
+		}
+	})
+}
+
+// rollbackMeshConfig rolls back the pending mesh config if pushing it failed. meshConfig is the mesh config
+// the failed push was built from.
+func (s *DiscoveryServer) rollbackMeshConfig(meshConfig *meshconfig.MeshConfig, version string, err error) {
+	w := s.pendingMeshConfig()
+	if w == nil || meshConfig != w.Mesh() {
+		return
+	}
+	if w.Rollback(fmt.Sprintf("push %s failed: %v", version, err)) {
+		log.Warnf("rolled back mesh config after push %s failed: %v", version, err)
+	}
+}
+
+// recordGenerateResult rolls back the pending mesh config once generating config from it failed for
+// features.MeshConfigRollbackFailedProxyRatio of the connected proxies. Connections that already failed
+// with the committed mesh config are not counted, as the update is not the cause of their errors.
+func (s *DiscoveryServer) recordGenerateResult(con *Connection, push *model.PushContext, err error) {
+	w := s.pendingMeshConfig()
+	if w == nil || push.Mesh != w.Mesh() {
+		con.generateFailed = err != nil
+		return
+	}
+	if err == nil || con.generateFailed {
+		return
+	}
+	c := &s.meshCommitter
+	c.mu.Lock()
+	if c.failedMesh != push.Mesh {
+		c.failedMesh = push.Mesh
+		c.failed = map[string]struct{}{}
+	}
+	c.failed[con.ConID] = struct{}{}
+	failed := len(c.failed)
+	c.mu.Unlock()
+	if float64(failed) < features.MeshConfigRollbackFailedProxyRatio*float64(s.adsClientCount()) {
+		log.Debugf("push %s failed for %s: %v", push.PushVersion, con.ConID, err)
+		return
+	}
+	s.rollbackMeshConfig(push.Mesh, push.PushVersion, fmt.Errorf("generating config failed for %d proxies, last %s: %v",
+		failed, con.ConID, err))
+}
@@ -0,0 +1,67 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func TestRecordGenerateResult(t *testing.T) {
+	enabled, ratio := features.EnableMeshConfigRollback, features.MeshConfigRollbackFailedProxyRatio
+	features.EnableMeshConfigRollback, features.MeshConfigRollbackFailedProxyRatio = true, 0.5
+	t.Cleanup(func() {
+		features.EnableMeshConfigRollback, features.MeshConfigRollbackFailedProxyRatio = enabled, ratio
+	})
+
+	defaultMesh := mesh.DefaultMeshConfig()
+	w := &mesh.InternalWatcher{MeshConfig: &defaultMesh}
+	w.EnableStagedApply()
+	s := &DiscoveryServer{Env: &model.Environment{Watcher: w}, adsClients: map[string]*Connection{}}
+	cons := make([]*Connection, 4)
+	for i := range cons {
+		cons[i] = &Connection{ConID: fmt.Sprintf("proxy-%d", i)}
+		s.adsClients[cons[i].ConID] = cons[i]
+	}
+	errGenerate := errors.New("generate failed")
+
+	// proxy-0 already fails with the committed mesh config
+	committed := &model.PushContext{Mesh: w.Mesh()}
+	s.recordGenerateResult(cons[0], committed, errGenerate)
+	s.recordGenerateResult(cons[1], committed, nil)
+
+	w.HandleMeshConfigData("ingressClass: foo")
+	pending := &model.PushContext{Mesh: w.Mesh(), PushVersion: "1"}
+
+	s.recordGenerateResult(cons[0], pending, errGenerate)
+	s.recordGenerateResult(cons[1], pending, errGenerate)
+	s.recordGenerateResult(cons[1], pending, errGenerate)
+	if !w.Pending() {
+		t.Fatalf("mesh config rolled back after errors for a single new proxy")
+	}
+
+	s.recordGenerateResult(cons[2], pending, errGenerate)
+	if w.Pending() {
+		t.Fatalf("mesh config not rolled back after errors for half of the proxies")
+	}
+	if got := w.LastRejection(); got == nil || got.Source != mesh.SourcePush {
+		t.Fatalf("unexpected rejection %+v", got)
+	}
+}
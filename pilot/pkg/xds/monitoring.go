@@ -26,10 +26,13 @@ import (
 )
 
 var (
+	classTag   = monitoring.MustCreateLabel("class")
 	errTag     = monitoring.MustCreateLabel("err")
 	nodeTag    = monitoring.MustCreateLabel("node")
 	typeTag    = monitoring.MustCreateLabel("type")
 	versionTag = monitoring.MustCreateLabel("version")
+	kindTag    = monitoring.MustCreateLabel("kind")
+	rankTag    = monitoring.MustCreateLabel("rank")
 
 	// pilot_total_xds_rejects should be used instead. This is for backwards compatibility
 	cdsReject = monitoring.NewGauge(
@@ -125,6 +128,12 @@ var (
 		monitoring.WithLabels(typeTag),
 	)
 
+	debounceWindow = monitoring.NewGauge(
+		"pilot_debounce_window_seconds",
+		"The delay in seconds added to config/registry events for debouncing, adjusted to the push load if PILOT_DEBOUNCE_ADAPTIVE is enabled.",
+		monitoring.WithUnit(monitoring.Seconds),
+	)
+
 	sendTime = monitoring.NewDistribution(
 		"pilot_xds_send_time",
 		"Total time in seconds Pilot takes to send generated configuration.",
@@ -138,6 +147,19 @@ var (
 		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
 	)
 
+	proxiesClassQueueTime = monitoring.NewDistribution(
+		"pilot_proxy_class_queue_time",
+		"Time in seconds, a proxy is in the push queue before being dequeued, labeled by the priority class of the "+
+			"push. Only recorded if PILOT_ENABLE_PUSH_PRIORITY is enabled.",
+		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(classTag),
+	)
+
+	skippedGatewayPushes = monitoring.NewSum(
+		"pilot_xds_skipped_gateway_pushes",
+		"Total number of pushes to gateways skipped because they do not depend on the updated config.",
+	)
+
 	pushTriggers = monitoring.NewSum(
 		"pilot_push_triggers",
 		"Total number of times a push was triggered, labeled by reason for the push.",
@@ -187,6 +209,22 @@ var (
 		monitoring.WithLabels(typeTag),
 		monitoring.WithUnit(monitoring.Bytes),
 	)
+
+	// Only recorded when PILOT_PUSH_COST_METRICS is enabled. Configs are labeled by kind and rank rather than
+	// by name to bound the cardinality; their names are reported on /debug/push_cost.
+	pushCostAttributedBytes = monitoring.NewSum(
+		"pilot_xds_push_cost_attributed_bytes",
+		"Total size of the pushed configuration attributed to services and VirtualServices.",
+		monitoring.WithLabels(typeTag, kindTag),
+		monitoring.WithUnit(monitoring.Bytes),
+	)
+
+	pushCostTopConfigBytes = monitoring.NewGauge(
+		"pilot_xds_push_cost_top_config_bytes",
+		"Total size of the pushed configuration attributed to the config with the given rank among the most expensive.",
+		monitoring.WithLabels(rankTag),
+		monitoring.WithUnit(monitoring.Bytes),
+	)
 )
 
 func recordXDSClients(version string, delta float64) {
@@ -265,14 +303,19 @@ func init() {
 		pushTime,
 		proxiesConvergeDelay,
 		proxiesQueueTime,
+		proxiesClassQueueTime,
 		pushContextErrors,
 		totalXDSInternalErrors,
 		inboundUpdates,
 		pushTriggers,
+		skippedGatewayPushes,
 		sendTime,
+		debounceWindow,
 		totalDelayedPushes,
 		totalDelayedPushTimeouts,
 		pilotSDSCertificateErrors,
 		configSizeBytes,
+		pushCostAttributedBytes,
+		pushCostTopConfigBytes,
 	)
 }
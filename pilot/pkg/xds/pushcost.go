@@ -0,0 +1,408 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/strcase"
+)
+
+// pushCostMetricRanks is the number of the most expensive configs reported by pilot_xds_push_cost_top_config_bytes.
+const pushCostMetricRanks = 10
+
+// pushCostTracker keeps statistics of the pushes to each connection and, when PILOT_PUSH_COST_ATTRIBUTION is
+// enabled, the bytes pushed for each service and VirtualService. It is reported on /debug/push_cost. It is only
+// created when push cost tracking is enabled; except for status, its methods are no-ops on a nil tracker.
+type pushCostTracker struct {
+	mutex sync.RWMutex
+	// connections is keyed by the proxy of the connection, as generators only know about the proxy.
+	connections map[*model.Proxy]*ConnectionPushCost
+	configs     map[pushCostKey]*ConfigPushCost
+}
+
+type pushCostKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// ConnectionPushCost is the cost of the pushes to a single connection.
+type ConnectionPushCost struct {
+	ConnectionID string `json:"connectionID"`
+	ProxyID      string `json:"proxyID"`
+	// Bytes is the total size of the resources pushed to the connection.
+	Bytes int64 `json:"bytes"`
+	// Types is keyed by the short type, such as CDS.
+	Types map[string]*TypePushCost `json:"types"`
+}
+
+// TypePushCost is the cost of the pushes of a single type to a connection.
+type TypePushCost struct {
+	Pushes                int     `json:"pushes"`
+	Bytes                 int64   `json:"bytes"`
+	LastBytes             int     `json:"lastBytes"`
+	LastResources         int     `json:"lastResources"`
+	GenerationSeconds     float64 `json:"generationSeconds"`
+	LastGenerationSeconds float64 `json:"lastGenerationSeconds"`
+	// CacheHits and CacheMisses are only reported for the types using the XDS cache, which are EDS and SDS.
+	// Clusters and routes are always built from the push context.
+	CacheHits     int     `json:"cacheHits,omitempty"`
+	CacheMisses   int     `json:"cacheMisses,omitempty"`
+	CacheHitRatio float64 `json:"cacheHitRatio,omitempty"`
+}
+
+// ConfigPushCost is the size of the resources attributed to a config, summed over all pushes.
+type ConfigPushCost struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Bytes     int64  `json:"bytes"`
+	// Types is the bytes attributed to the config, keyed by the short type.
+	Types map[string]int64 `json:"types"`
+}
+
+// PushCostStatus is the response of /debug/push_cost.
+type PushCostStatus struct {
+	Connections []*ConnectionPushCost `json:"connections"`
+	Configs     []*ConfigPushCost     `json:"configs,omitempty"`
+}
+
+func newPushCostTracker() *pushCostTracker {
+	return &pushCostTracker{
+		connections: map[*model.Proxy]*ConnectionPushCost{},
+		configs:     map[pushCostKey]*ConfigPushCost{},
+	}
+}
+
+func (t *pushCostTracker) connect(con *Connection) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.connections[con.proxy] = &ConnectionPushCost{
+		ConnectionID: con.ConID,
+		ProxyID:      con.proxy.ID,
+		Types:        map[string]*TypePushCost{},
+	}
+}
+
+func (t *pushCostTracker) disconnect(con *Connection) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.connections, con.proxy)
+}
+
+// typeCost returns the stats of the type for the proxy, or nil if the proxy is not connected. Generators also
+// run for debug requests, which are not tracked. The caller must hold the lock.
+func (t *pushCostTracker) typeCost(proxy *model.Proxy, typeURL string) (*ConnectionPushCost, *TypePushCost) {
+	c := t.connections[proxy]
+	if c == nil {
+		return nil, nil
+	}
+	short := v3.GetShortType(typeURL)
+	tc := c.Types[short]
+	if tc == nil {
+		tc = &TypePushCost{}
+		c.Types[short] = tc
+	}
+	return c, tc
+}
+
+// recordPush records the size of the resources pushed to the proxy and the time it took to generate them.
+func (t *pushCostTracker) recordPush(proxy *model.Proxy, typeURL string, res model.Resources, size int, generation time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	c, tc := t.typeCost(proxy, typeURL)
+	if c == nil {
+		return
+	}
+	c.Bytes += int64(size)
+	tc.Pushes++
+	tc.Bytes += int64(size)
+	tc.LastBytes = size
+	tc.LastResources = len(res)
+	tc.GenerationSeconds += generation.Seconds()
+	tc.LastGenerationSeconds = generation.Seconds()
+}
+
+// recordCacheReads records the number of resources generators served from, or added to, the XDS cache.
+func (t *pushCostTracker) recordCacheReads(proxy *model.Proxy, typeURL string, hits, misses int) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, tc := t.typeCost(proxy, typeURL); tc != nil {
+		tc.CacheHits += hits
+		tc.CacheMisses += misses
+	}
+}
+
+// attribute decodes the resources pushed to the proxy and adds their size to the config they were built from.
+// Clusters, endpoints and virtual hosts are attributed to their service; routes and filter chains to the
+// VirtualService they were generated from. Other resources are not attributed.
+func (t *pushCostTracker) attribute(proxy *model.Proxy, push *model.PushContext, typeURL string, res model.Resources) {
+	if t == nil {
+		return
+	}
+	sizes := map[pushCostKey]int{}
+	service := func(hostname string) (pushCostKey, bool) {
+		svc := push.ServiceForHostname(proxy, host.Name(hostname))
+		if svc == nil {
+			return pushCostKey{}, false
+		}
+		kind := "Service"
+		if svc.Attributes.ServiceRegistry == serviceregistry.External {
+			kind = gvk.ServiceEntry.Kind
+		}
+		return pushCostKey{kind: kind, namespace: svc.Attributes.Namespace, name: hostname}, true
+	}
+	addService := func(hostname string, size int) {
+		if key, ok := service(hostname); ok {
+			sizes[key] += size
+		}
+	}
+	addConfig := func(metadata *core.Metadata, size int) bool {
+		key, ok := configMetadataKey(metadata)
+		if ok {
+			sizes[key] += size
+		}
+		return ok
+	}
+
+	for _, r := range res {
+		switch typeURL {
+		case v3.ClusterType:
+			c := &cluster.Cluster{}
+			if err := r.UnmarshalTo(c); err != nil {
+				continue
+			}
+			_, _, hostname, _ := model.ParseSubsetKey(c.Name)
+			addService(string(hostname), len(r.Value))
+		case v3.EndpointType:
+			cla := &endpoint.ClusterLoadAssignment{}
+			if err := r.UnmarshalTo(cla); err != nil {
+				continue
+			}
+			_, _, hostname, _ := model.ParseSubsetKey(cla.ClusterName)
+			addService(string(hostname), len(r.Value))
+		case v3.RouteType:
+			rc := &route.RouteConfiguration{}
+			if err := r.UnmarshalTo(rc); err != nil {
+				continue
+			}
+			for _, vh := range rc.VirtualHosts {
+				remaining := proto.Size(vh)
+				for _, rt := range vh.Routes {
+					size := proto.Size(rt)
+					if addConfig(rt.Metadata, size) {
+						remaining -= size
+					}
+				}
+				// Virtual hosts are named after the host and port of the service.
+				hostname := vh.Name
+				if i := strings.LastIndex(hostname, ":"); i > 0 {
+					hostname = hostname[:i]
+				}
+				addService(hostname, remaining)
+			}
+		case v3.ListenerType:
+			l := &listener.Listener{}
+			if err := r.UnmarshalTo(l); err != nil {
+				continue
+			}
+			for _, fc := range l.FilterChains {
+				addConfig(fc.Metadata, proto.Size(fc))
+			}
+		}
+	}
+	if len(sizes) == 0 {
+		return
+	}
+
+	short := v3.GetShortType(typeURL)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for key, size := range sizes {
+		c := t.configs[key]
+		if c == nil {
+			c = &ConfigPushCost{Kind: key.kind, Namespace: key.namespace, Name: key.name, Types: map[string]int64{}}
+			t.configs[key] = c
+		}
+		c.Bytes += int64(size)
+		c.Types[short] += int64(size)
+		if features.EnablePushCostMetrics {
+			pushCostAttributedBytes.With(typeTag.Value(short), kindTag.Value(key.kind)).Record(float64(size))
+		}
+	}
+}
+
+// prune removes the configs updated by a full push that no longer exist, so the configs of deleted services and
+// VirtualServices are not reported forever.
+func (t *pushCostTracker) prune(push *model.PushContext, configsUpdated map[model.ConfigKey]struct{}) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.configs) == 0 {
+		return
+	}
+	for ck := range configsUpdated {
+		if ck.Kind == gvk.ServiceEntry {
+			// Services are updated by hostname, both for Kubernetes services and ServiceEntries.
+			if push.ServiceIndex.HostnameAndNamespace[host.Name(ck.Name)][ck.Namespace] == nil {
+				delete(t.configs, pushCostKey{kind: "Service", namespace: ck.Namespace, name: ck.Name})
+				delete(t.configs, pushCostKey{kind: gvk.ServiceEntry.Kind, namespace: ck.Namespace, name: ck.Name})
+			}
+			continue
+		}
+		if push.IstioConfigStore == nil || push.IstioConfigStore.Get(ck.Kind, ck.Name, ck.Namespace) == nil {
+			delete(t.configs, pushCostKey{kind: ck.Kind.Kind, namespace: ck.Namespace, name: ck.Name})
+		}
+	}
+}
+
+// configMetadataKey returns the config referenced by the istio metadata of a resource, which is of the form
+// /apis/<group>/<version>/namespaces/<namespace>/<kind>/<name>.
+func configMetadataKey(metadata *core.Metadata) (pushCostKey, bool) {
+	if metadata == nil || metadata.FilterMetadata[util.IstioMetadataKey] == nil {
+		return pushCostKey{}, false
+	}
+	config := metadata.FilterMetadata[util.IstioMetadataKey].Fields["config"].GetStringValue()
+	parts := strings.Split(strings.TrimPrefix(config, "/"), "/")
+	if len(parts) != 7 || parts[0] != "apis" || parts[3] != "namespaces" {
+		return pushCostKey{}, false
+	}
+	return pushCostKey{kind: strcase.CamelCase(parts[5]), namespace: parts[4], name: parts[6]}, true
+}
+
+// status returns the connections with the most pushed bytes and the most expensive configs, up to top of each.
+// If proxyID is set, only the connections of that proxy are returned.
+func (t *pushCostTracker) status(top int, proxyID string) PushCostStatus {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	out := PushCostStatus{
+		Connections: make([]*ConnectionPushCost, 0, len(t.connections)),
+		Configs:     make([]*ConfigPushCost, 0, len(t.configs)),
+	}
+	for _, c := range t.connections {
+		if proxyID != "" && c.ProxyID != proxyID {
+			continue
+		}
+		cp := *c
+		cp.Types = make(map[string]*TypePushCost, len(c.Types))
+		for short, tc := range c.Types {
+			tcp := *tc
+			if reads := tc.CacheHits + tc.CacheMisses; reads > 0 {
+				tcp.CacheHitRatio = float64(tc.CacheHits) / float64(reads)
+			}
+			cp.Types[short] = &tcp
+		}
+		out.Connections = append(out.Connections, &cp)
+	}
+	sort.Slice(out.Connections, func(i, j int) bool {
+		if out.Connections[i].Bytes != out.Connections[j].Bytes {
+			return out.Connections[i].Bytes > out.Connections[j].Bytes
+		}
+		return out.Connections[i].ConnectionID < out.Connections[j].ConnectionID
+	})
+	if len(out.Connections) > top {
+		out.Connections = out.Connections[:top]
+	}
+
+	if proxyID != "" {
+		out.Configs = nil
+		return out
+	}
+	for _, c := range t.topConfigs(top) {
+		cp := *c
+		cp.Types = make(map[string]int64, len(c.Types))
+		for short, bytes := range c.Types {
+			cp.Types[short] = bytes
+		}
+		out.Configs = append(out.Configs, &cp)
+	}
+	return out
+}
+
+// topConfigs returns the configs with the most attributed bytes. The caller must hold the lock.
+func (t *pushCostTracker) topConfigs(top int) []*ConfigPushCost {
+	configs := make([]*ConfigPushCost, 0, len(t.configs))
+	for _, c := range t.configs {
+		configs = append(configs, c)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].Bytes != configs[j].Bytes {
+			return configs[i].Bytes > configs[j].Bytes
+		}
+		if configs[i].Kind != configs[j].Kind {
+			return configs[i].Kind < configs[j].Kind
+		}
+		if configs[i].Namespace != configs[j].Namespace {
+			return configs[i].Namespace < configs[j].Namespace
+		}
+		return configs[i].Name < configs[j].Name
+	})
+	if len(configs) > top {
+		configs = configs[:top]
+	}
+	return configs
+}
+
+// recordMetrics reports the bytes of the most expensive configs. The configs are labeled by their rank only,
+// so the number of series stays bounded; their names are reported on /debug/push_cost.
+func (t *pushCostTracker) recordMetrics() {
+	if t == nil {
+		return
+	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	top := t.topConfigs(pushCostMetricRanks)
+	for rank := 1; rank <= pushCostMetricRanks; rank++ {
+		bytes := int64(0)
+		if rank <= len(top) {
+			bytes = top[rank-1].Bytes
+		}
+		pushCostTopConfigBytes.With(rankTag.Value(strconv.Itoa(rank))).Record(float64(bytes))
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const pushCostConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: example
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: example
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  http:
+  - route:
+    - destination:
+        host: example.com
+`
+
+func TestPushCost(t *testing.T) {
+	original := features.EnablePushCostAttribution
+	features.EnablePushCostAttribution = true
+	defer func() {
+		features.EnablePushCostAttribution = original
+	}()
+
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: pushCostConfig})
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(nil)
+	ads.RequestResponseAck(&discovery.DiscoveryRequest{
+		TypeUrl:       v3.EndpointType,
+		ResourceNames: []string{"outbound|80||example.com"},
+	})
+	ads.RequestResponseAck(&discovery.DiscoveryRequest{
+		TypeUrl:       v3.RouteType,
+		ResourceNames: []string{"80"},
+	})
+
+	get := func(query string) (int, PushCostStatus) {
+		req := httptest.NewRequest("GET", "/debug/push_cost"+query, nil)
+		rr := httptest.NewRecorder()
+		s.Discovery.pushCostz(rr, req)
+		status := PushCostStatus{}
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, status
+	}
+
+	_, status := get("?proxyID=test.default")
+	if len(status.Connections) != 1 {
+		t.Fatalf("expected one connection, got %+v", status.Connections)
+	}
+	con := status.Connections[0]
+	for _, short := range []string{"CDS", "EDS", "RDS"} {
+		tc := con.Types[short]
+		if tc == nil || tc.Pushes == 0 || tc.Bytes == 0 || tc.LastResources == 0 {
+			t.Errorf("expected %s pushes to be recorded, got %+v", short, tc)
+		}
+	}
+	if eds := con.Types["EDS"]; eds != nil && eds.CacheHits+eds.CacheMisses == 0 {
+		t.Errorf("expected EDS cache reads to be recorded, got %+v", eds)
+	}
+	if status.Configs != nil {
+		t.Errorf("expected no configs when filtering by proxy, got %+v", status.Configs)
+	}
+
+	_, status = get("")
+	configs := map[string]*ConfigPushCost{}
+	for _, c := range status.Configs {
+		configs[c.Kind+"/"+c.Namespace+"/"+c.Name] = c
+	}
+	if se := configs["ServiceEntry/default/example.com"]; se == nil || se.Types["CDS"] == 0 || se.Types["EDS"] == 0 {
+		t.Errorf("expected clusters and endpoints to be attributed to the ServiceEntry, got %+v", se)
+	}
+	if vs := configs["VirtualService/default/example"]; vs == nil || vs.Types["RDS"] == 0 {
+		t.Errorf("expected routes to be attributed to the VirtualService, got %+v", vs)
+	}
+
+	if _, status = get("?top=1"); len(status.Configs) != 1 {
+		t.Errorf("expected a single config, got %+v", status.Configs)
+	}
+	if code, _ := get("?top=none"); code != http.StatusBadRequest {
+		t.Errorf("expected an invalid top to be rejected, got %v", code)
+	}
+
+	if err := s.Store().Delete(gvk.VirtualService, "example", "default", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Store().Delete(gvk.ServiceEntry, "example", "default", nil); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, status := get(""); len(status.Configs) != 0 {
+			return fmt.Errorf("expected deleted configs to be removed, got %+v", status.Configs)
+		}
+		return nil
+	})
+
+	ads.Cleanup()
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, status := get(""); len(status.Connections) != 0 {
+			return fmt.Errorf("expected disconnected proxies to be removed, got %+v", status.Connections)
+		}
+		return nil
+	})
+}
+
+func TestPushCostDisabled(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: pushCostConfig})
+	if s.Discovery.pushCost != nil {
+		t.Fatalf("expected no push cost tracking by default")
+	}
+	ads := s.ConnectADS().WithType(v3.ClusterType)
+	ads.RequestResponseAck(nil)
+
+	rr := httptest.NewRecorder()
+	s.Discovery.pushCostz(rr, httptest.NewRequest("GET", "/debug/push_cost", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected push cost to be unavailable, got %v", rr.Code)
+	}
+}
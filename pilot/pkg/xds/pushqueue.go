@@ -16,101 +16,261 @@ package xds
 
 import (
 	"sync"
+	"time"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 )
 
+// pushClass is the priority of a push in the PushQueue. Pushes of lower classes are dequeued more often, but
+// every class is served in proportion to its weight, so lower priority pushes are never starved.
+type pushClass int
+
+const (
+	// pushClassGateway is used for gateways, which usually carry the traffic entering the mesh.
+	pushClassGateway pushClass = iota
+	// pushClassAffected is used for proxies whose config depends on the updated config.
+	pushClassAffected
+	// pushClassDefault is used for all other pushes.
+	pushClassDefault
+
+	numPushClasses
+)
+
+// pushClassWeights is the share of the dequeued pushes each class gets while all classes have pending pushes.
+var pushClassWeights = [numPushClasses]int{
+	pushClassGateway:  4,
+	pushClassAffected: 2,
+	pushClassDefault:  1,
+}
+
+func (c pushClass) String() string {
+	switch c {
+	case pushClassGateway:
+		return "gateway"
+	case pushClassAffected:
+		return "affected"
+	default:
+		return "default"
+	}
+}
+
+// classifyPush returns the class of a push to the connection.
+func classifyPush(con *Connection, req *model.PushRequest) pushClass {
+	if !features.EnablePushPriority || con.proxy == nil {
+		return pushClassDefault
+	}
+	if con.proxy.Type == model.Router {
+		return pushClassGateway
+	}
+	// Without ConfigsUpdated, the push applies to everyone.
+	if len(req.ConfigsUpdated) == 0 {
+		return pushClassDefault
+	}
+	con.proxy.RLock()
+	defer con.proxy.RUnlock()
+	// The scope is only nil until the proxy is initialized.
+	if con.proxy.SidecarScope == nil {
+		return pushClassDefault
+	}
+	for config := range req.ConfigsUpdated {
+		if con.proxy.SidecarScope.DependsOnConfig(config) {
+			return pushClassAffected
+		}
+	}
+	return pushClassDefault
+}
+
+type pendingPush struct {
+	request *model.PushRequest
+	class   pushClass
+}
+
+type processingPush struct {
+	// class the push was dequeued from, counted against the concurrency limit of the class.
+	class pushClass
+	// next is set if the connection was enqueued again while being pushed.
+	next *pendingPush
+}
+
 type PushQueue struct {
 	cond *sync.Cond
 
 	// pending stores all connections in the queue. If the same connection is enqueued again,
-	// the PushRequest will be merged.
-	pending map[*Connection]*model.PushRequest
+	// the PushRequest will be merged, and the push moved to the highest priority class of the two.
+	pending map[*Connection]*pendingPush
 
-	// queue maintains ordering of the queue
-	queue []*Connection
+	// queues maintain ordering of the queue within each class. A connection moved to a higher priority
+	// class is left behind in the queue of its previous class, and skipped when reached.
+	queues [numPushClasses][]*Connection
 
 	// processing stores all connections that have been Dequeue(), but not MarkDone().
-	// The value stored will be initially be nil, but may be populated if the connection is Enqueue().
-	// If model.PushRequest is not nil, it will be Enqueued again once MarkDone has been called.
-	processing map[*Connection]*model.PushRequest
+	// If the connection is Enqueue() in the meantime, it will be Enqueued again once MarkDone has been called.
+	processing map[*Connection]*processingPush
+
+	// limits is the maximum number of connections of each class being processed at the same time. 0 is unlimited.
+	limits [numPushClasses]int
+	// inflight is the number of connections of each class being processed.
+	inflight [numPushClasses]int
+	// credits implement a smooth weighted round robin between the classes with pending pushes.
+	credits [numPushClasses]int
 
 	shuttingDown bool
 }
 
 func NewPushQueue() *PushQueue {
 	return &PushQueue{
-		pending:    make(map[*Connection]*model.PushRequest),
-		processing: make(map[*Connection]*model.PushRequest),
-		cond:       sync.NewCond(&sync.Mutex{}),
+		pending:    make(map[*Connection]*pendingPush),
+		processing: make(map[*Connection]*processingPush),
+		limits: [numPushClasses]int{
+			pushClassGateway:  features.GatewayPushThrottle,
+			pushClassAffected: features.AffectedPushThrottle,
+			pushClassDefault:  features.DefaultPushThrottle,
+		},
+		cond: sync.NewCond(&sync.Mutex{}),
 	}
 }
 
 // Enqueue will mark a proxy as pending a push. If it is already pending, pushInfo will be merged.
 // ServiceEntry updates will be added together, and full will be set if either were full
 func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
+	// Classify outside of the queue lock, as it locks the proxy.
+	class := classifyPush(con, pushRequest)
+
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 
 	if p.shuttingDown {
 		return
 	}
+	// Released once the push of the connection is done, or when merged into an older request.
+	pushRequest.Span.Hold()
 
 	// If its already in progress, merge the info and return
-	if request, f := p.processing[con]; f {
-		p.processing[con] = request.Merge(pushRequest)
+	if processing, f := p.processing[con]; f {
+		processing.next = mergePendingPush(processing.next, pushRequest, class)
 		return
 	}
 
-	if request, f := p.pending[con]; f {
-		p.pending[con] = request.Merge(pushRequest)
+	if pending, f := p.pending[con]; f {
+		pending.request = pending.request.Merge(pushRequest)
+		if class < pending.class {
+			pending.class = class
+			p.queues[class] = append(p.queues[class], con)
+			p.cond.Signal()
+		}
 		return
 	}
 
-	p.pending[con] = pushRequest
-	p.queue = append(p.queue, con)
+	p.pending[con] = &pendingPush{request: pushRequest, class: class}
+	p.queues[class] = append(p.queues[class], con)
 	// Signal waiters on Dequeue that a new item is available
 	p.cond.Signal()
 }
 
+func mergePendingPush(pending *pendingPush, request *model.PushRequest, class pushClass) *pendingPush {
+	if pending == nil {
+		return &pendingPush{request: request, class: class}
+	}
+	pending.request = pending.request.Merge(request)
+	if class < pending.class {
+		pending.class = class
+	}
+	return pending
+}
+
+// next removes the connection to push next from the queues. Among the classes with pending pushes that have not
+// reached their concurrency limit, each class is picked in proportion to its weight, spreading the picks of a class
+// evenly. The caller must hold the lock.
+func (p *PushQueue) next() (*Connection, *pendingPush) {
+	best, total := pushClass(-1), 0
+	for class := pushClass(0); class < numPushClasses; class++ {
+		if !p.ready(class) {
+			// Idle classes do not accumulate credits
+			p.credits[class] = 0
+			continue
+		}
+		if p.limits[class] > 0 && p.inflight[class] >= p.limits[class] {
+			continue
+		}
+		p.credits[class] += pushClassWeights[class]
+		total += pushClassWeights[class]
+		if best < 0 || p.credits[class] > p.credits[best] {
+			best = class
+		}
+	}
+	if best < 0 {
+		return nil, nil
+	}
+	p.credits[best] -= total
+	con := p.queues[best][0]
+	p.queues[best] = p.queues[best][1:]
+	return con, p.pending[con]
+}
+
+// ready drops the connections moved to another class, or already dequeued from another class, from the head of
+// the queue of the class, and reports whether a connection is left. The caller must hold the lock.
+func (p *PushQueue) ready(class pushClass) bool {
+	for len(p.queues[class]) > 0 {
+		con := p.queues[class][0]
+		if pending := p.pending[con]; pending != nil && pending.class == class {
+			return true
+		}
+		p.queues[class] = p.queues[class][1:]
+	}
+	return false
+}
+
 // Remove a proxy from the queue. If there are no proxies ready to be removed, this will block
 func (p *PushQueue) Dequeue() (con *Connection, request *model.PushRequest, shutdown bool) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 
-	// Block until there is one to remove. Enqueue will signal when one is added.
-	for len(p.queue) == 0 && !p.shuttingDown {
+	// Block until there is one to remove. Enqueue will signal when one is added, and MarkDone when a
+	// class is no longer at its limit.
+	var pending *pendingPush
+	for {
+		if con, pending = p.next(); con != nil {
+			break
+		}
+		if p.shuttingDown && len(p.pending) == 0 {
+			// We must be shutting down.
+			return nil, nil, true
+		}
 		p.cond.Wait()
 	}
 
-	if len(p.queue) == 0 {
-		// We must be shutting down.
-		return nil, nil, true
-	}
-
-	con, p.queue = p.queue[0], p.queue[1:]
-
-	request = p.pending[con]
 	delete(p.pending, con)
 
 	// Mark the connection as in progress
-	p.processing[con] = nil
+	p.processing[con] = &processingPush{class: pending.class}
+	p.inflight[pending.class]++
+	queueTime := time.Since(pending.request.Start).Seconds()
+	proxiesQueueTime.Record(queueTime)
+	if features.EnablePushPriority {
+		proxiesClassQueueTime.With(classTag.Value(pending.class.String())).Record(queueTime)
+	}
 
-	return con, request, false
+	return con, pending.request, false
 }
 
 func (p *PushQueue) MarkDone(con *Connection) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
-	request := p.processing[con]
+	processing := p.processing[con]
+	if processing == nil {
+		return
+	}
 	delete(p.processing, con)
+	p.inflight[processing.class]--
+	// Waiters may have been blocked by the limit of the class.
+	p.cond.Broadcast()
 
 	// If the info is present, that means Enqueue was called while connection was not yet marked done.
 	// This means we need to add it back to the queue.
-	if request != nil {
-		p.pending[con] = request
-		p.queue = append(p.queue, con)
-		p.cond.Signal()
+	if processing.next != nil {
+		p.pending[con] = processing.next
+		p.queues[processing.next.class] = append(p.queues[processing.next.class], con)
 	}
 }
 
@@ -118,7 +278,7 @@ func (p *PushQueue) MarkDone(con *Connection) {
 func (p *PushQueue) Pending() int {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
-	return len(p.queue)
+	return len(p.pending)
 }
 
 // ShutDown will cause queue to ignore all new items added to it. As soon as the
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/tests/util/leak"
@@ -294,3 +295,146 @@ func TestProxyQueue(t *testing.T) {
 		}
 	})
 }
+
+func TestPushQueuePriority(t *testing.T) {
+	leak.Check(t)
+	enabled := features.EnablePushPriority
+	features.EnablePushPriority = true
+	// Parallel subtests run after this function returns, so restore the feature once they are done.
+	t.Cleanup(func() {
+		features.EnablePushPriority = enabled
+	})
+	updated := model.ConfigKey{Kind: gvk.ServiceEntry, Name: "foo.com", Namespace: "default"}
+	affectedScope := &model.SidecarScope{}
+	affectedScope.AddConfigDependencies(updated)
+
+	sidecar := func(name string, scope *model.SidecarScope) *Connection {
+		return &Connection{ConID: name, proxy: &model.Proxy{Type: model.SidecarProxy, SidecarScope: scope}}
+	}
+	gateway := func(name string) *Connection {
+		return &Connection{ConID: name, proxy: &model.Proxy{Type: model.Router}}
+	}
+	configPush := func() *model.PushRequest {
+		return &model.PushRequest{ConfigsUpdated: map[model.ConfigKey]struct{}{updated: {}}}
+	}
+
+	t.Run("classes", func(t *testing.T) {
+		t.Parallel()
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		unaffected := sidecar("unaffected", &model.SidecarScope{})
+		affected := sidecar("affected", affectedScope)
+		gw := gateway("gateway")
+		p.Enqueue(unaffected, configPush())
+		p.Enqueue(affected, configPush())
+		p.Enqueue(gw, configPush())
+
+		ExpectDequeue(t, p, gw)
+		ExpectDequeue(t, p, affected)
+		ExpectDequeue(t, p, unaffected)
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("all proxies are affected by pushes without updated configs", func(t *testing.T) {
+		t.Parallel()
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		first := sidecar("first", &model.SidecarScope{})
+		second := sidecar("second", affectedScope)
+		p.Enqueue(first, &model.PushRequest{})
+		p.Enqueue(second, &model.PushRequest{})
+
+		ExpectDequeue(t, p, first)
+		ExpectDequeue(t, p, second)
+	})
+
+	t.Run("merged pushes are promoted", func(t *testing.T) {
+		t.Parallel()
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		first := sidecar("first", &model.SidecarScope{})
+		second := sidecar("second", affectedScope)
+		p.Enqueue(first, &model.PushRequest{})
+		p.Enqueue(second, &model.PushRequest{})
+		p.Enqueue(second, configPush())
+
+		ExpectDequeue(t, p, second)
+		ExpectDequeue(t, p, first)
+		// The connection left behind in the default class is not pushed again
+		ExpectTimeout(t, p)
+	})
+
+	t.Run("class limits", func(t *testing.T) {
+		t.Parallel()
+		p := NewPushQueue()
+		defer p.ShutDown()
+		p.limits[pushClassGateway] = 1
+
+		gw1, gw2 := gateway("gateway-1"), gateway("gateway-2")
+		unaffected := sidecar("unaffected", nil)
+		p.Enqueue(gw1, configPush())
+		p.Enqueue(gw2, configPush())
+		p.Enqueue(unaffected, configPush())
+
+		ExpectDequeue(t, p, gw1)
+		// The second gateway waits for the first, but does not block other classes
+		ExpectDequeue(t, p, unaffected)
+		if pending := p.Pending(); pending != 1 {
+			t.Fatalf("Expected the second gateway to be pending, got %v pending", pending)
+		}
+		p.MarkDone(gw1)
+		ExpectDequeue(t, p, gw2)
+	})
+
+	t.Run("lower classes are not starved", func(t *testing.T) {
+		t.Parallel()
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		unaffected := sidecar("unaffected", &model.SidecarScope{})
+		affected := sidecar("affected", affectedScope)
+		p.Enqueue(unaffected, configPush())
+		p.Enqueue(affected, configPush())
+		gateways := map[*Connection]bool{}
+		for i := 0; i < 10; i++ {
+			gw := gateway(fmt.Sprintf("gateway-%d", i))
+			gateways[gw] = true
+			p.Enqueue(gw, configPush())
+		}
+
+		// Gateways are pushed again as soon as they are done, so there is always a gateway push pending.
+		dequeued := map[string]int{}
+		for i := 0; i < 14; i++ {
+			con, _, _ := p.Dequeue()
+			p.MarkDone(con)
+			dequeued[con.ConID]++
+			if gateways[con] {
+				p.Enqueue(con, configPush())
+			}
+		}
+		if dequeued["unaffected"] != 1 || dequeued["affected"] != 1 {
+			t.Fatalf("expected the sidecars to be pushed under sustained gateway pushes, got %v", dequeued)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		original := features.EnablePushPriority
+		features.EnablePushPriority = false
+		defer func() {
+			features.EnablePushPriority = original
+		}()
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		unaffected := sidecar("unaffected", &model.SidecarScope{})
+		gw := gateway("gateway")
+		p.Enqueue(unaffected, configPush())
+		p.Enqueue(gw, configPush())
+
+		ExpectDequeue(t, p, unaffected)
+		ExpectDequeue(t, p, gw)
+	})
+}
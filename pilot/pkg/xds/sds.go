@@ -27,6 +27,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/secrets"
 	authnmodel "istio.io/istio/pilot/pkg/security/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
 )
@@ -80,7 +81,8 @@ func parseResourceName(resource, defaultNamespace string) (SecretResource, error
 }
 
 func needsUpdate(proxy *model.Proxy, updates model.XdsUpdates) bool {
-	if proxy.Type != model.Router {
+	// Sidecars do not request secrets themselves; when enabled, their agent requests them on their behalf.
+	if proxy.Type != model.Router && !(proxy.Type == model.SidecarProxy && features.EnableSidecarCredentialName) {
 		return false
 	}
 	if len(updates) == 0 {
@@ -177,6 +179,7 @@ func (s *SecretGen) Generate(proxy *model.Proxy, push *model.PushContext, w *mod
 			}
 		}
 	}
+	s.pushCost.recordCacheReads(proxy, v3.SecretType, cached, regenerated)
 	log.Infof("SDS: PUSH for node:%s resources:%d size:%s cached:%v/%v",
 		proxy.ID, len(results), util.ByteCount(ResourceSize(results)), cached, cached+regenerated)
 	return results, nil
@@ -252,15 +255,22 @@ type SecretGen struct {
 	secrets secrets.MulticlusterController
 	// Cache for XDS resources
 	cache model.XdsCache
+	// pushCost records the cache reads of the generated secrets, if set.
+	pushCost *pushCostTracker
 }
 
 var _ model.XdsResourceGenerator = &SecretGen{}
 
-func NewSecretGen(sc secrets.MulticlusterController, cache model.XdsCache) *SecretGen {
+// NewSecretGen creates a generator for secrets. If server is set, its cache reads are reported on /debug/push_cost.
+func NewSecretGen(sc secrets.MulticlusterController, cache model.XdsCache, server *DiscoveryServer) *SecretGen {
 	// TODO: Currently we only have a single secrets controller (Kubernetes). In the future, we will need a mapping
 	// of resource type to secret controller (ie kubernetes:// -> KubernetesController, vault:// -> VaultController)
-	return &SecretGen{
+	sg := &SecretGen{
 		secrets: sc,
 		cache:   cache,
 	}
+	if server != nil {
+		sg.pushCost = server.pushCost
+	}
+	return sg
 }
@@ -407,6 +407,8 @@ func extractAttributesMetadata(envVars []string, plat platform.Environment, meta
 			meta.Namespace = val
 		case "SERVICE_ACCOUNT":
 			meta.ServiceAccount = val
+		case "HOST_IP":
+			meta.HostIP = val
 		}
 	}
 	if plat != nil && len(plat.Metadata()) > 0 {
@@ -0,0 +1,35 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	sourceTag = monitoring.MustCreateLabel("source")
+
+	// The version of a rejected config is not a label, as every update has a new one. It is reported with
+	// the last rejection on /debug/mesh?status=true.
+	configRejections = monitoring.NewSum(
+		"mesh_config_rejections_total",
+		"Total number of mesh config updates that were rejected or rolled back, by source.",
+		monitoring.WithLabels(sourceTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(configRejections)
+}
@@ -15,6 +15,8 @@
 package mesh
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -41,7 +43,51 @@ type Watcher interface {
 	AddMeshHandler(func())
 }
 
-var _ Watcher = &InternalWatcher{}
+// StagedWatcher is a Watcher whose updates can be rolled back until they are committed. This allows
+// a consumer to keep the previous mesh config if the new one turns out to be unusable.
+type StagedWatcher interface {
+	Watcher
+
+	// EnableStagedApply keeps the last committed mesh config around on updates, so that they can be
+	// rolled back.
+	EnableStagedApply()
+	// Pending reports whether the current mesh config has not been committed yet.
+	Pending() bool
+	// Commit accepts the current mesh config; it can no longer be rolled back.
+	Commit()
+	// Rollback restores the last committed mesh config and records the current one as rejected. It
+	// reports whether there was a pending mesh config to roll back.
+	Rollback(reason string) bool
+	// LastRejection returns the most recently rejected mesh config update, or nil if there was none.
+	LastRejection() *Rejection
+}
+
+// Sources of mesh config updates.
+const (
+	// SourceMeshConfig is the revision mesh config.
+	SourceMeshConfig = "meshconfig"
+	// SourceUserMeshConfig is the user mesh config, which is overridden by the revision mesh config.
+	SourceUserMeshConfig = "user"
+	// SourcePush is a mesh config that was applied, but rolled back because pushing it failed.
+	SourcePush = "push"
+)
+
+// Rejection describes a mesh config update that was not applied, or was rolled back.
+type Rejection struct {
+	// Source of the rejected update, one of the Source constants.
+	Source string `json:"source"`
+	// Version identifies the rejected config. It is a hash of its YAML, or of the merged mesh config
+	// for rolled back updates.
+	Version string `json:"version"`
+	// Reason the update was rejected.
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+var (
+	_ Watcher       = &InternalWatcher{}
+	_ StagedWatcher = &InternalWatcher{}
+)
 
 type InternalWatcher struct {
 	mutex    sync.Mutex
@@ -51,6 +97,20 @@ type InternalWatcher struct {
 
 	userMeshConfig string
 	revMeshConfig  string
+
+	// staged is set if updates can be rolled back until they are committed.
+	staged bool
+	// committed holds the last committed state while an update is pending.
+	committed *watcherState
+	// lastRejection is the most recently rejected update.
+	lastRejection *Rejection
+}
+
+// watcherState is a snapshot of the config of an InternalWatcher.
+type watcherState struct {
+	meshConfig     *meshconfig.MeshConfig
+	userMeshConfig string
+	revMeshConfig  string
 }
 
 // NewFixedWatcher creates a new Watcher that always returns the given mesh config. It will never
@@ -99,45 +159,57 @@ func (w *InternalWatcher) AddMeshHandler(h func()) {
 }
 
 // HandleMeshConfigData keeps track of the standard mesh config. These are merged with the user
-// mesh config, but takes precedence.
+// mesh config, but takes precedence. If the merged config fails to parse or validate, the update is
+// rejected and the previous config is kept.
 func (w *InternalWatcher) HandleMeshConfigData(yaml string) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
+	merged, err := merge(w.userMeshConfig, yaml)
+	if err != nil {
+		w.rejectLocked(SourceMeshConfig, configVersion(yaml), fmt.Sprintf("revision config invalid: %v", err))
+		return
+	}
+	w.stageLocked(merged)
 	w.revMeshConfig = yaml
-	merged := w.merged()
 	w.handleMeshConfigInternal(merged)
 }
 
 // HandleUserMeshConfig keeps track of user mesh config overrides. These are merged with the standard
-// mesh config, which takes precedence.
+// mesh config, which takes precedence. If the merged config fails to parse or validate, the update is
+// rejected and the previous config is kept.
 func (w *InternalWatcher) HandleUserMeshConfig(yaml string) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
+	merged, err := merge(yaml, w.revMeshConfig)
+	if err != nil {
+		w.rejectLocked(SourceUserMeshConfig, configVersion(yaml), fmt.Sprintf("user config invalid: %v", err))
+		return
+	}
+	w.stageLocked(merged)
 	w.userMeshConfig = yaml
-	merged := w.merged()
 	w.handleMeshConfigInternal(merged)
 }
 
-// merged returns the merged user and revision config.
-func (w *InternalWatcher) merged() *meshconfig.MeshConfig {
+// merge returns the merged user and revision config. The result is validated.
+func merge(userMeshConfig, revMeshConfig string) (*meshconfig.MeshConfig, error) {
 	mc := DefaultMeshConfig()
-	if w.userMeshConfig != "" {
-		mc1, err := ApplyMeshConfig(w.userMeshConfig, mc)
+	if userMeshConfig != "" {
+		mc1, err := ApplyMeshConfig(userMeshConfig, mc)
 		if err != nil {
-			log.Errorf("user config invalid, ignoring it %v %s", err, w.userMeshConfig)
+			return nil, err
 		}
 		mc = *mc1
 		log.Infoa("Applied user config: ", spew.Sdump(mc))
 	}
-	if w.revMeshConfig != "" {
-		mc1, err := ApplyMeshConfig(w.revMeshConfig, mc)
+	if revMeshConfig != "" {
+		mc1, err := ApplyMeshConfig(revMeshConfig, mc)
 		if err != nil {
-			log.Errorf("revision config invalid, ignoring it %v %s", err, w.userMeshConfig)
+			return nil, err
 		}
 		mc = *mc1
 		log.Infoa("Applied revision mesh config: ", spew.Sdump(mc))
 	}
-	return &mc
+	return &mc, nil
 }
 
 // HandleMeshConfig calls all handlers for a given mesh configuration update. This must be called
@@ -145,9 +217,93 @@ func (w *InternalWatcher) merged() *meshconfig.MeshConfig {
 func (w *InternalWatcher) HandleMeshConfig(meshConfig *meshconfig.MeshConfig) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
+	w.stageLocked(meshConfig)
 	w.handleMeshConfigInternal(meshConfig)
 }
 
+// EnableStagedApply keeps the last committed mesh config around on updates, so that they can be
+// rolled back until they are committed.
+func (w *InternalWatcher) EnableStagedApply() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.staged = true
+}
+
+// Pending reports whether the current mesh config has not been committed yet.
+func (w *InternalWatcher) Pending() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.committed != nil
+}
+
+// Commit accepts the current mesh config; it can no longer be rolled back.
+func (w *InternalWatcher) Commit() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.committed != nil {
+		log.Infof("mesh configuration committed")
+	}
+	w.committed = nil
+}
+
+// Rollback restores the last committed mesh config, calling all handlers, and records the current
+// mesh config as rejected. It reports whether there was a pending mesh config to roll back.
+func (w *InternalWatcher) Rollback(reason string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.committed == nil {
+		return false
+	}
+	w.rejectLocked(SourcePush, configVersion(w.MeshConfig.String()), reason)
+	committed := w.committed
+	w.committed = nil
+	w.userMeshConfig = committed.userMeshConfig
+	w.revMeshConfig = committed.revMeshConfig
+	w.handleMeshConfigInternal(committed.meshConfig)
+	return true
+}
+
+// LastRejection returns the most recently rejected mesh config update, or nil if there was none.
+func (w *InternalWatcher) LastRejection() *Rejection {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.lastRejection == nil {
+		return nil
+	}
+	r := *w.lastRejection
+	return &r
+}
+
+// stageLocked records the current state as the last committed one, if staged apply is enabled and
+// the given mesh config replaces a committed one. Must be called under a lock, before any change.
+func (w *InternalWatcher) stageLocked(meshConfig *meshconfig.MeshConfig) {
+	if !w.staged || w.committed != nil || reflect.DeepEqual(meshConfig, w.MeshConfig) {
+		return
+	}
+	w.committed = &watcherState{
+		meshConfig:     w.MeshConfig,
+		userMeshConfig: w.userMeshConfig,
+		revMeshConfig:  w.revMeshConfig,
+	}
+}
+
+// rejectLocked records a rejected update. Must be called under a lock.
+func (w *InternalWatcher) rejectLocked(source, version, reason string) {
+	log.Errorf("mesh configuration %s from %s rejected, keeping the previous config: %s", version, source, reason)
+	w.lastRejection = &Rejection{
+		Source:  source,
+		Version: version,
+		Reason:  reason,
+		Time:    time.Now(),
+	}
+	configRejections.With(sourceTag.Value(source)).Increment()
+}
+
+// configVersion returns a short hash identifying the given config.
+func configVersion(config string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(config)))[:16]
+}
+
 // handleMeshConfigInternal behaves the same as HandleMeshConfig but must be called under a lock
 func (w *InternalWatcher) handleMeshConfigInternal(meshConfig *meshconfig.MeshConfig) {
 	var handlers []func()
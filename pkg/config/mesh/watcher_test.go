@@ -71,6 +71,77 @@ func TestWatcherShouldNotifyHandlers(t *testing.T) {
 	}
 }
 
+func TestWatcherRejectsInvalidConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	defaultMesh := mesh.DefaultMeshConfig()
+	w := &mesh.InternalWatcher{MeshConfig: &defaultMesh}
+	updates := 0
+	w.AddMeshHandler(func() {
+		updates++
+	})
+
+	w.HandleMeshConfigData("ingressClass: foo")
+	g.Expect(updates).To(Equal(1))
+	g.Expect(w.Mesh().IngressClass).To(Equal("foo"))
+	g.Expect(w.LastRejection()).To(BeNil())
+
+	// Unparseable config is rejected
+	w.HandleMeshConfigData("ingressClass: [")
+	g.Expect(updates).To(Equal(1))
+	g.Expect(w.Mesh().IngressClass).To(Equal("foo"))
+	g.Expect(w.LastRejection()).ToNot(BeNil())
+	g.Expect(w.LastRejection().Source).To(Equal(mesh.SourceMeshConfig))
+
+	// Config that fails validation is rejected
+	w.HandleUserMeshConfig("proxyListenPort: -1")
+	g.Expect(updates).To(Equal(1))
+	g.Expect(w.LastRejection().Source).To(Equal(mesh.SourceUserMeshConfig))
+
+	// The rejected revision config is not merged with later user config
+	w.HandleUserMeshConfig("ingressService: bar")
+	g.Expect(updates).To(Equal(2))
+	g.Expect(w.Mesh().IngressClass).To(Equal("foo"))
+	g.Expect(w.Mesh().IngressService).To(Equal("bar"))
+}
+
+func TestWatcherStagedApply(t *testing.T) {
+	g := NewWithT(t)
+
+	defaultMesh := mesh.DefaultMeshConfig()
+	w := &mesh.InternalWatcher{MeshConfig: &defaultMesh}
+	w.EnableStagedApply()
+	updates := 0
+	w.AddMeshHandler(func() {
+		updates++
+	})
+
+	w.HandleMeshConfigData("ingressClass: foo")
+	g.Expect(w.Pending()).To(BeTrue())
+	w.Commit()
+	g.Expect(w.Pending()).To(BeFalse())
+	g.Expect(w.Rollback("too late")).To(BeFalse())
+
+	w.HandleMeshConfigData("ingressClass: bar")
+	w.HandleUserMeshConfig("ingressService: baz")
+	g.Expect(w.Pending()).To(BeTrue())
+	g.Expect(updates).To(Equal(3))
+
+	// Rolling back restores the last committed config and notifies the handlers
+	g.Expect(w.Rollback("push failed")).To(BeTrue())
+	g.Expect(updates).To(Equal(4))
+	g.Expect(w.Pending()).To(BeFalse())
+	g.Expect(w.Mesh().IngressClass).To(Equal("foo"))
+	g.Expect(w.Mesh().IngressService).To(Equal(defaultMesh.IngressService))
+	g.Expect(w.LastRejection().Source).To(Equal(mesh.SourcePush))
+	g.Expect(w.LastRejection().Reason).To(Equal("push failed"))
+
+	// Later updates are merged with the restored config
+	w.HandleUserMeshConfig("ingressService: qux")
+	g.Expect(w.Mesh().IngressClass).To(Equal("foo"))
+	g.Expect(w.Mesh().IngressService).To(Equal("qux"))
+}
+
 func newWatcher(t testing.TB, filename string) mesh.Watcher {
 	t.Helper()
 	w, err := mesh.NewFileWatcher(filewatcher.NewWatcher(), filename)
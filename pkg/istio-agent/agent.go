@@ -57,6 +57,9 @@ const (
 	// Location of K8S CA root.
 	k8sCAPath = "./var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
 
+	// Location of the old style mounted root cert.
+	legacyRootCertPath = "./etc/certs/root-cert.pem"
+
 	// CitadelCACertPath is the directory for Citadel CA certificate.
 	// This is mounted from config map 'istio-ca-root-cert'. Part of startup,
 	// this may be replaced with ./etc/certs, if a root-cert.pem is found, to
@@ -68,6 +71,13 @@ const (
 	MetadataClientCertKey   = "ISTIO_META_TLS_CLIENT_KEY"
 	MetadataClientCertChain = "ISTIO_META_TLS_CLIENT_CERT_CHAIN"
 	MetadataClientRootCert  = "ISTIO_META_TLS_CLIENT_ROOT_CERT"
+
+	// MetadataWasmTrustedKeys holds the PEM encoded public keys trusted to sign Wasm modules. It is usually set
+	// mesh-wide with defaultConfig.proxyMetadata. If set, remotely loaded Wasm modules must have a valid
+	// detached signature, served next to the module with a ".sig" suffix. Keys are not read from mesh config,
+	// and signatures of modules pulled from OCI registries are not supported. The agent fails to start if it is set
+	// while ISTIO_AGENT_ENABLE_WASM_REMOTE_LOAD_CONVERSION is disabled, as Envoy would then load modules unverified.
+	MetadataWasmTrustedKeys = "WASM_TRUSTED_KEYS"
 )
 
 // Agent contains the configuration of the agent, based on the injected
@@ -241,25 +251,52 @@ func (a *Agent) Close() {
 //
 // TODO: additional checks for existence. Fail early, instead of obscure envoy errors.
 func (a *Agent) FindRootCAForXDS() string {
-	if a.cfg.XDSRootCerts == security.SystemRootCerts {
+	_, err := os.Stat(legacyRootCertPath)
+	return RootCAForXDS(XDSRootCAOptions{
+		XDSRootCerts:       a.cfg.XDSRootCerts,
+		LegacyCertsMounted: err == nil,
+		PilotCertProvider:  a.secOpts.PilotCertProvider,
+		ProvCert:           a.secOpts.ProvCert,
+		FileMountedCerts:   a.secOpts.FileMountedCerts,
+		ProxyMetadata:      a.proxyConfig.ProxyMetadata,
+	})
+}
+
+// XDSRootCAOptions are the agent settings determining the root CA of the XDS connection.
+type XDSRootCAOptions struct {
+	// XDSRootCerts is the XDS_ROOT_CA setting.
+	XDSRootCerts string
+	// LegacyCertsMounted is true if the old style certificates are mounted in ./etc/certs.
+	LegacyCertsMounted bool
+	PilotCertProvider  string
+	ProvCert           string
+	FileMountedCerts   bool
+	// ProxyMetadata is the proxy metadata of the proxy config.
+	ProxyMetadata map[string]string
+}
+
+// RootCAForXDS returns the root CA for the XDS connection, as Agent.FindRootCAForXDS does. This allows tools to
+// determine it without running the agent. An empty result means the system root certificates are used.
+func RootCAForXDS(o XDSRootCAOptions) string {
+	if o.XDSRootCerts == security.SystemRootCerts {
 		return ""
-	} else if a.cfg.XDSRootCerts != "" {
-		return a.cfg.XDSRootCerts
-	} else if _, err := os.Stat("./etc/certs/root-cert.pem"); err == nil {
+	} else if o.XDSRootCerts != "" {
+		return o.XDSRootCerts
+	} else if o.LegacyCertsMounted {
 		// Old style - mounted cert. This is used for XDS auth only,
 		// not connecting to CA_ADDR because this mode uses external
 		// agent (Secret refresh, etc)
-		return "./etc/certs/root-cert.pem"
-	} else if a.secOpts.PilotCertProvider == "kubernetes" {
+		return legacyRootCertPath
+	} else if o.PilotCertProvider == "kubernetes" {
 		// Using K8S - this is likely incorrect, may work by accident (https://github.com/istio/istio/issues/22161)
 		return k8sCAPath
-	} else if a.secOpts.ProvCert != "" {
+	} else if o.ProvCert != "" {
 		// This was never completely correct - PROV_CERT are only intended for auth with CA_ADDR,
 		// and should not be involved in determining the root CA.
-		return a.secOpts.ProvCert + "/root-cert.pem"
-	} else if a.secOpts.FileMountedCerts {
+		return o.ProvCert + "/root-cert.pem"
+	} else if o.FileMountedCerts {
 		// FileMountedCerts - Load it from Proxy Metadata.
-		return a.proxyConfig.ProxyMetadata[MetadataClientRootCert]
+		return o.ProxyMetadata[MetadataClientRootCert]
 	} else {
 		// PILOT_CERT_PROVIDER - default is istiod
 		// This is the default - a mounted config map on K8S
@@ -267,6 +304,17 @@ func (a *Agent) FindRootCAForXDS() string {
 	}
 }
 
+// BootstrapRootCA returns the root CA file to configure in the Envoy bootstrap for the given XDS root CA.
+func BootstrapRootCA(xdsRootCA string) string {
+	if xdsRootCA == "" {
+		// Envoy only supports load from file. If we want to use system certs, use best guess
+		// To be more correct this could lookup all the "well known" paths but this is extremely \
+		// unlikely to run on a non-debian based machine, and if it is it can be explicitly configured
+		return "/etc/ssl/certs/ca-certificates.crt"
+	}
+	return xdsRootCA
+}
+
 // Find the root CA to use when connecting to the CA (Istiod or external).
 func (a *Agent) FindRootCAForCA() string {
 	if a.cfg.CARootCerts == security.SystemRootCerts {
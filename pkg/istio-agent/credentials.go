@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"fmt"
+
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/nodeagent/cache"
+)
+
+// credentialHandler handles the credentialName secrets Istiod sends in response to requests the agent
+// makes on behalf of a sidecar. They are stored in the secret cache, which pushes them to Envoy over SDS.
+func credentialHandler(sc *cache.SecretManagerClient) ResponseHandler {
+	return func(resp *any.Any) error {
+		var secret tls.Secret
+		// nolint: staticcheck
+		if err := ptypes.UnmarshalAny(resp, &secret); err != nil {
+			proxyLog.Errorf("failed to unmarshall secret: %v", err)
+			return err
+		}
+		item, err := secretItemFromEnvoySecret(&secret)
+		if err != nil {
+			proxyLog.Errorf("invalid secret %s: %v", secret.Name, err)
+			return err
+		}
+		sc.UpdateCredential(item)
+		return nil
+	}
+}
+
+// secretItemFromEnvoySecret converts an Envoy tls.Secret with inline data to a security.SecretItem.
+func secretItemFromEnvoySecret(secret *tls.Secret) (*security.SecretItem, error) {
+	item := &security.SecretItem{
+		ResourceName: secret.Name,
+	}
+	switch t := secret.Type.(type) {
+	case *tls.Secret_TlsCertificate:
+		item.CertificateChain = t.TlsCertificate.GetCertificateChain().GetInlineBytes()
+		item.PrivateKey = t.TlsCertificate.GetPrivateKey().GetInlineBytes()
+		if len(item.CertificateChain) == 0 || len(item.PrivateKey) == 0 {
+			return nil, fmt.Errorf("missing inline certificate chain or private key")
+		}
+	case *tls.Secret_ValidationContext:
+		item.RootCert = t.ValidationContext.GetTrustedCa().GetInlineBytes()
+		if len(item.RootCert) == 0 {
+			return nil, fmt.Errorf("missing inline trusted CA")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported secret type %T", secret.Type)
+	}
+	return item, nil
+}
+
+// WatchCredentials requests the credentials with the given resource names from Istiod, on the current
+// upstream connection and on any later one. The names replace those of any previous call.
+func (p *XdsProxy) WatchCredentials(resourceNames []string) {
+	p.connectedMutex.Lock()
+	p.credentialNames = resourceNames
+	con := p.connected
+	p.connectedMutex.Unlock()
+
+	if con == nil {
+		return
+	}
+	if con.deltaRequestsChan != nil {
+		select {
+		case con.deltaRequestsChan <- deltaCredentialRequest(resourceNames):
+		case <-con.stopChan:
+		}
+		return
+	}
+	select {
+	case con.requestsChan <- credentialRequest(resourceNames):
+	case <-con.stopChan:
+	}
+}
+
+func (p *XdsProxy) getCredentialNames() []string {
+	p.connectedMutex.RLock()
+	defer p.connectedMutex.RUnlock()
+	return p.credentialNames
+}
+
+func credentialRequest(resourceNames []string) *discovery.DiscoveryRequest {
+	return &discovery.DiscoveryRequest{
+		TypeUrl:       v3.SecretType,
+		ResourceNames: resourceNames,
+	}
+}
+
+// deltaCredentialRequest subscribes to the credentials with the given resource names. Credentials are never
+// unsubscribed, so subscribing to all of them again is harmless.
+func deltaCredentialRequest(resourceNames []string) *discovery.DeltaDiscoveryRequest {
+	return &discovery.DeltaDiscoveryRequest{
+		TypeUrl:                v3.SecretType,
+		ResourceNamesSubscribe: resourceNames,
+	}
+}
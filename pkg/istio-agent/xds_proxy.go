@@ -50,6 +50,7 @@ import (
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/pkg/features"
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
+	"istio.io/istio/pilot/pkg/model"
 	nds "istio.io/istio/pilot/pkg/proto"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/constants"
@@ -73,10 +74,20 @@ const (
 var connectionNumber = atomic.NewUint32(0)
 
 // ResponseHandler handles a XDS response in the agent. These will not be forwarded to Envoy.
-// Currently, all handlers function on a single resource per type, so the API only exposes one
-// resource.
+// The handler is called once for each resource of the response; an empty response is only ACKed.
 type ResponseHandler func(resp *any.Any) error
 
+// handleResources passes each resource to the handler, returning the first error.
+func handleResources(h ResponseHandler, resources []*any.Any) error {
+	var err error
+	for _, r := range resources {
+		if herr := h(r); herr != nil && err == nil {
+			err = herr
+		}
+	}
+	return err
+}
+
 // XDS Proxy proxies all XDS requests from envoy to istiod, in addition to allowing
 // subsystems inside the agent to also communicate with either istiod/envoy (eg dns, sds, etc).
 // The goal here is to consolidate all xds related connections to istiod/envoy into a
@@ -107,6 +118,9 @@ type XdsProxy struct {
 	initialDeltaRequest *discovery.DeltaDiscoveryRequest
 	connectedMutex      sync.RWMutex
 
+	// credentialNames are the credentialName secrets requested from istiod on behalf of the sidecar.
+	credentialNames []string
+
 	// Wasm cache and ecds channel are used to replace wasm remote load with local file.
 	wasmCache wasm.Cache
 
@@ -178,6 +192,16 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		}
 	}
 
+	// Gateways request credentialName secrets over ADS themselves, so only sidecars fetch them through the agent.
+	if features.EnableSidecarCredentialName && ia.cfg.ProxyType == model.SidecarProxy && ia.secretCache != nil {
+		proxy.handlers[v3.SecretType] = credentialHandler(ia.secretCache)
+		ia.secretCache.SetCredentialWatcher(proxy.WatchCredentials)
+	}
+
+	if err = proxy.initWasmSignatureVerifier(ia); err != nil {
+		return nil, err
+	}
+
 	proxyLog.Infof("Initializing with upstream address %q and cluster %q", proxy.istiodAddress, proxy.clusterID)
 
 	if err = proxy.initDownstreamServer(); err != nil {
@@ -332,6 +356,10 @@ func (p *XdsProxy) StreamAggregatedResources(downstream discovery.AggregatedDisc
 				if initialRequest != nil {
 					con.requestsChan <- initialRequest
 				}
+				// Resume watching credentials requested on a previous connection
+				if names := p.getCredentialNames(); len(names) > 0 {
+					con.requestsChan <- credentialRequest(names)
+				}
 				initialRequestsSent = true
 			}
 		}
@@ -447,12 +475,7 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 			proxyLog.Debugf("response for type url %s", resp.TypeUrl)
 			metrics.XdsProxyResponses.Increment()
 			if h, f := p.handlers[resp.TypeUrl]; f {
-				if len(resp.Resources) == 0 {
-					// Empty response, nothing to do
-					// This assumes internal types are always singleton
-					return
-				}
-				err := h(resp.Resources[0])
+				err := handleResources(h, resp.Resources)
 				var errorResp *google_rpc.Status
 				if err != nil {
 					errorResp = &google_rpc.Status{
@@ -460,13 +483,18 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 						Message: err.Error(),
 					}
 				}
-				// Send ACK/NACK
-				con.requestsChan <- &discovery.DiscoveryRequest{
+				ack := &discovery.DiscoveryRequest{
 					VersionInfo:   resp.VersionInfo,
 					TypeUrl:       resp.TypeUrl,
 					ResponseNonce: resp.Nonce,
 					ErrorDetail:   errorResp,
 				}
+				// Istiod takes the resource names of an ACK as the new subscription, so they must be repeated.
+				if resp.TypeUrl == v3.SecretType {
+					ack.ResourceNames = p.getCredentialNames()
+				}
+				// Send ACK/NACK
+				con.requestsChan <- ack
 				continue
 			}
 			switch resp.TypeUrl {
@@ -591,6 +619,29 @@ func (p *XdsProxy) getCertKeyPaths(agent *Agent) (string, string) {
 	return key, cert
 }
 
+// initWasmSignatureVerifier makes the Wasm cache verify module signatures, if trusted keys are configured. It fails
+// if the keys are set but modules cannot be verified, as Envoy would otherwise load them unverified.
+func (p *XdsProxy) initWasmSignatureVerifier(agent *Agent) error {
+	verifier, err := wasm.NewSignatureVerifier([]byte(agent.proxyConfig.ProxyMetadata[MetadataWasmTrustedKeys]))
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", MetadataWasmTrustedKeys, err)
+	}
+	if verifier == nil {
+		return nil
+	}
+	if !features.WasmRemoteLoadConversion {
+		return fmt.Errorf("%s requires ISTIO_AGENT_ENABLE_WASM_REMOTE_LOAD_CONVERSION, as Wasm modules are only verified "+
+			"when the agent downloads them", MetadataWasmTrustedKeys)
+	}
+	cache, ok := p.wasmCache.(*wasm.LocalFileCache)
+	if !ok {
+		return fmt.Errorf("%s is set but the Wasm module cache does not verify signatures", MetadataWasmTrustedKeys)
+	}
+	cache.SetSignatureVerifier(verifier)
+	proxyLog.Infof("Wasm module signature verification enabled")
+	return nil
+}
+
 func (p *XdsProxy) buildUpstreamClientDialOpts(sa *Agent) ([]grpc.DialOption, error) {
 	tlsOpts, err := p.getTLSDialOption(sa)
 	if err != nil {
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -83,6 +84,10 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream discovery.AggregatedDisco
 				if initialRequest != nil {
 					con.deltaRequestsChan <- initialRequest
 				}
+				// Resume watching credentials requested on a previous connection
+				if names := p.getCredentialNames(); len(names) > 0 {
+					con.deltaRequestsChan <- deltaCredentialRequest(names)
+				}
 				initialRequestsSent = true
 			}
 		}
@@ -197,12 +202,11 @@ func (p *XdsProxy) handleUpstreamDeltaResponse(con *ProxyConnection) {
 			proxyLog.Debugf("response for type url %s", resp.TypeUrl)
 			metrics.XdsProxyResponses.Increment()
 			if h, f := p.handlers[resp.TypeUrl]; f {
-				if len(resp.Resources) == 0 {
-					// Empty response, nothing to do
-					// This assumes internal types are always singleton
-					return
+				resources := make([]*any.Any, 0, len(resp.Resources))
+				for _, r := range resp.Resources {
+					resources = append(resources, r.Resource)
 				}
-				err := h(resp.Resources[0].Resource)
+				err := handleResources(h, resources)
 				var errorResp *google_rpc.Status
 				if err != nil {
 					errorResp = &google_rpc.Status{
@@ -16,6 +16,11 @@ package istioagent
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -29,12 +34,14 @@ import (
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	wasmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/any"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/proto"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
@@ -49,6 +56,7 @@ import (
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/env"
 	"istio.io/istio/pkg/test/util/retry"
+	istiowasm "istio.io/istio/pkg/wasm"
 )
 
 func init() {
@@ -388,6 +396,61 @@ func TestXdsProxyReconnects(t *testing.T) {
 	})
 }
 
+func TestXdsProxyHandleUpstreamResponse(t *testing.T) {
+	var handled []string
+	proxy := &XdsProxy{
+		handlers: map[string]ResponseHandler{
+			"internal": func(resp *any.Any) error {
+				handled = append(handled, string(resp.Value))
+				if string(resp.Value) == "bad" {
+					return errors.New("bad resource")
+				}
+				return nil
+			},
+		},
+	}
+	con := &ProxyConnection{
+		requestsChan:  make(chan *discovery.DiscoveryRequest, 1),
+		responsesChan: make(chan *discovery.DiscoveryResponse, 1),
+		stopChan:      make(chan struct{}),
+	}
+	go proxy.handleUpstreamResponse(con)
+	defer close(con.stopChan)
+
+	expectAck := func(nonce string, nack bool) {
+		t.Helper()
+		select {
+		case ack := <-con.requestsChan:
+			if ack.ResponseNonce != nonce || ack.TypeUrl != "internal" {
+				t.Fatalf("got ACK for nonce %q and type %q, want nonce %q", ack.ResponseNonce, ack.TypeUrl, nonce)
+			}
+			if got := ack.ErrorDetail != nil; got != nack {
+				t.Fatalf("got NACK %v, want %v", got, nack)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("no ACK for nonce %q", nonce)
+		}
+	}
+
+	// An empty response is ACKed, and later responses are still handled.
+	con.responsesChan <- &discovery.DiscoveryResponse{TypeUrl: "internal", Nonce: "1"}
+	expectAck("1", false)
+
+	// Every resource is handled; a single failure NACKs the response.
+	con.responsesChan <- &discovery.DiscoveryResponse{
+		TypeUrl: "internal",
+		Nonce:   "2",
+		Resources: []*any.Any{
+			{TypeUrl: "internal", Value: []byte("bad")},
+			{TypeUrl: "internal", Value: []byte("good")},
+		},
+	}
+	expectAck("2", true)
+	if fmt.Sprint(handled) != "[bad good]" {
+		t.Fatalf("got handled resources %v, want [bad good]", handled)
+	}
+}
+
 type fakeAckCache struct{}
 
 func (f *fakeAckCache) Get(string, string, time.Duration) (string, error) {
@@ -516,6 +579,51 @@ func TestECDSWasmConversion(t *testing.T) {
 	}
 }
 
+func TestInitWasmSignatureVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trustedKeys := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	cases := []struct {
+		name       string
+		keys       string
+		cache      istiowasm.Cache
+		conversion bool
+		wantErr    bool
+	}{
+		{name: "no keys", cache: &fakeAckCache{}, conversion: true},
+		{name: "no keys without conversion", cache: &fakeAckCache{}},
+		{name: "verified", keys: trustedKeys, cache: istiowasm.NewLocalFileCache(t.TempDir(), time.Hour, time.Hour), conversion: true},
+		{name: "invalid keys", keys: "invalid", cache: &fakeAckCache{}, conversion: true, wantErr: true},
+		{name: "cache without verification", keys: trustedKeys, cache: &fakeAckCache{}, conversion: true, wantErr: true},
+		{name: "conversion disabled", keys: trustedKeys, cache: istiowasm.NewLocalFileCache(t.TempDir(), time.Hour, time.Hour), wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			conversion := features.WasmRemoteLoadConversion
+			features.WasmRemoteLoadConversion = tt.conversion
+			defer func() { features.WasmRemoteLoadConversion = conversion }()
+			defer tt.cache.Cleanup()
+
+			p := &XdsProxy{wasmCache: tt.cache}
+			agent := &Agent{proxyConfig: &meshconfig.ProxyConfig{ProxyMetadata: map[string]string{MetadataWasmTrustedKeys: tt.keys}}}
+			err := p.initWasmSignatureVerifier(agent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if vc, ok := tt.cache.(istiowasm.SignatureVerifyingCache); ok && err == nil && vc.VerifiesSignatures() != (tt.keys != "") {
+				t.Fatalf("expected the cache to verify signatures: %v", tt.keys != "")
+			}
+		})
+	}
+}
+
 func stream(t *testing.T, conn *grpc.ClientConn) discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient {
 	t.Helper()
 	adsClient := discovery.NewAggregatedDiscoveryServiceClient(conn)
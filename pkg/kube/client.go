@@ -155,6 +155,9 @@ type ExtendedClient interface {
 	// AllDiscoveryDo makes an http request to each Istio discovery instance.
 	AllDiscoveryDo(ctx context.Context, namespace, path string) (map[string][]byte, error)
 
+	// DiscoveryDo makes an http request to the Istio discovery instance in the specified pod.
+	DiscoveryDo(ctx context.Context, podName, podNamespace, path string) ([]byte, error)
+
 	// GetIstioVersions gets the version for each Istio control plane component.
 	GetIstioVersions(ctx context.Context, namespace string) (*version.MeshInfo, error)
 
@@ -460,6 +463,7 @@ func (c *client) RunAndWait(stop <-chan struct{}) {
 	c.metadataInformer.Start(stop)
 	c.istioInformer.Start(stop)
 	c.gatewayapiInformer.Start(stop)
+	c.mcsapisInformers.Start(stop)
 	if c.fastSync {
 		// WaitForCacheSync will virtually never be synced on the first call, as its called immediately after Start()
 		// This triggers a 100ms delay per call, which is often called 2-3 times in a test, delaying tests.
@@ -469,6 +473,7 @@ func (c *client) RunAndWait(stop <-chan struct{}) {
 		fastWaitForCacheSyncDynamic(c.metadataInformer)
 		fastWaitForCacheSync(c.istioInformer)
 		fastWaitForCacheSync(c.gatewayapiInformer)
+		fastWaitForCacheSync(c.mcsapisInformers)
 		_ = wait.PollImmediate(time.Microsecond, wait.ForeverTestTimeout, func() (bool, error) {
 			if c.informerWatchesPending.Load() == 0 {
 				return true, nil
@@ -481,6 +486,7 @@ func (c *client) RunAndWait(stop <-chan struct{}) {
 		c.metadataInformer.WaitForCacheSync(stop)
 		c.istioInformer.WaitForCacheSync(stop)
 		c.gatewayapiInformer.WaitForCacheSync(stop)
+		c.mcsapisInformers.WaitForCacheSync(stop)
 	}
 }
 
@@ -631,20 +637,9 @@ func (c *client) AllDiscoveryDo(ctx context.Context, istiodNamespace, path strin
 	var errs error
 	result := map[string][]byte{}
 	for _, istiod := range istiods {
-		res, err := c.CoreV1().Pods(istiod.Namespace).ProxyGet("", istiod.Name, "15014", path, nil).DoRaw(ctx)
+		res, err := c.DiscoveryDo(ctx, istiod.Name, istiod.Namespace, path)
 		if err != nil {
-			execRes, execErr := c.extractExecResult(istiod.Name, istiod.Namespace, discoveryContainer,
-				fmt.Sprintf("%s request GET %s", pilotDiscoveryPath, path))
-			if execErr != nil {
-				errs = multierror.Append(errs,
-					fmt.Errorf("error port-forwarding into %s.%s: %v", istiod.Name, istiod.Namespace, err),
-					execErr,
-				)
-				continue
-			}
-			if len(execRes) > 0 {
-				result[istiod.Name] = []byte(execRes)
-			}
+			errs = multierror.Append(errs, err)
 			continue
 		}
 		if len(res) > 0 {
@@ -658,6 +653,22 @@ func (c *client) AllDiscoveryDo(ctx context.Context, istiodNamespace, path strin
 	return nil, errs
 }
 
+func (c *client) DiscoveryDo(ctx context.Context, podName, podNamespace, path string) ([]byte, error) {
+	res, err := c.CoreV1().Pods(podNamespace).ProxyGet("", podName, "15014", path, nil).DoRaw(ctx)
+	if err == nil {
+		return res, nil
+	}
+	execRes, execErr := c.extractExecResult(podName, podNamespace, discoveryContainer,
+		fmt.Sprintf("%s request GET %s", pilotDiscoveryPath, path))
+	if execErr != nil {
+		return nil, multierror.Append(
+			fmt.Errorf("error port-forwarding into %s.%s: %v", podName, podNamespace, err),
+			execErr,
+		)
+	}
+	return []byte(execRes), nil
+}
+
 func (c *client) EnvoyDo(ctx context.Context, podName, podNamespace, method, path string, _ []byte) ([]byte, error) {
 	formatError := func(err error) error {
 		return fmt.Errorf("failure running port forward process: %v", err)
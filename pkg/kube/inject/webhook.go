@@ -703,6 +703,20 @@ func applyOverlay(target *corev1.Pod, overlayJSON []byte) (*corev1.Pod, error) {
 	return &pod, nil
 }
 
+// effectiveMeshConfig returns the mesh config with the ProxyConfig overrides of the namespace and workload
+// of the pod merged into its default ProxyConfig. The pod annotation is applied over it when rendering.
+func (wh *Webhook) effectiveMeshConfig(pod *corev1.Pod) *meshconfig.MeshConfig {
+	if wh.env == nil || wh.env.ProxyConfigSource == nil {
+		return wh.meshConfig
+	}
+	mc, err := model.GetProxyConfigs(wh.env).EffectiveMeshConfig(pod.Namespace, pod.Labels, wh.meshConfig)
+	if err != nil {
+		log.Warnf("Ignoring ProxyConfig overrides for %s/%s: %v", pod.Namespace, potentialPodName(pod.ObjectMeta), err)
+		return wh.meshConfig
+	}
+	return mc
+}
+
 func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.AdmissionResponse {
 	req := ar.Request
 	var pod corev1.Pod
@@ -739,7 +753,7 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 		templates:           wh.Config.Templates,
 		defaultTemplate:     wh.Config.DefaultTemplates,
 		aliases:             wh.Config.Aliases,
-		meshConfig:          wh.meshConfig,
+		meshConfig:          wh.effectiveMeshConfig(&pod),
 		valuesConfig:        wh.valuesConfig,
 		revision:            wh.revision,
 		injectedAnnotations: wh.Config.InjectedAnnotations,
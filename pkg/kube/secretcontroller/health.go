@@ -0,0 +1,212 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretcontroller
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/pkg/log"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	clusterTag = monitoring.MustCreateLabel("cluster")
+
+	clusterHealthy = monitoring.NewGauge(
+		"remote_cluster_healthy",
+		"Whether the remote cluster passed its last health check (1) or not (0).",
+		monitoring.WithLabels(clusterTag),
+	)
+
+	healthCheckFailures = monitoring.NewSum(
+		"remote_cluster_health_check_failures_total",
+		"Number of failed health checks of remote clusters.",
+		monitoring.WithLabels(clusterTag),
+	)
+)
+
+// ClusterStatus describes the health of a remote cluster.
+type ClusterStatus struct {
+	ID         string `json:"id"`
+	SecretName string `json:"secretName"`
+	// Synced is set once the informers of the cluster have synced.
+	Synced bool `json:"synced"`
+	// SyncLagSeconds is the time the informers took to sync, or have been syncing for so far.
+	SyncLagSeconds float64 `json:"syncLagSeconds"`
+	// Healthy is set if the last health check passed.
+	Healthy bool `json:"healthy"`
+	// LastCheck is the time of the last health check, zero if the cluster has not been checked yet.
+	LastCheck time.Time `json:"lastCheck,omitempty"`
+	// LastError is the reason the last health check failed.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// clusterHealth tracks the health of a remote cluster.
+type clusterHealth struct {
+	mu        sync.Mutex
+	created   time.Time
+	syncedAt  time.Time
+	checked   bool
+	lastCheck time.Time
+	lastErr   error
+	// rebuild is set if the cluster's credentials were rejected, so that its client is rebuilt from the secret
+	// even if the kubeconfig did not change, picking up rotated token files and refreshed exec credentials.
+	rebuild bool
+}
+
+func newClusterHealth() clusterHealth {
+	return clusterHealth{created: time.Now()}
+}
+
+func (h *clusterHealth) markSynced() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.syncedAt = time.Now()
+}
+
+func (h *clusterHealth) needsRebuild() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rebuild
+}
+
+// record stores the result of a health check. It returns true if the cluster's client should be rebuilt.
+func (h *clusterHealth) record(err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checked = true
+	h.lastCheck = time.Now()
+	h.lastErr = err
+	if kerrors.IsUnauthorized(err) && !h.rebuild {
+		h.rebuild = true
+		return true
+	}
+	return false
+}
+
+// syncLag returns how long the informers took to sync, or have been syncing for so far, and whether they synced.
+func (h *clusterHealth) syncLag() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.syncedAt.IsZero() {
+		return time.Since(h.created), false
+	}
+	return h.syncedAt.Sub(h.created), true
+}
+
+// maxHealthCheckTimeout bounds how long a health check waits for the API server of a remote cluster.
+const maxHealthCheckTimeout = 5 * time.Second
+
+// healthCheckTimeout returns the timeout of health checks run every interval, short enough that a check
+// completes well before the next one starts.
+func healthCheckTimeout(interval time.Duration) time.Duration {
+	if timeout := interval / 2; timeout < maxHealthCheckTimeout {
+		return timeout
+	}
+	return maxHealthCheckTimeout
+}
+
+// checkHealth checks that the API server of the cluster is reachable and that its informers have synced in time.
+func (r *Cluster) checkHealth(timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := r.Client.Kube().Discovery().ServerVersion()
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return err
+		}
+	case <-time.After(timeout):
+		return fmt.Errorf("API server did not respond within %v", timeout)
+	}
+
+	if lag, synced := r.health.syncLag(); !synced && features.RemoteClusterTimeout > 0 && lag > features.RemoteClusterTimeout {
+		return fmt.Errorf("informers have not synced after %v", lag.Round(time.Second))
+	}
+	return nil
+}
+
+func (r *Cluster) status(clusterID string) ClusterStatus {
+	lag, synced := r.health.syncLag()
+	r.health.mu.Lock()
+	defer r.health.mu.Unlock()
+	out := ClusterStatus{
+		ID:             clusterID,
+		SecretName:     r.secretName,
+		Synced:         synced,
+		SyncLagSeconds: lag.Seconds(),
+		Healthy:        r.health.checked && r.health.lastErr == nil,
+		LastCheck:      r.health.lastCheck,
+	}
+	if r.health.lastErr != nil {
+		out.LastError = r.health.lastErr.Error()
+	}
+	return out
+}
+
+// checkHealth checks all remote clusters. Clusters whose credentials are rejected are rebuilt from their secret.
+func (c *Controller) checkHealth() {
+	c.cs.RLock()
+	clusters := make(map[string]*Cluster, len(c.cs.remoteClusters))
+	for id, cluster := range c.cs.remoteClusters {
+		clusters[id] = cluster
+	}
+	c.cs.RUnlock()
+
+	wg := sync.WaitGroup{}
+	for id, cluster := range clusters {
+		id, cluster := id, cluster
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := cluster.checkHealth(healthCheckTimeout(features.RemoteClusterHealthCheckInterval))
+			rebuild := cluster.health.record(err)
+			if err != nil {
+				log.Warnf("health check of cluster_id=%v from secret=%v failed: %v", id, cluster.secretName, err)
+				healthCheckFailures.With(clusterTag.Value(id)).Increment()
+				clusterHealthy.With(clusterTag.Value(id)).Record(0)
+			} else {
+				clusterHealthy.With(clusterTag.Value(id)).Record(1)
+			}
+			if rebuild {
+				log.Infof("credentials of cluster_id=%v were rejected, rebuilding its client from secret=%v", id, cluster.secretName)
+				c.queue.Add(cluster.secretName)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ClusterStatus returns the status of all remote clusters, sorted by cluster ID.
+func (c *Controller) ClusterStatus() []ClusterStatus {
+	c.cs.RLock()
+	defer c.cs.RUnlock()
+	out := make([]ClusterStatus, 0, len(c.cs.remoteClusters))
+	for id, cluster := range c.cs.remoteClusters {
+		out = append(out, cluster.status(id))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -34,6 +35,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/client-go/util/workqueue"
 
 	"istio.io/istio/pilot/pkg/features"
@@ -49,7 +51,7 @@ const (
 )
 
 func init() {
-	monitoring.MustRegister(timeouts)
+	monitoring.MustRegister(timeouts, clusterHealthy, healthCheckFailures)
 }
 
 var timeouts = monitoring.NewSum(
@@ -96,12 +98,16 @@ type Cluster struct {
 	initialSync *atomic.Bool
 	// SyncTimeout is marked after features.RemoteClusterTimeout
 	SyncTimeout *atomic.Bool
+
+	// health is the result of the last health check.
+	health clusterHealth
 }
 
 // Run starts the cluster's informers and waits for caches to sync. Once caches are synced, we mark the cluster synced.
 // This should be called after each of the handlers have registered informers, and should be run in a goroutine.
 func (r *Cluster) Run() {
 	r.Client.RunAndWait(r.Stop)
+	r.health.markSynced()
 	r.initialSync.Store(true)
 }
 
@@ -235,6 +241,9 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 		})
 	}
 	go wait.Until(c.runWorker, 5*time.Second, stopCh)
+	if features.RemoteClusterHealthCheckInterval > 0 {
+		go wait.Until(c.checkHealth, features.RemoteClusterHealthCheckInterval, stopCh)
+	}
 	<-stopCh
 	c.close()
 }
@@ -355,6 +364,10 @@ var BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
 		return nil, fmt.Errorf("kubeconfig is not valid: %v", err)
 	}
 
+	if err := validateExecPlugins(rawConfig, features.RemoteClusterExecPlugins); err != nil {
+		return nil, err
+	}
+
 	clientConfig := clientcmd.NewDefaultClientConfig(*rawConfig, &clientcmd.ConfigOverrides{})
 
 	clients, err := kube.NewClient(clientConfig)
@@ -364,6 +377,31 @@ var BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
 	return clients, nil
 }
 
+// validateExecPlugins rejects kubeconfigs using exec credential plugins whose command is not in the comma separated
+// allow-list. Remote secrets are written by mesh administrators, but the plugins run inside istiod. An empty
+// allow-list allows all plugins.
+func validateExecPlugins(config *clientcmdapi.Config, allowList string) error {
+	allowed := map[string]struct{}{}
+	for _, command := range strings.Split(allowList, ",") {
+		if command = strings.TrimSpace(command); command != "" {
+			allowed[command] = struct{}{}
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for name, authInfo := range config.AuthInfos {
+		if authInfo == nil || authInfo.Exec == nil {
+			continue
+		}
+		if _, f := allowed[authInfo.Exec.Command]; !f {
+			return fmt.Errorf("kubeconfig user %q uses exec credential plugin %q, which is not allowed by "+
+				"PILOT_REMOTE_CLUSTER_EXEC_PLUGINS", name, authInfo.Exec.Command)
+		}
+	}
+	return nil
+}
+
 func (c *Controller) createRemoteCluster(kubeConfig []byte, secretName string) (*Cluster, error) {
 	clients, err := BuildClientsFromConfig(kubeConfig)
 	if err != nil {
@@ -378,6 +416,7 @@ func (c *Controller) createRemoteCluster(kubeConfig []byte, secretName string) (
 		initialSync:   atomic.NewBool(false),
 		SyncTimeout:   &c.remoteSyncTimeout,
 		kubeConfigSha: sha256.Sum256(kubeConfig),
+		health:        newClusterHealth(),
 	}, nil
 }
 
@@ -393,7 +432,7 @@ func (c *Controller) addMemberCluster(secretName string, s *corev1.Secret) {
 				continue
 			}
 			kubeConfigSha := sha256.Sum256(kubeConfig)
-			if bytes.Equal(kubeConfigSha[:], prev.kubeConfigSha[:]) {
+			if bytes.Equal(kubeConfigSha[:], prev.kubeConfigSha[:]) && !prev.health.needsRebuild() {
 				log.Infof("%s cluster_id=%v from secret=%v: (kubeconfig are identical)", clusterID, secretName)
 				continue
 			}
@@ -405,8 +444,15 @@ func (c *Controller) addMemberCluster(secretName string, s *corev1.Secret) {
 			log.Errorf("%s cluster_id=%v from secret=%v: %v", action, clusterID, secretName, err)
 			continue
 		}
+		prev, _ := c.cs.Get(clusterID)
 		c.cs.Store(clusterID, remoteCluster)
-		if err := callback(clusterID, remoteCluster); err != nil {
+		err = callback(clusterID, remoteCluster)
+		if prev != nil {
+			// The previous client is no longer tracked once replaced, whether or not the callback succeeded, so
+			// stop its informers. Clusters rebuilt after failed health checks would otherwise leak them.
+			close(prev.Stop)
+		}
+		if err != nil {
 			log.Errorf("%s cluster_id from secret=%v: %s %v", action, clusterID, secretName, err)
 			continue
 		}
@@ -432,6 +478,7 @@ func (c *Controller) deleteMemberCluster(secretName string) {
 			}
 			close(cluster.Stop)
 			delete(c.cs.remoteClusters, clusterID)
+			clusterHealthy.With(clusterTag.Value(clusterID)).Record(0)
 		}
 	}
 }
@@ -22,8 +22,11 @@ import (
 	"time"
 
 	. "github.com/onsi/gomega"
+	"go.uber.org/atomic"
 	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/kube"
@@ -174,3 +177,59 @@ func Test_SecretController(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateExecPlugins(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.AuthInfos["token"] = &clientcmdapi.AuthInfo{Token: "token"}
+	if err := validateExecPlugins(config, ""); err != nil {
+		t.Fatalf("expected kubeconfig without exec plugins to be allowed: %v", err)
+	}
+
+	config.AuthInfos["exec"] = &clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: "aws-iam-authenticator"}}
+	if err := validateExecPlugins(config, ""); err != nil {
+		t.Fatalf("expected exec plugin to be allowed by default: %v", err)
+	}
+	if err := validateExecPlugins(config, "gke-gcloud-auth-plugin"); err == nil {
+		t.Fatalf("expected exec plugin not in the allow-list to be rejected")
+	}
+	if err := validateExecPlugins(config, "gke-gcloud-auth-plugin, aws-iam-authenticator"); err != nil {
+		t.Fatalf("expected allowed exec plugin to be accepted: %v", err)
+	}
+}
+
+func TestClusterHealth(t *testing.T) {
+	g := NewWithT(t)
+	c := NewController(kube.NewFakeClient(), secretNamespace, addCallback, updateCallback, deleteCallback)
+	cluster := &Cluster{
+		secretName:  "istio-system/s0",
+		Client:      kube.NewFakeClient(),
+		Stop:        make(chan struct{}),
+		initialSync: atomic.NewBool(false),
+		SyncTimeout: atomic.NewBool(false),
+		health:      newClusterHealth(),
+	}
+	c.cs.Store("c0", cluster)
+
+	status := c.ClusterStatus()
+	g.Expect(status).To(HaveLen(1))
+	g.Expect(status[0].ID).To(Equal("c0"))
+	g.Expect(status[0].SecretName).To(Equal("istio-system/s0"))
+	g.Expect(status[0].Synced).To(BeFalse())
+	g.Expect(status[0].Healthy).To(BeFalse())
+
+	cluster.Run()
+	c.checkHealth()
+	status = c.ClusterStatus()
+	g.Expect(status[0].Synced).To(BeTrue())
+	g.Expect(status[0].Healthy).To(BeTrue())
+	g.Expect(status[0].LastCheck.IsZero()).To(BeFalse())
+	g.Expect(status[0].LastError).To(BeEmpty())
+
+	// Rejected credentials mark the cluster for a rebuild, once
+	g.Expect(cluster.health.record(kerrors.NewUnauthorized("token expired"))).To(BeTrue())
+	g.Expect(cluster.health.record(kerrors.NewUnauthorized("token expired"))).To(BeFalse())
+	g.Expect(cluster.health.needsRebuild()).To(BeTrue())
+	status = c.ClusterStatus()
+	g.Expect(status[0].Healthy).To(BeFalse())
+	g.Expect(status[0].LastError).To(ContainSubstring("token expired"))
+}
@@ -19,12 +19,14 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"istio.io/istio/pkg/cmd"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/proto"
 	"istio.io/istio/pkg/test/echo/server"
 	"istio.io/pkg/log"
 )
@@ -33,6 +35,7 @@ var (
 	httpPorts        []int
 	grpcPorts        []int
 	tcpPorts         []int
+	udpPorts         []int
 	tlsPorts         []int
 	instanceIPPorts  []int
 	localhostIPPorts []int
@@ -45,6 +48,14 @@ var (
 	key              string
 	istioVersion     string
 
+	faultDelay       time.Duration
+	faultDelayJitter time.Duration
+	faultErrorRate   float64
+	faultErrorCode   int
+	faultGRPCCode    int
+	faultResetRate   float64
+	faultChunkDelay  time.Duration
+
 	loggingOptions = log.DefaultOptions()
 
 	rootCmd = &cobra.Command{
@@ -54,7 +65,7 @@ var (
 		Long:              `Echo application for testing Istio E2E`,
 		PersistentPreRunE: configureLogging,
 		Run: func(cmd *cobra.Command, args []string) {
-			ports := make(common.PortList, len(httpPorts)+len(grpcPorts)+len(tcpPorts))
+			ports := make(common.PortList, len(httpPorts)+len(grpcPorts)+len(tcpPorts)+len(udpPorts))
 			tlsByPort := map[int]bool{}
 			for _, p := range tlsPorts {
 				tlsByPort[p] = true
@@ -94,6 +105,14 @@ var (
 				}
 				portIndex++
 			}
+			for i, p := range udpPorts {
+				ports[portIndex] = &common.Port{
+					Name:     "udp-" + strconv.Itoa(i),
+					Protocol: protocol.UDP,
+					Port:     p,
+				}
+				portIndex++
+			}
 			instanceIPByPort := map[int]struct{}{}
 			for _, p := range instanceIPPorts {
 				instanceIPByPort[p] = struct{}{}
@@ -114,6 +133,15 @@ var (
 				Cluster:               cluster,
 				IstioVersion:          istioVersion,
 				UDSServer:             uds,
+				Fault: &proto.Fault{
+					DelayMicros:       common.DurationToMicros(faultDelay),
+					DelayJitterMicros: common.DurationToMicros(faultDelayJitter),
+					ErrorRate:         faultErrorRate,
+					ErrorCode:         int32(faultErrorCode),
+					GrpcCode:          int32(faultGRPCCode),
+					ResetRate:         faultResetRate,
+					ChunkDelayMicros:  common.DurationToMicros(faultChunkDelay),
+				},
 			})
 
 			if err := s.Start(); err != nil {
@@ -143,6 +171,7 @@ func init() {
 	rootCmd.PersistentFlags().IntSliceVar(&httpPorts, "port", []int{8080}, "HTTP/1.1 ports")
 	rootCmd.PersistentFlags().IntSliceVar(&grpcPorts, "grpc", []int{7070}, "GRPC ports")
 	rootCmd.PersistentFlags().IntSliceVar(&tcpPorts, "tcp", []int{9090}, "TCP ports")
+	rootCmd.PersistentFlags().IntSliceVar(&udpPorts, "udp", []int{}, "UDP ports")
 	rootCmd.PersistentFlags().IntSliceVar(&tlsPorts, "tls", []int{}, "Ports that are using TLS. These must be defined as http/grpc/tcp.")
 	rootCmd.PersistentFlags().IntSliceVar(&instanceIPPorts, "bind-ip", []int{}, "Ports that are bound to INSTANCE_IP rather than wildcard IP.")
 	rootCmd.PersistentFlags().IntSliceVar(&localhostIPPorts, "bind-localhost", []int{}, "Ports that are bound to localhost rather than wildcard IP.")
@@ -154,6 +183,16 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&crt, "crt", "", "gRPC TLS server-side certificate")
 	rootCmd.PersistentFlags().StringVar(&key, "key", "", "gRPC TLS server-side key")
 	rootCmd.PersistentFlags().StringVar(&istioVersion, "istio-version", "", "Istio sidecar version")
+	rootCmd.PersistentFlags().DurationVar(&faultDelay, "fault-delay", 0, "Delay added to every request")
+	rootCmd.PersistentFlags().DurationVar(&faultDelayJitter, "fault-delay-jitter", 0,
+		"Maximum random delay added to every request, on top of --fault-delay")
+	rootCmd.PersistentFlags().Float64Var(&faultErrorRate, "fault-error-rate", 0, "Fraction of requests that fail, between 0 and 1")
+	rootCmd.PersistentFlags().IntVar(&faultErrorCode, "fault-error-code", 503, "HTTP status code of failed requests")
+	rootCmd.PersistentFlags().IntVar(&faultGRPCCode, "fault-grpc-code", 14, "gRPC status code of failed requests")
+	rootCmd.PersistentFlags().Float64Var(&faultResetRate, "fault-reset-rate", 0,
+		"Fraction of connections that are reset without a response, between 0 and 1")
+	rootCmd.PersistentFlags().DurationVar(&faultChunkDelay, "fault-chunk-delay", 0,
+		"Delay between the chunks of HTTP response bodies, to simulate a slow upstream")
 
 	loggingOptions.AttachCobraFlags(rootCmd)
 
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/proto"
+)
+
+// Headers used to ask the echo server to inject faults. Durations use the time.ParseDuration format and rates are
+// fractions between 0 and 1. For gRPC, the same names are used as (lower case) metadata keys.
+const (
+	FaultDelayHeader       = "X-Echo-Fault-Delay"
+	FaultDelayJitterHeader = "X-Echo-Fault-Delay-Jitter"
+	FaultErrorRateHeader   = "X-Echo-Fault-Error-Rate"
+	FaultErrorCodeHeader   = "X-Echo-Fault-Error-Code"
+	FaultGRPCCodeHeader    = "X-Echo-Fault-Grpc-Code"
+	FaultResetRateHeader   = "X-Echo-Fault-Reset-Rate"
+	FaultChunkDelayHeader  = "X-Echo-Fault-Chunk-Delay"
+)
+
+// FaultHeaders returns the request headers that ask the echo server to inject the given fault.
+func FaultHeaders(f *proto.Fault) http.Header {
+	h := make(http.Header)
+	if f == nil {
+		return h
+	}
+	setDuration := func(name string, micros int64) {
+		if micros > 0 {
+			h.Set(name, MicrosToDuration(micros).String())
+		}
+	}
+	setRate := func(name string, rate float64) {
+		if rate > 0 {
+			h.Set(name, strconv.FormatFloat(rate, 'f', -1, 64))
+		}
+	}
+	setCode := func(name string, code int32) {
+		if code != 0 {
+			h.Set(name, strconv.Itoa(int(code)))
+		}
+	}
+	setDuration(FaultDelayHeader, f.DelayMicros)
+	setDuration(FaultDelayJitterHeader, f.DelayJitterMicros)
+	setRate(FaultErrorRateHeader, f.ErrorRate)
+	setCode(FaultErrorCodeHeader, f.ErrorCode)
+	setCode(FaultGRPCCodeHeader, f.GrpcCode)
+	setRate(FaultResetRateHeader, f.ResetRate)
+	setDuration(FaultChunkDelayHeader, f.ChunkDelayMicros)
+	return h
+}
+
+// ParseFault returns the fault requested by the given request headers. Fields not set in the headers are taken
+// from defaults, which holds the faults configured for the server instance and may be nil.
+func ParseFault(h http.Header, defaults *proto.Fault) (*proto.Fault, error) {
+	f := &proto.Fault{}
+	if defaults != nil {
+		*f = *defaults
+	}
+	var err error
+	parseDuration := func(name string, out *int64) {
+		if v := h.Get(name); v != "" && err == nil {
+			d, perr := time.ParseDuration(v)
+			if perr != nil {
+				err = fmt.Errorf("invalid %s %q: %v", name, v, perr)
+				return
+			}
+			*out = DurationToMicros(d)
+		}
+	}
+	parseRate := func(name string, out *float64) {
+		if v := h.Get(name); v != "" && err == nil {
+			r, perr := strconv.ParseFloat(v, 64)
+			if perr != nil || r < 0 || r > 1 {
+				err = fmt.Errorf("invalid %s %q: must be a number between 0 and 1", name, v)
+				return
+			}
+			*out = r
+		}
+	}
+	parseCode := func(name string, out *int32) {
+		if v := h.Get(name); v != "" && err == nil {
+			c, perr := strconv.Atoi(v)
+			if perr != nil {
+				err = fmt.Errorf("invalid %s %q: %v", name, v, perr)
+				return
+			}
+			*out = int32(c)
+		}
+	}
+	parseDuration(FaultDelayHeader, &f.DelayMicros)
+	parseDuration(FaultDelayJitterHeader, &f.DelayJitterMicros)
+	parseRate(FaultErrorRateHeader, &f.ErrorRate)
+	parseCode(FaultErrorCodeHeader, &f.ErrorCode)
+	parseCode(FaultGRPCCodeHeader, &f.GrpcCode)
+	parseRate(FaultResetRateHeader, &f.ResetRate)
+	parseDuration(FaultChunkDelayHeader, &f.ChunkDelayMicros)
+	return f, err
+}
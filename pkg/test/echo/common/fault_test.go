@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	echoproto "istio.io/istio/pkg/test/echo/proto"
+)
+
+func TestParseFault(t *testing.T) {
+	defaults := &echoproto.Fault{
+		DelayMicros: DurationToMicros(time.Second),
+		ErrorRate:   0.5,
+		ErrorCode:   503,
+	}
+	cases := []struct {
+		name     string
+		headers  map[string]string
+		defaults *echoproto.Fault
+		want     *echoproto.Fault
+		wantErr  string
+	}{
+		{
+			name: "no fault",
+			want: &echoproto.Fault{},
+		},
+		{
+			name:     "defaults",
+			defaults: defaults,
+			want:     defaults,
+		},
+		{
+			name: "all headers",
+			headers: map[string]string{
+				FaultDelayHeader:       "10ms",
+				FaultDelayJitterHeader: "5ms",
+				FaultErrorRateHeader:   "0.25",
+				FaultErrorCodeHeader:   "429",
+				FaultGRPCCodeHeader:    "8",
+				FaultResetRateHeader:   "1",
+				FaultChunkDelayHeader:  "1s",
+			},
+			want: &echoproto.Fault{
+				DelayMicros:       10000,
+				DelayJitterMicros: 5000,
+				ErrorRate:         0.25,
+				ErrorCode:         429,
+				GrpcCode:          8,
+				ResetRate:         1,
+				ChunkDelayMicros:  1000000,
+			},
+		},
+		{
+			name:     "headers override defaults",
+			headers:  map[string]string{FaultErrorCodeHeader: "500", FaultResetRateHeader: "0.1"},
+			defaults: defaults,
+			want: &echoproto.Fault{
+				DelayMicros: DurationToMicros(time.Second),
+				ErrorRate:   0.5,
+				ErrorCode:   500,
+				ResetRate:   0.1,
+			},
+		},
+		{
+			name:    "invalid delay",
+			headers: map[string]string{FaultDelayHeader: "10"},
+			wantErr: FaultDelayHeader,
+		},
+		{
+			name:    "rate above 1",
+			headers: map[string]string{FaultErrorRateHeader: "1.5"},
+			wantErr: FaultErrorRateHeader,
+		},
+		{
+			name:    "negative rate",
+			headers: map[string]string{FaultResetRateHeader: "-0.1"},
+			wantErr: FaultResetRateHeader,
+		},
+		{
+			name:    "invalid rate",
+			headers: map[string]string{FaultErrorRateHeader: "half"},
+			wantErr: FaultErrorRateHeader,
+		},
+		{
+			name:    "invalid code",
+			headers: map[string]string{FaultGRPCCodeHeader: "UNAVAILABLE"},
+			wantErr: FaultGRPCCodeHeader,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			h := make(http.Header)
+			for k, v := range tt.headers {
+				h.Set(k, v)
+			}
+			got, err := ParseFault(h, tt.defaults)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error for %s, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFaultHeadersRoundTrip(t *testing.T) {
+	f := &echoproto.Fault{
+		DelayMicros:       1500,
+		DelayJitterMicros: 200,
+		ErrorRate:         0.3,
+		ErrorCode:         418,
+		GrpcCode:          14,
+		ResetRate:         0.05,
+		ChunkDelayMicros:  100000,
+	}
+	got, err := ParseFault(FaultHeaders(f), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, f) {
+		t.Errorf("got %v, want %v", got, f)
+	}
+	if h := FaultHeaders(nil); len(h) != 0 {
+		t.Errorf("expected no headers for a nil fault, got %v", h)
+	}
+}
@@ -22,6 +22,7 @@ type EchoMetrics struct {
 	HTTPRequests monitoring.Metric
 	GrpcRequests monitoring.Metric
 	TCPRequests  monitoring.Metric
+	UDPRequests  monitoring.Metric
 }
 
 var (
@@ -39,9 +40,13 @@ var (
 			"istio_echo_tcp_requests_total",
 			"The number of tcp requests total",
 		),
+		UDPRequests: monitoring.NewSum(
+			"istio_echo_udp_requests_total",
+			"The number of udp requests total",
+		),
 	}
 )
 
 func init() {
-	monitoring.MustRegister(Metrics.HTTPRequests, Metrics.GrpcRequests, Metrics.TCPRequests)
+	monitoring.MustRegister(Metrics.HTTPRequests, Metrics.GrpcRequests, Metrics.TCPRequests, Metrics.UDPRequests)
 }
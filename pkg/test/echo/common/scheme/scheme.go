@@ -23,5 +23,6 @@ const (
 	GRPC      Instance = "grpc"
 	WebSocket Instance = "ws"
 	TCP       Instance = "tcp"
+	UDP       Instance = "udp"
 	DNS       Instance = "dns"
 )
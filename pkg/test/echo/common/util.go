@@ -50,12 +50,15 @@ func GetCount(request *proto.ForwardEchoRequest) int {
 	return DefaultCount
 }
 
-// GetHeaders returns the headers for the message.
+// GetHeaders returns the headers for the message, including the headers requesting the fault to inject, if any.
 func GetHeaders(request *proto.ForwardEchoRequest) http.Header {
 	headers := make(http.Header)
 	for _, h := range request.Headers {
 		headers.Add(h.Key, h.Value)
 	}
+	for k, v := range FaultHeaders(request.Fault) {
+		headers[k] = v
+	}
 	return headers
 }
 
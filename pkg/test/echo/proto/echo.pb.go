@@ -183,7 +183,9 @@ type ForwardEchoRequest struct {
 	// List of ALPNs to present. If not set, this will be automatically be set based on the protocol
 	Alpn *Alpn `protobuf:"bytes,13,opt,name=alpn,proto3" json:"alpn,omitempty"`
 	// Server name (SNI) to present in TLS connections. If not set, Host will be used for http requests.
-	ServerName           string   `protobuf:"bytes,20,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	ServerName string `protobuf:"bytes,20,opt,name=serverName,proto3" json:"serverName,omitempty"`
+	// Faults for the destination server to inject when handling the requests.
+	Fault                *Fault   `protobuf:"bytes,21,opt,name=fault,proto3" json:"fault,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -354,6 +356,13 @@ func (m *ForwardEchoRequest) GetServerName() string {
 	return ""
 }
 
+func (m *ForwardEchoRequest) GetFault() *Fault {
+	if m != nil {
+		return m.Fault
+	}
+	return nil
+}
+
 type Alpn struct {
 	Value                []string `protobuf:"bytes,1,rep,name=value,proto3" json:"value,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -432,6 +441,101 @@ func (m *ForwardEchoResponse) GetOutput() []string {
 	return nil
 }
 
+// Fault describes faults a server injects when handling an echo request.
+type Fault struct {
+	// Fixed delay before responding.
+	DelayMicros int64 `protobuf:"varint,1,opt,name=delay_micros,json=delayMicros,proto3" json:"delay_micros,omitempty"`
+	// Maximum random delay added to the fixed delay.
+	DelayJitterMicros int64 `protobuf:"varint,2,opt,name=delay_jitter_micros,json=delayJitterMicros,proto3" json:"delay_jitter_micros,omitempty"`
+	// Fraction of requests, between 0 and 1, that fail with error_code (HTTP) or grpc_code (gRPC).
+	ErrorRate float64 `protobuf:"fixed64,3,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`
+	// HTTP status code for failed requests. Defaults to 503.
+	ErrorCode int32 `protobuf:"varint,4,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	// gRPC status code for failed requests. Defaults to UNAVAILABLE.
+	GrpcCode int32 `protobuf:"varint,5,opt,name=grpc_code,json=grpcCode,proto3" json:"grpc_code,omitempty"`
+	// Fraction of requests, between 0 and 1, for which the connection is reset without a response.
+	ResetRate float64 `protobuf:"fixed64,6,opt,name=reset_rate,json=resetRate,proto3" json:"reset_rate,omitempty"`
+	// If set, the response body is streamed in chunks with this delay between them.
+	ChunkDelayMicros     int64    `protobuf:"varint,7,opt,name=chunk_delay_micros,json=chunkDelayMicros,proto3" json:"chunk_delay_micros,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Fault) Reset()         { *m = Fault{} }
+func (m *Fault) String() string { return proto.CompactTextString(m) }
+func (*Fault) ProtoMessage()    {}
+func (*Fault) Descriptor() ([]byte, []int) {
+	return fileDescriptor_08134aea513e0001, []int{6}
+}
+
+func (m *Fault) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Fault.Unmarshal(m, b)
+}
+func (m *Fault) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Fault.Marshal(b, m, deterministic)
+}
+func (m *Fault) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Fault.Merge(m, src)
+}
+func (m *Fault) XXX_Size() int {
+	return xxx_messageInfo_Fault.Size(m)
+}
+func (m *Fault) XXX_DiscardUnknown() {
+	xxx_messageInfo_Fault.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Fault proto.InternalMessageInfo
+
+func (m *Fault) GetDelayMicros() int64 {
+	if m != nil {
+		return m.DelayMicros
+	}
+	return 0
+}
+
+func (m *Fault) GetDelayJitterMicros() int64 {
+	if m != nil {
+		return m.DelayJitterMicros
+	}
+	return 0
+}
+
+func (m *Fault) GetErrorRate() float64 {
+	if m != nil {
+		return m.ErrorRate
+	}
+	return 0
+}
+
+func (m *Fault) GetErrorCode() int32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *Fault) GetGrpcCode() int32 {
+	if m != nil {
+		return m.GrpcCode
+	}
+	return 0
+}
+
+func (m *Fault) GetResetRate() float64 {
+	if m != nil {
+		return m.ResetRate
+	}
+	return 0
+}
+
+func (m *Fault) GetChunkDelayMicros() int64 {
+	if m != nil {
+		return m.ChunkDelayMicros
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*EchoRequest)(nil), "proto.EchoRequest")
 	proto.RegisterType((*EchoResponse)(nil), "proto.EchoResponse")
@@ -439,6 +543,7 @@ func init() {
 	proto.RegisterType((*ForwardEchoRequest)(nil), "proto.ForwardEchoRequest")
 	proto.RegisterType((*Alpn)(nil), "proto.Alpn")
 	proto.RegisterType((*ForwardEchoResponse)(nil), "proto.ForwardEchoResponse")
+	proto.RegisterType((*Fault)(nil), "proto.Fault")
 }
 
 func init() {
@@ -446,38 +551,47 @@ func init() {
 }
 
 var fileDescriptor_08134aea513e0001 = []byte{
-	// 495 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x52, 0xdb, 0x8e, 0xd3, 0x3c,
-	0x10, 0x56, 0xfe, 0x26, 0x69, 0x77, 0xb2, 0xdd, 0xee, 0x3f, 0xad, 0x2a, 0x53, 0x21, 0x88, 0x2a,
-	0xa1, 0xcd, 0x0d, 0x05, 0xb5, 0x4f, 0x80, 0x80, 0x8a, 0x1b, 0xb8, 0xc8, 0x22, 0x6e, 0x51, 0x48,
-	0x67, 0x69, 0xd4, 0x34, 0xce, 0xda, 0x4e, 0x57, 0x7d, 0x03, 0x5e, 0x8e, 0x77, 0x42, 0x3e, 0x94,
-	0xa4, 0xb0, 0xe2, 0xca, 0xfe, 0x0e, 0x1e, 0x7f, 0x1e, 0x0f, 0x00, 0xe5, 0x5b, 0xbe, 0xa8, 0x05,
-	0x57, 0x1c, 0x03, 0xb3, 0xcc, 0x6f, 0x20, 0x7a, 0x9f, 0x6f, 0x79, 0x4a, 0xf7, 0x0d, 0x49, 0x85,
-	0x0c, 0xfa, 0x7b, 0x92, 0x32, 0xfb, 0x4e, 0xcc, 0x8b, 0xbd, 0xe4, 0x22, 0x3d, 0xc1, 0x79, 0x02,
-	0x97, 0xd6, 0x28, 0x6b, 0x5e, 0x49, 0xfa, 0x87, 0xf3, 0x35, 0x84, 0x1f, 0x28, 0xdb, 0x90, 0xc0,
-	0x6b, 0xe8, 0xed, 0xe8, 0xe8, 0x74, 0xbd, 0xc5, 0x09, 0x04, 0x87, 0xac, 0x6c, 0x88, 0xfd, 0x67,
-	0x38, 0x0b, 0xe6, 0x3f, 0x7d, 0xc0, 0x35, 0x17, 0x0f, 0x99, 0xd8, 0x74, 0xc3, 0x4c, 0x20, 0xc8,
-	0x79, 0x53, 0x29, 0x53, 0x20, 0x48, 0x2d, 0xd0, 0x45, 0xef, 0x6b, 0x69, 0x0a, 0x04, 0xa9, 0xde,
-	0xe2, 0x0b, 0xb8, 0x52, 0xc5, 0x9e, 0x78, 0xa3, 0xbe, 0xee, 0x8b, 0x5c, 0x70, 0xc9, 0x7a, 0xb1,
-	0x97, 0xf4, 0xd2, 0xa1, 0x63, 0x3f, 0x1a, 0x52, 0x1f, 0x6c, 0x44, 0xc9, 0x7c, 0x9b, 0xa6, 0x11,
-	0x25, 0xde, 0x40, 0x7f, 0x6b, 0x92, 0x4a, 0x16, 0xc4, 0xbd, 0x24, 0x5a, 0x0e, 0x6d, 0x73, 0x16,
-	0x36, 0x7f, 0x7a, 0x52, 0xbb, 0x8f, 0x0d, 0xcf, 0x1e, 0x8b, 0x53, 0x08, 0xf7, 0xa4, 0xb6, 0x7c,
-	0xc3, 0x2e, 0x8c, 0xe0, 0x90, 0xce, 0xbe, 0x55, 0xaa, 0x5e, 0xb2, 0x7e, 0xec, 0x25, 0x83, 0xd4,
-	0x82, 0x13, 0xbb, 0x62, 0xa3, 0x96, 0x5d, 0x61, 0x0c, 0x91, 0x24, 0x71, 0x20, 0xb1, 0x2e, 0x84,
-	0x54, 0x6c, 0x60, 0xb4, 0x2e, 0x85, 0x09, 0x8c, 0xee, 0x78, 0x59, 0xf2, 0x87, 0x94, 0x36, 0x85,
-	0xa0, 0x5c, 0x49, 0x76, 0x65, 0x5c, 0x7f, 0xd2, 0x88, 0xe0, 0xe7, 0x24, 0x14, 0x03, 0x93, 0xc6,
-	0xec, 0x4f, 0xdf, 0x10, 0xb5, 0xdf, 0x30, 0x85, 0x30, 0xcf, 0xde, 0x6a, 0xdf, 0xa5, 0x4d, 0x6d,
-	0x11, 0xce, 0x60, 0xa0, 0x4f, 0xac, 0x8b, 0x92, 0xd8, 0xb5, 0x51, 0x7e, 0x63, 0xdd, 0x83, 0x1d,
-	0x1d, 0x8d, 0xf4, 0xbf, 0xed, 0x81, 0x83, 0xf8, 0x0c, 0xc0, 0x9e, 0x37, 0x22, 0x1a, 0xb1, 0xc3,
-	0xe0, 0x02, 0xb0, 0xa8, 0x24, 0xe5, 0x8d, 0xa0, 0xdb, 0x5d, 0x51, 0x7f, 0x21, 0x51, 0xdc, 0x1d,
-	0xd9, 0xd8, 0x3c, 0xe0, 0x11, 0x05, 0x9f, 0x83, 0x9f, 0x95, 0x75, 0xc5, 0x86, 0xb1, 0x97, 0x44,
-	0xcb, 0xc8, 0xfd, 0xc9, 0x9b, 0xb2, 0xae, 0x52, 0x23, 0xe8, 0x0b, 0x6d, 0x77, 0x3e, 0x65, 0x7b,
-	0x62, 0x13, 0x7b, 0x61, 0xcb, 0xcc, 0x9f, 0x82, 0xaf, 0xdd, 0xed, 0xb4, 0x79, 0x71, 0xaf, 0x9d,
-	0xb6, 0x97, 0x30, 0x3e, 0x1b, 0x36, 0x37, 0xd0, 0x53, 0x08, 0x79, 0xa3, 0xea, 0x46, 0x39, 0xb7,
-	0x43, 0xcb, 0x1f, 0x1e, 0x8c, 0xb4, 0xf1, 0x33, 0x49, 0x75, 0x4b, 0xe2, 0x50, 0xe4, 0x84, 0xaf,
-	0xc0, 0xd7, 0x14, 0xa2, 0xcb, 0xd6, 0x99, 0xda, 0xd9, 0xf8, 0x8c, 0x73, 0xc5, 0xdf, 0x41, 0xd4,
-	0xb9, 0x13, 0x9f, 0x38, 0xcf, 0xdf, 0x43, 0x3f, 0x9b, 0x3d, 0x26, 0xd9, 0x2a, 0xdf, 0x42, 0x23,
-	0xad, 0x7e, 0x05, 0x00, 0x00, 0xff, 0xff, 0x06, 0x06, 0xb1, 0xb3, 0xc8, 0x03, 0x00, 0x00,
+	// 628 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x94, 0xcd, 0x6e, 0xd3, 0x40,
+	0x10, 0xc7, 0xe5, 0x26, 0xce, 0xc7, 0x38, 0xfd, 0xda, 0x94, 0x6a, 0x09, 0x14, 0x4c, 0x24, 0x54,
+	0x1f, 0x20, 0xa0, 0xf4, 0x09, 0x50, 0x4b, 0x84, 0x90, 0xe0, 0xe0, 0x22, 0xae, 0x95, 0xb1, 0xa7,
+	0x8d, 0x89, 0x93, 0x75, 0x77, 0xd7, 0xad, 0x72, 0xe5, 0xc4, 0xeb, 0xf2, 0x06, 0x68, 0x67, 0xd7,
+	0x38, 0x81, 0x8a, 0x53, 0x77, 0xfe, 0xbf, 0xd9, 0xbf, 0x67, 0x67, 0xa6, 0x01, 0xc0, 0x74, 0x2e,
+	0x26, 0xa5, 0x14, 0x5a, 0x30, 0x9f, 0xfe, 0x8c, 0x4f, 0x21, 0x78, 0x9f, 0xce, 0x45, 0x8c, 0xb7,
+	0x15, 0x2a, 0xcd, 0x38, 0x74, 0x97, 0xa8, 0x54, 0x72, 0x83, 0xdc, 0x0b, 0xbd, 0xa8, 0x1f, 0xd7,
+	0xe1, 0x38, 0x82, 0x81, 0x4d, 0x54, 0xa5, 0x58, 0x29, 0xfc, 0x4f, 0xe6, 0x5b, 0xe8, 0x7c, 0xc0,
+	0x24, 0x43, 0xc9, 0x0e, 0xa0, 0xb5, 0xc0, 0xb5, 0xe3, 0xe6, 0xc8, 0x8e, 0xc0, 0xbf, 0x4b, 0x8a,
+	0x0a, 0xf9, 0x0e, 0x69, 0x36, 0x18, 0xff, 0x6a, 0x03, 0x9b, 0x09, 0x79, 0x9f, 0xc8, 0x6c, 0xb3,
+	0x98, 0x23, 0xf0, 0x53, 0x51, 0xad, 0x34, 0x19, 0xf8, 0xb1, 0x0d, 0x8c, 0xe9, 0x6d, 0xa9, 0xc8,
+	0xc0, 0x8f, 0xcd, 0x91, 0xbd, 0x84, 0x3d, 0x9d, 0x2f, 0x51, 0x54, 0xfa, 0x6a, 0x99, 0xa7, 0x52,
+	0x28, 0xde, 0x0a, 0xbd, 0xa8, 0x15, 0xef, 0x3a, 0xf5, 0x13, 0x89, 0xe6, 0x62, 0x25, 0x0b, 0xde,
+	0xb6, 0xd5, 0x54, 0xb2, 0x60, 0xa7, 0xd0, 0x9d, 0x53, 0xa5, 0x8a, 0xfb, 0x61, 0x2b, 0x0a, 0xa6,
+	0xbb, 0xb6, 0x39, 0x13, 0x5b, 0x7f, 0x5c, 0xd3, 0xcd, 0xc7, 0x76, 0xb6, 0x1e, 0xcb, 0x8e, 0xa1,
+	0xb3, 0x44, 0x3d, 0x17, 0x19, 0xef, 0x13, 0x70, 0x91, 0xa9, 0x7d, 0xae, 0x75, 0x39, 0xe5, 0xdd,
+	0xd0, 0x8b, 0x7a, 0xb1, 0x0d, 0x6a, 0xf5, 0x8c, 0xef, 0x37, 0xea, 0x19, 0x0b, 0x21, 0x50, 0x28,
+	0xef, 0x50, 0xce, 0x72, 0xa9, 0x34, 0xef, 0x11, 0xdb, 0x94, 0x58, 0x04, 0xfb, 0xd7, 0xa2, 0x28,
+	0xc4, 0x7d, 0x8c, 0x59, 0x2e, 0x31, 0xd5, 0x8a, 0xef, 0x51, 0xd6, 0xdf, 0x32, 0x63, 0xd0, 0x4e,
+	0x51, 0x6a, 0x0e, 0x54, 0x0d, 0x9d, 0xeb, 0x31, 0x04, 0xcd, 0x18, 0x8e, 0xa1, 0x93, 0x26, 0xe7,
+	0x26, 0x6f, 0x60, 0xab, 0xb6, 0x11, 0x1b, 0x41, 0xcf, 0xdc, 0x98, 0xe5, 0x05, 0xf2, 0x03, 0x22,
+	0x7f, 0x62, 0xd3, 0x83, 0x05, 0xae, 0x09, 0x1d, 0xda, 0x1e, 0xb8, 0x90, 0x3d, 0x03, 0xb0, 0xf7,
+	0x09, 0x32, 0x82, 0x1b, 0x0a, 0x9b, 0x00, 0xcb, 0x57, 0x0a, 0xd3, 0x4a, 0xe2, 0xe5, 0x22, 0x2f,
+	0xbf, 0xa2, 0xcc, 0xaf, 0xd7, 0x7c, 0x48, 0x0f, 0x78, 0x80, 0xb0, 0xe7, 0xd0, 0x4e, 0x8a, 0x72,
+	0xc5, 0x77, 0x43, 0x2f, 0x0a, 0xa6, 0x81, 0x9b, 0xc9, 0xbb, 0xa2, 0x5c, 0xc5, 0x04, 0xcc, 0x07,
+	0x6d, 0x77, 0x3e, 0x27, 0x4b, 0xe4, 0x47, 0xf6, 0x83, 0x8d, 0xc2, 0xc6, 0xe0, 0x5f, 0x27, 0x55,
+	0xa1, 0xf9, 0x23, 0x72, 0x18, 0x38, 0x87, 0x99, 0xd1, 0x62, 0x8b, 0xc6, 0x4f, 0xa1, 0x6d, 0x1c,
+	0x9b, 0x8d, 0xf4, 0xc2, 0x56, 0xb3, 0x91, 0xaf, 0x61, 0xb8, 0xb5, 0x90, 0x6e, 0xe9, 0x8f, 0xa1,
+	0x23, 0x2a, 0x5d, 0x56, 0xda, 0x65, 0xbb, 0x68, 0xfc, 0x63, 0x07, 0x7c, 0x72, 0x67, 0x2f, 0x60,
+	0x90, 0x61, 0x91, 0xac, 0xeb, 0x4d, 0xf4, 0x68, 0x13, 0x03, 0xd2, 0xdc, 0x1e, 0x4e, 0x60, 0x68,
+	0x53, 0xbe, 0xe7, 0x5a, 0xa3, 0xac, 0x33, 0x77, 0x28, 0xf3, 0x90, 0xd0, 0x47, 0x22, 0x2e, 0xff,
+	0x04, 0x00, 0xa5, 0x14, 0xf2, 0x4a, 0x26, 0x1a, 0x69, 0xb5, 0xbd, 0xb8, 0x4f, 0x4a, 0x9c, 0x68,
+	0x6c, 0x70, 0x2a, 0x32, 0xa4, 0xed, 0xf6, 0x1d, 0x3e, 0x17, 0x19, 0xb2, 0x27, 0xd0, 0xbf, 0x91,
+	0x65, 0x6a, 0xa9, 0x4f, 0xb4, 0x67, 0x04, 0x82, 0x27, 0x00, 0x12, 0x15, 0x6a, 0x6b, 0xdd, 0xb1,
+	0xd6, 0xa4, 0x90, 0xf5, 0x2b, 0x60, 0xe9, 0xbc, 0x5a, 0x2d, 0xae, 0xb6, 0x9e, 0xd4, 0xa5, 0x42,
+	0x0f, 0x88, 0x5c, 0x34, 0xef, 0x9a, 0xfe, 0xf4, 0x60, 0xdf, 0x74, 0xeb, 0x0b, 0x2a, 0x7d, 0x89,
+	0xf2, 0x2e, 0x4f, 0x91, 0xbd, 0x81, 0xb6, 0x91, 0x18, 0x73, 0x23, 0xd8, 0xf8, 0xf7, 0x1e, 0x0d,
+	0xb7, 0x34, 0xd7, 0xe1, 0x0b, 0x08, 0x36, 0x1a, 0xcf, 0x1e, 0xd7, 0xa3, 0xfb, 0xe7, 0xd7, 0x61,
+	0x34, 0x7a, 0x08, 0x59, 0x97, 0x6f, 0x1d, 0x42, 0x67, 0xbf, 0x07, 0x00, 0x30, 0x0d, 0xcc, 0x04,
+	0xf1, 0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
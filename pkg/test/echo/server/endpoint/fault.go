@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/proto"
+)
+
+// injectDelay sleeps for the fixed delay of the fault plus a random jitter.
+func injectDelay(f *proto.Fault) {
+	d := common.MicrosToDuration(f.GetDelayMicros())
+	if jitter := f.GetDelayJitterMicros(); jitter > 0 {
+		d += common.MicrosToDuration(rand.Int63n(jitter + 1))
+	}
+	if d > 0 {
+		epLog.Infof("Injecting delay of %v", d)
+		time.Sleep(d)
+	}
+}
+
+// shouldFail reports whether the request must be failed with an error.
+func shouldFail(f *proto.Fault) bool {
+	return f.GetErrorRate() > 0 && rand.Float64() < f.GetErrorRate()
+}
+
+// shouldReset reports whether the connection must be reset without a response.
+func shouldReset(f *proto.Fault) bool {
+	return f.GetResetRate() > 0 && rand.Float64() < f.GetResetRate()
+}
+
+// faultHTTPCode returns the HTTP status code for a failed request.
+func faultHTTPCode(f *proto.Fault) int {
+	if code := f.GetErrorCode(); code > 0 {
+		return int(code)
+	}
+	return http.StatusServiceUnavailable
+}
+
+// faultGRPCCode returns the gRPC status code for a failed request.
+func faultGRPCCode(f *proto.Fault) codes.Code {
+	if code := f.GetGrpcCode(); code > 0 {
+		return codes.Code(code)
+	}
+	return codes.Unavailable
+}
+
+// resetConn closes the connection so that the peer sees a TCP RST rather than a FIN.
+func resetConn(conn net.Conn) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
+
+// resetListener tracks the connections it accepts, so that the connection of a request can be reset by a handler
+// that has no access to it, such as a gRPC handler.
+type resetListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns map[string]net.Conn
+}
+
+func newResetListener(l net.Listener) *resetListener {
+	return &resetListener{
+		Listener: l,
+		conns:    map[string]net.Conn{},
+	}
+}
+
+// Accept implements net.Listener.
+func (l *resetListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	key := conn.RemoteAddr().String()
+	l.mu.Lock()
+	l.conns[key] = conn
+	l.mu.Unlock()
+	return &trackedConn{Conn: conn, onClose: func() {
+		l.mu.Lock()
+		delete(l.conns, key)
+		l.mu.Unlock()
+	}}, nil
+}
+
+// reset resets the connection from the given peer. It returns false if the connection is unknown.
+func (l *resetListener) reset(peer net.Addr) bool {
+	if l == nil || peer == nil {
+		return false
+	}
+	l.mu.Lock()
+	conn, ok := l.conns[peer.String()]
+	l.mu.Unlock()
+	if ok {
+		resetConn(conn)
+	}
+	return ok
+}
+
+// trackedConn is a connection accepted by a resetListener.
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
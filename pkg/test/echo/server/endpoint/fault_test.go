@@ -0,0 +1,266 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/proto"
+)
+
+// startEndpoint starts an endpoint for the given protocol on a free port, and returns the port.
+func startEndpoint(t *testing.T, p protocol.Instance, fault *proto.Fault) int {
+	t.Helper()
+	// The HTTP readiness check expects the server not to be ready until all of its endpoints are.
+	var serverReady atomic.Value
+	serverReady.Store(false)
+	ep, err := New(Config{
+		Port:          &common.Port{Name: string(p), Protocol: p},
+		ListenerIP:    "127.0.0.1",
+		IsServerReady: func() bool { return serverReady.Load().(bool) },
+		Fault:         fault,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := make(chan struct{})
+	if err := ep.Start(func() {
+		serverReady.Store(true)
+		close(ready)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ep.Close() })
+	select {
+	case <-ready:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%s endpoint not ready", p)
+	}
+	return ep.GetConfig().Port.Port
+}
+
+func TestHTTPFault(t *testing.T) {
+	port := startEndpoint(t, protocol.HTTP, nil)
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+
+	cases := []struct {
+		name     string
+		headers  map[string]string
+		wantCode int
+		wantErr  bool
+		minTime  time.Duration
+	}{
+		{
+			name:     "no fault",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "error",
+			headers:  map[string]string{common.FaultErrorRateHeader: "1", common.FaultErrorCodeHeader: "418"},
+			wantCode: http.StatusTeapot,
+		},
+		{
+			name:     "default error code",
+			headers:  map[string]string{common.FaultErrorRateHeader: "1"},
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:     "delay",
+			headers:  map[string]string{common.FaultDelayHeader: "200ms"},
+			wantCode: http.StatusOK,
+			minTime:  200 * time.Millisecond,
+		},
+		{
+			name:    "reset",
+			headers: map[string]string{common.FaultResetRateHeader: "1"},
+			wantErr: true,
+		},
+		{
+			name: "invalid fault is not applied",
+			headers: map[string]string{
+				common.FaultErrorRateHeader: "1",
+				common.FaultDelayHeader:     "1h",
+				common.FaultResetRateHeader: "2",
+			},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+			start := time.Now()
+			resp, err := client.Do(req)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("expected the connection to be reset, got status %d", resp.StatusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("got status %d, want %d: %s", resp.StatusCode, tt.wantCode, body)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minTime {
+				t.Errorf("got response after %v, want at least %v", elapsed, tt.minTime)
+			}
+		})
+	}
+}
+
+func TestHTTPServerFault(t *testing.T) {
+	port := startEndpoint(t, protocol.HTTP, &proto.Fault{ErrorRate: 1, ErrorCode: 500})
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("got status %d, want the server fault %d", resp.StatusCode, http.StatusInternalServerError)
+	}
+
+	// Request headers override the server fault.
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/", port), nil)
+	req.Header.Set(common.FaultErrorRateHeader, "0")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestGRPCFault(t *testing.T) {
+	port := startEndpoint(t, protocol.GRPC, nil)
+
+	cases := []struct {
+		name     string
+		headers  map[string]string
+		wantCode codes.Code
+		reset    bool
+	}{
+		{
+			name:     "no fault",
+			wantCode: codes.OK,
+		},
+		{
+			name:     "error",
+			headers:  map[string]string{common.FaultErrorRateHeader: "1", common.FaultGRPCCodeHeader: strconv.Itoa(int(codes.ResourceExhausted))},
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "invalid fault",
+			headers:  map[string]string{common.FaultErrorRateHeader: "invalid"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "reset",
+			headers:  map[string]string{common.FaultResetRateHeader: "1"},
+			wantCode: codes.Unavailable,
+			reset:    true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := grpc.DialContext(ctx, fmt.Sprintf("127.0.0.1:%d", port), grpc.WithInsecure(), grpc.WithBlock())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			client := proto.NewEchoTestServiceClient(conn)
+
+			md := metadata.New(tt.headers)
+			_, err = client.Echo(metadata.NewOutgoingContext(ctx, md), &proto.EchoRequest{Message: "hello"})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("got code %v, want %v: %v", got, tt.wantCode, err)
+			}
+			// A reset closes the connection, rather than only failing the request.
+			if tt.reset && !conn.WaitForStateChange(ctx, connectivity.Ready) {
+				t.Fatalf("expected the connection to be reset, got state %v", conn.GetState())
+			}
+		})
+	}
+}
+
+func TestTCPFault(t *testing.T) {
+	t.Run("reset", func(t *testing.T) {
+		port := startEndpoint(t, protocol.TCP, &proto.Fault{ResetRate: 1})
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			// The connection may be reset before the client sees it established.
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		_, _ = conn.Write([]byte("hello\n"))
+		if _, err := io.ReadAll(conn); err == nil {
+			t.Fatal("expected the connection to be reset")
+		}
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		port := startEndpoint(t, protocol.TCP, &proto.Fault{DelayMicros: common.DurationToMicros(200 * time.Millisecond)})
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		start := time.Now()
+		if _, err := conn.Write([]byte("hello\n")); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 1024)
+		if _, err := conn.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("got response after %v, want at least 200ms", elapsed)
+		}
+	})
+}
@@ -19,16 +19,19 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 
 	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/common/response"
@@ -62,6 +65,9 @@ func (s *grpcInstance) Start(onReady OnReadyFunc) error {
 	}
 	// Store the actual listening port back to the argument.
 	s.Port.Port = p
+	// Track the connections, so that resets can be injected.
+	resets := newResetListener(listener)
+	listener = resets
 
 	if s.Port.TLS {
 		fmt.Printf("Listening GRPC (over TLS) on %v\n", p)
@@ -77,6 +83,7 @@ func (s *grpcInstance) Start(onReady OnReadyFunc) error {
 	}
 	proto.RegisterEchoTestServiceServer(s.server, &grpcHandler{
 		Config: s.Config,
+		resets: resets,
 	})
 	reflection.Register(s.server)
 
@@ -128,6 +135,7 @@ func (s *grpcInstance) Close() error {
 
 type grpcHandler struct {
 	Config
+	resets *resetListener
 }
 
 func (h *grpcHandler) Echo(ctx context.Context, req *proto.EchoRequest) (*proto.EchoResponse, error) {
@@ -153,6 +161,32 @@ func (h *grpcHandler) Echo(ctx context.Context, req *proto.EchoRequest) (*proto.
 
 	epLog.Infof("GRPC Request:\n  Host: %s\n  Message: %s\n  Headers: %v\n", host, req.GetMessage(), md)
 
+	// Inject the faults requested by the x-echo-fault-* metadata or configured for the server.
+	faultHeaders := make(http.Header)
+	for key, values := range md {
+		for _, value := range values {
+			faultHeaders.Add(key, value)
+		}
+	}
+	fault, err := common.ParseFault(faultHeaders, h.Fault)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	injectDelay(fault)
+	if shouldReset(fault) {
+		// gRPC has no way to reset a stream from a handler, so the connection of the request is reset instead.
+		if p, ok := peer.FromContext(ctx); ok && h.resets.reset(p.Addr) {
+			epLog.Infof("Injecting connection reset")
+			return nil, status.Error(codes.Unavailable, "connection reset by echo server")
+		}
+		epLog.Warnf("Connection of the request not found, injecting an error instead of a reset")
+	}
+	if shouldFail(fault) {
+		code := faultGRPCCode(fault)
+		epLog.Infof("Injecting error: %v", code)
+		return nil, status.Error(code, "fault injected by echo server")
+	}
+
 	portNumber := 0
 	if h.Port != nil {
 		portNumber = h.Port.Port
@@ -149,10 +149,10 @@ func (s *httpInstance) awaitReady(onReady OnReadyFunc, port int) {
 			},
 		}
 	} else if s.Port.TLS {
-		url = fmt.Sprintf("https://127.0.0.1:%d", port)
+		url = "https://" + readyAddress(s.ListenerIP, port)
 		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
 	} else {
-		url = fmt.Sprintf("http://127.0.0.1:%d", port)
+		url = "http://" + readyAddress(s.ListenerIP, port)
 	}
 
 	err := retry.UntilSuccess(func() error {
@@ -233,6 +233,30 @@ func (h *httpHandler) echo(w http.ResponseWriter, r *http.Request) {
 		writeError(&body, "ParseForm() error: "+err.Error())
 	}
 
+	// Inject the faults requested by the X-Echo-Fault-* headers or configured for the server.
+	fault, err := common.ParseFault(r.Header, h.Fault)
+	if err != nil {
+		writeError(&body, "fault error: "+err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		writeField(&body, response.StatusCodeField, strconv.Itoa(http.StatusBadRequest))
+		_, _ = w.Write(body.Bytes())
+		return
+	}
+	injectDelay(fault)
+	if shouldReset(fault) {
+		epLog.Infof("Injecting connection reset")
+		resetHTTP(w)
+		return
+	}
+	if shouldFail(fault) {
+		code := faultHTTPCode(fault)
+		epLog.Infof("Injecting error: %d", code)
+		w.WriteHeader(code)
+		writeField(&body, response.StatusCodeField, strconv.Itoa(code))
+		_, _ = w.Write(body.Bytes())
+		return
+	}
+
 	// If the request has form ?delay=[:duration] wait for duration
 	// For example, ?delay=10s will cause the response to wait 10s before responding
 	if err := delayResponse(r); err != nil {
@@ -254,12 +278,45 @@ func (h *httpHandler) echo(w http.ResponseWriter, r *http.Request) {
 	h.addResponsePayload(r, &body)
 
 	w.Header().Set("Content-Type", "application/text")
-	if _, err := w.Write(body.Bytes()); err != nil {
+	if chunkDelay := common.MicrosToDuration(fault.GetChunkDelayMicros()); chunkDelay > 0 {
+		writeChunked(w, body.Bytes(), chunkDelay)
+	} else if _, err := w.Write(body.Bytes()); err != nil {
 		epLog.Warn(err)
 	}
 	epLog.Infof("Response Headers: %+v", w.Header())
 }
 
+// writeChunked writes the body one line at a time, flushing and waiting for delay after each line, to simulate
+// a slow upstream.
+func writeChunked(w http.ResponseWriter, body []byte, delay time.Duration) {
+	flusher, _ := w.(http.Flusher)
+	for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if _, err := w.Write(line); err != nil {
+			epLog.Warn(err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		time.Sleep(delay)
+	}
+}
+
+// resetHTTP aborts the request without a response. HTTP/1.x connections are reset, HTTP/2 streams are reset
+// by aborting the handler.
+func resetHTTP(w http.ResponseWriter) {
+	if hj, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			resetConn(conn)
+			return
+		}
+	}
+	panic(http.ErrAbortHandler)
+}
+
 func (h *httpHandler) webSocketEcho(w http.ResponseWriter, r *http.Request) {
 	// adapted from https://github.com/gorilla/websocket/blob/master/examples/echo/server.go
 	// First send upgrade headers
@@ -20,6 +20,7 @@ import (
 
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/proto"
 )
 
 // IsServerReadyFunc is a function that indicates whether the server is currently ready to handle traffic.
@@ -40,6 +41,8 @@ type Config struct {
 	Port          *common.Port
 	ListenerIP    string
 	IstioVersion  string
+	// Fault is the fault injected into every request, unless overridden by the request headers.
+	Fault *proto.Fault
 }
 
 // Instance of an endpoint that serves the Echo application on a single port/protocol.
@@ -59,6 +62,8 @@ func New(cfg Config) (Instance, error) {
 			return newGRPC(cfg), nil
 		case protocol.TCP:
 			return newTCP(cfg), nil
+		case protocol.UDP:
+			return newUDP(cfg), nil
 		default:
 			return nil, fmt.Errorf("unsupported protocol: %s", cfg.Port.Protocol)
 		}
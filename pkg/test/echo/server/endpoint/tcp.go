@@ -100,6 +100,14 @@ func (s *tcpInstance) echo(conn net.Conn) {
 		_ = conn.Close()
 	}()
 
+	// TCP has no request headers, so only the faults configured for the server apply.
+	injectDelay(s.Fault)
+	if shouldReset(s.Fault) {
+		epLog.Infof("Injecting connection reset")
+		resetConn(conn)
+		return
+	}
+
 	// If this is server first, client expects a message from server. Send the magic string.
 	if s.Port.ServerFirst {
 		_, _ = conn.Write([]byte(common.ServerFirstMagicString))
@@ -169,7 +177,7 @@ func (s *tcpInstance) Close() error {
 func (s *tcpInstance) awaitReady(onReady OnReadyFunc, port int) {
 	defer onReady()
 
-	address := fmt.Sprintf("127.0.0.1:%d", port)
+	address := readyAddress(s.ListenerIP, port)
 
 	err := retry.UntilSuccess(func() error {
 		conn, err := net.Dial("tcp", address)
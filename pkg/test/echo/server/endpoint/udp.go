@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/common/response"
+)
+
+var _ Instance = &udpInstance{}
+
+// maxDatagramSize is the largest UDP payload we accept.
+const maxDatagramSize = 65507
+
+type udpInstance struct {
+	Config
+	conn net.PacketConn
+}
+
+func newUDP(config Config) Instance {
+	return &udpInstance{
+		Config: config,
+	}
+}
+
+func (s *udpInstance) GetConfig() Config {
+	return s.Config
+}
+
+func (s *udpInstance) Start(onReady OnReadyFunc) error {
+	if s.Port.TLS {
+		return fmt.Errorf("TLS is not supported for UDP port %d", s.Port.Port)
+	}
+	conn, err := net.ListenPacket("udp", fmt.Sprintf("%s:%d", s.ListenerIP, s.Port.Port))
+	if err != nil {
+		return err
+	}
+	// Store the actual listening port back to the argument.
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	s.Port.Port = port
+	s.conn = conn
+	fmt.Printf("Listening UDP on %v\n", port)
+
+	// Start serving UDP traffic.
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				epLog.Warn("UDP read failed: " + err.Error())
+				return
+			}
+			s.echo(addr, buf[:n])
+		}
+	}()
+
+	// There is no handshake for UDP, so the endpoint is ready as soon as the socket is bound.
+	epLog.Infof("ready for UDP endpoint %s", conn.LocalAddr())
+	onReady()
+
+	return nil
+}
+
+// echo replies to a single datagram with the response fields followed by the received payload.
+func (s *udpInstance) echo(addr net.Addr, payload []byte) {
+	defer common.Metrics.UDPRequests.With(common.PortLabel.Value(strconv.Itoa(s.Port.Port))).Increment()
+
+	ip, _, _ := net.SplitHostPort(addr.String())
+	var out bytes.Buffer
+	writeField(&out, response.StatusCodeField, response.StatusCodeOK)
+	writeField(&out, response.ClusterField, s.Cluster)
+	writeField(&out, response.IstioVersionField, s.IstioVersion)
+	writeField(&out, response.ServiceVersionField, s.Version)
+	writeField(&out, response.ServicePortField, strconv.Itoa(s.Port.Port))
+	writeField(&out, response.IPField, ip)
+	out.Write(payload)
+
+	if _, err := s.conn.WriteTo(out.Bytes(), addr); err != nil {
+		epLog.Warnf("UDP write to %s failed: %v", addr, err)
+	}
+}
+
+func (s *udpInstance) Close() error {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/common/response"
+)
+
+func TestUDPEndpoint(t *testing.T) {
+	ep, err := New(Config{
+		Port:       &common.Port{Name: "udp", Protocol: protocol.UDP},
+		ListenerIP: "127.0.0.1",
+		Version:    "v1",
+		Cluster:    "cluster-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := make(chan struct{})
+	if err := ep.Start(func() { close(ready) }); err != nil {
+		t.Fatal(err)
+	}
+	defer ep.Close()
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("UDP endpoint not ready")
+	}
+	port := ep.GetConfig().Port.Port
+	if port == 0 {
+		t.Fatal("expected the listening port to be stored in the config")
+	}
+
+	conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write([]byte("hello udp\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, maxDatagramSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:n])
+	for _, want := range []string{
+		fmt.Sprintf("%s=%s", response.StatusCodeField, response.StatusCodeOK),
+		fmt.Sprintf("%s=%s", response.ClusterField, "cluster-1"),
+		fmt.Sprintf("%s=%s", response.ServiceVersionField, "v1"),
+		fmt.Sprintf("%s=%d", response.ServicePortField, port),
+		fmt.Sprintf("%s=%s", response.IPField, "127.0.0.1"),
+		"hello udp",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected response to contain %q, got:\n%s", want, got)
+		}
+	}
+}
+
+func TestUDPEndpointTLS(t *testing.T) {
+	ep := newUDP(Config{Port: &common.Port{Name: "udp", Protocol: protocol.UDP, TLS: true}, ListenerIP: "127.0.0.1"})
+	if err := ep.Start(func() {}); err == nil {
+		ep.Close()
+		t.Fatal("expected TLS to be rejected for UDP")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 
 	"istio.io/istio/pkg/test/echo/common/response"
 	"istio.io/pkg/log"
@@ -57,6 +58,14 @@ func listenOnUDS(uds string) (net.Listener, error) {
 	return ln, nil
 }
 
+// readyAddress returns the address used to check that an endpoint listening on the given IP and port is ready.
+func readyAddress(listenerIP string, port int) string {
+	if listenerIP == "" {
+		listenerIP = "127.0.0.1"
+	}
+	return net.JoinHostPort(listenerIP, strconv.Itoa(port))
+}
+
 // nolint: interfacer
 func writeField(out *bytes.Buffer, field response.Field, value string) {
 	_, _ = out.WriteString(string(field) + "=" + value + "\n")
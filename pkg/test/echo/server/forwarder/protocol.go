@@ -259,6 +259,19 @@ func newProtocol(cfg Config) (protocol, error) {
 				return tls.Dial("tcp", address, tlsConfig)
 			},
 		}, nil
+	case scheme.UDP:
+		if getClientCertificate != nil {
+			return nil, fmt.Errorf("TLS is not supported for UDP")
+		}
+		return &udpProtocol{
+			conn: func() (net.Conn, error) {
+				dialer := net.Dialer{
+					Timeout: timeout,
+				}
+				address := rawURL[len(u.Scheme+"://"):]
+				return dialer.Dial("udp", address)
+			},
+		}, nil
 	}
 
 	return nil, fmt.Errorf("unrecognized protocol %q", u.String())
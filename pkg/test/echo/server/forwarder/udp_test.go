@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/proto"
+)
+
+// startUDPServer starts a UDP server replying to every datagram with reply, or not at all if reply is empty, and
+// returns its address.
+func startUDPServer(t *testing.T, reply string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if reply != "" {
+				_, _ = conn.WriteTo([]byte(reply+string(buf[:n])), addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestUDPForwarder(t *testing.T) {
+	cases := []struct {
+		name    string
+		reply   string
+		wantErr bool
+		want    []string
+	}{
+		{
+			name:  "success",
+			reply: "StatusCode=200\nServicePort=5000\n",
+			want:  []string{"[0] Url=udp://", "[0] Echo=ping", "[0 body] StatusCode=200", "[0 body] ServicePort=5000", "[0 body] ping"},
+		},
+		{
+			name:    "bad status",
+			reply:   "StatusCode=500\n",
+			wantErr: true,
+		},
+		{
+			name:    "no reply",
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := startUDPServer(t, tt.reply)
+			f, err := New(Config{Request: &proto.ForwardEchoRequest{
+				Url:           fmt.Sprintf("udp://%s", addr),
+				Count:         1,
+				Message:       "ping",
+				TimeoutMicros: (time.Second).Microseconds(),
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			resp, err := f.Run(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got response %v", resp.Output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Join(resp.Output, "")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/proto"
 	"istio.io/istio/pkg/test/echo/server/endpoint"
 	"istio.io/pkg/log"
 )
@@ -46,6 +47,10 @@ type Config struct {
 	Cluster               string
 	Dialer                common.Dialer
 	IstioVersion          string
+	// InstanceIP is the IP that ports in BindIPPortsMap listen on. Defaults to the INSTANCE_IP environment variable.
+	InstanceIP string
+	// Fault is injected into every request served by the server, unless overridden by the request.
+	Fault *proto.Fault
 }
 
 var _ io.Closer = &Instance{}
@@ -125,6 +130,9 @@ func (s *Instance) getListenerIP(port *common.Port) (string, error) {
 	if _, f := s.BindIPPortsMap[port.Port]; !f {
 		return "", nil
 	}
+	if s.InstanceIP != "" {
+		return s.InstanceIP, nil
+	}
 	if ip, f := os.LookupEnv("INSTANCE_IP"); f {
 		return ip, nil
 	}
@@ -147,6 +155,7 @@ func (s *Instance) newEndpoint(port *common.Port, udsServer string) (endpoint.In
 		Dialer:        s.Dialer,
 		ListenerIP:    ip,
 		IstioVersion:  s.IstioVersion,
+		Fault:         s.Fault,
 	})
 }
 
@@ -183,6 +192,7 @@ func (s *Instance) validate() error {
 	for _, port := range s.Ports {
 		switch port.Protocol {
 		case protocol.TCP:
+		case protocol.UDP:
 		case protocol.HTTP:
 		case protocol.HTTPS:
 		case protocol.HTTP2:
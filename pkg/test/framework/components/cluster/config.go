@@ -25,6 +25,7 @@ const (
 	Fake       Kind = "Fake"
 	Aggregate  Kind = "Aggregate"
 	StaticVM   Kind = "StaticVM"
+	Local      Kind = "Local"
 	Unknown    Kind = "Unknown"
 )
 
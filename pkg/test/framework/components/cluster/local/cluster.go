@@ -0,0 +1,237 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/util/yml"
+)
+
+func init() {
+	cluster.RegisterFactory(cluster.Local, build)
+}
+
+var _ echo.Cluster = &Cluster{}
+
+// firstWorkloadIP is the first loopback address handed out to workloads. 127.0.0.1 is left to istiod.
+var firstWorkloadIP = net.IPv4(127, 0, 1, 1)
+
+// Cluster is a cluster backed by fake Kubernetes clients and an in-memory Istio config store, which are
+// served by an in-process istiod. Workloads run on the local machine, each with its own loopback address.
+type Cluster struct {
+	// ExtendedClient is a fake client holding the Kubernetes resources applied to the cluster.
+	kube.ExtendedClient
+
+	// Topology is embedded to include common functionality.
+	cluster.Topology
+
+	// configs holds the Istio resources applied to the cluster.
+	configs model.ConfigStoreCache
+
+	mu     sync.Mutex
+	nextIP net.IP
+}
+
+func build(_ cluster.Config, topology cluster.Topology) (cluster.Cluster, error) {
+	return &Cluster{
+		ExtendedClient: kube.NewFakeClient(),
+		Topology:       topology,
+		configs:        memory.NewSyncController(memory.Make(collections.Pilot)),
+		nextIP:         firstWorkloadIP,
+	}, nil
+}
+
+// ConfigStore returns the store holding the Istio resources applied to the cluster.
+func (c *Cluster) ConfigStore() model.ConfigStoreCache {
+	return c.configs
+}
+
+// AllocateIP returns a loopback address that is not used by any other workload of the cluster.
+func (c *Cluster) AllocateIP() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ip := c.nextIP.To4()
+	if ip[2] == 255 && ip[3] == 254 {
+		return "", fmt.Errorf("ran out of loopback addresses for local workloads")
+	}
+	next := net.IPv4(ip[0], ip[1], ip[2], ip[3]+1).To4()
+	if ip[3] == 254 {
+		next = net.IPv4(ip[0], ip[1], ip[2]+1, 1).To4()
+	}
+	c.nextIP = next
+	return ip.String(), nil
+}
+
+// CanDeploy for a local cluster returns true for all configs that are not VMs.
+func (c *Cluster) CanDeploy(cfg echo.Config) (echo.Config, bool) {
+	if cfg.DeployAsVM {
+		return echo.Config{}, false
+	}
+	return cfg, true
+}
+
+// GetKubernetesVersion returns a fixed version, as there is no API server.
+func (c *Cluster) GetKubernetesVersion() (*version.Info, error) {
+	return &version.Info{Major: "1", Minor: "20"}, nil
+}
+
+// ApplyYAMLFiles stores the Istio resources in the given files in the config store and the Kubernetes
+// resources in the fake client. There is no API server to apply them with kubectl semantics, so
+// existing resources are replaced rather than merged.
+func (c *Cluster) ApplyYAMLFiles(namespace string, yamlFiles ...string) error {
+	return c.forEachResource(namespace, yamlFiles, c.applyConfig, c.applyObject)
+}
+
+// ApplyYAMLFilesDryRun only validates that the given files can be parsed.
+func (c *Cluster) ApplyYAMLFilesDryRun(namespace string, yamlFiles ...string) error {
+	return c.forEachResource(namespace, yamlFiles,
+		func(config.Config) error { return nil },
+		func(runtime.Object, schema.GroupVersionKind) error { return nil })
+}
+
+// DeleteYAMLFiles deletes the resources in the given files.
+func (c *Cluster) DeleteYAMLFiles(namespace string, yamlFiles ...string) error {
+	return c.forEachResource(namespace, yamlFiles, c.deleteConfig, c.deleteObject)
+}
+
+func (c *Cluster) forEachResource(namespace string, yamlFiles []string,
+	onConfig func(config.Config) error, onObject func(runtime.Object, schema.GroupVersionKind) error) error {
+	for _, f := range yamlFiles {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		for _, doc := range yml.SplitString(string(b)) {
+			if strings.TrimSpace(doc) == "" {
+				continue
+			}
+			configs, _, err := crd.ParseInputs(doc)
+			if err != nil {
+				return fmt.Errorf("%s: %v", f, err)
+			}
+			if len(configs) > 0 {
+				for _, cfg := range configs {
+					if namespace != "" {
+						cfg.Namespace = namespace
+					}
+					if err := onConfig(cfg); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(doc), nil, nil)
+			if err != nil {
+				return fmt.Errorf("%s: unsupported resource in local cluster: %v", f, err)
+			}
+			if namespace != "" {
+				if m, err := meta.Accessor(obj); err == nil {
+					m.SetNamespace(namespace)
+				}
+			}
+			if err := onObject(obj, *gvk); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) applyConfig(cfg config.Config) error {
+	if existing := c.configs.Get(cfg.GroupVersionKind, cfg.Name, cfg.Namespace); existing != nil {
+		cfg.ResourceVersion = existing.ResourceVersion
+		_, err := c.configs.Update(cfg)
+		return err
+	}
+	_, err := c.configs.Create(cfg)
+	return err
+}
+
+func (c *Cluster) deleteConfig(cfg config.Config) error {
+	if c.configs.Get(cfg.GroupVersionKind, cfg.Name, cfg.Namespace) == nil {
+		return nil
+	}
+	return c.configs.Delete(cfg.GroupVersionKind, cfg.Name, cfg.Namespace, nil)
+}
+
+func (c *Cluster) fakeClientset() (*fake.Clientset, error) {
+	fc, ok := c.Kube().(*fake.Clientset)
+	if !ok {
+		return nil, fmt.Errorf("local cluster %s is not backed by a fake client", c.Name())
+	}
+	return fc, nil
+}
+
+func (c *Cluster) applyObject(obj runtime.Object, gvk schema.GroupVersionKind) error {
+	fc, err := c.fakeClientset()
+	if err != nil {
+		return err
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	err = fc.Tracker().Create(gvr, obj, m.GetNamespace())
+	if kerrors.IsAlreadyExists(err) {
+		err = fc.Tracker().Update(gvr, obj, m.GetNamespace())
+	}
+	return err
+}
+
+func (c *Cluster) deleteObject(obj runtime.Object, gvk schema.GroupVersionKind) error {
+	fc, err := c.fakeClientset()
+	if err != nil {
+		return err
+	}
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	if err := fc.Tracker().Delete(gvr, m.GetNamespace(), m.GetName()); err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Cluster) String() string {
+	buf := &bytes.Buffer{}
+	_, _ = fmt.Fprint(buf, c.Topology.String())
+	_, _ = fmt.Fprintf(buf, "Local:              true\n")
+	return buf.String()
+}
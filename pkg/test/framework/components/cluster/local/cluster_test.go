@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+)
+
+func newCluster(t *testing.T) *Cluster {
+	t.Helper()
+	c, err := build(cluster.Config{Name: "local", Kind: cluster.Local},
+		cluster.NewTopology(cluster.Config{Name: "local", Kind: cluster.Local}, cluster.Map{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.(*Cluster)
+}
+
+func TestAllocateIP(t *testing.T) {
+	c := newCluster(t)
+	for _, want := range []string{"127.0.1.1", "127.0.1.2", "127.0.1.3"} {
+		got, err := c.AllocateIP()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	c.nextIP = net.IPv4(127, 0, 1, 254)
+	if got, _ := c.AllocateIP(); got != "127.0.1.254" {
+		t.Fatalf("got %s, want 127.0.1.254", got)
+	}
+	if got, _ := c.AllocateIP(); got != "127.0.2.1" {
+		t.Fatalf("expected the next subnet, got %s", got)
+	}
+
+	c.nextIP = net.IPv4(127, 0, 255, 254)
+	if _, err := c.AllocateIP(); err == nil {
+		t.Fatal("expected an error once loopback addresses run out")
+	}
+}
+
+const resources = `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs
+spec:
+  hosts:
+  - b
+  http:
+  - route:
+    - destination:
+        host: b
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+data:
+  key: value
+`
+
+func TestApplyYAMLFiles(t *testing.T) {
+	c := newCluster(t)
+	f := filepath.Join(t.TempDir(), "resources.yaml")
+	if err := ioutil.WriteFile(f, []byte(resources), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Applying twice replaces the existing resources.
+	for i := 0; i < 2; i++ {
+		if err := c.ApplyYAMLFiles("ns", f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.ConfigStore().Get(gvk.VirtualService, "vs", "ns") == nil {
+		t.Fatal("VirtualService was not stored")
+	}
+	if _, err := c.Kube().CoreV1().ConfigMaps("ns").Get(context.TODO(), "cm", metav1.GetOptions{}); err != nil {
+		t.Fatalf("ConfigMap was not created: %v", err)
+	}
+
+	if err := c.DeleteYAMLFiles("ns", f); err != nil {
+		t.Fatal(err)
+	}
+	if c.ConfigStore().Get(gvk.VirtualService, "vs", "ns") != nil {
+		t.Fatal("VirtualService was not deleted")
+	}
+	if _, err := c.Kube().CoreV1().ConfigMaps("ns").Get(context.TODO(), "cm", metav1.GetOptions{}); err == nil {
+		t.Fatal("ConfigMap was not deleted")
+	}
+	// Deleting resources that do not exist is not an error.
+	if err := c.DeleteYAMLFiles("ns", f); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"istio.io/istio/pkg/test/echo/client"
 	"istio.io/istio/pkg/test/echo/common/scheme"
+	"istio.io/istio/pkg/test/echo/proto"
 	"istio.io/istio/pkg/test/framework/components/cluster"
 )
 
@@ -81,6 +82,9 @@ type CallOptions struct {
 	// is returned directly.
 	FollowRedirects bool
 
+	// Fault asks the target to inject delays, errors or resets into its responses.
+	Fault *proto.Fault
+
 	// Validator for server responses. If no validator is provided, only the number of responses received
 	// will be verified.
 	Validator Validator
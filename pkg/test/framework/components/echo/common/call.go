@@ -51,7 +51,7 @@ func callInternal(srcName string, opts *echo.CallOptions, send sendFunc,
 	switch opts.Scheme {
 	case scheme.DNS:
 		targetURL = fmt.Sprintf("%s://%s", string(opts.Scheme), opts.Address)
-	case scheme.TCP:
+	case scheme.TCP, scheme.UDP:
 		targetURL = fmt.Sprintf("%s://%s", string(opts.Scheme), addressAndPort)
 	default:
 		targetURL = fmt.Sprintf("%s://%s%s", string(opts.Scheme), addressAndPort, opts.Path)
@@ -80,6 +80,7 @@ func callInternal(srcName string, opts *echo.CallOptions, send sendFunc,
 		CaCertFile:         opts.CaCertFile,
 		InsecureSkipVerify: opts.InsecureSkipVerify,
 		FollowRedirects:    opts.FollowRedirects,
+		Fault:              opts.Fault,
 	}
 
 	var responses client.ParsedResponses
@@ -258,6 +259,8 @@ func schemeForPort(port *echo.Port) (scheme.Instance, error) {
 		return scheme.HTTPS, nil
 	case protocol.TCP:
 		return scheme.TCP, nil
+	case protocol.UDP:
+		return scheme.UDP, nil
 	default:
 		return "", fmt.Errorf("failed creating call for port %s: unsupported protocol %s",
 			port.Name, port.Protocol)
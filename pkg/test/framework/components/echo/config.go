@@ -20,6 +20,7 @@ import (
 
 	"github.com/mitchellh/copystructure"
 
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/framework/components/cluster"
 	"istio.io/istio/pkg/test/framework/components/namespace"
@@ -147,6 +148,16 @@ func (c Config) PortByName(name string) *Port {
 	return nil
 }
 
+// PortByProtocol returns the first port with the given protocol, or nil if there is none.
+func (c Config) PortByProtocol(p protocol.Instance) *Port {
+	for _, port := range c.Ports {
+		if port.Protocol == p {
+			return &port
+		}
+	}
+	return nil
+}
+
 // FQDN returns the fully qualified domain name for the service.
 func (c Config) FQDN() string {
 	out := c.Service
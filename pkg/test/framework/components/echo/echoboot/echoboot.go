@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pkg/test/framework/components/echo/kube"
 
 	// force registraton of factory func
+	_ "istio.io/istio/pkg/test/framework/components/echo/local"
 	_ "istio.io/istio/pkg/test/framework/components/echo/staticvm"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/scopes"
@@ -14,7 +14,10 @@
 
 package echotest
 
-import "istio.io/istio/pkg/test/framework/components/echo"
+import (
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
 
 type (
 	Filter            func(echo.Instances) echo.Instances
@@ -134,6 +137,12 @@ func VirtualMachines(instances echo.Instances) echo.Instances {
 	return instances.Match(echo.IsVirtualMachine())
 }
 
+// HasPortProtocol includes deployments with a port using the given protocol, e.g. to only target UDP capable
+// destinations.
+func HasPortProtocol(p protocol.Instance) Filter {
+	return FilterMatch(echo.HasPortProtocol(p))
+}
+
 // ExternalServices includes services that are based on naked pods with a custom DefaultHostHeader
 func ExternalServices(instances echo.Instances) echo.Instances {
 	return instances.Match(echo.IsExternal())
@@ -21,6 +21,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test/framework"
 	"istio.io/istio/pkg/test/framework/components/cluster"
 	"istio.io/istio/pkg/test/framework/components/echo"
@@ -184,6 +185,24 @@ func TestFilters(t *testing.T) {
 	}
 }
 
+func TestHasPortProtocol(t *testing.T) {
+	udp1 := &fakeInstance{Cluster: cls1, Namespace: fakeNamespace("echo"), Service: "udp", Ports: []echo.Port{
+		{Name: "http", Protocol: protocol.HTTP},
+		{Name: "udp", Protocol: protocol.UDP},
+	}}
+	http1 := &fakeInstance{Cluster: cls1, Namespace: fakeNamespace("echo"), Service: "http", Ports: []echo.Port{
+		{Name: "http", Protocol: protocol.HTTP},
+	}}
+	instances := echo.Instances{a1, udp1, http1}
+
+	if got := HasPortProtocol(protocol.UDP)(instances); len(got) != 1 || got[0] != udp1 {
+		t.Errorf("expected only the UDP capable instance, got %v", got.Services().Services())
+	}
+	if got := HasPortProtocol(protocol.HTTP)(instances); len(got) != 2 {
+		t.Errorf("expected both HTTP capable instances, got %v", got.Services().Services())
+	}
+}
+
 func compare(t *testing.T, got echo.Instances, want echo.Instances) {
 	if len(got) != len(want) {
 		t.Errorf("got %d instnaces but expected %d", len(got), len(want))
@@ -19,6 +19,7 @@ import (
 	"sort"
 	"strings"
 
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/framework/components/cluster"
 )
@@ -138,6 +139,13 @@ func IsNaked() Matcher {
 	}
 }
 
+// HasPortProtocol matches instances that have a port with the given protocol.
+func HasPortProtocol(p protocol.Instance) Matcher {
+	return func(i Instance) bool {
+		return i.Config().PortByProtocol(p) != nil
+	}
+}
+
 // IsHeadless matches instances that are backed by headless services.
 func IsHeadless() Matcher {
 	return func(i Instance) bool {
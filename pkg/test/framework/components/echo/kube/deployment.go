@@ -81,6 +81,9 @@ spec:
   - name: {{ $p.Name }}
     port: {{ $p.ServicePort }}
     targetPort: {{ $p.InstancePort }}
+{{- if eq $p.Protocol "UDP" }}
+    protocol: UDP
+{{- end }}
 {{- end }}
   selector:
     app: {{ .Service }}
@@ -164,6 +167,8 @@ spec:
           - --grpc
 {{- else if eq .Protocol "TCP" }}
           - --tcp
+{{- else if eq .Protocol "UDP" }}
+          - --udp
 {{- else }}
           - --port
 {{- end }}
@@ -184,6 +189,8 @@ spec:
 {{- range $i, $p := $.WorkloadOnlyPorts }}
 {{- if eq .Protocol "TCP" }}
           - --tcp
+{{- else if eq .Protocol "UDP" }}
+          - --udp
 {{- else }}
           - --port
 {{- end }}
@@ -209,6 +216,9 @@ spec:
         ports:
 {{- range $i, $p := $.ContainerPorts }}
         - containerPort: {{ $p.Port }} 
+{{- if eq .Protocol "UDP" }}
+          protocol: UDP
+{{- end }}
 {{- if eq .Port 3333 }}
           name: tcp-health-port
 {{- end }}
@@ -359,6 +369,8 @@ spec:
              --grpc \
 {{- else if eq .Protocol "TCP" }}
              --tcp \
+{{- else if eq .Protocol "UDP" }}
+             --udp \
 {{- else }}
              --port \
 {{- end }}
@@ -379,6 +391,8 @@ spec:
 {{- range $i, $p := $.WorkloadOnlyPorts }}
 {{- if eq .Protocol "TCP" }}
              --tcp \
+{{- else if eq .Protocol "UDP" }}
+             --udp \
 {{- else }}
              --port \
 {{- end }}
@@ -0,0 +1,254 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package local deploys echo instances for the local environment. Each workload is an echo server running in
+// the test process and listening on its own loopback address, so 127.0.0.0/8 must be routable (the default
+// on Linux). Unless sidecar injection is disabled, each workload also runs an Envoy, found with
+// pkg/test/envoy and started from the Istio bootstrap, which gets its config from the local istiod. As there
+// is no iptables interception, Envoy listens on explicit addresses set by a Sidecar resource per workload,
+// and the echo server sends calls to services to Envoy rather than to the called workload. Envoy binds the
+// service ports, so ports below 1024 need net.ipv4.ip_unprivileged_port_start to be lowered, and traffic
+// between sidecars is plaintext, as no certificates are provisioned.
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+var (
+	_ echo.Instance = &instance{}
+	_ io.Closer     = &instance{}
+)
+
+func init() {
+	echo.RegisterFactory(cluster.Local, newInstances)
+}
+
+type instance struct {
+	id     resource.ID
+	ctx    resource.Context
+	config echo.Config
+
+	mu        sync.Mutex
+	workloads []*workload
+}
+
+func newInstances(ctx resource.Context, config []echo.Config) (echo.Instances, error) {
+	errG := multierror.Group{}
+	mu := sync.Mutex{}
+	var out echo.Instances
+	for _, c := range config {
+		c := c
+		errG.Go(func() error {
+			i, err := newInstance(ctx, c)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			out = append(out, i)
+			return nil
+		})
+	}
+	if err := errG.Wait().ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func newInstance(ctx resource.Context, config echo.Config) (echo.Instance, error) {
+	if common.GetPortForProtocol(&config, protocol.GRPC) == nil {
+		return nil, errors.New("unable fo find GRPC command port")
+	}
+	if len(config.Subsets) == 0 {
+		config.Subsets = []echo.SubsetConfig{{}}
+	}
+	if err := createService(config); err != nil {
+		return nil, fmt.Errorf("failed creating service %s: %v", config.Service, err)
+	}
+
+	i := &instance{
+		ctx:    ctx,
+		config: config,
+	}
+	if err := i.startWorkloads(); err != nil {
+		_ = i.Close()
+		return nil, err
+	}
+	i.id = ctx.TrackResource(i)
+	return i, nil
+}
+
+// createService creates the Kubernetes Service of the echo instance, so that istiod generates config for it.
+func createService(cfg echo.Config) error {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cfg.Service,
+			Namespace:   cfg.Namespace.Name(),
+			Labels:      map[string]string{"app": cfg.Service},
+			Annotations: map[string]string{},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": cfg.Service},
+		},
+	}
+	for k, v := range cfg.ServiceAnnotations {
+		svc.Annotations[k.Name] = v.Value
+	}
+	if cfg.Headless {
+		svc.Spec.ClusterIP = corev1.ClusterIPNone
+	}
+	for _, p := range cfg.Ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       p.Name,
+			Port:       int32(p.ServicePort),
+			TargetPort: intstr.FromInt(p.InstancePort),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	_, err := cfg.Cluster.Kube().CoreV1().Services(cfg.Namespace.Name()).Create(context.TODO(), svc, metav1.CreateOptions{})
+	if kerrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// startWorkloads starts one workload per subset. Workloads are started in order, as they share the
+// Endpoints of the service.
+func (i *instance) startWorkloads() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for idx, subset := range i.config.Subsets {
+		w, err := newWorkload(i.ctx, i.config, subset, idx)
+		if err != nil {
+			return err
+		}
+		i.workloads = append(i.workloads, w)
+	}
+	return nil
+}
+
+// stopWorkloads unregisters and stops all workloads.
+func (i *instance) stopWorkloads() (err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for _, w := range i.workloads {
+		err = multierror.Append(err, w.unregister(), w.Close()).ErrorOrNil()
+	}
+	i.workloads = nil
+	return
+}
+
+func (i *instance) ID() resource.ID {
+	return i.id
+}
+
+func (i *instance) Config() echo.Config {
+	return i.config
+}
+
+// Address returns the address of the first workload, as there is no cluster IP in the local environment.
+func (i *instance) Address() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.workloads) == 0 {
+		return ""
+	}
+	return i.workloads[0].Address()
+}
+
+func (i *instance) Workloads() ([]echo.Workload, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	out := make([]echo.Workload, 0, len(i.workloads))
+	for _, w := range i.workloads {
+		out = append(out, w)
+	}
+	return out, nil
+}
+
+func (i *instance) WorkloadsOrFail(t test.Failer) []echo.Workload {
+	t.Helper()
+	w, err := i.Workloads()
+	if err != nil {
+		t.Fatalf("failed getting workloads for %s", i.Config().Service)
+	}
+	return w
+}
+
+func (i *instance) defaultClient() (*client.Instance, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if len(i.workloads) == 0 {
+		return nil, fmt.Errorf("no workloads for %s", i.config.Service)
+	}
+	return i.workloads[0].Instance, nil
+}
+
+func (i *instance) Call(opts echo.CallOptions) (client.ParsedResponses, error) {
+	return common.ForwardEcho(i.Config().Service, i.defaultClient, &opts, false)
+}
+
+func (i *instance) CallOrFail(t test.Failer, opts echo.CallOptions) client.ParsedResponses {
+	t.Helper()
+	res, err := i.Call(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func (i *instance) CallWithRetry(opts echo.CallOptions, retryOptions ...retry.Option) (client.ParsedResponses, error) {
+	return common.ForwardEcho(i.Config().Service, i.defaultClient, &opts, true, retryOptions...)
+}
+
+func (i *instance) CallWithRetryOrFail(t test.Failer, opts echo.CallOptions, retryOptions ...retry.Option) client.ParsedResponses {
+	t.Helper()
+	res, err := i.CallWithRetry(opts, retryOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// Restart replaces all workloads with new ones, on new addresses.
+func (i *instance) Restart() error {
+	if err := i.stopWorkloads(); err != nil {
+		return err
+	}
+	return i.startWorkloads()
+}
+
+func (i *instance) Close() error {
+	return i.stopWorkloads()
+}
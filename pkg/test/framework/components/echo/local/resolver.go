@@ -0,0 +1,202 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+// services resolves the addresses of all local echo services. It is shared by all echo instances, since they
+// all run in the test process.
+var services = newResolver()
+
+// resolver maps the host names of local echo services to the loopback addresses of their workloads, standing
+// in for cluster DNS and kube-proxy, which are not available in the local environment.
+type resolver struct {
+	mu sync.RWMutex
+	// hosts maps each name a service can be called by, and the address of each workload, to the service.
+	hosts map[string]*resolvedService
+}
+
+type resolvedService struct {
+	// ports maps service ports to instance ports.
+	ports map[int]int
+	ips   []string
+	// workload is true if the host is the address of a workload, rather than a name of the service.
+	workload bool
+}
+
+func newResolver() *resolver {
+	return &resolver{
+		hosts: map[string]*resolvedService{},
+	}
+}
+
+// hostNames returns all names the service of the given config can be called by.
+func hostNames(cfg echo.Config) []string {
+	ns := "default"
+	if cfg.Namespace != nil {
+		ns = cfg.Namespace.Name()
+	}
+	out := []string{
+		cfg.Service,
+		cfg.Service + "." + ns,
+		cfg.Service + "." + ns + ".svc",
+	}
+	if fqdn := cfg.FQDN(); fqdn != out[2] {
+		out = append(out, fqdn)
+	}
+	return out
+}
+
+func servicePorts(cfg echo.Config) map[int]int {
+	ports := map[int]int{}
+	for _, p := range cfg.Ports {
+		ports[p.ServicePort] = p.InstancePort
+	}
+	return ports
+}
+
+// add registers a workload of the service with the given config.
+func (r *resolver) add(cfg echo.Config, ip string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range hostNames(cfg) {
+		svc, ok := r.hosts[h]
+		if !ok {
+			svc = &resolvedService{ports: servicePorts(cfg)}
+			r.hosts[h] = svc
+		}
+		svc.ips = append(svc.ips, ip)
+	}
+	r.hosts[ip] = &resolvedService{ports: servicePorts(cfg), ips: []string{ip}, workload: true}
+}
+
+// remove unregisters a workload of the service with the given config.
+func (r *resolver) remove(cfg echo.Config, ip string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range hostNames(cfg) {
+		svc, ok := r.hosts[h]
+		if !ok {
+			continue
+		}
+		for i, existing := range svc.ips {
+			if existing == ip {
+				svc.ips = append(svc.ips[:i], svc.ips[i+1:]...)
+				break
+			}
+		}
+		if len(svc.ips) == 0 {
+			delete(r.hosts, h)
+		}
+	}
+	delete(r.hosts, ip)
+}
+
+// resolve returns the address of a random workload for the given host and service port. Unknown hosts are
+// returned unchanged. If outbound is set, services are instead resolved to the outbound listeners of the
+// caller's sidecar at that address, which route the call by its host and service port.
+func (r *resolver) resolve(host string, port int, outbound string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	svc, ok := r.hosts[host]
+	if !ok || len(svc.ips) == 0 {
+		return net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	if outbound != "" && !svc.workload {
+		return net.JoinHostPort(outbound, strconv.Itoa(port))
+	}
+	if instancePort, ok := svc.ports[port]; ok {
+		port = instancePort
+	}
+	return net.JoinHostPort(svc.ips[rand.Intn(len(svc.ips))], strconv.Itoa(port))
+}
+
+// resolveAddress resolves an address in host:port form.
+func (r *resolver) resolveAddress(address string, outbound string) string {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return address
+	}
+	return r.resolve(host, port, outbound)
+}
+
+// resolveURL resolves the host of the given URL, keeping the original host for the Host header.
+func (r *resolver) resolveURL(u *url.URL, outbound string) *url.URL {
+	out := *u
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https", "wss":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	out.Host = r.resolveAddress(net.JoinHostPort(u.Hostname(), port), outbound)
+	return &out
+}
+
+// dialer returns an echo Dialer that connects to local echo services by their host names, through the
+// sidecar outbound listeners at the given address if it is not empty.
+func (r *resolver) dialer(outbound string) common.Dialer {
+	return common.Dialer{
+		GRPC: func(ctx context.Context, address string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+			return common.DefaultGRPCDialFunc(ctx, r.resolveAddress(address, outbound), opts...)
+		},
+		Websocket: func(dialer *websocket.Dialer, urlStr string, requestHeader http.Header) (*websocket.Conn, *http.Response, error) {
+			u, err := url.Parse(urlStr)
+			if err != nil {
+				return nil, nil, err
+			}
+			header := requestHeader.Clone()
+			if header == nil {
+				header = http.Header{}
+			}
+			if header.Get("Host") == "" {
+				header.Set("Host", u.Host)
+			}
+			return common.DefaultWebsocketDialFunc(dialer, r.resolveURL(u, outbound).String(), header)
+		},
+		HTTP: func(client *http.Client, req *http.Request) (*http.Response, error) {
+			out := req.Clone(req.Context())
+			if out.Host == "" {
+				out.Host = req.URL.Host
+			}
+			out.URL = r.resolveURL(req.URL, outbound)
+			return common.DefaultHTTPDoFunc(client, out)
+		},
+		TCP: func(dialer net.Dialer, ctx context.Context, address string) (net.Conn, error) {
+			return common.DefaultTCPDialFunc(dialer, ctx, r.resolveAddress(address, outbound))
+		},
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"net/url"
+	"testing"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+type fakeNamespace string
+
+func (n fakeNamespace) Name() string {
+	return string(n)
+}
+
+func (n fakeNamespace) Prefix() string {
+	return string(n)
+}
+
+func (n fakeNamespace) SetLabel(string, string) error {
+	return nil
+}
+
+func (n fakeNamespace) RemoveLabel(string) error {
+	return nil
+}
+
+var echoConfig = echo.Config{
+	Service:   "b",
+	Namespace: fakeNamespace("ns"),
+	Domain:    "cluster.local",
+	Ports: []echo.Port{
+		{Name: "http", Protocol: protocol.HTTP, ServicePort: 80, InstancePort: 18080},
+		{Name: "grpc", Protocol: protocol.GRPC, ServicePort: 7070, InstancePort: 17070},
+	},
+}
+
+func TestResolve(t *testing.T) {
+	r := newResolver()
+	r.add(echoConfig, "127.0.1.1")
+
+	cases := []struct {
+		name     string
+		host     string
+		port     int
+		outbound string
+		want     string
+	}{
+		{"short name", "b", 80, "", "127.0.1.1:18080"},
+		{"namespaced name", "b.ns", 7070, "", "127.0.1.1:17070"},
+		{"fqdn", "b.ns.svc.cluster.local", 80, "", "127.0.1.1:18080"},
+		{"unknown service port", "b", 8080, "", "127.0.1.1:8080"},
+		{"unknown host", "c", 80, "", "c:80"},
+		{"through sidecar", "b.ns.svc", 80, "127.0.1.2", "127.0.1.2:80"},
+		{"workload address bypasses sidecar", "127.0.1.1", 80, "127.0.1.2", "127.0.1.1:18080"},
+		{"unknown host bypasses sidecar", "c", 80, "127.0.1.2", "c:80"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.resolve(tt.host, tt.port, tt.outbound); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	u, _ := url.Parse("http://b/path")
+	if got := r.resolveURL(u, "127.0.1.2").String(); got != "http://127.0.1.2:80/path" {
+		t.Fatalf("got URL %s", got)
+	}
+
+	r.remove(echoConfig, "127.0.1.1")
+	if got := r.resolve("b", 80, ""); got != "b:80" {
+		t.Fatalf("expected removed service to be unresolved, got %s", got)
+	}
+}
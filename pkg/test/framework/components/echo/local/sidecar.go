@@ -0,0 +1,381 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/go-multierror"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/bootstrap"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/gvk"
+	// Import all XDS config types
+	_ "istio.io/istio/pkg/config/xds"
+	"istio.io/istio/pkg/envoy"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/env"
+	testenvoy "istio.io/istio/pkg/test/envoy"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const (
+	// workloadLabel is added to the pod of each workload, so that its Sidecar resource selects only that pod.
+	workloadLabel = "test.istio.io/local-workload"
+
+	envoyLiveTimeout = 10 * time.Second
+	envoyStopTimeout = 3 * time.Second
+)
+
+var _ echo.Sidecar = &sidecar{}
+
+// sidecar is an Envoy started from the Istio bootstrap for a local workload. Without iptables, it runs in the
+// NONE interception mode: a Sidecar resource created for the workload binds its inbound listeners to the
+// workload address, in front of the echo server, and its outbound listeners to a separate address that the
+// echo server dials instead of the called service.
+type sidecar struct {
+	w         *workload
+	nodeID    string
+	adminPort uint32
+	logFile   string
+	envoy     envoy.Instance
+}
+
+// newSidecar starts the Envoy of the given workload, connected to the plaintext xDS server at discovery.
+// appPorts maps each service instance port to the port the echo server listens on.
+func newSidecar(w *workload, dir string, discovery net.TCPAddr, appPorts map[int]int) (s *sidecar, err error) {
+	binary, err := testenvoy.FindBinary()
+	if err != nil {
+		return nil, err
+	}
+	adminPort, err := freePort("127.0.0.1")
+	if err != nil {
+		return nil, err
+	}
+	ns := w.cfg.Namespace.Name()
+	s = &sidecar{
+		w:         w,
+		nodeID:    fmt.Sprintf("sidecar~%s~%s.%s~%s.svc.cluster.local", w.ip, w.podName, ns, ns),
+		adminPort: uint32(adminPort),
+		logFile:   path.Join(dir, "envoy.log"),
+	}
+	if err := s.applySidecarConfig(appPorts); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = s.Close()
+		}
+	}()
+
+	configFile, err := s.writeBootstrap(dir, discovery)
+	if err != nil {
+		return nil, err
+	}
+	s.envoy, err = envoy.New(envoy.Config{
+		Name:       w.podName,
+		BinaryPath: binary,
+		WorkingDir: dir,
+		AdminPort:  s.adminPort,
+		Options: envoy.Options{
+			envoy.ConfigPath(configFile),
+			envoy.LogPath(s.logFile),
+			envoy.DisableHotRestart(true),
+			envoy.Concurrency(1),
+			envoy.DrainDuration(time.Second),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.envoy.Start(context.Background()).WaitLive().WithTimeout(envoyLiveTimeout).Do(); err != nil {
+		return nil, fmt.Errorf("failed starting Envoy for %s: %v", w.podName, err)
+	}
+	return s, nil
+}
+
+// applySidecarConfig creates the Sidecar resource of the workload. Inbound listeners are bound to the workload
+// address on the instance ports and forward to the echo server, and outbound listeners are bound to the
+// outbound address of the workload.
+func (s *sidecar) applySidecarConfig(appPorts map[int]int) error {
+	spec := &networking.Sidecar{
+		WorkloadSelector: &networking.WorkloadSelector{
+			Labels: map[string]string{workloadLabel: s.w.podName},
+		},
+		Egress: []*networking.IstioEgressListener{{
+			Bind:  s.w.outboundIP,
+			Hosts: []string{"*/*"},
+		}},
+	}
+	for _, p := range s.w.cfg.Ports {
+		spec.Ingress = append(spec.Ingress, &networking.IstioIngressListener{
+			Port: &networking.Port{
+				Number:   uint32(p.InstancePort),
+				Protocol: string(p.Protocol),
+				Name:     p.Name,
+			},
+			Bind: s.w.ip,
+			// 0.0.0.0 stands for the address of the workload.
+			DefaultEndpoint: "0.0.0.0:" + strconv.Itoa(appPorts[p.InstancePort]),
+		})
+	}
+	_, err := s.w.cluster.ConfigStore().Create(config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.Sidecar,
+			Name:             s.w.podName,
+			Namespace:        s.w.cfg.Namespace.Name(),
+		},
+		Spec: spec,
+	})
+	return err
+}
+
+// writeBootstrap writes the Envoy bootstrap, generated from the same template as for sidecars in a cluster.
+func (s *sidecar) writeBootstrap(dir string, discovery net.TCPAddr) (string, error) {
+	pc := mesh.DefaultProxyConfig()
+	pc.ConfigPath = dir
+	pc.DiscoveryAddress = discovery.String()
+	pc.ControlPlaneAuthPolicy = meshconfig.AuthenticationPolicy_NONE
+	pc.ProxyAdminPort = int32(s.adminPort)
+	pc.ProxyBootstrapTemplatePath = path.Join(env.IstioSrc, "tools/packaging/common/envoy_bootstrap.json")
+	pc.Tracing = nil
+
+	labels, err := json.Marshal(s.w.labels())
+	if err != nil {
+		return "", err
+	}
+	node, err := bootstrap.GetNodeMetaData(bootstrap.MetadataOptions{
+		ID:          s.nodeID,
+		InstanceIPs: []string{s.w.ip},
+		ProxyConfig: &pc,
+		Envs: []string{
+			bootstrap.IstioMetaPrefix + "INTERCEPTION_MODE=" + string(model.InterceptionNone),
+			bootstrap.IstioMetaPrefix + "NAMESPACE=" + s.w.cfg.Namespace.Name(),
+			bootstrap.IstioMetaJSONPrefix + "LABELS=" + string(labels),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	out := &bytes.Buffer{}
+	if err := bootstrap.New(bootstrap.Config{Node: node}).WriteTo(pc.ProxyBootstrapTemplatePath, out); err != nil {
+		return "", err
+	}
+	cfg, err := bindStaticListeners(out.Bytes(), s.w.ip)
+	if err != nil {
+		return "", err
+	}
+	configFile := path.Join(dir, "envoy-rev0.json")
+	if err := ioutil.WriteFile(configFile, cfg, 0o600); err != nil {
+		return "", err
+	}
+	return configFile, nil
+}
+
+// bindStaticListeners binds the static listeners of the bootstrap, such as the Prometheus and readiness
+// listeners, to the workload address rather than the wildcard address, which is shared by all sidecars.
+func bindStaticListeners(bootstrapJSON []byte, ip string) ([]byte, error) {
+	cfg := map[string]interface{}{}
+	if err := json.Unmarshal(bootstrapJSON, &cfg); err != nil {
+		return nil, fmt.Errorf("failed parsing Envoy bootstrap: %v", err)
+	}
+	resources, _ := cfg["static_resources"].(map[string]interface{})
+	listeners, _ := resources["listeners"].([]interface{})
+	for _, l := range listeners {
+		address, _ := l.(map[string]interface{})["address"].(map[string]interface{})
+		if socket, ok := address["socket_address"].(map[string]interface{}); ok {
+			socket["address"] = ip
+		}
+	}
+	return json.Marshal(cfg)
+}
+
+// Close stops Envoy and removes the Sidecar resource of the workload.
+func (s *sidecar) Close() (err error) {
+	if s.envoy != nil {
+		stopErr := s.envoy.ShutdownAndWait().WithTimeout(envoyStopTimeout).Do()
+		if stopErr == context.DeadlineExceeded {
+			stopErr = s.envoy.KillAndWait().WithTimeout(envoyStopTimeout).Do()
+		}
+		err = multierror.Append(err, stopErr).ErrorOrNil()
+	}
+	store := s.w.cluster.ConfigStore()
+	if store.Get(gvk.Sidecar, s.w.podName, s.w.cfg.Namespace.Name()) != nil {
+		err = multierror.Append(err, store.Delete(gvk.Sidecar, s.w.podName, s.w.cfg.Namespace.Name(), nil)).ErrorOrNil()
+	}
+	return
+}
+
+func (s *sidecar) NodeID() string {
+	return s.nodeID
+}
+
+func (s *sidecar) Info() (*envoyAdmin.ServerInfo, error) {
+	return s.envoy.GetServerInfo()
+}
+
+func (s *sidecar) InfoOrFail(t test.Failer) *envoyAdmin.ServerInfo {
+	t.Helper()
+	info, err := s.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func (s *sidecar) Config() (*envoyAdmin.ConfigDump, error) {
+	return s.envoy.GetConfigDump()
+}
+
+func (s *sidecar) ConfigOrFail(t test.Failer) *envoyAdmin.ConfigDump {
+	t.Helper()
+	cfg, err := s.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func (s *sidecar) WaitForConfig(accept func(*envoyAdmin.ConfigDump) (bool, error), options ...retry.Option) error {
+	return common.WaitForConfig(s.Config, accept, options...)
+}
+
+func (s *sidecar) WaitForConfigOrFail(t test.Failer, accept func(*envoyAdmin.ConfigDump) (bool, error), options ...retry.Option) {
+	t.Helper()
+	if err := s.WaitForConfig(accept, options...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (s *sidecar) Clusters() (*envoyAdmin.Clusters, error) {
+	msg := &envoyAdmin.Clusters{}
+	if err := s.adminRequest("clusters?format=json", msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *sidecar) ClustersOrFail(t test.Failer) *envoyAdmin.Clusters {
+	t.Helper()
+	clusters, err := s.Clusters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clusters
+}
+
+func (s *sidecar) Listeners() (*envoyAdmin.Listeners, error) {
+	msg := &envoyAdmin.Listeners{}
+	if err := s.adminRequest("listeners?format=json", msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (s *sidecar) ListenersOrFail(t test.Failer) *envoyAdmin.Listeners {
+	t.Helper()
+	listeners, err := s.Listeners()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listeners
+}
+
+func (s *sidecar) Stats() (map[string]*dto.MetricFamily, error) {
+	body, err := s.adminGet("stats/prometheus")
+	if err != nil {
+		return nil, err
+	}
+	parser := expfmt.TextParser{}
+	mfMap, err := parser.TextToMetricFamilies(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing prometheus stats: %v", err)
+	}
+	return mfMap, nil
+}
+
+func (s *sidecar) StatsOrFail(t test.Failer) map[string]*dto.MetricFamily {
+	t.Helper()
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func (s *sidecar) adminRequest(path string, out proto.Message) error {
+	body, err := s.adminGet(path)
+	if err != nil {
+		return err
+	}
+	jspb := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := jspb.Unmarshal(strings.NewReader(body), out); err != nil {
+		return fmt.Errorf("failed parsing Envoy admin response from '/%s': %v\nResponse JSON: %s", path, err, body)
+	}
+	return nil
+}
+
+func (s *sidecar) adminGet(path string) (string, error) {
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/%s", s.adminPort, path))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from Envoy admin '/%s': %s", resp.StatusCode, path, body)
+	}
+	return string(body), nil
+}
+
+// Logs returns the log file of Envoy.
+func (s *sidecar) Logs() (string, error) {
+	logs, err := ioutil.ReadFile(s.logFile)
+	return string(logs), err
+}
+
+func (s *sidecar) LogsOrFail(t test.Failer) string {
+	t.Helper()
+	logs, err := s.Logs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
@@ -0,0 +1,129 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/cluster/clusterboot"
+	localcluster "istio.io/istio/pkg/test/framework/components/cluster/local"
+)
+
+func newTestWorkload(t *testing.T) *workload {
+	t.Helper()
+	clusters, err := clusterboot.NewFactory().With(cluster.Config{Name: "local", Kind: cluster.Local}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &workload{
+		cfg:        echoConfig,
+		cluster:    clusters[0].(*localcluster.Cluster),
+		ip:         "127.0.1.1",
+		outboundIP: "127.0.1.2",
+		podName:    "b-0",
+	}
+}
+
+func TestSidecarConfig(t *testing.T) {
+	w := newTestWorkload(t)
+	s := &sidecar{w: w}
+	if err := s.applySidecarConfig(map[int]int{18080: 30001, 17070: 30002}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := w.cluster.ConfigStore().Get(gvk.Sidecar, "b-0", "ns")
+	if cfg == nil {
+		t.Fatal("Sidecar resource was not created")
+	}
+	spec := cfg.Spec.(*networking.Sidecar)
+	if got := spec.WorkloadSelector.Labels[workloadLabel]; got != "b-0" {
+		t.Fatalf("expected the Sidecar to select the workload pod, got %q", got)
+	}
+	if got := spec.Egress[0].Bind; got != "127.0.1.2" {
+		t.Fatalf("expected outbound listeners on the outbound address, got %q", got)
+	}
+	for _, in := range spec.Ingress {
+		if in.Bind != "127.0.1.1" {
+			t.Fatalf("expected inbound listeners on the workload address, got %q", in.Bind)
+		}
+	}
+	if got := spec.Ingress[0].DefaultEndpoint; got != "0.0.0.0:30001" {
+		t.Fatalf("expected inbound traffic to go to the echo server port, got %q", got)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.cluster.ConfigStore().Get(gvk.Sidecar, "b-0", "ns") != nil {
+		t.Fatal("Sidecar resource was not deleted")
+	}
+}
+
+func TestSidecarBootstrap(t *testing.T) {
+	w := newTestWorkload(t)
+	s := &sidecar{
+		w:         w,
+		nodeID:    "sidecar~127.0.1.1~b-0.ns~ns.svc.cluster.local",
+		adminPort: 30000,
+	}
+	dir := t.TempDir()
+	file, err := s.writeBootstrap(dir, net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 15010})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrap := map[string]interface{}{}
+	if err := json.Unmarshal(b, &bootstrap); err != nil {
+		t.Fatal(err)
+	}
+
+	node := bootstrap["node"].(map[string]interface{})
+	if node["id"] != s.nodeID {
+		t.Fatalf("got node ID %v", node["id"])
+	}
+	metadata := node["metadata"].(map[string]interface{})
+	if metadata["INTERCEPTION_MODE"] != "NONE" {
+		t.Fatalf("got interception mode %v", metadata["INTERCEPTION_MODE"])
+	}
+	if labels := metadata["LABELS"].(map[string]interface{}); labels[workloadLabel] != "b-0" {
+		t.Fatalf("got labels %v", labels)
+	}
+	if !strings.Contains(string(b), `"port_value":15010`) {
+		t.Fatal("expected the bootstrap to connect to the local istiod")
+	}
+	admin := bootstrap["admin"].(map[string]interface{})["address"].(map[string]interface{})["socket_address"]
+	if port := admin.(map[string]interface{})["port_value"]; port != float64(30000) {
+		t.Fatalf("got admin port %v", port)
+	}
+	listeners := bootstrap["static_resources"].(map[string]interface{})["listeners"].([]interface{})
+	if len(listeners) == 0 {
+		t.Fatal("expected static listeners")
+	}
+	for _, l := range listeners {
+		socket := l.(map[string]interface{})["address"].(map[string]interface{})["socket_address"].(map[string]interface{})
+		if socket["address"] != "127.0.1.1" {
+			t.Fatalf("expected static listeners on the workload address, got %v", socket["address"])
+		}
+	}
+}
@@ -0,0 +1,409 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path"
+	"strconv"
+
+	"github.com/hashicorp/go-multierror"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/echo/client"
+	echoCommon "istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/server"
+	"istio.io/istio/pkg/test/env"
+	localcluster "istio.io/istio/pkg/test/framework/components/cluster/local"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/resource"
+)
+
+var _ echo.Workload = &workload{}
+
+// workload is an echo server running in the test process, listening on its own loopback address. Unless
+// sidecar injection is disabled for its subset, an Envoy sidecar listens on the instance ports of the address
+// and the echo server listens on other ports behind it.
+type workload struct {
+	*client.Instance
+
+	cfg     echo.Config
+	subset  echo.SubsetConfig
+	cluster *localcluster.Cluster
+	ip      string
+	podName string
+	server  *server.Instance
+
+	// outboundIP is the address of the outbound listeners of the sidecar, or empty without a sidecar.
+	outboundIP string
+	sidecar    *sidecar
+}
+
+func newWorkload(ctx resource.Context, cfg echo.Config, subset echo.SubsetConfig, index int) (w *workload, err error) {
+	c, ok := cfg.Cluster.(*localcluster.Cluster)
+	if !ok {
+		return nil, fmt.Errorf("cluster %s is not a local cluster", cfg.Cluster.Name())
+	}
+	ip, err := c.AllocateIP()
+	if err != nil {
+		return nil, err
+	}
+	name := cfg.Service
+	if subset.Version != "" {
+		name += "-" + subset.Version
+	}
+	w = &workload{
+		cfg:     cfg,
+		subset:  subset,
+		cluster: c,
+		ip:      ip,
+		podName: fmt.Sprintf("%s-%d", name, index),
+	}
+	if w.hasSidecar() {
+		if w.outboundIP, err = c.AllocateIP(); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		if err != nil {
+			_ = w.Close()
+		}
+	}()
+
+	appPorts, err := w.appPorts()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.start(ctx, appPorts); err != nil {
+		return nil, err
+	}
+	if err := w.register(); err != nil {
+		return nil, err
+	}
+	if w.hasSidecar() {
+		if err := w.startSidecar(ctx, appPorts); err != nil {
+			_ = w.unregister()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// hasSidecar returns false if sidecar injection is disabled for the subset of the workload.
+func (w *workload) hasSidecar() bool {
+	return w.subset.Annotations == nil || w.subset.Annotations.GetBool(echo.SidecarInject)
+}
+
+// appPorts maps each instance port of the service to the port the echo server listens on. Without a sidecar
+// they are the same; with one, the instance ports belong to Envoy.
+func (w *workload) appPorts() (map[int]int, error) {
+	out := map[int]int{}
+	for _, p := range w.cfg.Ports {
+		out[p.InstancePort] = p.InstancePort
+		if w.hasSidecar() {
+			port, err := freePort(w.ip)
+			if err != nil {
+				return nil, err
+			}
+			out[p.InstancePort] = port
+		}
+	}
+	return out, nil
+}
+
+// startSidecar starts the Envoy sidecar of the workload, once its pod is known to istiod.
+func (w *workload) startSidecar(ctx resource.Context, appPorts map[int]int) error {
+	i, err := istio.Get(ctx)
+	if err != nil {
+		return err
+	}
+	local, ok := i.(istio.LocalInstance)
+	if !ok {
+		return fmt.Errorf("istio is not deployed in the local environment")
+	}
+	dir, err := ctx.CreateTmpDirectory(w.podName + "-envoy")
+	if err != nil {
+		return err
+	}
+	w.sidecar, err = newSidecar(w, dir, local.DiscoveryAddress(), appPorts)
+	return err
+}
+
+// start starts the echo server and connects the client to it. The client bypasses the sidecar, like port
+// forwarding to a pod does in a cluster.
+func (w *workload) start(ctx resource.Context, appPorts map[int]int) error {
+	ports := make(echoCommon.PortList, 0, len(w.cfg.Ports)+len(w.cfg.WorkloadOnlyPorts))
+	bindIP := map[int]struct{}{}
+	useTLS := false
+	for _, p := range w.cfg.Ports {
+		ports = append(ports, &echoCommon.Port{
+			Name:        p.Name,
+			Port:        appPorts[p.InstancePort],
+			Protocol:    p.Protocol,
+			TLS:         p.TLS,
+			ServerFirst: p.ServerFirst,
+			InstanceIP:  true,
+		})
+		bindIP[appPorts[p.InstancePort]] = struct{}{}
+		useTLS = useTLS || p.TLS
+	}
+	for _, p := range w.cfg.WorkloadOnlyPorts {
+		ports = append(ports, &echoCommon.Port{
+			Name:        "workload-only-" + strconv.Itoa(p.Port),
+			Port:        p.Port,
+			Protocol:    p.Protocol,
+			TLS:         p.TLS,
+			ServerFirst: p.ServerFirst,
+			InstanceIP:  true,
+		})
+		bindIP[p.Port] = struct{}{}
+		useTLS = useTLS || p.TLS
+	}
+
+	var certFile, keyFile string
+	if useTLS {
+		var err error
+		if certFile, keyFile, err = w.writeCerts(ctx); err != nil {
+			return err
+		}
+	}
+
+	w.server = server.New(server.Config{
+		Ports:          ports,
+		BindIPPortsMap: bindIP,
+		TLSCert:        certFile,
+		TLSKey:         keyFile,
+		Version:        w.subset.Version,
+		Cluster:        w.cluster.Name(),
+		Dialer:         services.dialer(w.outboundIP),
+		InstanceIP:     w.ip,
+	})
+	if err := w.server.Start(); err != nil {
+		return fmt.Errorf("failed starting echo server %s: %v", w.podName, err)
+	}
+
+	grpcPort := common.GetPortForProtocol(&w.cfg, protocol.GRPC)
+	var tls *echoCommon.TLSSettings
+	if grpcPort.TLS {
+		tls = w.cfg.TLSSettings
+	}
+	c, err := client.New(net.JoinHostPort(w.ip, strconv.Itoa(appPorts[grpcPort.InstancePort])), tls)
+	if err != nil {
+		return err
+	}
+	w.Instance = c
+	return nil
+}
+
+// writeCerts writes the certificate and key from the TLS settings to files for the echo server. Without TLS
+// settings, the same test certificate that is baked into the echo image is used.
+func (w *workload) writeCerts(ctx resource.Context) (string, string, error) {
+	if w.cfg.TLSSettings == nil || w.cfg.TLSSettings.ProxyProvision {
+		dir := path.Join(env.IstioSrc, "tests/testdata/certs")
+		return path.Join(dir, "cert.crt"), path.Join(dir, "cert.key"), nil
+	}
+	dir, err := ctx.CreateTmpDirectory(w.podName)
+	if err != nil {
+		return "", "", err
+	}
+	certFile := path.Join(dir, "cert-chain.pem")
+	keyFile := path.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, []byte(w.cfg.TLSSettings.ClientCert), 0o600); err != nil {
+		return "", "", err
+	}
+	if err := ioutil.WriteFile(keyFile, []byte(w.cfg.TLSSettings.Key), 0o600); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// register makes the workload visible to istiod, as a ready pod and an endpoint of its service, and to
+// callers through the resolver.
+func (w *workload) register() error {
+	ns := w.cfg.Namespace.Name()
+	serviceAccount := "default"
+	if w.cfg.ServiceAccount {
+		serviceAccount = w.cfg.Service
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      w.podName,
+			Namespace: ns,
+			Labels:    w.labels(),
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: serviceAccount,
+			NodeName:           w.cluster.Name(),
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: w.ip,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+	if _, err := w.cluster.Kube().CoreV1().Pods(ns).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	if err := w.updateEndpoints(func(subset *corev1.EndpointSubset) {
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{
+			IP: w.ip,
+			TargetRef: &corev1.ObjectReference{
+				Kind:      "Pod",
+				Name:      w.podName,
+				Namespace: ns,
+			},
+		})
+	}); err != nil {
+		return err
+	}
+	services.add(w.cfg, w.ip)
+	return nil
+}
+
+// labels returns the labels of the pod of the workload.
+func (w *workload) labels() map[string]string {
+	labels := map[string]string{
+		"app":         w.cfg.Service,
+		workloadLabel: w.podName,
+	}
+	if w.subset.Version != "" {
+		labels["version"] = w.subset.Version
+	}
+	return labels
+}
+
+// updateEndpoints applies f to the single subset of the Endpoints of the workload's service.
+func (w *workload) updateEndpoints(f func(*corev1.EndpointSubset)) error {
+	endpoints := w.cluster.Kube().CoreV1().Endpoints(w.cfg.Namespace.Name())
+	ep, err := endpoints.Get(context.TODO(), w.cfg.Service, metav1.GetOptions{})
+	create := kerrors.IsNotFound(err)
+	if err != nil && !create {
+		return err
+	}
+	if create {
+		ep = &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      w.cfg.Service,
+				Namespace: w.cfg.Namespace.Name(),
+			},
+		}
+	}
+	if len(ep.Subsets) == 0 {
+		subset := corev1.EndpointSubset{}
+		for _, p := range w.cfg.Ports {
+			subset.Ports = append(subset.Ports, corev1.EndpointPort{
+				Name:     p.Name,
+				Port:     int32(p.InstancePort),
+				Protocol: corev1.ProtocolTCP,
+			})
+		}
+		ep.Subsets = []corev1.EndpointSubset{subset}
+	}
+	f(&ep.Subsets[0])
+	if create {
+		_, err = endpoints.Create(context.TODO(), ep, metav1.CreateOptions{})
+	} else {
+		_, err = endpoints.Update(context.TODO(), ep, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// unregister removes the workload from the cluster and the resolver.
+func (w *workload) unregister() error {
+	services.remove(w.cfg, w.ip)
+	var errs error
+	err := w.cluster.Kube().CoreV1().Pods(w.cfg.Namespace.Name()).Delete(context.TODO(), w.podName, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		errs = multierror.Append(errs, err)
+	}
+	err = w.updateEndpoints(func(subset *corev1.EndpointSubset) {
+		addresses := subset.Addresses[:0]
+		for _, a := range subset.Addresses {
+			if a.IP != w.ip {
+				addresses = append(addresses, a)
+			}
+		}
+		subset.Addresses = addresses
+	})
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	return errs
+}
+
+// Close stops the sidecar, the echo server and its client.
+func (w *workload) Close() (err error) {
+	if w.sidecar != nil {
+		err = multierror.Append(err, w.sidecar.Close()).ErrorOrNil()
+	}
+	if w.Instance != nil {
+		err = multierror.Append(err, w.Instance.Close()).ErrorOrNil()
+	}
+	if w.server != nil {
+		err = multierror.Append(err, w.server.Close()).ErrorOrNil()
+	}
+	return
+}
+
+func (w *workload) PodName() string {
+	return w.podName
+}
+
+func (w *workload) Address() string {
+	return w.ip
+}
+
+// Sidecar returns the Envoy sidecar of the workload, or nil if sidecar injection is disabled.
+func (w *workload) Sidecar() echo.Sidecar {
+	if w.sidecar == nil {
+		return nil
+	}
+	return w.sidecar
+}
+
+// freePort returns a port that is currently not in use on the given address.
+func freePort(ip string) (int, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Logs returns nothing, as the echo server logs to the test output.
+func (w *workload) Logs() (string, error) {
+	return "", nil
+}
+
+func (w *workload) LogsOrFail(t test.Failer) string {
+	t.Helper()
+	logs, err := w.Logs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logs
+}
@@ -0,0 +1,74 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/cluster/clusterboot"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/scopes"
+)
+
+// ClusterName is the name of the single cluster of the local environment.
+const ClusterName = "local"
+
+// Environment is the implementation of the local environment. It has a single cluster, backed by fake
+// Kubernetes clients, which is served by an in-process istiod. Echo instances run on the loopback interface.
+type Environment struct {
+	id       resource.ID
+	ctx      resource.Context
+	clusters []cluster.Cluster
+}
+
+var _ resource.Environment = &Environment{}
+
+// New returns a new local environment.
+func New(ctx resource.Context) (resource.Environment, error) {
+	scopes.Framework.Infof("Test Framework Local environment")
+	e := &Environment{
+		ctx: ctx,
+	}
+	e.id = ctx.TrackResource(e)
+
+	clusters, err := clusterboot.NewFactory().With(cluster.Config{
+		Name: ClusterName,
+		Kind: cluster.Local,
+	}).Build()
+	if err != nil {
+		return nil, err
+	}
+	e.clusters = clusters
+
+	return e, nil
+}
+
+func (e *Environment) EnvironmentName() string {
+	return "Local"
+}
+
+func (e *Environment) IsMultinetwork() bool {
+	return false
+}
+
+func (e *Environment) Clusters() cluster.Clusters {
+	out := make([]cluster.Cluster, 0, len(e.clusters))
+	out = append(out, e.clusters...)
+	return out
+}
+
+// ID implements resource.Instance
+func (e *Environment) ID() resource.ID {
+	return e.id
+}
@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"errors"
+	"net"
+
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/istio/ingress"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+var errIngressNotSupported = errors.New("ingress gateways are not supported in the local environment")
+
+var _ ingress.Instance = unsupportedIngress{}
+
+// unsupportedIngress is the ingress of the local environment, where no gateway is deployed. Calls return
+// errIngressNotSupported, and the OrFail variants skip the test when possible.
+type unsupportedIngress struct{}
+
+func (unsupportedIngress) HTTPAddress() net.TCPAddr {
+	return net.TCPAddr{}
+}
+
+func (unsupportedIngress) HTTPSAddress() net.TCPAddr {
+	return net.TCPAddr{}
+}
+
+func (unsupportedIngress) TCPAddress() net.TCPAddr {
+	return net.TCPAddr{}
+}
+
+func (unsupportedIngress) DiscoveryAddress() net.TCPAddr {
+	return net.TCPAddr{}
+}
+
+func (unsupportedIngress) AddressForPort(int) net.TCPAddr {
+	return net.TCPAddr{}
+}
+
+func (unsupportedIngress) CallEcho(echo.CallOptions) (client.ParsedResponses, error) {
+	return nil, errIngressNotSupported
+}
+
+func (unsupportedIngress) CallEchoOrFail(t test.Failer, _ echo.CallOptions) client.ParsedResponses {
+	t.Helper()
+	skipOrFail(t)
+	return nil
+}
+
+func (unsupportedIngress) CallEchoWithRetry(echo.CallOptions, ...retry.Option) (client.ParsedResponses, error) {
+	return nil, errIngressNotSupported
+}
+
+func (unsupportedIngress) CallEchoWithRetryOrFail(t test.Failer, _ echo.CallOptions, _ ...retry.Option) client.ParsedResponses {
+	t.Helper()
+	skipOrFail(t)
+	return nil
+}
+
+func (unsupportedIngress) ProxyStats() (map[string]int, error) {
+	return nil, errIngressNotSupported
+}
+
+func (unsupportedIngress) PodID(int) (string, error) {
+	return "", errIngressNotSupported
+}
+
+func (unsupportedIngress) Namespace() string {
+	return ""
+}
+
+// skipOrFail skips the test if t supports it, and fails it otherwise.
+func skipOrFail(t test.Failer) {
+	t.Helper()
+	if s, ok := t.(interface{ Skip(args ...interface{}) }); ok {
+		s.Skip(errIngressNotSupported)
+		return
+	}
+	t.Fatal(errIngressNotSupported)
+}
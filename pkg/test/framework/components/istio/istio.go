@@ -122,7 +122,9 @@ func Deploy(ctx resource.Context, cfg *Config) (i Instance, err error) {
 		}
 	}()
 
-	if cfg.DeployHelm {
+	if ctx.Settings().Environment == resource.LocalEnvironment {
+		i, err = deployLocal(ctx, *cfg)
+	} else if cfg.DeployHelm {
 		i, err = deployWithHelm(ctx, ctx.Environment().(*kube.Environment), *cfg)
 	} else {
 		i, err = deploy(ctx, ctx.Environment().(*kube.Environment), *cfg)
@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/framework/components/cluster"
+	"istio.io/istio/pkg/test/framework/components/cluster/local"
+	"istio.io/istio/pkg/test/framework/components/istio/ingress"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/scopes"
+)
+
+// LocalInstance is the Istio control plane of the local environment: an in-process istiod serving xDS on
+// the loopback interface.
+type LocalInstance interface {
+	Instance
+
+	// DiscoveryAddress returns the address of the plaintext xDS server.
+	DiscoveryAddress() net.TCPAddr
+
+	// Discovery returns the in-process discovery server.
+	Discovery() *xds.DiscoveryServer
+}
+
+type localComponent struct {
+	id       resource.ID
+	settings Config
+
+	server     *xds.FakeDiscoveryServer
+	grpcServer *grpc.Server
+	address    net.TCPAddr
+}
+
+var (
+	_ io.Closer     = &localComponent{}
+	_ LocalInstance = &localComponent{}
+)
+
+// deployLocal starts an in-process istiod serving the config and workloads of the local cluster.
+func deployLocal(ctx resource.Context, cfg Config) (Instance, error) {
+	c, ok := ctx.Clusters().Default().(*local.Cluster)
+	if !ok {
+		return nil, fmt.Errorf("cluster %s is not a local cluster", ctx.Clusters().Default().Name())
+	}
+
+	i := &localComponent{
+		settings: cfg,
+	}
+	err := runWithContext(ctx, func(t test.Failer) {
+		i.server = xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+			KubeClient:        c,
+			ConfigStoreCaches: []model.ConfigStoreCache{c.ConfigStore()},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed starting local istiod: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	i.address = *listener.Addr().(*net.TCPAddr)
+	i.grpcServer = grpc.NewServer()
+	i.server.Discovery.Register(i.grpcServer)
+	go func() {
+		if err := i.grpcServer.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+			scopes.Framework.Errorf("local istiod xDS server failed: %v", err)
+		}
+	}()
+	scopes.Framework.Infof("Local istiod serving xDS on %s", i.address.String())
+
+	i.id = ctx.TrackResource(i)
+	return i, nil
+}
+
+// ID implements resource.Instance
+func (i *localComponent) ID() resource.ID {
+	return i.id
+}
+
+func (i *localComponent) Settings() Config {
+	return i.settings
+}
+
+// IngressFor returns an ingress whose calls fail with errIngressNotSupported, as ingress gateways are not
+// supported in the local environment.
+func (i *localComponent) IngressFor(cluster.Cluster) ingress.Instance {
+	return unsupportedIngress{}
+}
+
+// CustomIngressFor returns an ingress whose calls fail with errIngressNotSupported, as ingress gateways are
+// not supported in the local environment.
+func (i *localComponent) CustomIngressFor(cluster.Cluster, string, string) ingress.Instance {
+	return unsupportedIngress{}
+}
+
+func (i *localComponent) RemoteDiscoveryAddressFor(cluster.Cluster) (net.TCPAddr, error) {
+	return i.address, nil
+}
+
+func (i *localComponent) DiscoveryAddress() net.TCPAddr {
+	return i.address
+}
+
+func (i *localComponent) Discovery() *xds.DiscoveryServer {
+	return i.server.Discovery
+}
+
+func (i *localComponent) Close() error {
+	if i.grpcServer != nil {
+		i.grpcServer.Stop()
+	}
+	return nil
+}
+
+// runWithContext runs f with a test.Failer backed by the given context, so that fakes written for unit tests
+// can be used from suite setup functions. Cleanup functions run when the context is done, and a call to Fatal
+// ends f and is returned as an error.
+func runWithContext(ctx resource.Context, f func(t test.Failer)) error {
+	c := &contextFailer{ctx: ctx}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(c)
+	}()
+	<-done
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+type contextFailer struct {
+	ctx resource.Context
+
+	mu  sync.Mutex
+	err error
+}
+
+var _ test.Failer = &contextFailer{}
+
+func (c *contextFailer) Fail() {
+	c.Fatal("fail called")
+}
+
+func (c *contextFailer) FailNow() {
+	c.Fatal("fail now called")
+}
+
+func (c *contextFailer) Fatal(args ...interface{}) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = errors.New(fmt.Sprint(args...))
+	}
+	c.mu.Unlock()
+	scopes.Framework.Error(args...)
+	runtime.Goexit()
+}
+
+func (c *contextFailer) Fatalf(format string, args ...interface{}) {
+	c.Fatal(fmt.Sprintf(format, args...))
+}
+
+func (c *contextFailer) Log(args ...interface{}) {
+	scopes.Framework.Info(args...)
+}
+
+func (c *contextFailer) Logf(format string, args ...interface{}) {
+	scopes.Framework.Infof(fmt.Sprintf(format, args...))
+}
+
+func (c *contextFailer) TempDir() string {
+	dir, err := c.ctx.CreateTmpDirectory("local")
+	if err != nil {
+		c.Fatal(err)
+	}
+	return dir
+}
+
+func (c *contextFailer) Helper() {
+}
+
+func (c *contextFailer) Cleanup(f func()) {
+	c.ctx.Cleanup(f)
+}
@@ -28,13 +28,18 @@ const (
 
 	// Multicluster indicates that the test requires a multicluster configuration.
 	Multicluster Instance = "multicluster"
+
+	// Local indicates that the test can run in the local environment, against an in-process istiod and echo
+	// instances running on the loopback interface, without a Kubernetes cluster.
+	Local Instance = "local"
 )
 
 var all = NewSet(
 	Postsubmit,
 	CustomSetup,
 	Flaky,
-	Multicluster)
+	Multicluster,
+	Local)
 
 // Find the label with the given name
 func Find(name string) (Instance, bool) {
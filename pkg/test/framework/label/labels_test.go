@@ -58,6 +58,9 @@ func TestLabels(t *testing.T) {
 		{filter: "multicluster,customsetup", labels: NewSet(Multicluster), expected: false},
 		{filter: "+multicluster,+customsetup", labels: NewSet(Multicluster), expected: false},
 		{filter: "-multicluster", labels: NewSet(), expected: true},
+		{filter: "local", labels: NewSet(Local), expected: true},
+		{filter: "local", labels: NewSet(Multicluster), expected: false},
+		{filter: "-local", labels: NewSet(Local, Postsubmit), expected: false},
 		{filter: "-multicluster", labels: NewSet(Multicluster), expected: false},
 	}
 
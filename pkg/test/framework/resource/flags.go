@@ -52,6 +52,14 @@ func SettingsFromCommandLine(testID string) (*Settings, error) {
 				" -istio.test.deprecation_failure must not be used at the same time")
 	}
 
+	switch s.Environment {
+	case KubeEnvironment, LocalEnvironment:
+	case "":
+		s.Environment = KubeEnvironment
+	default:
+		return nil, fmt.Errorf("unknown environment %q for --istio.test.env", s.Environment)
+	}
+
 	if s.Revision != "" && s.IstioVersions != nil {
 		return nil,
 			fmt.Errorf("cannot use --istio.test.revision and --istio.test.versions at the same time," +
@@ -66,8 +74,8 @@ func init() {
 	flag.StringVar(&settingsFromCommandLine.BaseDir, "istio.test.work_dir", os.TempDir(),
 		"Local working directory for creating logs/temp files. If left empty, os.TempDir() is used.")
 
-	var env string
-	flag.StringVar(&env, "istio.test.env", "", "Deprecated. This flag does nothing")
+	flag.StringVar(&settingsFromCommandLine.Environment, "istio.test.env", settingsFromCommandLine.Environment,
+		fmt.Sprintf("The environment to run the tests in, either %q or %q.", KubeEnvironment, LocalEnvironment))
 
 	flag.BoolVar(&settingsFromCommandLine.NoCleanup, "istio.test.nocleanup", settingsFromCommandLine.NoCleanup,
 		"Do not cleanup resources after test completion")
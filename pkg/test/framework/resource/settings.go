@@ -27,6 +27,13 @@ import (
 const (
 	// maxTestIDLength is the maximum length allowed for testID.
 	maxTestIDLength = 30
+
+	// KubeEnvironment runs tests against one or more Kubernetes clusters.
+	KubeEnvironment = "kube"
+
+	// LocalEnvironment runs tests against an in-process istiod and echo instances on the loopback interface.
+	// Only suites labeled with label.Local are run.
+	LocalEnvironment = "local"
 )
 
 // Settings is the set of arguments to the test driver.
@@ -70,6 +77,9 @@ type Settings struct {
 	// The label selector, in parsed form.
 	Selector label.Selector
 
+	// Environment is the name of the environment to run the tests in, either KubeEnvironment or LocalEnvironment.
+	Environment string
+
 	// EnvironmentFactory allows caller to override the environment creation. If nil, a default is used based
 	// on the known environment names.
 	EnvironmentFactory EnvironmentFactory
@@ -112,7 +122,8 @@ func (s *Settings) Clone() *Settings {
 // DefaultSettings returns a default settings instance.
 func DefaultSettings() *Settings {
 	return &Settings{
-		RunID: uuid.New(),
+		RunID:       uuid.New(),
+		Environment: KubeEnvironment,
 	}
 }
 
@@ -122,6 +133,7 @@ func (s *Settings) String() string {
 
 	result += fmt.Sprintf("TestID:            %s\n", s.TestID)
 	result += fmt.Sprintf("RunID:             %s\n", s.RunID.String())
+	result += fmt.Sprintf("Environment:       %s\n", s.Environment)
 	result += fmt.Sprintf("NoCleanup:         %v\n", s.NoCleanup)
 	result += fmt.Sprintf("BaseDir:           %s\n", s.BaseDir)
 	result += fmt.Sprintf("Selector:          %v\n", s.Selector)
@@ -33,6 +33,7 @@ import (
 	kubelib "istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/test/framework/components/cluster"
 	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/environment/local"
 	"istio.io/istio/pkg/test/framework/config"
 	ferrors "istio.io/istio/pkg/test/framework/errors"
 	"istio.io/istio/pkg/test/framework/label"
@@ -72,6 +73,9 @@ var (
 		// Outside of standard Istio  GOPATH
 		".*/istio/tests/integration/",
 	)
+
+	// localSelector selects the suites that can run in the local environment.
+	localSelector = label.NewSelector([]label.Instance{label.Local}, nil)
 )
 
 // getSettingsFunc is a function used to extract the default settings for the Suite.
@@ -328,6 +332,12 @@ func (s *suiteImpl) run() (errLevel int) {
 		return s.doSkip(ctx)
 	}
 
+	// Only suites that opt in can run without a Kubernetes cluster.
+	if ctx.Settings().Environment == resource.LocalEnvironment && !localSelector.Selects(s.labels) {
+		s.Skip(fmt.Sprintf("Suite is not labeled %q and cannot run in the local environment", label.Local))
+		return s.doSkip(ctx)
+	}
+
 	start := time.Now()
 
 	defer func() {
@@ -499,6 +509,9 @@ func initRuntime(s *suiteImpl) error {
 }
 
 func newEnvironment(ctx resource.Context) (resource.Environment, error) {
+	if ctx.Settings().Environment == resource.LocalEnvironment {
+		return local.New(ctx)
+	}
 	s, err := kube.NewSettingsFromCommandLine()
 	if err != nil {
 		return nil, err
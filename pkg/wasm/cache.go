@@ -44,6 +44,12 @@ type Cache interface {
 	Cleanup()
 }
 
+// SignatureVerifyingCache is optionally implemented by a Cache that can verify the signature of Wasm modules.
+type SignatureVerifyingCache interface {
+	// VerifiesSignatures reports whether modules are only returned if their signature is trusted.
+	VerifiesSignatures() bool
+}
+
 // LocalFileCache for downloaded Wasm modules. Currently it stores the Wasm module as local file.
 type LocalFileCache struct {
 	// Map from Wasm module checksum to cache entry.
@@ -52,6 +58,9 @@ type LocalFileCache struct {
 	// http fetcher fetches Wasm module with HTTP get.
 	httpFetcher *HTTPFetcher
 
+	// verifier verifies the signature of downloaded modules, if set.
+	verifier *SignatureVerifier
+
 	// directory path used to store Wasm module.
 	dir string
 
@@ -98,6 +107,19 @@ func NewLocalFileCache(dir string, purgeInterval, moduleExpiry time.Duration) *L
 	return cache
 }
 
+// SetSignatureVerifier makes the cache only return modules with a detached signature trusted by the verifier.
+// The signature of a module is downloaded from the module URL with a ".sig" suffix added to its path. Signatures
+// of OCI images are not supported, as modules are only fetched over HTTP. It must be called before the cache is
+// used.
+func (c *LocalFileCache) SetSignatureVerifier(verifier *SignatureVerifier) {
+	c.verifier = verifier
+}
+
+// VerifiesSignatures reports whether modules are only returned if their signature is trusted.
+func (c *LocalFileCache) VerifiesSignatures() bool {
+	return c.verifier != nil
+}
+
 // Get returns path the local Wasm module file.
 func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration) (string, error) {
 	url, err := url.Parse(downloadURL)
@@ -131,6 +153,13 @@ func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration
 			return "", fmt.Errorf("module downloaded from %v has checksum %v, which does not match: %v", downloadURL, dChecksum, checksum)
 		}
 
+		if c.verifier != nil {
+			if err := c.verifySignature(url, b, timeout); err != nil {
+				wasmRemoteFetchCount.With(resultTag.Value(signatureFailure)).Increment()
+				return "", err
+			}
+		}
+
 		wasmRemoteFetchCount.With(resultTag.Value(fetchSuccess)).Increment()
 
 		// TODO(bianpengyuan): Add sanity check on downloaded file to make sure it is a valid Wasm module.
@@ -148,6 +177,19 @@ func (c *LocalFileCache) Get(downloadURL, checksum string, timeout time.Duration
 	}
 }
 
+// verifySignature downloads the detached signature of the module and verifies it.
+func (c *LocalFileCache) verifySignature(moduleURL *url.URL, module []byte, timeout time.Duration) error {
+	sigURL := signatureURL(moduleURL)
+	sig, err := c.httpFetcher.Fetch(sigURL, timeout)
+	if err != nil {
+		return fmt.Errorf("%w: cannot fetch signature %v: %v", ErrSignatureVerification, sigURL, err)
+	}
+	if err := c.verifier.Verify(module, sig); err != nil {
+		return fmt.Errorf("module downloaded from %v: %w", moduleURL, err)
+	}
+	return nil
+}
+
 // Cleanup closes background Wasm module purge routine.
 func (c *LocalFileCache) Cleanup() {
 	close(c.stopChan)
@@ -15,6 +15,7 @@
 package wasm
 
 import (
+	"errors"
 	"sync"
 	"time"
 
@@ -159,6 +160,13 @@ func convert(resource *any.Any, cache Cache) (newExtensionConfig *any.Any, sendN
 	f, err := cache.Get(httpURI.GetUri(), remote.GetSha256(), timeout)
 	if err != nil {
 		status = fetchFailure
+		if errors.Is(err, ErrSignatureVerification) {
+			status = untrustedModule
+		}
+		if vc, ok := cache.(SignatureVerifyingCache); ok && vc.VerifiesSignatures() {
+			// Failing open would let Envoy fetch the module itself, without verifying its signature.
+			sendNack = true
+		}
 		wasmLog.Errorf("cannot fetch Wasm module %v: %v", remote.GetHttpUri().GetUri(), err)
 		return
 	}
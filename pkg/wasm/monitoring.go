@@ -22,6 +22,7 @@ const (
 	fetchSuccess     = "success"
 	downloadFailure  = "download_failure"
 	checksumMismatch = "checksum_mismatched"
+	signatureFailure = "signature_verification_failure"
 
 	// For Wasm conversion metric.
 	conversionSuccess   = "success"
@@ -29,6 +30,7 @@ const (
 	marshalFailure      = "marshal_failure"
 	fetchFailure        = "fetch_failure"
 	missRemoteFetchHint = "miss_remote_fetch_hint"
+	untrustedModule     = "untrusted_module"
 )
 
 var (
@@ -48,13 +50,13 @@ var (
 
 	wasmRemoteFetchCount = monitoring.NewSum(
 		"wasm_remote_fetch_count",
-		"number of Wasm remote fetches and results, including success, download failure, and checksum mismatch.",
+		"number of Wasm remote fetches and results, including success, download failure, checksum mismatch, and signature verification failure.",
 		monitoring.WithLabels(resultTag),
 	)
 
 	wasmConfigConversionCount = monitoring.NewSum(
 		"wasm_config_conversion_count",
-		"number of Wasm config conversion count and results, including success, no remote load, marshal failure, remote fetch failure, miss remote fetch hint.",
+		"number of Wasm config conversion count and results, including success, no remote load, marshal failure, remote fetch failure, miss remote fetch hint, untrusted module.",
 		monitoring.WithLabels(resultTag),
 	)
 
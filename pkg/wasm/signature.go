@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// signatureSuffix is appended to the path of a Wasm module URL to get the URL of its detached signature.
+const signatureSuffix = ".sig"
+
+// ErrSignatureVerification is returned, wrapped, if a Wasm module is not signed by any of the trusted keys.
+var ErrSignatureVerification = errors.New("wasm module signature verification failed")
+
+// SignatureVerifier verifies the detached signatures of Wasm modules against a set of trusted public keys.
+// The trusted keys are configured per proxy with the WASM_TRUSTED_KEYS proxy metadata; there is no mesh config
+// field for them.
+type SignatureVerifier struct {
+	keys []crypto.PublicKey
+}
+
+// NewSignatureVerifier creates a verifier trusting the given PEM encoded PKIX public keys. ECDSA, RSA and
+// Ed25519 keys are supported. It returns nil if no keys are given, in which case signatures are not verified.
+func NewSignatureVerifier(pemKeys []byte) (*SignatureVerifier, error) {
+	v := &SignatureVerifier{}
+	rest := bytes.TrimSpace(pemKeys)
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("invalid PEM encoded public key")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %v", err)
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+		v.keys = append(v.keys, key)
+		rest = bytes.TrimSpace(rest)
+	}
+	if len(v.keys) == 0 {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// Verify checks that the signature of the module was made by one of the trusted keys. The signature may be
+// raw or base64 encoded. ECDSA (ASN.1) and RSA (PKCS #1 v1.5) signatures are over the SHA-256 digest of the
+// module, Ed25519 signatures over the module itself.
+func (v *SignatureVerifier) Verify(module, signature []byte) error {
+	// Raw signatures may contain bytes that look like whitespace, so they are only trimmed for decoding.
+	sigs := [][]byte{signature}
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature))); err == nil {
+		sigs = append(sigs, decoded)
+	}
+	digest := sha256.Sum256(module)
+	for _, sig := range sigs {
+		if len(sig) == 0 {
+			continue
+		}
+		for _, key := range v.keys {
+			switch k := key.(type) {
+			case *ecdsa.PublicKey:
+				if ecdsa.VerifyASN1(k, digest[:], sig) {
+					return nil
+				}
+			case *rsa.PublicKey:
+				if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+					return nil
+				}
+			case ed25519.PublicKey:
+				if ed25519.Verify(k, module, sig) {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("%w: not signed by any of the %d trusted keys", ErrSignatureVerification, len(v.keys))
+}
+
+// signatureURL returns the URL of the detached signature of the Wasm module at the given URL.
+func signatureURL(moduleURL *url.URL) string {
+	u := *moduleURL
+	u.Path += signatureSuffix
+	u.RawPath = ""
+	return u.String()
+}
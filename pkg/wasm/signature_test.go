@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func pemPublicKey(t *testing.T, key crypto.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	module := []byte("wasm module")
+	digest := sha256.Sum256(module)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSig := ed25519.Sign(edKey, module)
+	_, untrustedKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := NewSignatureVerifier(nil); err != nil || v != nil {
+		t.Fatalf("expected no verifier without keys, got %v %v", v, err)
+	}
+	if _, err := NewSignatureVerifier([]byte("not a key")); err == nil {
+		t.Fatalf("expected invalid keys to be rejected")
+	}
+
+	v, err := NewSignatureVerifier(append(pemPublicKey(t, &ecKey.PublicKey), pemPublicKey(t, edPub)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name      string
+		module    []byte
+		signature []byte
+		wantErr   bool
+	}{
+		{name: "ecdsa", module: module, signature: ecSig},
+		{name: "ecdsa base64", module: module, signature: []byte(base64.StdEncoding.EncodeToString(ecSig) + "\n")},
+		{name: "ed25519", module: module, signature: edSig},
+		{name: "untrusted key", module: module, signature: ed25519.Sign(untrustedKey, module), wantErr: true},
+		{name: "modified module", module: []byte("other module"), signature: edSig, wantErr: true},
+		{name: "empty signature", module: module, wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := v.Verify(c.module, c.signature)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, want error %v", err, c.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrSignatureVerification) {
+				t.Fatalf("expected a signature verification error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWasmCacheSignature(t *testing.T) {
+	module := []byte("wasm module")
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/signed.wasm", "/unsigned.wasm":
+			_, _ = w.Write(module)
+		case "/signed.wasm.sig":
+			_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, module))))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	cache := NewLocalFileCache(t.TempDir(), DefaultWasmModulePurgeInteval, DefaultWasmModuleExpiry)
+	defer close(cache.stopChan)
+	v, err := NewSignatureVerifier(pemPublicKey(t, pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.SetSignatureVerifier(v)
+	if !cache.VerifiesSignatures() {
+		t.Fatalf("expected the cache to verify signatures")
+	}
+
+	if _, err := cache.Get(ts.URL+"/signed.wasm", "", 0); err != nil {
+		t.Fatalf("failed to get signed module: %v", err)
+	}
+	if _, err := cache.Get(ts.URL+"/unsigned.wasm", "", 0); !errors.Is(err, ErrSignatureVerification) {
+		t.Fatalf("expected unsigned module to fail signature verification, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	authnmodel "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/security"
+)
+
+// credentialStore holds the credentials referenced by DestinationRule credentialName, which the agent
+// fetches from Istiod on behalf of Envoy. The agent cannot read Kubernetes Secrets itself; Istiod
+// authorizes the request against the identity of the workload, as it does for gateways.
+type credentialStore struct {
+	mu sync.Mutex
+	// items holds the latest version of each credential received from Istiod.
+	items map[string]*security.SecretItem
+	// ready is closed once the credential has first been received.
+	ready map[string]chan struct{}
+	// watch is called with all requested credentials whenever a new one is requested.
+	watch func(resourceNames []string)
+}
+
+// IsCredentialResource reports whether the resource name refers to a credential fetched from Istiod.
+func IsCredentialResource(resourceName string) bool {
+	return strings.HasPrefix(resourceName, authnmodel.KubernetesSecretTypeURI)
+}
+
+// IsCredentialRootResource reports whether the resource name refers to the CA certificate of a
+// credential fetched from Istiod.
+func IsCredentialRootResource(resourceName string) bool {
+	return IsCredentialResource(resourceName) && strings.HasSuffix(resourceName, authnmodel.SdsCaSuffix)
+}
+
+// SetCredentialWatcher sets the function used to fetch credentials from Istiod. It is called with the
+// names of all requested credentials whenever a new credential is requested; received credentials must
+// be passed to UpdateCredential. Without a watcher, credentials cannot be served.
+func (sc *SecretManagerClient) SetCredentialWatcher(f func(resourceNames []string)) {
+	sc.credentials.mu.Lock()
+	sc.credentials.watch = f
+	names := sc.credentials.namesLocked()
+	sc.credentials.mu.Unlock()
+	if f != nil && len(names) > 0 {
+		f(names)
+	}
+}
+
+// UpdateCredential stores a credential received from Istiod and notifies the proxy, so that rotated
+// credentials are pushed to Envoy.
+func (sc *SecretManagerClient) UpdateCredential(item *security.SecretItem) {
+	sc.credentials.mu.Lock()
+	if sc.credentials.items == nil {
+		sc.credentials.items = map[string]*security.SecretItem{}
+	}
+	sc.credentials.items[item.ResourceName] = item
+	ch, f := sc.credentials.ready[item.ResourceName]
+	if !f {
+		// Istiod sent a credential we have not asked for yet; nobody can be waiting for it.
+		ch = make(chan struct{})
+		if sc.credentials.ready == nil {
+			sc.credentials.ready = map[string]chan struct{}{}
+		}
+		sc.credentials.ready[item.ResourceName] = ch
+	}
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+	sc.credentials.mu.Unlock()
+
+	resourceLog(item.ResourceName).Debugf("received credential from istiod")
+	sc.CallUpdateCallback(item.ResourceName)
+}
+
+// namesLocked returns the sorted names of all requested credentials. The caller must hold mu.
+func (s *credentialStore) namesLocked() []string {
+	names := make([]string, 0, len(s.ready))
+	for name := range s.ready {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// generateCredentialSecret returns the credential with the given resource name, requesting it from
+// Istiod and waiting for it if it has not been received yet. The first return value reports whether
+// the resource name refers to a credential.
+func (sc *SecretManagerClient) generateCredentialSecret(resourceName string) (bool, *security.SecretItem, error) {
+	if !IsCredentialResource(resourceName) {
+		return false, nil, nil
+	}
+
+	s := &sc.credentials
+	s.mu.Lock()
+	if s.watch == nil {
+		s.mu.Unlock()
+		return true, nil, fmt.Errorf("cannot fetch credential %s: the agent is not proxying XDS to istiod, "+
+			"or PILOT_ENABLE_SIDECAR_CREDENTIAL_NAME is not enabled for it", resourceName)
+	}
+	if item, f := s.items[resourceName]; f {
+		s.mu.Unlock()
+		return true, item, nil
+	}
+	ch, requested := s.ready[resourceName]
+	var names []string
+	if !requested {
+		if s.ready == nil {
+			s.ready = map[string]chan struct{}{}
+		}
+		ch = make(chan struct{})
+		s.ready[resourceName] = ch
+		names = s.namesLocked()
+	}
+	watch := s.watch
+	s.mu.Unlock()
+
+	if !requested {
+		resourceLog(resourceName).Infof("requesting credential from istiod")
+		watch(names)
+	}
+
+	select {
+	case <-ch:
+	case <-time.After(totalTimeout):
+		return true, nil, fmt.Errorf("timed out waiting for credential %s from istiod", resourceName)
+	case <-sc.stop:
+		return true, nil, fmt.Errorf("secret manager closed while waiting for credential %s", resourceName)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return true, s.items[resourceName], nil
+}
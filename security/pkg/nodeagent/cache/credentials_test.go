@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	"istio.io/istio/pkg/security"
+)
+
+func TestGenerateCredentialSecret(t *testing.T) {
+	const resourceName = "kubernetes://db-client"
+
+	var mu sync.Mutex
+	var updates []string
+	sc := createCache(t, nil, func(resourceName string) {
+		mu.Lock()
+		defer mu.Unlock()
+		updates = append(updates, resourceName)
+	}, security.Options{})
+
+	if _, err := sc.GenerateSecret(resourceName); err == nil {
+		t.Fatalf("expected an error without a credential watcher")
+	}
+
+	watched := make(chan []string, 1)
+	sc.SetCredentialWatcher(func(resourceNames []string) {
+		watched <- resourceNames
+		// Istiod responds with the credential
+		go sc.UpdateCredential(&security.SecretItem{
+			ResourceName:     resourceName,
+			CertificateChain: []byte("cert"),
+			PrivateKey:       []byte("key"),
+		})
+	})
+
+	secret, err := sc.GenerateSecret(resourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(secret.CertificateChain, []byte("cert")) || !bytes.Equal(secret.PrivateKey, []byte("key")) {
+		t.Fatalf("unexpected secret: %+v", secret)
+	}
+	if got := <-watched; !reflect.DeepEqual(got, []string{resourceName}) {
+		t.Fatalf("expected watch for %v, got %v", resourceName, got)
+	}
+
+	// A rotated credential replaces the stored one and notifies the proxy
+	sc.UpdateCredential(&security.SecretItem{
+		ResourceName:     resourceName,
+		CertificateChain: []byte("rotated-cert"),
+		PrivateKey:       []byte("rotated-key"),
+	})
+	secret, err = sc.GenerateSecret(resourceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(secret.CertificateChain, []byte("rotated-cert")) {
+		t.Fatalf("expected rotated secret, got %+v", secret)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(updates, []string{resourceName, resourceName}) {
+		t.Fatalf("expected two updates for %v, got %v", resourceName, updates)
+	}
+}
+
+func TestIsCredentialRootResource(t *testing.T) {
+	cases := map[string]bool{
+		"kubernetes://db-client":         false,
+		"kubernetes://db-client-cacert":  true,
+		"file-root:/etc/certs/ca.pem":    false,
+		security.RootCertReqResourceName: false,
+	}
+	for name, want := range cases {
+		if got := IsCredentialRootResource(name); got != want {
+			t.Errorf("IsCredentialRootResource(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
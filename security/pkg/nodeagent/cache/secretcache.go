@@ -64,11 +64,14 @@ const (
 // Istiod will serve an SDS response, by selecting the appropriate cluster in the SDS configuration
 // it serves.
 //
-// SecretManagerClient supports two modes of retrieving certificate (potentially at the same time):
+// SecretManagerClient supports three modes of retrieving certificate (potentially at the same time):
 // * File based certificates. If certs are mounted under well-known path /etc/certs/{key,cert,root-cert.pem},
 //   requests for `default` and `ROOTCA` will automatically read from these files. Additionally,
 //   certificates from Gateway/DestinationRule can also be served. This is done by parsing resource
 //   names in accordance with model.SdsCertificateConfig (file-cert: and file-root:).
+// * Credentials from Istiod. Sidecar DestinationRule credentialName (kubernetes://) resources are
+//   requested from Istiod through the credential watcher, subject to the same authorization as
+//   gateways, and served once received. Updates from Istiod trigger the notifyCallback.
 // * On demand CSRs. This is used only for the `default` certificate. When this resource is
 //   requested, a CSR will be sent to the configured caClient.
 //
@@ -113,6 +116,9 @@ type SecretManagerClient struct {
 	// Dynamically configured Trust Bundle
 	configTrustBundle []byte
 
+	// credentials referenced by credentialName, fetched from Istiod
+	credentials credentialStore
+
 	// queue maintains all certificate rotation events that need to be triggered when they are about to expire
 	queue queue.Delayed
 	stop  chan struct{}
@@ -271,6 +277,14 @@ func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *secu
 		return ns, nil
 	}
 
+	// Then try credentials fetched from Istiod.
+	if sdsFromIstiod, ns, err := sc.generateCredentialSecret(resourceName); sdsFromIstiod {
+		if err != nil {
+			return nil, err
+		}
+		return ns, nil
+	}
+
 	ns := sc.getCachedSecret(resourceName)
 	if ns != nil {
 		return ns, nil
@@ -36,6 +36,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/nodeagent/cache"
 	"istio.io/pkg/log"
 )
 
@@ -207,7 +208,8 @@ func toEnvoySecret(s *security.SecretItem) *tls.Secret {
 	}
 
 	cfg, ok := model.SdsCertificateConfigFromResourceName(s.ResourceName)
-	if s.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) {
+	if s.ResourceName == security.RootCertReqResourceName || (ok && cfg.IsRootCertificate()) ||
+		cache.IsCredentialRootResource(s.ResourceName) {
 		secret.Type = &tls.Secret_ValidationContext{
 			ValidationContext: &tls.CertificateValidationContext{
 				TrustedCa: &core.DataSource{
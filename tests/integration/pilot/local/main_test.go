@@ -0,0 +1,78 @@
+// +build integ
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/framework"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/echoboot"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/components/namespace"
+	"istio.io/istio/pkg/test/framework/label"
+	"istio.io/istio/pkg/test/framework/resource"
+)
+
+var (
+	ns namespace.Instance
+	a  echo.Instance
+	b  echo.Instance
+)
+
+// Service ports are above 1024, so that sidecars in the local environment can bind them without privileges.
+var ports = []echo.Port{
+	{Name: "http", Protocol: protocol.HTTP, ServicePort: 8080, InstancePort: 18080},
+	{Name: "grpc", Protocol: protocol.GRPC, ServicePort: 7070, InstancePort: 17070},
+	{Name: "tcp", Protocol: protocol.TCP, ServicePort: 9090, InstancePort: 19090},
+}
+
+// TestMain defines the entrypoint for pilot tests that can run in the local environment, with
+// --istio.test.env=local, as well as on Kubernetes.
+func TestMain(m *testing.M) {
+	framework.
+		NewSuite(m).
+		Label(label.Local).
+		RequireSingleCluster().
+		Setup(istio.Setup(nil, nil)).
+		Setup(setupApps).
+		Run()
+}
+
+func setupApps(ctx resource.Context) (err error) {
+	ns, err = namespace.New(ctx, namespace.Config{
+		Prefix: "local",
+		Inject: true,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = echoboot.NewBuilder(ctx).
+		With(&a, echo.Config{
+			Service:   "a",
+			Namespace: ns,
+			Ports:     ports,
+		}).
+		With(&b, echo.Config{
+			Service:   "b",
+			Namespace: ns,
+			Ports:     ports,
+			Subsets:   []echo.SubsetConfig{{Version: "v1"}, {Version: "v2"}},
+		}).
+		Build()
+	return err
+}
@@ -0,0 +1,112 @@
+// +build integ
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"fmt"
+	"testing"
+
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/framework"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+func TestReachability(t *testing.T) {
+	framework.NewTest(t).
+		Features("traffic.reachability").
+		Run(func(t framework.TestContext) {
+			for _, port := range ports {
+				port := port
+				t.NewSubTest(port.Name).Run(func(t framework.TestContext) {
+					a.CallWithRetryOrFail(t, echo.CallOptions{
+						Target:    b,
+						PortName:  port.Name,
+						Count:     4,
+						Validator: echo.ExpectOK(),
+					})
+				})
+			}
+		})
+}
+
+func TestRouting(t *testing.T) {
+	framework.NewTest(t).
+		Features("traffic.routing").
+		Run(func(t framework.TestContext) {
+			t.Config().ApplyYAMLOrFail(t, ns.Name(), `
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: b
+spec:
+  host: b
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+  - name: v2
+    labels:
+      version: v2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: b
+spec:
+  hosts:
+  - b
+  http:
+  - route:
+    - destination:
+        host: b
+        subset: v2
+`)
+			// Only the sidecar of a can send all calls to v2, as the echo server would pick a random workload.
+			a.CallWithRetryOrFail(t, echo.CallOptions{
+				Target:   b,
+				PortName: "http",
+				Count:    10,
+				Validator: echo.And(echo.ExpectOK(), echo.ValidatorFunc(func(resp client.ParsedResponses, _ error) error {
+					return resp.Check(func(i int, r *client.ParsedResponse) error {
+						if r.Version != "v2" {
+							return fmt.Errorf("response %d: expected version v2, got %q", i, r.Version)
+						}
+						return nil
+					})
+				})),
+			})
+		})
+}
+
+func TestSidecar(t *testing.T) {
+	framework.NewTest(t).
+		Features("usability.observability.proxy-config").
+		Run(func(t framework.TestContext) {
+			for _, w := range a.WorkloadsOrFail(t) {
+				sidecar := w.Sidecar()
+				if sidecar == nil {
+					t.Fatalf("workload %s has no sidecar", w.Address())
+				}
+				if sidecar.NodeID() == "" {
+					t.Fatal("sidecar has no node ID")
+				}
+				listeners := sidecar.ListenersOrFail(t)
+				if len(listeners.ListenerStatuses) == 0 {
+					t.Fatal("sidecar has no listeners")
+				}
+			}
+		})
+}